@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/adapters/database/sqlite"
+	"lion-parcel-test/internal/app"
+)
+
+// runBackup snapshots the database into backup.dir, e.g. `backup` or `backup -list`.
+// It works while the server is running
+func runBackup(ctx context.Context, app *app.App, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	list := flags.Bool("list", false, "list the backups instead of creating one")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		return printResponse(app.Usecases.SystemUsecase.GetBackups(ctx))
+	}
+
+	return printResponse(app.Usecases.SystemUsecase.CreateBackup(ctx))
+}
+
+// runRestore replaces the sqlite database with a backup, e.g. `restore -file backups/movies-20240101T000000.000Z.db`.
+// It refuses while a server still has the database open
+func runRestore(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := flags.String("file", "", "backup to restore")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	if driver := config.Cfg.Database.Driver; driver != "" && driver != constant.DatabaseDriverSqlite {
+		return fmt.Errorf("restore only supports %s, back up and restore %s with its own tools", constant.DatabaseDriverSqlite, driver)
+	}
+
+	pending, err := sqlite.Restore(ctx, *file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %s\n", *file)
+	for _, status := range pending {
+		fmt.Printf("pending migration %04d_%s, applied on the next start or with `migrate up`\n", status.Version, status.Name)
+	}
+
+	return nil
+}
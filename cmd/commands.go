@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"lion-parcel-test/internal/app"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// runCommand runs a cli subcommand instead of the http server
+func runCommand(ctx context.Context, app *app.App, name string, args []string) error {
+	switch name {
+	case "import":
+		return runImport(ctx, app, args)
+	case "export":
+		return runExport(ctx, app, args)
+	case "backup":
+		return runBackup(ctx, app, args)
+	default:
+		return fmt.Errorf("unknown command %s, expected import, export, backup, restore or migrate", name)
+	}
+}
+
+// runImport loads a catalog file, e.g. `import -file catalog.csv -mode batch -batch-size 50 -dry-run`
+func runImport(ctx context.Context, app *app.App, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "catalog file to import, - reads stdin")
+	format := flags.String("format", "", "csv or jsonl, defaults to the file extension")
+	dryRun := flags.Bool("dry-run", false, "only validate the rows")
+	mode := flags.String("mode", "atomic", "atomic imports every row or none, batch commits batch-size rows at a time")
+	batchSize := flags.Int("batch-size", 100, "rows per transaction in batch mode")
+	userId := flags.Int("user-id", 0, "admin recorded in the revisions, 0 for the cli")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	var reader io.Reader = os.Stdin
+	if *file != "-" {
+		catalog, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer catalog.Close()
+
+		reader = catalog
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	resp := app.Usecases.MovieUsecase.ImportMovies(ctx, &usecase.ImportMoviesRequest{
+		Format:    *format,
+		Reader:    reader,
+		DryRun:    *dryRun,
+		Mode:      *mode,
+		BatchSize: *batchSize,
+		UserId:    *userId,
+	})
+
+	return printResponse(resp)
+}
+
+// runExport writes the catalog, e.g. `export -file movies.jsonl`
+func runExport(ctx context.Context, app *app.App, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	file := flags.String("file", "-", "file to write, - writes stdout")
+	format := flags.String("format", "", "csv or jsonl, defaults to the file extension or jsonl")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+	if *format == "" {
+		*format = "jsonl"
+	}
+
+	var writer io.Writer = os.Stdout
+	if *file != "-" {
+		out, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		writer = out
+	}
+
+	resp := app.Usecases.MovieUsecase.ExportMovies(ctx, &usecase.ExportMoviesRequest{
+		Format: *format,
+		Writer: writer,
+	})
+
+	// keep stdout clean for the exported catalog
+	if *file == "-" && resp.Code == "00" {
+		return nil
+	}
+
+	return printResponse(resp)
+}
+
+func printResponse(resp *dto.Response) error {
+	out, _ := jsoniter.MarshalIndent(resp, "", "  ")
+	fmt.Fprintln(os.Stderr, string(out))
+
+	if resp.Code != "00" {
+		return fmt.Errorf("%s: %s", resp.Code, resp.Desc)
+	}
+
+	return nil
+}
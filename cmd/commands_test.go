@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/e2e"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunImport(t *testing.T) {
+	h := e2e.New(t)
+	admin := h.AdminToken("admin")
+
+	if err := os.MkdirAll(h.Config.Storage.MovieDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"heat.mp4", "ronin.mp4"} {
+		if err := os.WriteFile(filepath.Join(h.Config.Storage.MovieDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "catalog.jsonl")
+	catalog := `{"title":"Heat","description":"heist","duration":170,"artists":"Al Pacino","genres":"Crime","file_name":"heat.mp4","status":"published"}
+{"title":"Ronin","description":"heist","duration":122,"artists":"Robert De Niro","genres":"Crime","file_name":"ronin.mp4","status":"published"}
+`
+	if err := os.WriteFile(file, []byte(catalog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	movies := func() int {
+		resp := h.Expect(h.Request(http.MethodGet, constant.RouteApiV1+"/admin/movies?pageSize=100", admin, nil), http.StatusOK, "00")
+
+		var list struct {
+			Movies []e2e.Movie `json:"movies"`
+		}
+		h.Decode(resp, &list)
+
+		return len(list.Movies)
+	}
+
+	// the flags reach the usecase as they are, it has to refuse them itself
+	for _, args := range [][]string{
+		{"-file", file, "-mode", "atmoic"},
+		{"-file", file, "-mode", "batch", "-batch-size", "-1"},
+		{"-file", file, "-mode", "batch", "-batch-size", "10001"},
+	} {
+		err := runImport(context.Background(), h.App, args)
+		if err == nil || !strings.HasPrefix(err.Error(), "VE") {
+			t.Fatalf("expected %v to be refused, got %v", args, err)
+		}
+	}
+	if n := movies(); n != 0 {
+		t.Fatalf("expected nothing imported by the refused runs, got %d movies", n)
+	}
+
+	if err := runImport(context.Background(), h.App, []string{"-file", file, "-mode", "batch", "-batch-size", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := movies(); n != 2 {
+		t.Fatalf("expected both movies imported, got %d", n)
+	}
+}
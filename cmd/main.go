@@ -4,6 +4,7 @@ import (
 	"context"
 	"lion-parcel-test/internal/app"
 	"lion-parcel-test/internal/delivery/http"
+	"lion-parcel-test/internal/delivery/scheduler"
 	"log"
 	"os"
 	"os/signal"
@@ -12,7 +13,8 @@ import (
 )
 
 func main() {
-	ctx, _ := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	defer func() {
 		r := recover()
 		if r != nil {
@@ -20,23 +22,68 @@ func main() {
 		}
 	}()
 
+	// migrate runs before the app, which refuses to start on a dirty or newer schema
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// restore replaces the database file, the app must not have it open
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		if err := runRestore(ctx, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	app, err := app.NewApp(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// `go run ./cmd <command> [flags]` runs a cli command instead of the server
+	if len(os.Args) > 1 {
+		err = runCommand(ctx, app, os.Args[1], os.Args[2:])
+		app.Close(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	httpServer, err := http.NewHttpServer(app)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	jobScheduler, err := scheduler.NewScheduler(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go jobScheduler.Run()
+
 	wait := gracefulShutdown(context.Background(), 30*time.Second, []operationNew{
+		{
+			name: "readiness",
+			op: func(ctx context.Context) error {
+				return app.Drain(ctx)
+			},
+		},
 		{
 			name: "server",
 			op: func(ctx context.Context) error {
 				return httpServer.Stop(ctx)
 			},
 		},
+		{
+			name: "scheduler",
+			op: func(ctx context.Context) error {
+				return jobScheduler.Stop(ctx)
+			},
+		},
 		{
 			name: "app",
 			op: func(ctx context.Context) error {
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/adapters/database/postgres"
+	"lion-parcel-test/internal/adapters/database/sqlite"
+	"lion-parcel-test/pkg/migrate"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// runMigrate manages the schema without starting the app, so it also works on a dirty or newer database,
+// e.g. `migrate up`, `migrate down -steps 2`, `migrate status`, `migrate create add_movie_rating`, `migrate force 1`
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected migrate up, down, status, create or force")
+	}
+
+	err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	// create only writes files, it doesn't need the database
+	if args[0] == "create" {
+		return runMigrateCreate(args[1:])
+	}
+
+	db, migrator, err := openMigrator()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		printMigrations("applied", applied)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return nil
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		all := flags.Bool("all", false, "roll back every migration")
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if *all {
+			*steps = int(^uint(0) >> 1)
+		}
+		if *steps < 1 {
+			return fmt.Errorf("-steps must be at least 1")
+		}
+
+		reverted, err := migrator.Down(ctx, *steps)
+		printMigrations("rolled back", reverted)
+		return err
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	case "force":
+		if len(args) != 2 {
+			return fmt.Errorf("expected migrate force <version>")
+		}
+
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %s", args[1])
+		}
+
+		return migrator.Force(ctx, version)
+	default:
+		return fmt.Errorf("unknown migrate command %s, expected up, down, status, create or force", args[0])
+	}
+}
+
+// runMigrateCreate writes the next numbered up and down files, e.g. `migrate create -dir path add_movie_rating`
+func runMigrateCreate(args []string) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", migrationsDir(), "migrations directory, defaults to the one of database.driver")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected migrate create <name>")
+	}
+
+	files, err := migrate.Create(*dir, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		fmt.Println("created", file)
+	}
+
+	return nil
+}
+
+func printMigrations(action string, migrations []migrate.Migration) {
+	for _, migration := range migrations {
+		fmt.Printf("%s %04d_%s\n", action, migration.Version, migration.Name)
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Dirty:
+			state = "dirty"
+		case status.Missing:
+			state = "missing file"
+		case status.Mismatch:
+			state = "checksum mismatch"
+		case status.Applied:
+			state = "applied"
+		}
+
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(out, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+
+	return out.Flush()
+}
+
+// openMigrator opens the database.driver database without checking its schema
+func openMigrator() (*sql.DB, *migrate.Migrator, error) {
+	open, newMigrator := sqlite.OpenDatabase, sqlite.NewMigrator
+	if config.Cfg.Database.Driver == constant.DatabaseDriverPostgres {
+		open, newMigrator = postgres.OpenDatabase, postgres.NewMigrator
+	}
+
+	db, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, migrator, nil
+}
+
+func migrationsDir() string {
+	if config.Cfg.Database.Driver == constant.DatabaseDriverPostgres {
+		return postgres.MigrationsDir
+	}
+
+	return sqlite.MigrationsDir
+}
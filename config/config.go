@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -15,17 +16,204 @@ type Config struct {
 	App struct {
 		Name string `mapstructure:"name"`
 		Port string `mapstructure:"port"`
+		// header holding the client ip when the app runs behind a proxy, e.g. X-Forwarded-For, empty uses the peer address
+		ProxyHeader string `mapstructure:"proxy_header"`
 	} `mapstructure:"app"`
 	Database struct {
-		Host string `mapstructure:"host"`
+		// sqlite (default) or postgres, the connection and pool fields below are only read by postgres
+		Driver string `mapstructure:"driver"`
+		Host   string `mapstructure:"host"`
 		// ENV = APP_DATABASE_HOST
 		Port     int    `mapstructure:"port"`
 		Username string `mapstructure:"username"`
 		Password string `mapstructure:"password"`
+		Name     string `mapstructure:"name"`
+		SSLMode  string `mapstructure:"ssl_mode"`
+		// connection pool, zero values keep the database/sql defaults
+		MaxOpenConns    int           `mapstructure:"max_open_conns"`
+		MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+		ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+		ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+		// how often WithTx runs a transaction again after SQLITE_BUSY or a postgres serialization failure
+		// and the first wait, doubled every retry
+		BusyRetries int           `mapstructure:"busy_retries"`
+		BusyBackoff time.Duration `mapstructure:"busy_backoff"`
+		// apply pending migrations on startup, when off the app refuses to start until `migrate up` ran
+		AutoMigrate bool `mapstructure:"auto_migrate"`
+		// sqlite tunes the sqlite driver, every field is optional
+		Sqlite struct {
+			// database file, ./movies.db when empty
+			Path string `mapstructure:"path"`
+			// journal_mode and synchronous pragmas, WAL and NORMAL when empty
+			JournalMode string `mapstructure:"journal_mode"`
+			Synchronous string `mapstructure:"synchronous"`
+			// how long a statement waits for a lock held by another connection or process before SQLITE_BUSY
+			BusyTimeout time.Duration `mapstructure:"busy_timeout"`
+			// enforces the REFERENCES clauses and their ON DELETE CASCADE, on unless set to false
+			ForeignKeys *bool `mapstructure:"foreign_keys"`
+			// cache_size pragma, pages when positive and KiB when negative, zero keeps the sqlite default
+			CacheSize int `mapstructure:"cache_size"`
+			// connections of the reader pool, writes always share one connection
+			MaxReaders int `mapstructure:"max_readers"`
+		} `mapstructure:"sqlite"`
 	} `mapstructure:"database"`
+	Log struct {
+		// where the log files go, ./logs when empty
+		Dir string `mapstructure:"dir"`
+		// debug, info, warn or error, info when empty
+		Level string `mapstructure:"level"`
+		// file, stdout or both, file when empty
+		Output string `mapstructure:"output"`
+		// megabytes of a log file before it is rotated, 0 only rotates daily
+		MaxSize int `mapstructure:"max_size"`
+		// how long rotated files are kept, 0 keeps them forever
+		MaxAge time.Duration `mapstructure:"max_age"`
+		// how many rotated files are kept, 0 keeps them all
+		MaxBackups int `mapstructure:"max_backups"`
+		// gzips the rotated files
+		Compress bool `mapstructure:"compress"`
+		Http     struct {
+			// headers logged as [REDACTED], Authorization, Proxy-Authorization, Cookie and Set-Cookie when empty
+			RedactHeaders []string `mapstructure:"redact_headers"`
+			// JSON fields logged as [REDACTED] at any depth, password, jwt, token and secret when empty
+			RedactFields []string `mapstructure:"redact_fields"`
+			// bytes of a body logged before it is truncated, 4096 when empty, -1 leaves the bodies out
+			MaxBodySize int `mapstructure:"max_body_size"`
+			// share of the requests of a route that are logged, the other routes and failed requests are always logged
+			Sampling []LogSampling `mapstructure:"sampling"`
+		} `mapstructure:"http"`
+	} `mapstructure:"log"`
+	Tracing struct {
+		// elastic (default, configured by the ELASTIC_APM_* variables), otlp or noop
+		Backend string `mapstructure:"backend"`
+		// service.name of the otlp spans, app.name when empty
+		ServiceName string `mapstructure:"service_name"`
+		// share of the new traces the otlp backend records, 1 when empty
+		SampleRate float64 `mapstructure:"sample_rate"`
+		Otlp       struct {
+			// base url of the collector's OTLP/HTTP receiver, the spans go to <endpoint>/v1/traces
+			Endpoint string            `mapstructure:"endpoint"`
+			Headers  map[string]string `mapstructure:"headers"`
+			// of an export, 10s when empty
+			Timeout time.Duration `mapstructure:"timeout"`
+		} `mapstructure:"otlp"`
+	} `mapstructure:"tracing"`
+	Health struct {
+		// of each check of /livez and /readyz, 2s when empty
+		Timeout time.Duration `mapstructure:"timeout"`
+		// megabytes the disk of storage.movie_dir needs free to be ready, 100 when empty, -1 turns the check off
+		MinFreeDisk int `mapstructure:"min_free_disk"`
+		// how long /readyz fails before the server stops on a shutdown, so load balancers stop sending requests first
+		DrainDelay time.Duration `mapstructure:"drain_delay"`
+	} `mapstructure:"health"`
+	Storage struct {
+		// where uploaded movie files are stored and served from, ./movies when empty
+		MovieDir string `mapstructure:"movie_dir"`
+	} `mapstructure:"storage"`
 	Jwt struct {
 		SecretKey string `mapstructure:"secret_key"`
 	} `mapstructure:"jwt"`
+	Catalog struct {
+		// external OMDb-style catalog used to enrich movies, point it at a fake server locally
+		BaseUrl string `mapstructure:"base_url"`
+		ApiKey  string `mapstructure:"api_key"`
+	} `mapstructure:"catalog"`
+	Webhooks struct {
+		// a subscription is disabled after this many failed deliveries in a row
+		MaxFailures int `mapstructure:"max_failures"`
+		// vote counts that notify vote.threshold_crossed
+		VoteThresholds []int `mapstructure:"vote_thresholds"`
+	} `mapstructure:"webhooks"`
+	Events struct {
+		// an event bus consumer skips an event after its handler failed this many times, 5 when empty
+		MaxAttempts int `mapstructure:"max_attempts"`
+	} `mapstructure:"events"`
+	Backup struct {
+		// where snapshots are written, ./backups when empty
+		Dir string `mapstructure:"dir"`
+		// how many of the newest snapshots are kept after a new one, 0 keeps all of them
+		Retention int `mapstructure:"retention"`
+	} `mapstructure:"backup"`
+	Cache struct {
+		// memory (in-process LRU) or redis, empty turns the cache off
+		Driver string `mapstructure:"driver"`
+		// how long a cached list or statistic is served, 30s when empty
+		TTL time.Duration `mapstructure:"ttl"`
+		// entries the memory cache keeps before evicting the least recently used, 1000 when empty
+		MaxEntries int         `mapstructure:"max_entries"`
+		Redis      RedisConfig `mapstructure:"redis"`
+	} `mapstructure:"cache"`
+	RateLimit struct {
+		// memory (default, per instance) or redis, shared by every instance
+		Store string      `mapstructure:"store"`
+		Redis RedisConfig `mapstructure:"redis"`
+		// limits by rule name, a route whose rule is missing isn't limited
+		Rules   map[string]RateLimitRule `mapstructure:"rules"`
+		Lockout struct {
+			// failed logins from an ip before it is locked out, 0 turns the lockout off
+			MaxFailures int `mapstructure:"max_failures"`
+			// failures are counted for this long from the first one, 15m when empty
+			Window time.Duration `mapstructure:"window"`
+			// the first lockout, doubled for every failure after it up to max_duration, 1m and 1h when empty
+			Duration    time.Duration `mapstructure:"duration"`
+			MaxDuration time.Duration `mapstructure:"max_duration"`
+		} `mapstructure:"lockout"`
+	} `mapstructure:"rate_limit"`
+	// CircuitBreakers configures each httpclient command by name, durations take units, e.g. "4s" or "500ms"
+	CircuitBreakers map[string]CircuitBreakerConfig `mapstructure:"circuit_breakers"`
+	Scheduler       struct {
+		// how often scheduled movies are checked for publishing, e.g. "1m"
+		PublishInterval time.Duration `mapstructure:"publish_interval"`
+		// how often pending webhook deliveries are sent, e.g. "5s"
+		WebhookInterval time.Duration `mapstructure:"webhook_interval"`
+		// how often outbox events are handed to the event bus consumers, e.g. "1s"
+		EventInterval time.Duration `mapstructure:"event_interval"`
+		// how often a database snapshot is taken, e.g. "24h", 0 turns the snapshots off
+		BackupInterval time.Duration `mapstructure:"backup_interval"`
+	} `mapstructure:"scheduler"`
+}
+
+// RedisConfig reaches a redis compatible server
+type RedisConfig struct {
+	// host:port, localhost:6379 when empty
+	Addr     string `mapstructure:"addr"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	// idle connections kept open, 10 when empty
+	PoolSize int `mapstructure:"pool_size"`
+	// dial, read and write timeout of a command, 1s when empty
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// LogSampling logs rate (0 to 1) of the requests to route
+type LogSampling struct {
+	// "GET /api/v1/movies", or "/api/v1/movies" for every method, as the route is declared, e.g. /api/v1/admin/movies/:id
+	Route string  `mapstructure:"route"`
+	Rate  float64 `mapstructure:"rate"`
+}
+
+// RateLimitRule is a token bucket of limit requests refilled over period
+type RateLimitRule struct {
+	// ip, user (the ip of anonymous callers) or route (every caller shares the bucket)
+	Key    string        `mapstructure:"key"`
+	Limit  int           `mapstructure:"limit"`
+	Period time.Duration `mapstructure:"period"`
+}
+
+type CircuitBreakerConfig struct {
+	Timeout                time.Duration `mapstructure:"timeout"`
+	MaxConcurrentRequests  int           `mapstructure:"max_concurrent_requests"`
+	ErrorPercentThreshold  int           `mapstructure:"error_percent_threshold"`
+	RequestVolumeThreshold int           `mapstructure:"request_volume_threshold"`
+	SleepWindow            time.Duration `mapstructure:"sleep_window"`
+	FallbackMsg            string        `mapstructure:"fallback_msg"`
+	Retry                  struct {
+		MaxRetries  int           `mapstructure:"max_retries"`
+		BaseDelay   time.Duration `mapstructure:"base_delay"`
+		MaxDelay    time.Duration `mapstructure:"max_delay"`
+		Methods     []string      `mapstructure:"methods"`
+		StatusCodes []int         `mapstructure:"status_codes"`
+	} `mapstructure:"retry"`
 }
 
 func LoadConfig() error {
@@ -63,7 +251,5 @@ func LoadConfig() error {
 
 	Cfg = &config
 
-	fmt.Println(Cfg)
-
 	return nil
 }
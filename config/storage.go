@@ -0,0 +1,24 @@
+package config
+
+const (
+	defaultMovieDir  = "./movies"
+	defaultBackupDir = "./backups"
+)
+
+// MovieDir is where uploaded movie files are stored and served from, storage.movie_dir or ./movies
+func (c *Config) MovieDir() string {
+	if c.Storage.MovieDir != "" {
+		return c.Storage.MovieDir
+	}
+
+	return defaultMovieDir
+}
+
+// BackupDir is where database snapshots are written, backup.dir or ./backups
+func (c *Config) BackupDir() string {
+	if c.Backup.Dir != "" {
+		return c.Backup.Dir
+	}
+
+	return defaultBackupDir
+}
@@ -4,4 +4,28 @@ const (
 	RouteApiV1               = "/api/v1"
 	UserSessionKey           = "user_session"
 	DuplicateConstraintError = "duplicate constraint error"
+	// ForeignKeyConstraintError is a row referencing one that doesn't exist
+	ForeignKeyConstraintError = "foreign key constraint error"
+
+	DatabaseDriverSqlite   = "sqlite"
+	DatabaseDriverPostgres = "postgres"
+
+	TracingBackendElastic = "elastic"
+	TracingBackendOtlp    = "otlp"
+	TracingBackendNoop    = "noop"
+
+	CacheDriverMemory = "memory"
+	CacheDriverRedis  = "redis"
+
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+
+	// what a rate limit rule counts the requests of
+	RateLimitKeyIp    = "ip"
+	RateLimitKeyUser  = "user"
+	RateLimitKeyRoute = "route"
+
+	RateLimitRuleRegister = "register"
+	RateLimitRuleLogin    = "login"
+	RateLimitRuleVote     = "vote"
 )
@@ -0,0 +1,15 @@
+package constant
+
+// domain events written to the outbox together with the change they describe
+const (
+	EventMovieCreated   = "MovieCreated"
+	EventMovieUpdated   = "MovieUpdated"
+	EventVoteCast       = "VoteCast"
+	EventVoteRemoved    = "VoteRemoved"
+	EventUserRegistered = "UserRegistered"
+)
+
+// consumers of the event bus, the name keys the stored offset so it must not change
+const (
+	EventConsumerMovieWebhooks = "movie-webhooks"
+)
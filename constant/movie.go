@@ -0,0 +1,16 @@
+package constant
+
+const (
+	MovieStatusDraft     = "draft"
+	MovieStatusScheduled = "scheduled"
+	MovieStatusPublished = "published"
+	MovieStatusArchived  = "archived"
+)
+
+const (
+	MovieRevisionActionCreate   = "create"
+	MovieRevisionActionUpdate   = "update"
+	MovieRevisionActionStatus   = "status"
+	MovieRevisionActionRollback = "rollback"
+	MovieRevisionActionEnrich   = "enrich"
+)
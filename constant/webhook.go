@@ -0,0 +1,19 @@
+package constant
+
+// events partners can subscribe to, WebhookEventAll matches every event
+const (
+	WebhookEventAll                  = "*"
+	WebhookEventMovieCreated         = "movie.created"
+	WebhookEventMovieUpdated         = "movie.updated"
+	WebhookEventMoviePublished       = "movie.published"
+	WebhookEventVoteThresholdCrossed = "vote.threshold_crossed"
+)
+
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// WebhookCommandPrefix prefixes the httpclient command of each subscription, configured like the "webhook" command
+const WebhookCommandPrefix = "webhook-"
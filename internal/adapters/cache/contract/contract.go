@@ -0,0 +1,109 @@
+// Package contract is the behaviour every cache client has to share, run it from the tests of a client with Run
+package contract
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Run runs every contract test with a client from newClient. A shared server may hold keys of earlier runs,
+// so every test uses keys of its own
+func Run(t *testing.T, newClient func(t *testing.T) adapter.CacheClient) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, cache adapter.CacheClient, key string)
+	}{
+		{"miss", testMiss},
+		{"set and get", testSetAndGet},
+		{"ttl", testTTL},
+		{"incr", testIncr},
+	}
+
+	run := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newClient(t)
+			defer cache.Close()
+
+			tt.test(t, cache, "contract:"+run+":"+tt.name)
+		})
+	}
+}
+
+func expectValue(t *testing.T, cache adapter.CacheClient, key string, want string) {
+	t.Helper()
+
+	got, err := cache.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("expected %q for %s, got %v", want, key, err)
+	}
+	if string(got) != want {
+		t.Fatalf("expected %q for %s, got %q", want, key, got)
+	}
+}
+
+func expectMiss(t *testing.T, cache adapter.CacheClient, key string) {
+	t.Helper()
+
+	got, err := cache.Get(context.Background(), key)
+	if !errors.Is(err, adapter.ErrCacheMiss) {
+		t.Fatalf("expected a miss for %s, got %q %v", key, got, err)
+	}
+}
+
+func testMiss(t *testing.T, cache adapter.CacheClient, key string) {
+	expectMiss(t, cache, key)
+}
+
+func testSetAndGet(t *testing.T, cache adapter.CacheClient, key string) {
+	ctx := context.Background()
+
+	// values are binary safe, the protocol of redis frames lines with \r\n
+	value := "{\"title\":\"line\r\nbreak\"}\x00"
+	if err := cache.Set(ctx, key, []byte(value), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expectValue(t, cache, key, value)
+
+	if err := cache.Set(ctx, key, []byte("replaced"), 0); err != nil {
+		t.Fatal(err)
+	}
+	expectValue(t, cache, key, "replaced")
+
+	if err := cache.Set(ctx, key+":empty", []byte{}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expectValue(t, cache, key+":empty", "")
+}
+
+func testTTL(t *testing.T, cache adapter.CacheClient, key string) {
+	if err := cache.Set(context.Background(), key, []byte("soon gone"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	expectValue(t, cache, key, "soon gone")
+
+	time.Sleep(100 * time.Millisecond)
+
+	expectMiss(t, cache, key)
+}
+
+func testIncr(t *testing.T, cache adapter.CacheClient, key string) {
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		got, err := cache.Incr(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("expected counter %d, got %d", want, got)
+		}
+	}
+
+	expectValue(t, cache, key, "3")
+}
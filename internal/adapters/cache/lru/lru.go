@@ -0,0 +1,137 @@
+// Package lru is the in-process cache, every instance of the app has its own
+package lru
+
+import (
+	"container/list"
+	"context"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/tracing"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultMaxEntries = 1000
+
+type entry struct {
+	key   string
+	value []byte
+	// zero when the entry doesn't expire
+	expiresAt time.Time
+}
+
+type lruClient struct {
+	mu         sync.Mutex
+	maxEntries int
+	// order holds the entries, the front one is the most recently used
+	order   *list.List
+	entries map[string]*list.Element
+	// counters aren't evicted, an entry cached under a counter value must not outlive it
+	counters map[string]int64
+}
+
+func NewLruClient() adapter.CacheClient {
+	maxEntries := config.Cfg.Cache.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+
+	return &lruClient{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		counters:   make(map[string]int64),
+	}
+}
+
+func (c *lruClient) Get(ctx context.Context, key string) ([]byte, error) {
+	span, _ := tracing.StartSpan(ctx, "Get", "cache")
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if counter, ok := c.counters[key]; ok {
+		return []byte(strconv.FormatInt(counter, 10)), nil
+	}
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, adapter.ErrCacheMiss
+	}
+
+	cached := element.Value.(*entry)
+	if !cached.expiresAt.IsZero() && !time.Now().Before(cached.expiresAt) {
+		c.remove(element)
+		return nil, adapter.ErrCacheMiss
+	}
+
+	c.order.MoveToFront(element)
+
+	return append([]byte(nil), cached.value...), nil
+}
+
+func (c *lruClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	span, _ := tracing.StartSpan(ctx, "Set", "cache")
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := &entry{
+		key:   key,
+		value: append([]byte(nil), value...),
+	}
+	if ttl > 0 {
+		cached.expiresAt = time.Now().Add(ttl)
+	}
+
+	delete(c.counters, key)
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = cached
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(cached)
+
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+func (c *lruClient) Incr(ctx context.Context, key string) (int64, error) {
+	span, _ := tracing.StartSpan(ctx, "Incr", "cache")
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	c.counters[key]++
+
+	return c.counters[key], nil
+}
+
+func (c *lruClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.counters = make(map[string]int64)
+
+	return nil
+}
+
+func (c *lruClient) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+}
@@ -0,0 +1,76 @@
+package lru_test
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/adapters/cache/contract"
+	"lion-parcel-test/internal/adapters/cache/lru"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	config.Cfg = &config.Config{}
+
+	os.Exit(m.Run())
+}
+
+func TestCacheContract(t *testing.T) {
+	contract.Run(t, func(t *testing.T) adapter.CacheClient {
+		return lru.NewLruClient()
+	})
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	config.Cfg.Cache.MaxEntries = 3
+	defer func() { config.Cfg.Cache.MaxEntries = 0 }()
+
+	ctx := context.Background()
+	cache := lru.NewLruClient()
+
+	cache.Incr(ctx, "generation")
+	for i := 1; i <= 3; i++ {
+		cache.Set(ctx, "movie:"+strconv.Itoa(i), []byte(strconv.Itoa(i)), time.Minute)
+	}
+
+	// movie:1 is used again, so movie:2 is the least recently used one
+	if _, err := cache.Get(ctx, "movie:1"); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set(ctx, "movie:4", []byte("4"), time.Minute)
+
+	for key, kept := range map[string]bool{"movie:1": true, "movie:2": false, "movie:3": true, "movie:4": true} {
+		_, err := cache.Get(ctx, key)
+		if kept && err != nil {
+			t.Fatalf("%s evicted: %v", key, err)
+		}
+		if !kept && !errors.Is(err, adapter.ErrCacheMiss) {
+			t.Fatalf("%s kept, expected it to be evicted", key)
+		}
+	}
+
+	// counters don't take a place and are never evicted
+	if value, err := cache.Get(ctx, "generation"); err != nil || string(value) != "1" {
+		t.Fatalf("counter evicted %q %v", value, err)
+	}
+}
+
+func TestValuesAreCopied(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLruClient()
+
+	value := []byte("heat")
+	cache.Set(ctx, "movie", value, 0)
+	value[0] = 'm'
+
+	got, _ := cache.Get(ctx, "movie")
+	got[1] = 'x'
+
+	if again, _ := cache.Get(ctx, "movie"); string(again) != "heat" {
+		t.Fatalf("cached value changed by its callers: %q", again)
+	}
+}
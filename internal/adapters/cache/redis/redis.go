@@ -0,0 +1,93 @@
+// Package redis is the cache shared by every instance of the app, it talks RESP to any redis compatible server
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/resp"
+	"lion-parcel-test/pkg/tracing"
+	"strconv"
+	"time"
+)
+
+type redisClient struct {
+	client *resp.Client
+}
+
+// NewRedisClient connects once to fail the start on a wrong address or password
+func NewRedisClient() (adapter.CacheClient, error) {
+	cfg := config.Cfg.Cache.Redis
+
+	client, err := resp.NewClient(resp.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+		PoolSize: cfg.PoolSize,
+		Timeout:  cfg.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisClient{
+		client: client,
+	}, nil
+}
+
+func (c *redisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	span, ctx := tracing.StartSpan(ctx, "Get", "cache")
+	defer span.End()
+
+	reply, err := c.client.Do(ctx, "GET", key)
+	if errors.Is(err, resp.ErrNil) {
+		return nil, adapter.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+
+	return []byte(value), nil
+}
+
+func (c *redisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	span, ctx := tracing.StartSpan(ctx, "Set", "cache")
+	defer span.End()
+
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := c.client.Do(ctx, args...)
+
+	return err
+}
+
+func (c *redisClient) Incr(ctx context.Context, key string) (int64, error) {
+	span, ctx := tracing.StartSpan(ctx, "Incr", "cache")
+	defer span.End()
+
+	reply, err := c.client.Do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+
+	counter, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCR reply %v", reply)
+	}
+
+	return counter, nil
+}
+
+func (c *redisClient) Close() error {
+	return c.client.Close()
+}
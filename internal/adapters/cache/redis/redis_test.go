@@ -0,0 +1,43 @@
+package redis_test
+
+import (
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/adapters/cache/contract"
+	"lion-parcel-test/internal/adapters/cache/redis"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// runs against a redis compatible server when TEST_REDIS_ADDR is set, e.g. localhost:6379, keys are left to expire
+func TestCacheContract(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	config.Cfg = &config.Config{}
+	config.Cfg.Cache.Redis.Addr = addr
+	config.Cfg.Cache.Redis.Password = os.Getenv("TEST_REDIS_PASSWORD")
+	config.Cfg.Cache.Redis.DB, _ = strconv.Atoi(os.Getenv("TEST_REDIS_DB"))
+
+	contract.Run(t, func(t *testing.T) adapter.CacheClient {
+		cache, err := redis.NewRedisClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return cache
+	})
+}
+
+func TestUnreachableServer(t *testing.T) {
+	config.Cfg = &config.Config{}
+	// nothing listens on the discard port
+	config.Cfg.Cache.Redis.Addr = "127.0.0.1:9"
+
+	if _, err := redis.NewRedisClient(); err == nil {
+		t.Fatal("expected the start to fail without a server")
+	}
+}
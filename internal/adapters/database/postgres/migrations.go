@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigrationsDir is where `migrate create` writes new files when the driver is postgres, relative to the repository root
+const MigrationsDir = "internal/adapters/database/postgres/migrations"
+
+// NewMigrator returns the migrator of the embedded migrations
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrate.New(db, files)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator.Rebind = Rebind
+
+	return migrator, nil
+}
+
+// migrateSchema refuses a dirty or newer schema and applies the pending migrations when database.auto_migrate is on
+func migrateSchema(ctx context.Context, db *sql.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	err = migrator.Check(ctx)
+	if !errors.Is(err, migrate.ErrPending) || !config.Cfg.Database.AutoMigrate {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Ctx(ctx).Infow("migration applied", "version", migration.Version, "name", migration.Name)
+	}
+
+	return err
+}
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS outbox_events_serialize ON outbox_events;
+DROP FUNCTION IF EXISTS lock_outbox_events();
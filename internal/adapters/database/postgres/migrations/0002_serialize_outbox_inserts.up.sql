@@ -0,0 +1,13 @@
+-- the ids of outbox_events are taken when a row is inserted but only seen once it commits, so a transaction
+-- could commit a lower id after a higher one was dispatched and the consumer offsets already moved past it.
+-- Every insert waits for a lock held until the end of the transaction before its id is taken, so ids commit in order.
+-- The trigger runs once per statement, before the defaults of the rows are evaluated
+CREATE OR REPLACE FUNCTION lock_outbox_events() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('outbox_events'));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_events_serialize BEFORE INSERT ON outbox_events
+    FOR EACH STATEMENT EXECUTE FUNCTION lock_outbox_events();
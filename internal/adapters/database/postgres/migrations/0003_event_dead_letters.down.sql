@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS event_dead_letters_event;
+DROP TABLE IF EXISTS event_dead_letters;
+ALTER TABLE event_consumer_offsets DROP COLUMN last_error;
+ALTER TABLE event_consumer_offsets DROP COLUMN attempts;
+ALTER TABLE event_consumer_offsets DROP COLUMN failing_event_id;
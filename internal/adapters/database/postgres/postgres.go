@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/metrics"
+	"lion-parcel-test/pkg/tracing"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var queryDuration = metrics.NewHistogramVec("db_query_duration_seconds",
+	"Time of the calls of the database client, by driver and method.",
+	metrics.ExponentialBuckets(0.0005, 2, 12), "driver", "method")
+
+type postgresClient struct {
+	db *sql.DB
+}
+
+// NewPostgresClient connects with the database config, repositories keep writing ? placeholders
+func NewPostgresClient() (adapter.DatabaseClient, error) {
+	db, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// the schema lives in the embedded migrations, see migrations.go
+	err = migrateSchema(context.Background(), db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &postgresClient{
+		db: db,
+	}, nil
+}
+
+// OpenDatabase opens the pool without touching the schema
+func OpenDatabase() (*sql.DB, error) {
+	cfg := config.Cfg.Database
+
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     cfg.Name,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// zero keeps the database/sql default, SetMaxIdleConns(0) would drop every idle connection
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	return db, nil
+}
+
+// Execute fills LastInsertID with 0, postgres has no such thing, insert with RETURNING id and QueryRow instead
+func (r *postgresClient) Execute(ctx context.Context, query string, args ...interface{}) adapter.ExecuteResult {
+	span, ctx := tracing.StartSpan(ctx, "Execute", "database")
+	defer span.End()
+	defer observeQuery("Execute", time.Now())
+
+	result, err := r.querier(ctx).ExecContext(ctx, Rebind(query), args...)
+	if err != nil {
+		noteRetryable(ctx, err)
+		return adapter.ExecuteResult{
+			Error: executeError(ctx, err),
+		}
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+
+	return adapter.ExecuteResult{
+		Result:       result,
+		RowsAffected: rowsAffected,
+		Error:        nil,
+	}
+}
+
+// ExecuteBatch runs the statements in a single transaction, nothing is committed when one of them fails.
+// Inside WithTx it becomes a savepoint of the caller's transaction
+func (r *postgresClient) ExecuteBatch(ctx context.Context, statements []adapter.Statement) ([]adapter.ExecuteResult, error) {
+	span, ctx := tracing.StartSpan(ctx, "ExecuteBatch", "database")
+	defer span.End()
+	defer observeQuery("ExecuteBatch", time.Now())
+
+	var results []adapter.ExecuteResult
+
+	err := r.WithTx(ctx, func(ctx context.Context) error {
+		// a retry runs the statements again from scratch
+		results = make([]adapter.ExecuteResult, 0, len(statements))
+
+		for _, statement := range statements {
+			result := r.Execute(ctx, statement.Query, statement.Args...)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// executeError maps foreign_key_violation to constant.ForeignKeyConstraintError and the other integrity constraint
+// violations (class 23, e.g. unique_violation) to constant.DuplicateConstraintError, the same way the sqlite adapter does
+func executeError(ctx context.Context, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Class() == "23" {
+		if pqErr.Code == "23503" {
+			return errors.New(constant.ForeignKeyConstraintError)
+		}
+
+		return errors.New(constant.DuplicateConstraintError)
+	}
+
+	tracing.CaptureError(ctx, err)
+	return fmt.Errorf("failed to execute query: %w", err)
+}
+
+func (r *postgresClient) QueryRows(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	span, ctx := tracing.StartSpan(ctx, "QueryRows", "database")
+	defer span.End()
+	defer observeQuery("QueryRows", time.Now())
+
+	rows, err := r.querier(ctx).QueryContext(ctx, Rebind(query), args...)
+	if err != nil {
+		noteRetryable(ctx, err)
+		tracing.CaptureError(ctx, err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (r *postgresClient) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	span, ctx := tracing.StartSpan(ctx, "QueryRow", "database")
+	defer span.End()
+	defer observeQuery("QueryRow", time.Now())
+
+	return r.querier(ctx).QueryRowContext(ctx, Rebind(query), args...)
+}
+
+func (r *postgresClient) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *postgresClient) CheckSchema(ctx context.Context) error {
+	migrator, err := NewMigrator(r.db)
+	if err != nil {
+		return err
+	}
+
+	return migrator.Check(ctx)
+}
+
+func (r *postgresClient) Close() error {
+	return r.db.Close()
+}
+
+// observeQuery times a call of the client, deferred with the time it started
+func observeQuery(method string, start time.Time) {
+	queryDuration.With(constant.DatabaseDriverPostgres, method).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rebind turns the ? placeholders of a query into $1, $2, ... Question marks inside
+// quoted strings, quoted identifiers and comments are left alone
+func Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var out strings.Builder
+	out.Grow(len(query) + 8)
+
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			end := closing(query, i+1, c)
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '?':
+			n++
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(n))
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String()
+}
+
+// closing is the index right after the quote that ends the literal opened before start, a doubled quote is escaped
+func closing(query string, start int, quote byte) int {
+	for i := start; i < len(query); i++ {
+		if query[i] != quote {
+			continue
+		}
+
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+
+		return i + 1
+	}
+
+	return len(query)
+}
@@ -0,0 +1,22 @@
+package postgres
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`SELECT 1;`, `SELECT 1;`},
+		{`SELECT * FROM movies WHERE id = ? AND (? = 0 OR version = ?);`, `SELECT * FROM movies WHERE id = $1 AND ($2 = 0 OR version = $3);`},
+		{`SELECT '?', "a?b", 'it''s ?' FROM t WHERE x = ?`, `SELECT '?', "a?b", 'it''s ?' FROM t WHERE x = $1`},
+		{"SELECT ? -- why?\nFROM t WHERE y = ?", "SELECT $1 -- why?\nFROM t WHERE y = $2"},
+		{`SELECT 'unterminated ?`, `SELECT 'unterminated ?`},
+	}
+
+	for _, tt := range tests {
+		if actual := Rebind(tt.query); actual != tt.expected {
+			t.Errorf("Rebind(%q) = %q, expected %q", tt.query, actual, tt.expected)
+		}
+	}
+}
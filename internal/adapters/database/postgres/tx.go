@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/tracing"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultRetries = 5
+	defaultBackoff = 20 * time.Millisecond
+	maxBackoff     = time.Second
+)
+
+type txKey struct{}
+
+// txState is the transaction a ctx carries, retryable remembers a serialization failure even when a repository replaced the error
+type txState struct {
+	tx         *sql.Tx
+	savepoints int
+	retryable  bool
+}
+
+// querier is what *sql.DB and *sql.Tx have in common
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func txFromContext(ctx context.Context) (*txState, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	return state, ok
+}
+
+// querier returns the transaction of ctx, or the pool when there is none
+func (r *postgresClient) querier(ctx context.Context) querier {
+	if state, ok := txFromContext(ctx); ok {
+		return state.tx
+	}
+
+	return r.db
+}
+
+// noteRetryable marks the transaction of ctx for a retry when err is a serialization failure or a deadlock
+func noteRetryable(ctx context.Context, err error) {
+	if state, ok := txFromContext(ctx); ok && isRetryable(err) {
+		state.retryable = true
+	}
+}
+
+// isRetryable is true for serialization_failure and deadlock_detected, running the transaction again can succeed
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+
+	return false
+}
+
+func (r *postgresClient) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state, ok := txFromContext(ctx); ok {
+		return withSavepoint(ctx, state, fn)
+	}
+
+	span, ctx := tracing.StartSpan(ctx, "WithTx", "database")
+	defer span.End()
+	defer observeQuery("WithTx", time.Now())
+
+	retries := config.Cfg.Database.BusyRetries
+	if retries <= 0 {
+		retries = defaultRetries
+	}
+
+	backoff := config.Cfg.Database.BusyBackoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	// only the outermost call retries, a savepoint can't outlive the transaction that hit the lock
+	for attempt := 0; ; attempt++ {
+		retryable, err := r.runTx(ctx, fn)
+		if err == nil || !retryable || attempt >= retries {
+			return err
+		}
+
+		// full jitter so writers that collided don't collide again
+		wait := time.Duration(rand.Int63n(int64(backoff) + 1))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// runTx runs fn once in a new transaction and reports whether it failed in a way worth retrying
+func (r *postgresClient) runTx(ctx context.Context, fn func(ctx context.Context) error) (retryable bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		return isRetryable(err), fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	state := &txState{tx: tx}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, state))
+	if err != nil {
+		tx.Rollback()
+		return state.retryable || isRetryable(err), err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		return isRetryable(err), fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return false, nil
+}
+
+// withSavepoint runs fn in a savepoint of the current transaction, an error only undoes what fn did
+func withSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) (err error) {
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+
+	_, err = state.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		noteRetryable(ctx, err)
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollbackSavepoint(ctx, state, name)
+			panic(p)
+		}
+	}()
+
+	err = fn(ctx)
+	if err != nil {
+		rollbackSavepoint(ctx, state, name)
+		return err
+	}
+
+	_, err = state.tx.ExecContext(ctx, "RELEASE "+name)
+	if err != nil {
+		noteRetryable(ctx, err)
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return nil
+}
+
+func rollbackSavepoint(ctx context.Context, state *txState, name string) {
+	_, err := state.tx.ExecContext(ctx, "ROLLBACK TO "+name)
+	if err == nil {
+		_, err = state.tx.ExecContext(ctx, "RELEASE "+name)
+	}
+
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+	}
+}
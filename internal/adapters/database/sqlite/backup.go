@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"lion-parcel-test/pkg/migrate"
+	"lion-parcel-test/pkg/tracing"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup writes a snapshot of the database to path while the app keeps serving requests. The copy goes to a
+// temporary file first and only replaces path once PRAGMA integrity_check passed on it
+func (r *sqliteClient) Backup(ctx context.Context, path string) error {
+	span, ctx := tracing.StartSpan(ctx, "Backup", "database")
+	defer span.End()
+
+	err := snapshot(ctx, r.reader, path)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// ErrDatabaseInUse is returned by Restore while another connection has the database open, e.g. a running server
+var ErrDatabaseInUse = errors.New("database is in use")
+
+// Restore replaces the database of the database.sqlite config with the backup at path. It refuses while the database
+// is open elsewhere, and the backup has to pass PRAGMA integrity_check and have a schema this binary can run, older
+// ones are migrated on the next start. The live database is only written once every check passed.
+// It returns the migrations the restored database still misses
+func Restore(ctx context.Context, path string) ([]migrate.Status, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	// held until the copy is done, so a server can't open the database halfway through
+	live, err := lockDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer live.Close()
+
+	backup, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer backup.Close()
+
+	// the checks run on a copy, checking the schema version creates schema_migrations in a legacy file
+	candidate := databasePath() + ".restore"
+	err = snapshot(ctx, backup, candidate)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(candidate)
+
+	restored, err := sql.Open("sqlite3", "file:"+candidate)
+	if err != nil {
+		return nil, err
+	}
+	defer restored.Close()
+
+	pending, err := checkSchema(ctx, restored)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s can't be restored: %w", path, err)
+	}
+
+	return pending, copyDatabase(ctx, live, restored)
+}
+
+// lockDatabase opens the database with an exclusive lock that is kept until it is closed. In WAL mode, the default,
+// every open connection holds a shared lock on the file, so it fails while a server has the database open
+func lockDatabase(ctx context.Context) (*sql.DB, error) {
+	// no busy timeout, a running server never lets go of its lock
+	db, err := sql.Open("sqlite3", "file:"+databasePath()+"?_busy_timeout=0&_locking_mode=EXCLUSIVE")
+	if err != nil {
+		return nil, err
+	}
+
+	// the lock belongs to the connection, the copy has to go through the same one
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	_, err = db.ExecContext(ctx, "BEGIN EXCLUSIVE; COMMIT;")
+	if err != nil {
+		db.Close()
+		if isBusy(err) {
+			return nil, fmt.Errorf("%w, stop the server before restoring %s", ErrDatabaseInUse, databasePath())
+		}
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// checkSchema refuses a dirty or newer schema and returns the migrations that are not applied yet
+func checkSchema(ctx context.Context, db *sql.DB) ([]migrate.Status, error) {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrator.Check(ctx)
+	if err != nil && !errors.Is(err, migrate.ErrPending) {
+		return nil, err
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]migrate.Status, 0)
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status)
+		}
+	}
+
+	return pending, nil
+}
+
+// snapshot copies src into a new file at path and checks its integrity, nothing is left at path when it fails
+func snapshot(ctx context.Context, src *sql.DB, path string) (err error) {
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	dest, err := sql.Open("sqlite3", "file:"+tmp)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	err = copyDatabase(ctx, dest, src)
+	if err != nil {
+		return err
+	}
+
+	// the copy keeps the WAL flag of the source, a snapshot is a single file
+	_, err = dest.ExecContext(ctx, "PRAGMA journal_mode = DELETE;")
+	if err != nil {
+		return err
+	}
+
+	err = checkIntegrity(ctx, dest)
+	if err != nil {
+		return err
+	}
+
+	// closed before the rename so no connection keeps the old name open
+	err = dest.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// copyDatabase copies the main database of src over the one of dest with the online backup API. A single step
+// copies every page under one read transaction, so the copy is consistent and, in WAL mode, doesn't block writers
+func copyDatabase(ctx context.Context, dest *sql.DB, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver interface{}) error {
+		return srcConn.Raw(func(srcDriver interface{}) error {
+			backup, err := destDriver.(*sqlite3.SQLiteConn).Backup("main", srcDriver.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return fmt.Errorf("failed to start backup: %w", err)
+			}
+
+			_, err = backup.Step(-1)
+			if err != nil {
+				backup.Finish()
+				return fmt.Errorf("failed to copy database: %w", err)
+			}
+
+			return backup.Finish()
+		})
+	})
+}
+
+// checkIntegrity runs PRAGMA integrity_check, which answers a single "ok" row for a healthy database
+func checkIntegrity(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check;")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	problems := make([]string, 0)
+	for rows.Next() {
+		var result string
+
+		err = rows.Scan(&result)
+		if err != nil {
+			return err
+		}
+
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/migrate"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// the migrations log what they apply
+	dir, err := os.MkdirTemp("", "sqlite-logs")
+	if err != nil {
+		panic(err)
+	}
+	if err = log.InitializeDir(dir); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	log.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newDatabase creates a migrated database at a temporary database.sqlite path with a user in it,
+// and returns its client and a backup taken right after
+func newDatabase(t *testing.T) (*sqliteClient, string) {
+	t.Helper()
+
+	cfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = cfg })
+
+	dir := t.TempDir()
+	config.Cfg = &config.Config{}
+	config.Cfg.Database.AutoMigrate = true
+	config.Cfg.Database.Sqlite.Path = filepath.Join(dir, "movies.db")
+
+	client, err := NewSqliteClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	addUser(t, client.(*sqliteClient).writer, "alice")
+
+	backup := filepath.Join(dir, "backup.db")
+	if err := client.(*sqliteClient).Backup(context.Background(), backup); err != nil {
+		t.Fatal(err)
+	}
+
+	return client.(*sqliteClient), backup
+}
+
+func addUser(t *testing.T, db *sql.DB, name string) {
+	t.Helper()
+
+	if _, err := db.Exec(`INSERT INTO users (name, email) VALUES (?, ?);`, name, name+"@example.com"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// users lists the users of the live database through a connection of its own
+func users(t *testing.T) string {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file:"+databasePath()+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT name FROM users ORDER BY id;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+// tamper changes the backup at path with statement
+func tamper(t *testing.T, path string, statement string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(statement); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// corrupt changes the email of alice in the users table but not in its unique index, the file still opens
+// and only PRAGMA integrity_check notices
+func corrupt(t *testing.T, path string) {
+	t.Helper()
+
+	var pageSize, rootPage int64
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.QueryRow(`PRAGMA page_size;`).Scan(&pageSize)
+	if err == nil {
+		err = db.QueryRow(`SELECT rootpage FROM sqlite_master WHERE name = 'users';`).Scan(&rootPage)
+	}
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := content[(rootPage-1)*pageSize : rootPage*pageSize]
+	at := bytes.Index(page, []byte("alice@example.com"))
+	if at < 0 {
+		t.Fatal("alice not found in the users table")
+	}
+	page[at] = 'z'
+
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	client, backup := newDatabase(t)
+	addUser(t, client.writer, "bob")
+
+	_, err := Restore(ctx, backup)
+	if !errors.Is(err, ErrDatabaseInUse) {
+		t.Fatalf("expected the open database to be refused, got %v", err)
+	}
+	if got := users(t); got != "alice,bob" {
+		t.Fatalf("expected the live database untouched, got users %s", got)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := Restore(ctx, backup)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("expected the backup to be restored, got %v %v", pending, err)
+	}
+	if got := users(t); got != "alice" {
+		t.Fatalf("expected the users of the backup, got %s", got)
+	}
+}
+
+func TestRestoreRefusesBadBackups(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		tamper func(t *testing.T, path string)
+		check  func(err error) bool
+	}{
+		{
+			name: "dirty",
+			tamper: func(t *testing.T, path string) {
+				tamper(t, path, `UPDATE schema_migrations SET dirty = TRUE WHERE version = (SELECT MAX(version) FROM schema_migrations);`)
+			},
+			check: func(err error) bool { return errors.Is(err, migrate.ErrDirty) },
+		},
+		{
+			name: "newer",
+			tamper: func(t *testing.T, path string) {
+				tamper(t, path, `INSERT INTO schema_migrations (version, name, checksum) VALUES (9999, 'from_the_future', '');`)
+			},
+			check: func(err error) bool { return errors.Is(err, migrate.ErrNewer) },
+		},
+		{
+			name:   "integrity",
+			tamper: corrupt,
+			check:  func(err error) bool { return err != nil && strings.Contains(err.Error(), "integrity check failed") },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, backup := newDatabase(t)
+			addUser(t, client.writer, "bob")
+			if err := client.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			tc.tamper(t, backup)
+
+			before, err := os.ReadFile(databasePath())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Restore(context.Background(), backup)
+			if !tc.check(err) {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			after, err := os.ReadFile(databasePath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(before, after) || users(t) != "alice,bob" {
+				t.Fatalf("expected the live database untouched, got users %s", users(t))
+			}
+			if _, err := os.Stat(databasePath() + ".restore"); !os.IsNotExist(err) {
+				t.Fatalf("expected the restore candidate to be removed, got %v", err)
+			}
+		})
+	}
+}
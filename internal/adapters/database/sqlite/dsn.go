@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"database/sql"
+	"lion-parcel-test/config"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultDatabaseFile = "./movies.db"
+	defaultJournalMode  = "WAL"
+	defaultSynchronous  = "NORMAL"
+	defaultBusyTimeout  = 5 * time.Second
+	defaultMaxReaders   = 4
+)
+
+// databasePath is the database file of the database.sqlite config
+func databasePath() string {
+	if path := config.Cfg.Database.Sqlite.Path; path != "" {
+		return path
+	}
+
+	return defaultDatabaseFile
+}
+
+// dsn builds the go-sqlite3 connection string of the database.sqlite config. The reader opens the file read only
+// and leaves the journal mode alone, it is a property of the file the writer already set
+func dsn(readOnly bool) string {
+	cfg := config.Cfg.Database.Sqlite
+
+	busyTimeout := cfg.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = defaultBusyTimeout
+	}
+
+	foreignKeys := cfg.ForeignKeys == nil || *cfg.ForeignKeys
+
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.FormatInt(busyTimeout.Milliseconds(), 10))
+	params.Set("_foreign_keys", strconv.FormatBool(foreignKeys))
+	params.Set("_synchronous", orDefault(cfg.Synchronous, defaultSynchronous))
+	if cfg.CacheSize != 0 {
+		params.Set("_cache_size", strconv.Itoa(cfg.CacheSize))
+	}
+
+	if readOnly {
+		params.Set("mode", "ro")
+	} else {
+		params.Set("_journal_mode", orDefault(cfg.JournalMode, defaultJournalMode))
+		// BEGIN IMMEDIATE takes the write lock up front, so another process can't make the transaction fail halfway
+		params.Set("_txlock", "immediate")
+	}
+
+	return "file:" + databasePath() + "?" + params.Encode()
+}
+
+// openWriter opens the pool every write goes through. It has a single connection, so concurrent writers
+// wait for it in database/sql instead of fighting over the file lock
+func openWriter() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn(false))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	return db, nil
+}
+
+// openReader opens the pool of the queries made outside a transaction, WAL lets them run next to the writer
+func openReader() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn(true))
+	if err != nil {
+		return nil, err
+	}
+
+	readers := config.Cfg.Database.Sqlite.MaxReaders
+	if readers <= 0 {
+		readers = defaultMaxReaders
+	}
+
+	db.SetMaxOpenConns(readers)
+	db.SetMaxIdleConns(readers)
+
+	return db, nil
+}
+
+func orDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
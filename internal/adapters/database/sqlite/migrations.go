@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigrationsDir is where `migrate create` writes new files, relative to the repository root.
+// They are embedded, so the binary has to be built again to pick them up
+const MigrationsDir = "internal/adapters/database/sqlite/migrations"
+
+// legacyColumns were added by ensureColumn before the schema was versioned
+var legacyColumns = []struct {
+	column     string
+	definition string
+}{
+	{"status", "TEXT NOT NULL DEFAULT 'published'"},
+	{"publish_at", "DATETIME"},
+	{"version", "INTEGER NOT NULL DEFAULT 1"},
+	{"year", "INTEGER NOT NULL DEFAULT 0"},
+	{"poster_url", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// OpenDatabase opens the writer pool of the database.sqlite config without touching the schema
+func OpenDatabase() (*sql.DB, error) {
+	return openWriter()
+}
+
+// NewMigrator returns the migrator of the embedded migrations
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrate.New(db, files)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator.Baseline = adoptLegacySchema
+
+	return migrator, nil
+}
+
+// migrateSchema refuses a dirty or newer schema and applies the pending migrations when database.auto_migrate is on
+func migrateSchema(ctx context.Context, db *sql.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	err = migrator.Check(ctx)
+	if !errors.Is(err, migrate.ErrPending) || !config.Cfg.Database.AutoMigrate {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Ctx(ctx).Infow("migration applied", "version", migration.Version, "name", migration.Name)
+	}
+
+	return err
+}
+
+// adoptLegacySchema gives a movies table created before the migrations the columns ensureColumn used to add,
+// 0001_initial_schema creates everything else with IF NOT EXISTS
+func adoptLegacySchema(ctx context.Context, tx *sql.Tx) error {
+	var tables int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'movies';`).Scan(&tables)
+	if err != nil || tables == 0 {
+		return err
+	}
+
+	for _, legacy := range legacyColumns {
+		if err = ensureColumn(ctx, tx, "movies", legacy.column, legacy.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS event_consumer_offsets;
+DROP TABLE IF EXISTS outbox_events;
+DROP INDEX IF EXISTS webhook_deliveries_event;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS movie_revisions;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS movies;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,17 @@
+-- the event a consumer keeps failing on and how often it failed, the offset still points before it
+ALTER TABLE event_consumer_offsets ADD COLUMN failing_event_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE event_consumer_offsets ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE event_consumer_offsets ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+
+-- events a consumer gave up on after max_attempts, the offset moved past them
+CREATE TABLE IF NOT EXISTS event_dead_letters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    consumer TEXT NOT NULL,
+    event_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    error TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS event_dead_letters_event ON event_dead_letters (consumer, event_id);
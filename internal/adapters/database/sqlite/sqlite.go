@@ -7,90 +7,108 @@ import (
 	"fmt"
 	"lion-parcel-test/constant"
 	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/metrics"
+	"lion-parcel-test/pkg/tracing"
+	"os"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 	_ "github.com/mattn/go-sqlite3"
-	"go.elastic.co/apm/v2"
 )
 
+var queryDuration = metrics.NewHistogramVec("db_query_duration_seconds",
+	"Time of the calls of the database client, by driver and method.",
+	metrics.ExponentialBuckets(0.0005, 2, 12), "driver", "method")
+
+// sqliteClient sends writes and transactions to the single connection of writer,
+// the queries made outside a transaction go to the reader pool
 type sqliteClient struct {
-	db *sql.DB
+	writer *sql.DB
+	reader *sql.DB
 }
 
 func NewSqliteClient() (adapter.DatabaseClient, error) {
-	db, err := sql.Open("sqlite3", "./movies.db")
+	writer, err := OpenDatabase()
 	if err != nil {
 		return nil, err
 	}
 
-	createUsersTable := `CREATE TABLE IF NOT EXISTS users (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT UNIQUE NOT NULL,
-    email TEXT UNIQUE NOT NULL,
-    is_admin BOOLEAN DEFAULT FALSE,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-	_, err = db.Exec(createUsersTable)
+	err = writer.Ping()
 	if err != nil {
+		writer.Close()
 		return nil, err
 	}
 
-	createMoviesTable := `CREATE TABLE IF NOT EXISTS movies (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    title TEXT NOT NULL,
-    description TEXT,
-    duration INTEGER NOT NULL,
-    artists TEXT,
-    genres TEXT,
-    watch_url TEXT,
-    views_count INTEGER DEFAULT 0,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	_, err = db.Exec(createMoviesTable)
+	// the schema lives in the embedded migrations, see migrations.go
+	err = migrateSchema(context.Background(), writer)
 	if err != nil {
+		writer.Close()
 		return nil, err
 	}
 
-	createVotesTable := `CREATE TABLE IF NOT EXISTS votes (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    user_id INTEGER NOT NULL,
-    movie_id INTEGER NOT NULL,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(user_id, movie_id),
-    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
-    FOREIGN KEY(movie_id) REFERENCES movies(id) ON DELETE CASCADE
-	);`
-	_, err = db.Exec(createVotesTable)
-	if err != nil {
-		return nil, err
+	// opened after the writer created the file and switched it to WAL
+	reader, err := openReader()
+	if err == nil {
+		err = reader.Ping()
 	}
-
-	err = db.Ping()
 	if err != nil {
+		writer.Close()
 		return nil, err
 	}
 
 	return &sqliteClient{
-		db: db,
+		writer: writer,
+		reader: reader,
 	}, nil
 }
 
+// ensureColumn adds the column to the table when an older database file doesn't have it yet
+func ensureColumn(ctx context.Context, q querier, table string, column string, definition string) error {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+
+		err = rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk)
+		if err != nil {
+			return err
+		}
+
+		if name == column {
+			return nil
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, definition))
+
+	return err
+}
+
 func (r *sqliteClient) Execute(ctx context.Context, query string, args ...interface{}) adapter.ExecuteResult {
-	span, ctx := apm.StartSpan(ctx, "Execute", "database")
+	span, ctx := tracing.StartSpan(ctx, "Execute", "database")
 	defer span.End()
+	defer observeQuery("Execute", time.Now())
 
-	result, err := r.db.ExecContext(ctx, query, args...)
+	result, err := r.querier(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrConstraint {
-			return adapter.ExecuteResult{
-				Error: errors.New(constant.DuplicateConstraintError),
-			}
-		}
-
-		apm.CaptureError(ctx, err)
+		noteBusy(ctx, err)
 		return adapter.ExecuteResult{
-			Error: fmt.Errorf("failed to execute query: %w", err),
+			Error: executeError(ctx, err),
 		}
 	}
 
@@ -105,14 +123,62 @@ func (r *sqliteClient) Execute(ctx context.Context, query string, args ...interf
 	}
 }
 
+// ExecuteBatch runs the statements in a single transaction, nothing is committed when one of them fails.
+// Inside WithTx it becomes a savepoint of the caller's transaction
+func (r *sqliteClient) ExecuteBatch(ctx context.Context, statements []adapter.Statement) ([]adapter.ExecuteResult, error) {
+	span, ctx := tracing.StartSpan(ctx, "ExecuteBatch", "database")
+	defer span.End()
+	defer observeQuery("ExecuteBatch", time.Now())
+
+	var results []adapter.ExecuteResult
+
+	err := r.WithTx(ctx, func(ctx context.Context) error {
+		// a busy retry runs the statements again from scratch
+		results = make([]adapter.ExecuteResult, 0, len(statements))
+
+		for _, statement := range statements {
+			result := r.Execute(ctx, statement.Query, statement.Args...)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// executeError maps a row referencing a missing one to constant.ForeignKeyConstraintError and the other
+// constraint violations to constant.DuplicateConstraintError so repositories can tell them apart
+func executeError(ctx context.Context, err error) error {
+	if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrConstraint {
+		if sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
+			return errors.New(constant.ForeignKeyConstraintError)
+		}
+
+		return errors.New(constant.DuplicateConstraintError)
+	}
+
+	tracing.CaptureError(ctx, err)
+	return fmt.Errorf("failed to execute query: %w", err)
+}
+
 func (r *sqliteClient) QueryRows(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	span, ctx := apm.StartSpan(ctx, "QueryRows", "database")
+	span, ctx := tracing.StartSpan(ctx, "QueryRows", "database")
 	defer span.End()
+	defer observeQuery("QueryRows", time.Now())
 
 	// Execute the query
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.queryQuerier(ctx, query).QueryContext(ctx, query, args...)
 	if err != nil {
-		apm.CaptureError(ctx, err)
+		noteBusy(ctx, err)
+		tracing.CaptureError(ctx, err)
 		return nil, err
 	}
 
@@ -120,21 +186,58 @@ func (r *sqliteClient) QueryRows(ctx context.Context, query string, args ...inte
 }
 
 func (r *sqliteClient) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	span, ctx := apm.StartSpan(ctx, "QueryRow", "database")
+	span, ctx := tracing.StartSpan(ctx, "QueryRow", "database")
 	defer span.End()
+	defer observeQuery("QueryRow", time.Now())
 
 	// Execute the query
-	row := r.db.QueryRowContext(ctx, query, args...)
+	row := r.queryQuerier(ctx, query).QueryRowContext(ctx, query, args...)
 
 	return row
 }
 
+// Ping waits for the connection of the writer as a write would, a long transaction makes it time out.
+// The open connections keep writing to a file whose permissions changed, so the file is opened for writing too
+func (s *sqliteClient) Ping(ctx context.Context) error {
+	err := s.writer.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.reader.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(databasePath(), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (s *sqliteClient) CheckSchema(ctx context.Context) error {
+	migrator, err := NewMigrator(s.writer)
+	if err != nil {
+		return err
+	}
+
+	return migrator.Check(ctx)
+}
+
 func (s *sqliteClient) Close() error {
-	err := s.db.Close()
+	err := s.reader.Close()
 
 	if err != nil {
+		s.writer.Close()
 		return err
 	}
 
-	return nil
+	return s.writer.Close()
+}
+
+// observeQuery times a call of the client, deferred with the time it started
+func observeQuery(method string, start time.Time) {
+	queryDuration.With(constant.DatabaseDriverSqlite, method).Observe(time.Since(start).Seconds())
 }
@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/tracing"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	defaultBusyRetries = 5
+	defaultBusyBackoff = 20 * time.Millisecond
+	maxBusyBackoff     = time.Second
+)
+
+type txKey struct{}
+
+// txState is the transaction a ctx carries, busy remembers a SQLITE_BUSY even when a repository replaced the error
+type txState struct {
+	tx         *sql.Tx
+	savepoints int
+	busy       bool
+}
+
+// querier is what *sql.DB and *sql.Tx have in common
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func txFromContext(ctx context.Context) (*txState, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	return state, ok
+}
+
+// querier returns the transaction of ctx, or the writer when there is none
+func (r *sqliteClient) querier(ctx context.Context) querier {
+	if state, ok := txFromContext(ctx); ok {
+		return state.tx
+	}
+
+	return r.writer
+}
+
+// queryQuerier is querier for QueryRows and QueryRow, a SELECT outside a transaction goes to the reader.
+// Anything else, e.g. an INSERT ... RETURNING, is a write
+func (r *sqliteClient) queryQuerier(ctx context.Context, query string) querier {
+	if _, ok := txFromContext(ctx); !ok && isSelect(query) {
+		return r.reader
+	}
+
+	return r.querier(ctx)
+}
+
+func isSelect(query string) bool {
+	query = strings.TrimSpace(query)
+
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
+
+// noteBusy marks the transaction of ctx for a retry when err is SQLITE_BUSY
+func noteBusy(ctx context.Context, err error) {
+	if state, ok := txFromContext(ctx); ok && isBusy(err) {
+		state.busy = true
+	}
+}
+
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+
+	return false
+}
+
+func (r *sqliteClient) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state, ok := txFromContext(ctx); ok {
+		return withSavepoint(ctx, state, fn)
+	}
+
+	span, ctx := tracing.StartSpan(ctx, "WithTx", "database")
+	defer span.End()
+	defer observeQuery("WithTx", time.Now())
+
+	retries := config.Cfg.Database.BusyRetries
+	if retries <= 0 {
+		retries = defaultBusyRetries
+	}
+
+	backoff := config.Cfg.Database.BusyBackoff
+	if backoff <= 0 {
+		backoff = defaultBusyBackoff
+	}
+
+	// only the outermost call retries, a savepoint can't outlive the transaction that hit the lock
+	for attempt := 0; ; attempt++ {
+		busy, err := r.runTx(ctx, fn)
+		if err == nil || !busy || attempt >= retries {
+			return err
+		}
+
+		// full jitter so writers that collided don't collide again
+		wait := time.Duration(rand.Int63n(int64(backoff) + 1))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > maxBusyBackoff {
+			backoff = maxBusyBackoff
+		}
+	}
+}
+
+// runTx runs fn once in a new transaction and reports whether it failed on SQLITE_BUSY
+func (r *sqliteClient) runTx(ctx context.Context, fn func(ctx context.Context) error) (busy bool, err error) {
+	tx, err := r.writer.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		return isBusy(err), fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	state := &txState{tx: tx}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, state))
+	if err != nil {
+		tx.Rollback()
+		return state.busy || isBusy(err), err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		return isBusy(err), fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return false, nil
+}
+
+// withSavepoint runs fn in a savepoint of the current transaction, an error only undoes what fn did
+func withSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) (err error) {
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+
+	_, err = state.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		noteBusy(ctx, err)
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollbackSavepoint(ctx, state, name)
+			panic(p)
+		}
+	}()
+
+	err = fn(ctx)
+	if err != nil {
+		rollbackSavepoint(ctx, state, name)
+		return err
+	}
+
+	_, err = state.tx.ExecContext(ctx, "RELEASE "+name)
+	if err != nil {
+		noteBusy(ctx, err)
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return nil
+}
+
+func rollbackSavepoint(ctx context.Context, state *txState, name string) {
+	_, err := state.tx.ExecContext(ctx, "ROLLBACK TO "+name)
+	if err == nil {
+		_, err = state.tx.ExecContext(ctx, "RELEASE "+name)
+	}
+
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+	}
+}
@@ -0,0 +1,105 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/httpclient"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Command is the circuit breaker guarding every catalog call
+const Command = "moviecatalog"
+
+// omdbMovie is the OMDb lookup response, TMDb-style proxies answering the same shape work too
+type omdbMovie struct {
+	Response string `json:"Response"`
+	Error    string `json:"Error"`
+	Title    string `json:"Title"`
+	Year     string `json:"Year"`
+	Plot     string `json:"Plot"`
+	Actors   string `json:"Actors"`
+	Genre    string `json:"Genre"`
+	Poster   string `json:"Poster"`
+}
+
+type catalogClient struct {
+	baseUrl string
+	apiKey  string
+}
+
+func NewCatalogClient() adapter.CatalogClient {
+	return &catalogClient{
+		baseUrl: strings.TrimRight(config.Cfg.Catalog.BaseUrl, "/"),
+		apiKey:  config.Cfg.Catalog.ApiKey,
+	}
+}
+
+func (c *catalogClient) LookupMovie(ctx context.Context, title string, year int) (adapter.CatalogMovie, error) {
+	span, ctx := tracing.StartSpan(ctx, "LookupMovie", "Adapter")
+	defer span.End()
+
+	query := url.Values{}
+	query.Set("t", title)
+	query.Set("plot", "short")
+	if year > 0 {
+		query.Set("y", strconv.Itoa(year))
+	}
+	if c.apiKey != "" {
+		query.Set("apikey", c.apiKey)
+	}
+
+	found, err := httpclient.DoJSON[omdbMovie](ctx, httpclient.Client, &httpclient.Request{
+		Method:   http.MethodGet,
+		Endpoint: c.baseUrl + "/",
+		Query:    query,
+		Command:  Command,
+	}, nil)
+	if err != nil {
+		return adapter.CatalogMovie{}, fmt.Errorf("%w: %v", adapter.ErrCatalogUnavailable, err)
+	}
+
+	if found.Response != "True" {
+		if strings.Contains(strings.ToLower(found.Error), "not found") {
+			return adapter.CatalogMovie{}, fmt.Errorf("%w: %s", adapter.ErrCatalogNotFound, title)
+		}
+		return adapter.CatalogMovie{}, fmt.Errorf("%w: %s", adapter.ErrCatalogUnavailable, found.Error)
+	}
+
+	return adapter.CatalogMovie{
+		Title:       found.Title,
+		Description: omdbValue(found.Plot),
+		Cast:        omdbValue(found.Actors),
+		Genres:      omdbValue(found.Genre),
+		Year:        omdbYear(found.Year),
+		Poster:      omdbValue(found.Poster),
+	}, nil
+}
+
+// omdbValue drops the "N/A" OMDb uses for unknown fields
+func omdbValue(value string) string {
+	if value == "N/A" {
+		return ""
+	}
+
+	return value
+}
+
+// omdbYear reads the first year of values such as "1999" or "2010–2014"
+func omdbYear(value string) int {
+	if len(value) < 4 {
+		return 0
+	}
+
+	year, err := strconv.Atoi(value[:4])
+	if err != nil {
+		return 0
+	}
+
+	return year
+}
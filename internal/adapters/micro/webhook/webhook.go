@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/httpclient"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// Command is the circuit breaker every endpoint command is configured like, see httpclient.NewCbSourceFrom
+const Command = "webhook"
+
+type webhookClient struct {
+}
+
+func NewWebhookClient() adapter.WebhookClient {
+	return &webhookClient{}
+}
+
+func (c *webhookClient) Send(ctx context.Context, req adapter.WebhookRequest) (adapter.WebhookResponse, error) {
+	span, ctx := tracing.StartSpan(ctx, "Send", "Adapter")
+	defer span.End()
+
+	httpclient.Client.NewCbSourceFrom(Command, req.Command)
+
+	response, err := httpclient.Client.Do(ctx, &httpclient.Request{
+		Method:   http.MethodPost,
+		Endpoint: req.Url,
+		Header:   req.Header,
+		Body:     httpclient.RawBody(req.Body),
+		Command:  req.Command,
+	})
+	if err != nil {
+		var statusErr *httpclient.StatusError
+		if errors.As(err, &statusErr) {
+			return adapter.WebhookResponse{StatusCode: statusErr.StatusCode, Body: statusErr.Body}, err
+		}
+		return adapter.WebhookResponse{}, err
+	}
+
+	return adapter.WebhookResponse{
+		StatusCode: response.StatusCode,
+		Body:       response.Body,
+	}, nil
+}
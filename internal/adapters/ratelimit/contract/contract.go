@@ -0,0 +1,142 @@
+// Package contract is the behaviour every rate limit store has to share, run it from the tests of a store with Run
+package contract
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Run runs every contract test with a store from newStore. A shared server may hold keys of earlier runs,
+// so every test uses keys of its own
+func Run(t *testing.T, newStore func(t *testing.T) adapter.RateLimitStore) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store adapter.RateLimitStore, key string)
+	}{
+		{"take", testTake},
+		{"refill", testRefill},
+		{"failures", testFailures},
+		{"lock", testLock},
+	}
+
+	run := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+
+			tt.test(t, store, "contract:"+run+":"+tt.name)
+		})
+	}
+}
+
+func take(t *testing.T, store adapter.RateLimitStore, key string, limit int, period time.Duration) adapter.RateLimitDecision {
+	t.Helper()
+
+	decision, err := store.Take(context.Background(), key, limit, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return decision
+}
+
+func testTake(t *testing.T, store adapter.RateLimitStore, key string) {
+	for remaining := 2; remaining >= 0; remaining-- {
+		decision := take(t, store, key, 3, time.Hour)
+		if !decision.Allowed || decision.Remaining != remaining {
+			t.Fatalf("expected an allowed request with %d remaining, got %+v", remaining, decision)
+		}
+		if decision.Reset <= 0 || decision.Reset > time.Hour {
+			t.Fatalf("expected the bucket to be full within the period, got %+v", decision)
+		}
+	}
+
+	decision := take(t, store, key, 3, time.Hour)
+	if decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("expected the empty bucket to refuse, got %+v", decision)
+	}
+	// a token comes back every 20 minutes
+	if decision.RetryAfter <= 0 || decision.RetryAfter > 20*time.Minute {
+		t.Fatalf("expected to retry within 20m, got %+v", decision)
+	}
+
+	// every key has a bucket of its own
+	if decision := take(t, store, key+":other", 3, time.Hour); !decision.Allowed {
+		t.Fatalf("expected another key to be allowed, got %+v", decision)
+	}
+}
+
+func testRefill(t *testing.T, store adapter.RateLimitStore, key string) {
+	take(t, store, key, 2, 200*time.Millisecond)
+	take(t, store, key, 2, 200*time.Millisecond)
+
+	decision := take(t, store, key, 2, 200*time.Millisecond)
+	if decision.Allowed {
+		t.Fatalf("expected the empty bucket to refuse, got %+v", decision)
+	}
+
+	time.Sleep(decision.RetryAfter + 20*time.Millisecond)
+
+	if decision := take(t, store, key, 2, 200*time.Millisecond); !decision.Allowed {
+		t.Fatalf("expected a refilled token after the retry delay, got %+v", decision)
+	}
+}
+
+func testFailures(t *testing.T, store adapter.RateLimitStore, key string) {
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		count, err := store.AddFailure(ctx, key, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Fatalf("expected %d failures, got %d", want, count)
+		}
+	}
+
+	// the window starts with the first failure
+	time.Sleep(250 * time.Millisecond)
+	if count, _ := store.AddFailure(ctx, key, time.Minute); count != 1 {
+		t.Fatalf("expected the failures to be forgotten after the window, got %d", count)
+	}
+
+	store.AddFailure(ctx, key, time.Minute)
+	if err := store.ResetFailures(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.AddFailure(ctx, key, time.Minute); count != 1 {
+		t.Fatalf("expected the failures to be reset, got %d", count)
+	}
+}
+
+func testLock(t *testing.T, store adapter.RateLimitStore, key string) {
+	ctx := context.Background()
+
+	lockedFor, err := store.LockedFor(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lockedFor != 0 {
+		t.Fatalf("expected a new key to be unlocked, got %s", lockedFor)
+	}
+
+	if err := store.Lock(ctx, key, 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	lockedFor, _ = store.LockedFor(ctx, key)
+	if lockedFor <= 0 || lockedFor > 200*time.Millisecond {
+		t.Fatalf("expected a lock of at most 200ms, got %s", lockedFor)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if lockedFor, _ := store.LockedFor(ctx, key); lockedFor != 0 {
+		t.Fatalf("expected the lock to expire, got %s", lockedFor)
+	}
+}
@@ -0,0 +1,178 @@
+// Package memory keeps the rate limits in process, every instance of the app counts its own requests
+package memory
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/tracing"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets, counters and locks that no longer matter are dropped
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// when the bucket is full again and can be forgotten
+	fullAt time.Time
+}
+
+type failures struct {
+	count     int64
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	failures  map[string]*failures
+	locks     map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() adapter.RateLimitStore {
+	return &memoryStore{
+		buckets:   make(map[string]*bucket),
+		failures:  make(map[string]*failures),
+		locks:     make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit int, period time.Duration) (adapter.RateLimitDecision, error) {
+	span, _ := tracing.StartSpan(ctx, "Take", "ratelimit")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	// tokens per nanosecond
+	rate := float64(limit) / float64(period)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit), b.tokens+float64(now.Sub(b.updated))*rate)
+	b.updated = now
+
+	decision := adapter.RateLimitDecision{}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / rate))
+	}
+
+	decision.Remaining = int(b.tokens)
+	decision.Reset = time.Duration(math.Ceil((float64(limit) - b.tokens) / rate))
+	b.fullAt = now.Add(decision.Reset)
+
+	return decision, nil
+}
+
+func (s *memoryStore) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	span, _ := tracing.StartSpan(ctx, "AddFailure", "ratelimit")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	f, ok := s.failures[key]
+	if !ok || !now.Before(f.expiresAt) {
+		f = &failures{expiresAt: now.Add(window)}
+		s.failures[key] = f
+	}
+
+	f.count++
+
+	return f.count, nil
+}
+
+func (s *memoryStore) ResetFailures(ctx context.Context, key string) error {
+	span, _ := tracing.StartSpan(ctx, "ResetFailures", "ratelimit")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+
+	return nil
+}
+
+func (s *memoryStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	span, _ := tracing.StartSpan(ctx, "Lock", "ratelimit")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locks[key] = time.Now().Add(d)
+
+	return nil
+}
+
+func (s *memoryStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	span, _ := tracing.StartSpan(ctx, "LockedFor", "ratelimit")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lockedFor := time.Until(s.locks[key])
+	if lockedFor <= 0 {
+		delete(s.locks, key)
+		return 0, nil
+	}
+
+	return lockedFor, nil
+}
+
+func (s *memoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buckets = make(map[string]*bucket)
+	s.failures = make(map[string]*failures)
+	s.locks = make(map[string]time.Time)
+
+	return nil
+}
+
+// sweep drops the full buckets, expired counters and past locks, a key per ip would grow the maps forever otherwise
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+
+	for key, f := range s.failures {
+		if !now.Before(f.expiresAt) {
+			delete(s.failures, key)
+		}
+	}
+
+	for key, until := range s.locks {
+		if !now.Before(until) {
+			delete(s.locks, key)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"lion-parcel-test/internal/adapters/ratelimit/contract"
+	"lion-parcel-test/internal/adapters/ratelimit/memory"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"testing"
+)
+
+func TestStoreContract(t *testing.T) {
+	contract.Run(t, func(t *testing.T) adapter.RateLimitStore {
+		return memory.NewMemoryStore()
+	})
+}
@@ -0,0 +1,181 @@
+// Package redis keeps the rate limits in a redis compatible server, so they hold across every instance of the app
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/pkg/resp"
+	"lion-parcel-test/pkg/tracing"
+	"strconv"
+	"time"
+)
+
+// takeScript refills and takes from the bucket in one step, so concurrent instances can't both take the last token.
+// The clock of the server is used, the instances' clocks may disagree
+const takeScript = `
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or limit
+local updated = tonumber(state[2]) or now
+
+local rate = limit / period
+tokens = math.min(limit, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+local reset = math.ceil((limit - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1))
+
+return {allowed, math.floor(tokens), reset, retry}
+`
+
+// addFailureScript starts the window with the first failure
+const addFailureScript = `
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`
+
+type redisStore struct {
+	client *resp.Client
+}
+
+// NewRedisStore connects once to fail the start on a wrong address or password
+func NewRedisStore() (adapter.RateLimitStore, error) {
+	cfg := config.Cfg.RateLimit.Redis
+
+	client, err := resp.NewClient(resp.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+		PoolSize: cfg.PoolSize,
+		Timeout:  cfg.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisStore{
+		client: client,
+	}, nil
+}
+
+func (s *redisStore) Take(ctx context.Context, key string, limit int, period time.Duration) (adapter.RateLimitDecision, error) {
+	span, ctx := tracing.StartSpan(ctx, "Take", "ratelimit")
+	defer span.End()
+
+	reply, err := s.client.Do(ctx, "EVAL", takeScript, "1", key, strconv.Itoa(limit), milliseconds(period))
+	if err != nil {
+		return adapter.RateLimitDecision{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 4 {
+		return adapter.RateLimitDecision{}, fmt.Errorf("redis: unexpected rate limit reply %v", reply)
+	}
+
+	numbers := make([]int64, len(values))
+	for i, value := range values {
+		numbers[i], ok = value.(int64)
+		if !ok {
+			return adapter.RateLimitDecision{}, fmt.Errorf("redis: unexpected rate limit reply %v", reply)
+		}
+	}
+
+	return adapter.RateLimitDecision{
+		Allowed:    numbers[0] == 1,
+		Remaining:  int(numbers[1]),
+		Reset:      time.Duration(numbers[2]) * time.Millisecond,
+		RetryAfter: time.Duration(numbers[3]) * time.Millisecond,
+	}, nil
+}
+
+func (s *redisStore) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	span, ctx := tracing.StartSpan(ctx, "AddFailure", "ratelimit")
+	defer span.End()
+
+	reply, err := s.client.Do(ctx, "EVAL", addFailureScript, "1", key, milliseconds(window))
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected failure count reply %v", reply)
+	}
+
+	return count, nil
+}
+
+func (s *redisStore) ResetFailures(ctx context.Context, key string) error {
+	span, ctx := tracing.StartSpan(ctx, "ResetFailures", "ratelimit")
+	defer span.End()
+
+	_, err := s.client.Do(ctx, "DEL", key)
+
+	return err
+}
+
+func (s *redisStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	span, ctx := tracing.StartSpan(ctx, "Lock", "ratelimit")
+	defer span.End()
+
+	_, err := s.client.Do(ctx, "SET", key, "1", "PX", milliseconds(d))
+
+	return err
+}
+
+func (s *redisStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	span, ctx := tracing.StartSpan(ctx, "LockedFor", "ratelimit")
+	defer span.End()
+
+	reply, err := s.client.Do(ctx, "PTTL", key)
+	if errors.Is(err, resp.ErrNil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// -2 when the key is missing, a lock is always set with an expiry
+	ttl, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected PTTL reply %v", reply)
+	}
+	if ttl <= 0 {
+		return 0, nil
+	}
+
+	return time.Duration(ttl) * time.Millisecond, nil
+}
+
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
+
+// milliseconds is d for PX and PEXPIRE, which refuse 0
+func milliseconds(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+
+	return strconv.FormatInt(ms, 10)
+}
@@ -0,0 +1,43 @@
+package redis_test
+
+import (
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/adapters/ratelimit/contract"
+	"lion-parcel-test/internal/adapters/ratelimit/redis"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// runs against a redis compatible server with scripting when TEST_REDIS_ADDR is set, keys are left to expire
+func TestStoreContract(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	config.Cfg = &config.Config{}
+	config.Cfg.RateLimit.Redis.Addr = addr
+	config.Cfg.RateLimit.Redis.Password = os.Getenv("TEST_REDIS_PASSWORD")
+	config.Cfg.RateLimit.Redis.DB, _ = strconv.Atoi(os.Getenv("TEST_REDIS_DB"))
+
+	contract.Run(t, func(t *testing.T) adapter.RateLimitStore {
+		store, err := redis.NewRedisStore()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return store
+	})
+}
+
+func TestUnreachableServer(t *testing.T) {
+	config.Cfg = &config.Config{}
+	// nothing listens on the discard port
+	config.Cfg.RateLimit.Redis.Addr = "127.0.0.1:9"
+
+	if _, err := redis.NewRedisStore(); err == nil {
+		t.Fatal("expected the start to fail without a server")
+	}
+}
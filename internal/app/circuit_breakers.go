@@ -0,0 +1,42 @@
+package app
+
+import (
+	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/httpclient"
+)
+
+// configureCircuitBreakers registers one httpclient command per configured breaker, unset fields keep the httpclient defaults
+func configureCircuitBreakers(breakers map[string]config.CircuitBreakerConfig) {
+	for command, breaker := range breakers {
+		opts := []httpclient.Option{httpclient.Client.CbWithCommand(command)}
+
+		if breaker.Timeout > 0 {
+			opts = append(opts, httpclient.Client.CbWithTimeout(breaker.Timeout))
+		}
+		if breaker.MaxConcurrentRequests > 0 {
+			opts = append(opts, httpclient.Client.CbWithMaxConcurrentRequests(breaker.MaxConcurrentRequests))
+		}
+		if breaker.ErrorPercentThreshold > 0 {
+			opts = append(opts, httpclient.Client.CbWithErrorPercentThreshold(breaker.ErrorPercentThreshold))
+		}
+		if breaker.RequestVolumeThreshold > 0 {
+			opts = append(opts, httpclient.Client.CbWithRequestVolumeThreshold(breaker.RequestVolumeThreshold))
+		}
+		if breaker.SleepWindow > 0 {
+			opts = append(opts, httpclient.Client.CbWithSleepWindow(breaker.SleepWindow))
+		}
+		if breaker.FallbackMsg != "" {
+			opts = append(opts, httpclient.Client.CbWithFallbackMsg(breaker.FallbackMsg))
+		}
+
+		opts = append(opts, httpclient.Client.CbWithRetryPolicy(httpclient.RetryPolicy{
+			MaxRetries:  breaker.Retry.MaxRetries,
+			BaseDelay:   breaker.Retry.BaseDelay,
+			MaxDelay:    breaker.Retry.MaxDelay,
+			Methods:     breaker.Retry.Methods,
+			StatusCodes: breaker.Retry.StatusCodes,
+		}))
+
+		httpclient.Client.NewCbSource(opts...)
+	}
+}
@@ -2,31 +2,122 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/adapters/cache/lru"
+	"lion-parcel-test/internal/adapters/cache/redis"
+	"lion-parcel-test/internal/adapters/database/postgres"
 	"lion-parcel-test/internal/adapters/database/sqlite"
+	"lion-parcel-test/internal/adapters/micro/catalog"
+	"lion-parcel-test/internal/adapters/micro/webhook"
+	ratelimitmemory "lion-parcel-test/internal/adapters/ratelimit/memory"
+	ratelimitredis "lion-parcel-test/internal/adapters/ratelimit/redis"
 	"lion-parcel-test/internal/interfaces/adapter"
 )
 
 type Dependencies struct {
-	sqlitedb adapter.DatabaseClient
+	database adapter.DatabaseClient
+	// backup is the database when it can back itself up, nil otherwise
+	backup adapter.BackupClient
+	// cache is nil when cache.driver is empty
+	cache          adapter.CacheClient
+	rateLimitStore adapter.RateLimitStore
+	catalog        adapter.CatalogClient
+	webhook        adapter.WebhookClient
 }
 
 func NewDependencies() (*Dependencies, error) {
 
-	db, err := sqlite.NewSqliteClient()
+	db, err := newDatabaseClient()
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	backup, _ := db.(adapter.BackupClient)
+
+	cache, err := newCacheClient()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	rateLimitStore, err := newRateLimitStore()
+	if err != nil {
+		if cache != nil {
+			cache.Close()
+		}
+		db.Close()
+		return nil, err
 	}
 
 	return &Dependencies{
-		sqlitedb: db,
+		database:       db,
+		backup:         backup,
+		cache:          cache,
+		rateLimitStore: rateLimitStore,
+		catalog:        catalog.NewCatalogClient(),
+		webhook:        webhook.NewWebhookClient(),
 	}, nil
 }
 
 func (d *Dependencies) Close(ctx context.Context) error {
-	err := d.sqlitedb.Close()
+	if d.cache != nil {
+		d.cache.Close()
+	}
+
+	d.rateLimitStore.Close()
+
+	err := d.database.Close()
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// newDatabaseClient connects to the database.driver backend, sqlite when it is empty
+func newDatabaseClient() (adapter.DatabaseClient, error) {
+	switch config.Cfg.Database.Driver {
+	case "", constant.DatabaseDriverSqlite:
+		return sqlite.NewSqliteClient()
+	case constant.DatabaseDriverPostgres:
+		return postgres.NewPostgresClient()
+	default:
+		return nil, fmt.Errorf("unknown database driver %s, expected %s or %s", config.Cfg.Database.Driver, constant.DatabaseDriverSqlite, constant.DatabaseDriverPostgres)
+	}
+}
+
+// newCacheClient connects to the cache.driver backend, nil when it is empty
+func newCacheClient() (adapter.CacheClient, error) {
+	switch config.Cfg.Cache.Driver {
+	case "":
+		return nil, nil
+	case constant.CacheDriverMemory:
+		return lru.NewLruClient(), nil
+	case constant.CacheDriverRedis:
+		return redis.NewRedisClient()
+	default:
+		return nil, fmt.Errorf("unknown cache driver %s, expected %s or %s", config.Cfg.Cache.Driver, constant.CacheDriverMemory, constant.CacheDriverRedis)
+	}
+}
+
+// newRateLimitStore connects to the rate_limit.store backend, memory when it is empty
+func newRateLimitStore() (adapter.RateLimitStore, error) {
+	for name, rule := range config.Cfg.RateLimit.Rules {
+		switch rule.Key {
+		case constant.RateLimitKeyIp, constant.RateLimitKeyUser, constant.RateLimitKeyRoute:
+		default:
+			return nil, fmt.Errorf("unknown key %s of rate limit rule %s, expected %s, %s or %s", rule.Key, name, constant.RateLimitKeyIp, constant.RateLimitKeyUser, constant.RateLimitKeyRoute)
+		}
+	}
+
+	switch config.Cfg.RateLimit.Store {
+	case "", constant.RateLimitStoreMemory:
+		return ratelimitmemory.NewMemoryStore(), nil
+	case constant.RateLimitStoreRedis:
+		return ratelimitredis.NewRedisStore()
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %s, expected %s or %s", config.Cfg.RateLimit.Store, constant.RateLimitStoreMemory, constant.RateLimitStoreRedis)
+	}
+}
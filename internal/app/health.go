@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/health"
+	"lion-parcel-test/pkg/httpclient"
+	"strings"
+)
+
+const defaultMinFreeDisk = 100
+
+// newHealth registers the checks of /livez and /readyz on the dependencies
+func newHealth(cfg *config.Config, dependencies *Dependencies) *health.Checker {
+	checker := health.NewChecker(health.Options{
+		Timeout: cfg.Health.Timeout,
+	})
+
+	checker.Add(health.Check{
+		Name: "database",
+		Run:  dependencies.database.Ping,
+		Live: true,
+	})
+
+	checker.Add(health.Check{
+		Name: "migrations",
+		Run:  dependencies.database.CheckSchema,
+	})
+
+	movieDir := cfg.MovieDir()
+
+	dirs := []string{movieDir}
+	if dependencies.backup != nil {
+		dirs = append(dirs, cfg.BackupDir())
+	}
+
+	checker.Add(health.Check{
+		Name: "storage",
+		Run:  health.Writable(dirs...),
+	})
+
+	minFreeDisk := cfg.Health.MinFreeDisk
+	if minFreeDisk == 0 {
+		minFreeDisk = defaultMinFreeDisk
+	}
+
+	if minFreeDisk > 0 {
+		checker.Add(health.Check{
+			Name: "disk",
+			Run:  health.DiskSpace(movieDir, uint64(minFreeDisk)<<20),
+		})
+	}
+
+	// every instance calls the same services, taking this one out of the load balancer wouldn't help
+	checker.Add(health.Check{
+		Name:    "circuit_breakers",
+		Run:     openCircuitBreakers,
+		Warning: true,
+	})
+
+	return checker
+}
+
+// openCircuitBreakers fails while a circuit is open and calls of its command fail fast
+func openCircuitBreakers(ctx context.Context) error {
+	var open []string
+	for _, status := range httpclient.Client.CircuitBreakers() {
+		if status.State == httpclient.CircuitStateOpen {
+			open = append(open, status.Command)
+		}
+	}
+
+	if len(open) > 0 {
+		return fmt.Errorf("circuit of %s is open", strings.Join(open, ", "))
+	}
+
+	return nil
+}
@@ -3,45 +3,64 @@ package app
 import (
 	"context"
 	"lion-parcel-test/config"
+	"lion-parcel-test/pkg/health"
 	"lion-parcel-test/pkg/httpclient"
 	"lion-parcel-test/pkg/log"
-
-	"github.com/opentracing/opentracing-go"
-	"go.elastic.co/apm/module/apmot"
+	"lion-parcel-test/pkg/tracing"
+	"time"
 )
 
 type App struct {
 	Usecases     *Usecases
 	Repos        *Repositories
 	Dependencies *Dependencies
+	// Health runs the checks of /livez and /readyz
+	Health *health.Checker
+
+	tracer tracing.Tracer
 }
 
+// NewApp loads the config of GO_ENV and builds the app on it
 func NewApp(ctx context.Context) (*App, error) {
 	err := config.LoadConfig()
 	if err != nil {
 		panic(err)
 	}
 
-	err = log.Initialize()
+	return NewAppWithConfig(ctx, config.Cfg)
+}
+
+// NewAppWithConfig builds the app on cfg instead of the config files, e.g. a temp database and storage dir of a test
+func NewAppWithConfig(ctx context.Context, cfg *config.Config) (*App, error) {
+	config.Cfg = cfg
+
+	err := log.InitializeOptions(log.Options{
+		Dir:        cfg.Log.Dir,
+		Level:      cfg.Log.Level,
+		Output:     cfg.Log.Output,
+		MaxSize:    cfg.Log.MaxSize,
+		MaxAge:     cfg.Log.MaxAge,
+		MaxBackups: cfg.Log.MaxBackups,
+		Compress:   cfg.Log.Compress,
+	})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
+	// set before anything starts a span, e.g. the migrations
+	tracer, err := newTracer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tracing.SetTracer(tracer)
+
 	httpclient.Init()
-	httpclient.Client.NewCbSource(
-		httpclient.Client.CbWithCommand("otherservice"),             // Name of the circuit breaker for this service
-		httpclient.Client.CbWithErrorPercentThreshold(50),           // Open the circuit if 50% of requests fail
-		httpclient.Client.CbWithFallbackMsg("otherservice Timeout"), // Message to return when the circuit is open
-		httpclient.Client.CbWithMaxConcurrentRequests(50),           // Allow up to 50 requests at the same time
-		httpclient.Client.CbWithSleepWindow(5),                      // Wait 5 seconds before trying to close the circuit again
-		httpclient.Client.CbWithTimeout(4000),                       // Timeout for each request is 4 seconds
-		httpclient.Client.CbWithRequestVolumeThreshold(100),         // Start checking errors after 100 requests
-	)
-	opentracing.SetGlobalTracer(apmot.New())
+	configureCircuitBreakers(config.Cfg.CircuitBreakers)
 
+	// a dirty or newer schema stops the start here
 	dependencies, err := NewDependencies()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	repos := NewRepos(dependencies)
@@ -52,14 +71,37 @@ func NewApp(ctx context.Context) (*App, error) {
 		Repos:        repos,
 		Usecases:     usecases,
 		Dependencies: dependencies,
+		Health:       newHealth(cfg, dependencies),
+		tracer:       tracer,
 	}, nil
 }
 
+// Drain fails /readyz and waits health.drain_delay, so load balancers stop sending requests before the server stops
+func (a *App) Drain(ctx context.Context) error {
+	a.Health.Drain()
+
+	select {
+	case <-time.After(config.Cfg.Health.DrainDelay):
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
 func (a *App) Close(ctx context.Context) error {
 	err := a.Dependencies.Close(ctx)
 	if err != nil {
 		return err
 	}
 
+	// the spans still buffered are sent before the process exits
+	err = a.tracer.Close(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the last entries of the shutdown are flushed, a later entry opens the file again
+	log.Close()
+
 	return nil
 }
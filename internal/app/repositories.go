@@ -2,18 +2,46 @@ package app
 
 import (
 	"lion-parcel-test/internal/interfaces/repository"
+	backuprepo "lion-parcel-test/internal/repository/backup"
+	cacherepo "lion-parcel-test/internal/repository/cache"
+	catalogrepo "lion-parcel-test/internal/repository/catalog"
 	movierepo "lion-parcel-test/internal/repository/movie"
+	outboxrepo "lion-parcel-test/internal/repository/outbox"
+	ratelimitrepo "lion-parcel-test/internal/repository/ratelimit"
+	txrepo "lion-parcel-test/internal/repository/transaction"
 	userrepo "lion-parcel-test/internal/repository/user"
+	webhookrepo "lion-parcel-test/internal/repository/webhook"
 )
 
 type Repositories struct {
-	userRepository  repository.UserRepository
-	movieRepository repository.MovieRepository
+	userRepository      repository.UserRepository
+	movieRepository     repository.MovieRepository
+	catalogRepository   repository.CatalogRepository
+	webhookRepository   repository.WebhookRepository
+	outboxRepository    repository.OutboxRepository
+	transactor          repository.Transactor
+	backupRepository    repository.BackupRepository
+	rateLimitRepository repository.RateLimitRepository
 }
 
 func NewRepos(dependencies *Dependencies) *Repositories {
+	movieRepository := movierepo.NewMovieRepository(dependencies.database)
+	transactor := txrepo.NewTransactor(dependencies.database)
+
+	// the transactor tells the cache when a transaction that wrote movies or votes committed
+	if dependencies.cache != nil {
+		movieRepository = cacherepo.NewMovieRepository(movieRepository, dependencies.cache)
+		transactor = cacherepo.NewTransactor(transactor, dependencies.cache)
+	}
+
 	return &Repositories{
-		userRepository:  userrepo.NewUserRepository(dependencies.sqlitedb),
-		movieRepository: movierepo.NewMovieRepository(dependencies.sqlitedb),
+		userRepository:      userrepo.NewUserRepository(dependencies.database),
+		movieRepository:     movieRepository,
+		catalogRepository:   catalogrepo.NewCatalogRepository(dependencies.catalog),
+		webhookRepository:   webhookrepo.NewWebhookRepository(dependencies.database, dependencies.webhook),
+		outboxRepository:    outboxrepo.NewOutboxRepository(dependencies.database),
+		transactor:          transactor,
+		backupRepository:    backuprepo.NewBackupRepository(dependencies.backup),
+		rateLimitRepository: ratelimitrepo.NewRateLimitRepository(dependencies.rateLimitStore),
 	}
 }
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/pkg/tracing"
+)
+
+// newTracer builds the tracing.backend tracer, elastic when it is empty
+func newTracer(cfg *config.Config) (tracing.Tracer, error) {
+	switch cfg.Tracing.Backend {
+	case "", constant.TracingBackendElastic:
+		return tracing.NewElasticTracer(), nil
+	case constant.TracingBackendOtlp:
+		serviceName := cfg.Tracing.ServiceName
+		if serviceName == "" {
+			serviceName = cfg.App.Name
+		}
+
+		return tracing.NewOtlpTracer(tracing.OtlpOptions{
+			Endpoint:    cfg.Tracing.Otlp.Endpoint,
+			Headers:     cfg.Tracing.Otlp.Headers,
+			ServiceName: serviceName,
+			SampleRate:  cfg.Tracing.SampleRate,
+			Timeout:     cfg.Tracing.Otlp.Timeout,
+		})
+	case constant.TracingBackendNoop:
+		return tracing.NewNoopTracer(), nil
+	default:
+		return nil, fmt.Errorf("unknown tracing backend %s, expected %s, %s or %s", cfg.Tracing.Backend, constant.TracingBackendElastic, constant.TracingBackendOtlp, constant.TracingBackendNoop)
+	}
+}
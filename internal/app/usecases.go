@@ -1,20 +1,38 @@
 package app
 
 import (
+	"lion-parcel-test/constant"
 	"lion-parcel-test/internal/interfaces/usecase"
+	eventuc "lion-parcel-test/internal/usecase/event"
 	movieuc "lion-parcel-test/internal/usecase/movie"
+	ratelimituc "lion-parcel-test/internal/usecase/ratelimit"
+	systemuc "lion-parcel-test/internal/usecase/system"
 	useruc "lion-parcel-test/internal/usecase/user"
+	webhookuc "lion-parcel-test/internal/usecase/webhook"
 )
 
 type Usecases struct {
-	UserUsecase  usecase.UserUsecase
-	MovieUsecase usecase.MovieUsecase
+	UserUsecase      usecase.UserUsecase
+	MovieUsecase     usecase.MovieUsecase
+	SystemUsecase    usecase.SystemUsecase
+	WebhookUsecase   usecase.WebhookUsecase
+	EventBus         usecase.EventBus
+	RateLimitUsecase usecase.RateLimitUsecase
 }
 
 func NewUsecases(repos *Repositories) *Usecases {
+	webhookUsecase := webhookuc.NewWebhookUsecase(repos.webhookRepository)
+	movieUsecase := movieuc.NewMovieUsecase(repos.movieRepository, repos.catalogRepository, repos.transactor, webhookUsecase)
+
+	eventBus := eventuc.NewEventBus(repos.outboxRepository)
+	eventBus.Subscribe(constant.EventConsumerMovieWebhooks, movieUsecase.HandleMovieEvent, constant.EventMovieCreated, constant.EventMovieUpdated, constant.EventVoteCast)
 
 	return &Usecases{
-		UserUsecase:  useruc.NewUserUsecase(repos.userRepository),
-		MovieUsecase: movieuc.NewMovieUsecase(repos.movieRepository),
+		UserUsecase:      useruc.NewUserUsecase(repos.userRepository, repos.rateLimitRepository),
+		MovieUsecase:     movieUsecase,
+		SystemUsecase:    systemuc.NewSystemUsecase(repos.backupRepository),
+		WebhookUsecase:   webhookUsecase,
+		EventBus:         eventBus,
+		RateLimitUsecase: ratelimituc.NewRateLimitUsecase(repos.rateLimitRepository),
 	}
 }
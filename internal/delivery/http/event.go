@@ -0,0 +1,28 @@
+package http
+
+import (
+	"lion-parcel-test/internal/interfaces/delivery"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/tracing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type eventHandler struct {
+	eventBus usecase.EventBus
+}
+
+func NewEventHandler(eventBus usecase.EventBus) delivery.EventHandler {
+	return &eventHandler{
+		eventBus: eventBus,
+	}
+}
+
+func (h *eventHandler) GetEventConsumers(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "GetEventConsumers", "Handler")
+	defer span.End()
+
+	resp := h.eventBus.GetEventConsumers(ctx)
+
+	return writeResponse(c, resp)
+}
@@ -5,11 +5,16 @@ import (
 	"lion-parcel-test/config"
 	"lion-parcel-test/constant"
 	"lion-parcel-test/internal/app"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/health"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/metrics"
 	"lion-parcel-test/pkg/middleware"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"go.elastic.co/apm/module/apmfiber/v2"
 )
 
 type HttpServer struct {
@@ -18,40 +23,71 @@ type HttpServer struct {
 
 func NewHttpServer(app *app.App) (*HttpServer, error) {
 	validate := validator.New()
-	r := fiber.New()
+	r := fiber.New(fiber.Config{
+		// c.IP() reads the client ip from it behind a proxy, the rate limits count by it
+		ProxyHeader: config.Cfg.App.ProxyHeader,
+	})
 
 	userHandler := NewUserHandler(app.Usecases.UserUsecase, validate)
 	movieHandler := NewMovieHandler(app.Usecases.MovieUsecase, validate)
+	systemHandler := NewSystemHandler(app.Usecases.SystemUsecase)
+	webhookHandler := NewWebhookHandler(app.Usecases.WebhookUsecase, validate)
+	eventHandler := NewEventHandler(app.Usecases.EventBus)
+	rateLimitHandler := NewRateLimitHandler(app.Usecases.RateLimitUsecase)
 
-	r.Use(apmfiber.Middleware())
-	r.Use(middleware.LoggingMiddleware)
+	r.Use(tracing.Middleware())
+	r.Use(middleware.RequestIdMiddleware)
+	r.Use(middleware.NewLoggingMiddleware(newHttpLogger()))
+	r.Use(middleware.MetricsMiddleware)
 	r.Use(userHandler.PopulateSession)
 
 	// All users
-	r.Post(constant.RouteApiV1+"/register", userHandler.Register)
-	r.Post(constant.RouteApiV1+"/login", userHandler.Login)
+	r.Post(constant.RouteApiV1+"/register", rateLimitHandler.Limit(constant.RateLimitRuleRegister), userHandler.Register)
+	r.Post(constant.RouteApiV1+"/login", rateLimitHandler.Limit(constant.RateLimitRuleLogin), userHandler.Login)
 
 	r.Get(constant.RouteApiV1+"/movies", movieHandler.GetMovies)
 	r.Get(constant.RouteApiV1+"/movies/search", movieHandler.SearchMovies)
 
 	// admin
 	adminR := r.Group(constant.RouteApiV1+"/admin", userHandler.IsAdmin)
+	adminR.Get("/movies", movieHandler.AdminGetMovies)
 	adminR.Post("/movies", movieHandler.CreateMovie)
 	adminR.Put("/movies/:id", movieHandler.UpdateMovie)
+	adminR.Patch("/movies/:id", movieHandler.PatchMovie)
+	adminR.Put("/movies/:id/status", movieHandler.UpdateMovieStatus)
+	adminR.Get("/movies/:id/revisions", movieHandler.GetMovieRevisions)
+	adminR.Post("/movies/:id/revisions/:revision_id/rollback", movieHandler.RollbackMovie)
+	adminR.Get("/movies/:id/enrichment", movieHandler.PreviewMovieEnrichment)
+	adminR.Post("/movies/:id/enrichment", movieHandler.EnrichMovie)
 	adminR.Get("/movies/most_viewed", movieHandler.MostViewed)
 	adminR.Get("/movies/most_viewed_genre", movieHandler.MostViewedGenre)
 	adminR.Get("/movies/most_voted", movieHandler.MostVoted)
 	adminR.Get("/movies/most_voted_genre", movieHandler.MostVotedGenre)
+	adminR.Post("/movies/import", movieHandler.ImportMovies)
+	adminR.Get("/movies/export", movieHandler.ExportMovies)
+	// keep after the static /movies/* routes so they aren't captured as an id
+	adminR.Get("/movies/:id", movieHandler.GetMovie)
+	adminR.Get("/circuit_breakers", systemHandler.GetCircuitBreakers)
+	adminR.Get("/backups", systemHandler.GetBackups)
+	adminR.Post("/backups", systemHandler.CreateBackup)
+	adminR.Get("/events/consumers", eventHandler.GetEventConsumers)
+	adminR.Get("/webhooks", webhookHandler.GetWebhookSubscriptions)
+	adminR.Post("/webhooks", webhookHandler.CreateWebhookSubscription)
+	adminR.Put("/webhooks/:id", webhookHandler.UpdateWebhookSubscription)
+	adminR.Delete("/webhooks/:id", webhookHandler.DeleteWebhookSubscription)
+	adminR.Get("/webhooks/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+	adminR.Post("/webhooks/:id/deliveries/:delivery_id/redeliver", webhookHandler.RedeliverWebhook)
 
 	// authenticated user
 	authUser := r.Group(constant.RouteApiV1+"/movies", userHandler.IsAuthenticated)
-	authUser.Post("/vote", movieHandler.VoteMovie)
+	authUser.Post("/vote", rateLimitHandler.Limit(constant.RateLimitRuleVote), movieHandler.VoteMovie)
 	authUser.Post("/unvote", movieHandler.UnvoteMovie)
 	authUser.Get("/votes", movieHandler.VotedMovies)
 
 	// middeware to add view count of that movies
 	// app.Use("/uploads", staticFileMiddleware)
-	r.Static("/movies", "./movies")
+	r.Use("/movies", movieHandler.AuthorizeMovieFile, movieHandler.CountStreamedBytes)
+	r.Static("/movies", config.Cfg.MovieDir())
 
 	r.Get("/healthz", func(c *fiber.Ctx) error {
 		c.Set("Content-Security-Policy", "default-src 'self'")
@@ -59,11 +95,79 @@ func NewHttpServer(app *app.App) (*HttpServer, error) {
 		return c.SendString("ok")
 	})
 
+	// probes of the load balancer and the orchestrator, left open like /healthz
+	r.Get("/livez", probe(app.Health.Live))
+	r.Get("/readyz", probe(app.Health.Ready))
+
+	// Prometheus scrapes it, like /healthz it is left open
+	r.Get("/metrics", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, metrics.ContentType)
+		_, err := metrics.Default.WriteTo(c)
+		return err
+	})
+
 	return &HttpServer{
 		r,
 	}, nil
 }
 
+// probe answers 503 while a check fails, the body has the result of every check either way
+func probe(run func(ctx context.Context) health.Report) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report := run(c.Context())
+
+		c.Status(http.StatusOK)
+		if !report.Ok() {
+			c.Status(http.StatusServiceUnavailable)
+		}
+
+		return c.JSON(report)
+	}
+}
+
+// newHttpLogger redacts and caps the request logs as log.http says
+func newHttpLogger() *log.HttpLogger {
+	cfg := config.Cfg.Log.Http
+
+	sampling := make([]log.HttpSampling, 0, len(cfg.Sampling))
+	for _, route := range cfg.Sampling {
+		sampling = append(sampling, log.HttpSampling{
+			Route: route.Route,
+			Rate:  route.Rate,
+		})
+	}
+
+	return log.NewHttpLogger(log.HttpOptions{
+		RedactHeaders: cfg.RedactHeaders,
+		RedactFields:  cfg.RedactFields,
+		MaxBodySize:   cfg.MaxBodySize,
+		Sampling:      sampling,
+	})
+}
+
+// writeResponse sends a usecase response along with its http code and headers
+func writeResponse(c *fiber.Ctx, resp *dto.Response) error {
+	for key, value := range resp.Headers {
+		c.Set(key, value)
+	}
+
+	if resp.HttpCode >= http.StatusBadRequest {
+		resp.RequestId = log.RequestIdFromContext(c.Context())
+	}
+
+	c.Status(resp.HttpCode)
+	c.JSON(resp)
+	return nil
+}
+
+// writeError sends an error of the handler itself, its status is already set on c
+func writeError(c *fiber.Ctx, resp *dto.Response) error {
+	resp.RequestId = log.RequestIdFromContext(c.Context())
+
+	c.JSON(resp)
+	return nil
+}
+
 func (s *HttpServer) Run() error {
 	return s.Listen(":" + config.Cfg.App.Port)
 }
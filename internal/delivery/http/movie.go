@@ -1,22 +1,93 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"lion-parcel-test/config"
 	"lion-parcel-test/constant"
 	"lion-parcel-test/internal/interfaces/delivery"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/metrics"
+	"lion-parcel-test/pkg/tracing"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	jsoniter "github.com/json-iterator/go"
-	"go.elastic.co/apm/v2"
 )
 
+var (
+	movieUploads     = metrics.NewCounter("movie_uploads_total", "Movie files uploaded, by a create or an update.")
+	movieUploadBytes = metrics.NewCounter("movie_upload_bytes_total", "Bytes of the movie files uploaded.")
+	movieBytesSent   = metrics.NewCounter("movie_streamed_bytes_total", "Bytes of the movie files sent, a range request counts its range.")
+)
+
+// movieFileName never reuses the name of an earlier upload, so older revisions keep pointing at their own file
+func movieFileName(fileName string) string {
+	name := strings.ReplaceAll(fileName, " ", "-")
+
+	if _, err := os.Stat(filepath.Join(config.Cfg.MovieDir(), name)); err == nil {
+		name = strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + name
+	}
+
+	return name
+}
+
+func saveMovieFile(c *fiber.Ctx, file *multipart.FileHeader, fileName string) error {
+	if err := os.MkdirAll(config.Cfg.MovieDir(), os.ModePerm); err != nil {
+		return err
+	}
+
+	err := c.SaveFile(file, filepath.Join(config.Cfg.MovieDir(), fileName))
+	if err != nil {
+		return err
+	}
+
+	movieUploads.Inc()
+	movieUploadBytes.Add(float64(file.Size))
+
+	return nil
+}
+
+// discardMovieFile removes the file saved for a change the usecase refused, e.g. a stale If-Match, no movie points at it
+func discardMovieFile(ctx context.Context, resp *dto.Response, fileName string) {
+	if resp.HttpCode >= 200 && resp.HttpCode <= 299 {
+		return
+	}
+
+	err := os.Remove(filepath.Join(config.Cfg.MovieDir(), fileName))
+	if err != nil && !os.IsNotExist(err) {
+		log.Ctx(ctx).Warnw("failed to remove movie file of a refused change", "file", fileName, "error", err.Error())
+	}
+}
+
+// parseIfMatch reads the expected movie version from If-Match, 0 when the header is missing or "*"
+func parseIfMatch(c *fiber.Ctx) (int, error) {
+	value := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(value, "W/"), `"`))
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header %s", value)
+	}
+
+	return version, nil
+}
+
 type movieHandler struct {
 	movieUsecase usecase.MovieUsecase
 	validate     *validator.Validate
@@ -29,9 +100,51 @@ func NewMovieHandler(movieUsecase usecase.MovieUsecase, validate *validator.Vali
 	}
 }
 
+// AuthorizeMovieFile goes in front of the static movie files, only admins get the files of unpublished movies
+func (h *movieHandler) AuthorizeMovieFile(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "AuthorizeMovieFile", "Handler")
+	defer span.End()
+
+	fileName, err := url.PathUnescape(strings.TrimPrefix(c.Path(), "/movies/"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return writeError(c, dto.NewError(http.StatusNotFound, "NA", "Not Exist", err))
+	}
+
+	reqStruct := usecase.AuthorizeMovieFileRequest{
+		FileName: fileName,
+	}
+
+	session, _ := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+	if session != nil {
+		reqStruct.IsAdmin = session.IsAdmin
+	}
+
+	resp := h.movieUsecase.AuthorizeMovieFile(ctx, &reqStruct)
+	if resp.HttpCode != http.StatusOK {
+		return writeResponse(c, resp)
+	}
+
+	return c.Next()
+}
+
+func (h *movieHandler) CountStreamedBytes(c *fiber.Ctx) error {
+	err := c.Next()
+
+	// a HEAD request and a 304 send no body
+	status := c.Response().StatusCode()
+	if err == nil && c.Method() == fiber.MethodGet && (status == fiber.StatusOK || status == fiber.StatusPartialContent) {
+		if length := c.Response().Header.ContentLength(); length > 0 {
+			movieBytesSent.Add(float64(length))
+		}
+	}
+
+	return err
+}
+
 func (h *movieHandler) CreateMovie(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "CreateMovie", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "CreateMovie", "Handler")
+	defer span.End()
 
 	jsonformfile := c.FormValue("json")
 
@@ -39,51 +152,43 @@ func (h *movieHandler) CreateMovie(c *fiber.Ctx) error {
 
 	err := jsoniter.UnmarshalFromString(jsonformfile, &reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusUnprocessableEntity)
-		c.JSON(dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
 	}
 
 	file, err := c.FormFile("file")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("File upload failed: " + err.Error())
 	}
-	reqStruct.FileName = strings.ReplaceAll(file.Filename, " ", "-")
+	reqStruct.FileName = movieFileName(file.Filename)
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
 
 	err = h.validate.Struct(reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusBadRequest)
-		c.JSON(dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
 	}
 
-	uploadDir := "./movies"
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		apm.CaptureError(ctx, err).Send()
+	if err := saveMovieFile(c, file, reqStruct.FileName); err != nil {
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusInternalServerError)
-		c.JSON(dto.NewError(http.StatusInternalServerError, "FM", "Failed to create upload directory", err))
-		return nil
-	}
-
-	savePath := filepath.Join(uploadDir, reqStruct.FileName)
-	if err := c.SaveFile(file, savePath); err != nil {
-		apm.CaptureError(ctx, err).Send()
-		c.Status(http.StatusInternalServerError)
-		c.JSON(dto.NewError(http.StatusInternalServerError, "FM", "Failed to save movie file", err))
+		return writeError(c, dto.NewError(http.StatusInternalServerError, "FM", "Failed to save movie file", err))
 	}
 
 	resp := h.movieUsecase.CreateMovie(ctx, &reqStruct)
+	discardMovieFile(ctx, resp, reqStruct.FileName)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) UpdateMovie(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "CreateMovie", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "CreateMovie", "Handler")
+	defer span.End()
 
 	jsonformfile := c.FormValue("json")
 
@@ -91,77 +196,74 @@ func (h *movieHandler) UpdateMovie(c *fiber.Ctx) error {
 
 	err := jsoniter.UnmarshalFromString(jsonformfile, &reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusUnprocessableEntity)
-		c.JSON(dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
 	}
 
 	file, err := c.FormFile("file")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("File upload failed: " + err.Error())
 	}
-	reqStruct.FileName = strings.ReplaceAll(file.Filename, " ", "-")
+	reqStruct.FileName = movieFileName(file.Filename)
 
 	id := c.Params("id")
 
 	reqStruct.Id = id
 
-	err = h.validate.Struct(reqStruct)
+	ifMatch, err := parseIfMatch(c)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusBadRequest)
-		c.JSON(dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
 	}
 
-	uploadDir := "./movies"
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		apm.CaptureError(ctx, err).Send()
-		c.Status(http.StatusInternalServerError)
-		c.JSON(dto.NewError(http.StatusInternalServerError, "FM", "Failed to create upload directory", err))
-		return nil
+	reqStruct.IfMatch = ifMatch
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
+
+	err = h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
 	}
 
-	savePath := filepath.Join(uploadDir, reqStruct.FileName)
-	if err := c.SaveFile(file, savePath); err != nil {
-		apm.CaptureError(ctx, err).Send()
+	if err := saveMovieFile(c, file, reqStruct.FileName); err != nil {
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusInternalServerError)
-		c.JSON(dto.NewError(http.StatusInternalServerError, "FM", "Failed to save movie file", err))
+		return writeError(c, dto.NewError(http.StatusInternalServerError, "FM", "Failed to save movie file", err))
 	}
 
 	resp := h.movieUsecase.UpdateMovie(ctx, &reqStruct)
+	discardMovieFile(ctx, resp, reqStruct.FileName)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) MostViewed(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "MostViewed", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "MostViewed", "Handler")
+	defer span.End()
 
 	resp := h.movieUsecase.MostViewed(ctx, nil)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) MostViewedGenre(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "MostViewed", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "MostViewed", "Handler")
+	defer span.End()
 
 	resp := h.movieUsecase.MostViewedGenre(ctx, nil)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) GetMovies(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "GetMovies", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "GetMovies", "Handler")
+	defer span.End()
 
 	page := c.Query("page", "1")
 	pageSize := c.Query("pageSize", "10")
@@ -176,14 +278,12 @@ func (h *movieHandler) GetMovies(c *fiber.Ctx) error {
 
 	resp := h.movieUsecase.GetMovies(ctx, &reqStruct)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) SearchMovies(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "SearchMovies", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "SearchMovies", "Handler")
+	defer span.End()
 
 	keyword := c.Query("keyword", "")
 
@@ -193,14 +293,12 @@ func (h *movieHandler) SearchMovies(c *fiber.Ctx) error {
 
 	resp := h.movieUsecase.SearchMovies(ctx, &reqStruct)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) VoteMovie(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "VoteMovie", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "VoteMovie", "Handler")
+	defer span.End()
 
 	reqBody := c.Body()
 
@@ -208,10 +306,9 @@ func (h *movieHandler) VoteMovie(c *fiber.Ctx) error {
 
 	err := jsoniter.Unmarshal(reqBody, &reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusUnprocessableEntity)
-		c.JSON(dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
 	}
 
 	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
@@ -220,22 +317,19 @@ func (h *movieHandler) VoteMovie(c *fiber.Ctx) error {
 
 	err = h.validate.Struct(reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusBadRequest)
-		c.JSON(dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
 	}
 
 	resp := h.movieUsecase.VoteMovie(ctx, &reqStruct)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) UnvoteMovie(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "UnvoteMovie", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "UnvoteMovie", "Handler")
+	defer span.End()
 
 	reqBody := c.Body()
 
@@ -243,10 +337,9 @@ func (h *movieHandler) UnvoteMovie(c *fiber.Ctx) error {
 
 	err := jsoniter.Unmarshal(reqBody, &reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusUnprocessableEntity)
-		c.JSON(dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
 	}
 
 	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
@@ -255,22 +348,19 @@ func (h *movieHandler) UnvoteMovie(c *fiber.Ctx) error {
 
 	err = h.validate.Struct(reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusBadRequest)
-		c.JSON(dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
 	}
 
 	resp := h.movieUsecase.UnvoteMovie(ctx, &reqStruct)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) VotedMovies(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "VotedMovies", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "VotedMovies", "Handler")
+	defer span.End()
 
 	var reqStruct usecase.VotedMoviesRequest
 
@@ -280,37 +370,431 @@ func (h *movieHandler) VotedMovies(c *fiber.Ctx) error {
 
 	err := h.validate.Struct(reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusBadRequest)
-		c.JSON(dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
 	}
 
 	resp := h.movieUsecase.VotedMovies(ctx, &reqStruct)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) MostVoted(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "MostVoted", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "MostVoted", "Handler")
+	defer span.End()
 
 	resp := h.movieUsecase.MostVoted(ctx, nil)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *movieHandler) MostVotedGenre(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "MostVotedGenre", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "MostVotedGenre", "Handler")
+	defer span.End()
 
 	resp := h.movieUsecase.MostVotedGenre(ctx, nil)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
+	return writeResponse(c, resp)
+}
+
+func (h *movieHandler) GetMovie(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "GetMovie", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.GetMovieRequest
+
+	reqStruct.Id = c.Params("id")
+
+	resp := h.movieUsecase.GetMovie(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *movieHandler) AdminGetMovies(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "AdminGetMovies", "Handler")
+	defer span.End()
+
+	page := c.Query("page", "1")
+	pageSize := c.Query("pageSize", "10")
+
+	pageInt, _ := strconv.Atoi(page)
+	pageSizeInt, _ := strconv.Atoi(pageSize)
+
+	var reqStruct usecase.AdminGetMoviesRequest
+
+	reqStruct.Status = c.Query("status", "")
+	reqStruct.Page = pageInt
+	reqStruct.PageSize = pageSizeInt
+
+	err := h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.movieUsecase.AdminGetMovies(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *movieHandler) UpdateMovieStatus(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "UpdateMovieStatus", "Handler")
+	defer span.End()
+
+	reqBody := c.Body()
+
+	var reqStruct usecase.UpdateMovieStatusRequest
+
+	err := jsoniter.Unmarshal(reqBody, &reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusUnprocessableEntity)
+		return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
+	}
+
+	reqStruct.Id = c.Params("id")
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
+
+	err = h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.movieUsecase.UpdateMovieStatus(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *movieHandler) GetMovieRevisions(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "GetMovieRevisions", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.GetMovieRevisionsRequest
+
+	reqStruct.Id = c.Params("id")
+
+	resp := h.movieUsecase.GetMovieRevisions(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *movieHandler) RollbackMovie(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "RollbackMovie", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.RollbackMovieRequest
+
+	revisionId, _ := strconv.Atoi(c.Params("revision_id"))
+
+	reqStruct.Id = c.Params("id")
+	reqStruct.RevisionId = revisionId
+
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	reqStruct.IfMatch = ifMatch
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
+
+	err = h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.movieUsecase.RollbackMovie(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+// PatchMovie accepts a JSON Merge Patch body, or a multipart form with the patch in "json" and an optional "file"
+func (h *movieHandler) PatchMovie(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "PatchMovie", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.PatchMovieRequest
+
+	patchBody := c.Body()
+
+	var file *multipart.FileHeader
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		patchBody = []byte(c.FormValue("json"))
+
+		// the file is optional, a missing one leaves the current file in place
+		if formFile, err := c.FormFile("file"); err == nil {
+			file = formFile
+			reqStruct.FileName = movieFileName(formFile.Filename)
+		}
+	}
+
+	err := jsoniter.Unmarshal(patchBody, &reqStruct.Patch)
+	if err == nil && reqStruct.Patch == nil {
+		err = fmt.Errorf("merge patch must be a JSON object")
+	}
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusUnprocessableEntity)
+		return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
+	}
+
+	reqStruct.Id = c.Params("id")
+
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	reqStruct.IfMatch = ifMatch
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
+
+	err = h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	if file != nil {
+		if err := saveMovieFile(c, file, reqStruct.FileName); err != nil {
+			tracing.CaptureError(ctx, err)
+			c.Status(http.StatusInternalServerError)
+			return writeError(c, dto.NewError(http.StatusInternalServerError, "FM", "Failed to save movie file", err))
+		}
+	}
+
+	resp := h.movieUsecase.PatchMovie(ctx, &reqStruct)
+	if file != nil {
+		discardMovieFile(ctx, resp, reqStruct.FileName)
+	}
+
+	return writeResponse(c, resp)
+}
+
+// catalogFormat picks the import format from the format query, the file extension or the content type, in that order
+func catalogFormat(format string, fileName string, contentType string) string {
+	if format != "" {
+		return format
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return "csv"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	}
+
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return "csv"
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/jsonl"):
+		return "jsonl"
+	}
+
+	return ""
+}
+
+// ImportMovies takes the catalog either as the "file" of a multipart form or as the raw request body
+func (h *movieHandler) ImportMovies(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "ImportMovies", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.ImportMoviesRequest
+
+	contentType := c.Get(fiber.HeaderContentType)
+	fileName := ""
+
+	if strings.HasPrefix(contentType, fiber.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("File upload failed: " + err.Error())
+		}
+
+		catalog, err := file.Open()
+		if err != nil {
+			tracing.CaptureError(ctx, err)
+			c.Status(http.StatusInternalServerError)
+			return writeError(c, dto.NewError(http.StatusInternalServerError, "FM", "Failed to open catalog file", err))
+		}
+		defer catalog.Close()
+
+		fileName = file.Filename
+		reqStruct.Reader = catalog
+	} else {
+		reqStruct.Reader = bytes.NewReader(c.Body())
+	}
+
+	reqStruct.Format = catalogFormat(c.Query("format"), fileName, contentType)
+	reqStruct.DryRun = c.QueryBool("dry_run", false)
+	reqStruct.Mode = c.Query("mode")
+	reqStruct.BatchSize = c.QueryInt("batch_size", 0)
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
+
+	err := h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.movieUsecase.ImportMovies(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+// ExportMovies streams the catalog, so errors after the first byte can only be logged
+func (h *movieHandler) ExportMovies(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "ExportMovies", "Handler")
+	defer span.End()
+
+	format := c.Query("format", "jsonl")
+
+	err := h.validate.Var(format, "oneof=csv jsonl")
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	if format == "csv" {
+		c.Set(fiber.HeaderContentType, "text/csv")
+	} else {
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	}
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="movies.`+format+`"`)
+
+	// the stream is written once the handler returned, the request ctx is handed back by then. The export runs on
+	// a ctx of its own with the request id and the span of the request, canceled once the client stopped reading
+	exportCtx, cancel := context.WithCancel(log.Detach(ctx))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		resp := h.movieUsecase.ExportMovies(exportCtx, &usecase.ExportMoviesRequest{
+			Format: format,
+			Writer: cancelOnError{Writer: w, cancel: cancel},
+		})
+		if resp.Code != "00" {
+			log.Ctx(exportCtx).Errorw("failed to export movies", "code", resp.Code, "desc", resp.Desc, "data", resp.Data)
+		} else {
+			log.Ctx(exportCtx).Infow("movies exported", "data", resp.Data)
+		}
+
+		w.Flush()
+	})
+
 	return nil
 }
+
+// cancelOnError cancels the ctx of a streamed response once a write fails, the client is gone by then
+type cancelOnError struct {
+	io.Writer
+	cancel context.CancelFunc
+}
+
+func (c cancelOnError) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	if err != nil {
+		c.cancel()
+	}
+
+	return n, err
+}
+
+// PreviewMovieEnrichment takes the lookup from the query, e.g. ?title=Heat&year=1995&fields=description,poster&overwrite=true
+func (h *movieHandler) PreviewMovieEnrichment(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "PreviewMovieEnrichment", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.EnrichMovieRequest
+
+	year, _ := strconv.Atoi(c.Query("year", "0"))
+
+	reqStruct.Id = c.Params("id")
+	reqStruct.Title = c.Query("title")
+	reqStruct.Year = year
+	reqStruct.Overwrite = c.QueryBool("overwrite", false)
+
+	if fields := c.Query("fields"); fields != "" {
+		reqStruct.Fields = strings.Split(fields, ",")
+	}
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
+
+	err := h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.movieUsecase.PreviewMovieEnrichment(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+// EnrichMovie takes the same lookup as PreviewMovieEnrichment as an optional json body, honors If-Match
+func (h *movieHandler) EnrichMovie(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "EnrichMovie", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.EnrichMovieRequest
+
+	if reqBody := c.Body(); len(reqBody) > 0 {
+		err := jsoniter.Unmarshal(reqBody, &reqStruct)
+		if err != nil {
+			tracing.CaptureError(ctx, err)
+			c.Status(http.StatusUnprocessableEntity)
+			return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
+		}
+	}
+
+	reqStruct.Id = c.Params("id")
+
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	reqStruct.IfMatch = ifMatch
+
+	session := c.Locals(constant.UserSessionKey).(*usecase.UserSession)
+
+	reqStruct.UserId = session.Id
+
+	err = h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.movieUsecase.EnrichMovie(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
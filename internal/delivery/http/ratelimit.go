@@ -0,0 +1,48 @@
+package http
+
+import (
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/delivery"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type rateLimitHandler struct {
+	rateLimitUsecase usecase.RateLimitUsecase
+}
+
+func NewRateLimitHandler(rateLimitUsecase usecase.RateLimitUsecase) delivery.RateLimitHandler {
+	return &rateLimitHandler{
+		rateLimitUsecase: rateLimitUsecase,
+	}
+}
+
+func (h *rateLimitHandler) Limit(rule string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		span, ctx := tracing.StartSpan(c.Context(), "Limit", "Handler")
+		defer span.End()
+
+		req := usecase.RateLimitRequest{
+			Rule:  rule,
+			Ip:    c.IP(),
+			Route: c.Method() + " " + c.Route().Path,
+		}
+		if session, ok := c.Locals(constant.UserSessionKey).(*usecase.UserSession); ok {
+			req.UserId = session.Id
+		}
+
+		resp := h.rateLimitUsecase.Allow(ctx, &req)
+		if resp.HttpCode != http.StatusOK {
+			return writeResponse(c, resp)
+		}
+
+		for key, value := range resp.Headers {
+			c.Set(key, value)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"lion-parcel-test/internal/interfaces/delivery"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/tracing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type systemHandler struct {
+	systemUsecase usecase.SystemUsecase
+}
+
+func NewSystemHandler(systemUsecase usecase.SystemUsecase) delivery.SystemHandler {
+	return &systemHandler{
+		systemUsecase: systemUsecase,
+	}
+}
+
+func (h *systemHandler) GetCircuitBreakers(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "GetCircuitBreakers", "Handler")
+	defer span.End()
+
+	resp := h.systemUsecase.GetCircuitBreakers(ctx)
+
+	return writeResponse(c, resp)
+}
+
+func (h *systemHandler) CreateBackup(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "CreateBackup", "Handler")
+	defer span.End()
+
+	resp := h.systemUsecase.CreateBackup(ctx)
+
+	return writeResponse(c, resp)
+}
+
+func (h *systemHandler) GetBackups(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "GetBackups", "Handler")
+	defer span.End()
+
+	resp := h.systemUsecase.GetBackups(ctx)
+
+	return writeResponse(c, resp)
+}
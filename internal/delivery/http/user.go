@@ -5,13 +5,15 @@ import (
 	"lion-parcel-test/internal/interfaces/delivery"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	jsoniter "github.com/json-iterator/go"
-	"go.elastic.co/apm/v2"
 )
 
 type userHandler struct {
@@ -27,8 +29,8 @@ func NewUserHandler(userUsecase usecase.UserUsecase, validate *validator.Validat
 }
 
 func (h *userHandler) Register(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "Register", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "Register", "Handler")
+	defer span.End()
 
 	reqBody := c.Body()
 
@@ -36,30 +38,26 @@ func (h *userHandler) Register(c *fiber.Ctx) error {
 
 	err := jsoniter.Unmarshal(reqBody, &reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusUnprocessableEntity)
-		c.JSON(dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
 	}
 
 	err = h.validate.Struct(reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusBadRequest)
-		c.JSON(dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
 	}
 
 	resp := h.userUsecase.Register(ctx, &reqStruct)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *userHandler) Login(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "Register", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "Register", "Handler")
+	defer span.End()
 
 	reqBody := c.Body()
 
@@ -67,30 +65,28 @@ func (h *userHandler) Login(c *fiber.Ctx) error {
 
 	err := jsoniter.Unmarshal(reqBody, &reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusUnprocessableEntity)
-		c.JSON(dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "FM", "error unmarshall", err))
 	}
 
 	err = h.validate.Struct(reqStruct)
 	if err != nil {
-		apm.CaptureError(ctx, err).Send()
+		tracing.CaptureError(ctx, err)
 		c.Status(http.StatusBadRequest)
-		c.JSON(dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
-		return nil
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
 	}
 
+	reqStruct.Ip = c.IP()
+
 	resp := h.userUsecase.Login(ctx, &reqStruct)
 
-	c.Status(resp.HttpCode)
-	c.JSON(resp)
-	return nil
+	return writeResponse(c, resp)
 }
 
 func (h *userHandler) PopulateSession(c *fiber.Ctx) error {
-	apmSpan, ctx := apm.StartSpan(c.Context(), "IsAdmin", "Handler")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(c.Context(), "IsAdmin", "Handler")
+	defer span.End()
 
 	authHeader := c.Get("Authorization")
 
@@ -125,14 +121,18 @@ func (h *userHandler) PopulateSession(c *fiber.Ctx) error {
 	}
 
 	c.Locals(constant.UserSessionKey, userSession)
+	c.Locals(log.UserIdKey, userSession.Id)
+
+	tracing.SetUser(c.Context(), strconv.Itoa(userSession.Id))
+
 	c.Next()
 
 	return nil
 }
 
 func (h *userHandler) IsAdmin(c *fiber.Ctx) error {
-	apmSpan, _ := apm.StartSpan(c.Context(), "IsAdmin", "Handler")
-	defer apmSpan.End()
+	span, _ := tracing.StartSpan(c.Context(), "IsAdmin", "Handler")
+	defer span.End()
 
 	authHeader := c.Get("Authorization")
 
@@ -162,8 +162,8 @@ func (h *userHandler) IsAdmin(c *fiber.Ctx) error {
 }
 
 func (h *userHandler) IsAuthenticated(c *fiber.Ctx) error {
-	apmSpan, _ := apm.StartSpan(c.Context(), "IsAdmin", "Handler")
-	defer apmSpan.End()
+	span, _ := tracing.StartSpan(c.Context(), "IsAdmin", "Handler")
+	defer span.End()
 
 	authHeader := c.Get("Authorization")
 
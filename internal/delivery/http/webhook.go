@@ -0,0 +1,153 @@
+package http
+
+import (
+	"lion-parcel-test/internal/interfaces/delivery"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+type webhookHandler struct {
+	webhookUsecase usecase.WebhookUsecase
+	validate       *validator.Validate
+}
+
+func NewWebhookHandler(webhookUsecase usecase.WebhookUsecase, validate *validator.Validate) delivery.WebhookHandler {
+	return &webhookHandler{
+		webhookUsecase: webhookUsecase,
+		validate:       validate,
+	}
+}
+
+func (h *webhookHandler) CreateWebhookSubscription(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "CreateWebhookSubscription", "Handler")
+	defer span.End()
+
+	reqBody := c.Body()
+
+	var reqStruct usecase.CreateWebhookSubscriptionRequest
+
+	err := jsoniter.Unmarshal(reqBody, &reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusUnprocessableEntity)
+		return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
+	}
+
+	err = h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.webhookUsecase.CreateWebhookSubscription(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *webhookHandler) GetWebhookSubscriptions(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "GetWebhookSubscriptions", "Handler")
+	defer span.End()
+
+	resp := h.webhookUsecase.GetWebhookSubscriptions(ctx)
+
+	return writeResponse(c, resp)
+}
+
+func (h *webhookHandler) UpdateWebhookSubscription(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "UpdateWebhookSubscription", "Handler")
+	defer span.End()
+
+	reqBody := c.Body()
+
+	var reqStruct usecase.UpdateWebhookSubscriptionRequest
+
+	err := jsoniter.Unmarshal(reqBody, &reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusUnprocessableEntity)
+		return writeError(c, dto.NewError(http.StatusUnprocessableEntity, "FM", "error unmarshall", err))
+	}
+
+	reqStruct.Id, _ = strconv.Atoi(c.Params("id"))
+
+	err = h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.webhookUsecase.UpdateWebhookSubscription(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *webhookHandler) DeleteWebhookSubscription(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "DeleteWebhookSubscription", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.DeleteWebhookSubscriptionRequest
+
+	reqStruct.Id, _ = strconv.Atoi(c.Params("id"))
+
+	err := h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.webhookUsecase.DeleteWebhookSubscription(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *webhookHandler) GetWebhookDeliveries(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "GetWebhookDeliveries", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.GetWebhookDeliveriesRequest
+
+	reqStruct.SubscriptionId, _ = strconv.Atoi(c.Params("id"))
+	reqStruct.Status = c.Query("status")
+
+	err := h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.webhookUsecase.GetWebhookDeliveries(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
+
+func (h *webhookHandler) RedeliverWebhook(c *fiber.Ctx) error {
+	span, ctx := tracing.StartSpan(c.Context(), "RedeliverWebhook", "Handler")
+	defer span.End()
+
+	var reqStruct usecase.RedeliverWebhookRequest
+
+	reqStruct.SubscriptionId, _ = strconv.Atoi(c.Params("id"))
+	reqStruct.DeliveryId, _ = strconv.Atoi(c.Params("delivery_id"))
+
+	err := h.validate.Struct(reqStruct)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		c.Status(http.StatusBadRequest)
+		return writeError(c, dto.NewError(http.StatusBadRequest, "VE", "Validation Error", err))
+	}
+
+	resp := h.webhookUsecase.RedeliverWebhook(ctx, &reqStruct)
+
+	return writeResponse(c, resp)
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"context"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/app"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/tracing"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPublishInterval = time.Minute
+	defaultWebhookInterval = 5 * time.Second
+	defaultEventInterval   = time.Second
+)
+
+// Scheduler runs the application's background jobs, like publishing scheduled movies, dispatching domain events,
+// sending webhooks and taking database snapshots
+type Scheduler struct {
+	app             *app.App
+	interval        time.Duration
+	webhookInterval time.Duration
+	eventInterval   time.Duration
+	// zero when the snapshots are off
+	backupInterval time.Duration
+	stop           chan struct{}
+	done           chan struct{}
+}
+
+func NewScheduler(app *app.App) (*Scheduler, error) {
+	interval := config.Cfg.Scheduler.PublishInterval
+	if interval <= 0 {
+		interval = defaultPublishInterval
+	}
+
+	webhookInterval := config.Cfg.Scheduler.WebhookInterval
+	if webhookInterval <= 0 {
+		webhookInterval = defaultWebhookInterval
+	}
+
+	eventInterval := config.Cfg.Scheduler.EventInterval
+	if eventInterval <= 0 {
+		eventInterval = defaultEventInterval
+	}
+
+	return &Scheduler{
+		app:             app,
+		interval:        interval,
+		webhookInterval: webhookInterval,
+		eventInterval:   eventInterval,
+		backupInterval:  config.Cfg.Scheduler.BackupInterval,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}, nil
+}
+
+// Run blocks and ticks until Stop is called. Every job ticks on a goroutine of its own,
+// so a long backup or a slow webhook receiver doesn't hold up the others
+func (s *Scheduler) Run() {
+	defer close(s.done)
+
+	var wg sync.WaitGroup
+
+	run := func(interval time.Duration, job func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.every(interval, job)
+		}()
+	}
+
+	run(s.interval, s.publishScheduledMovies)
+	run(s.webhookInterval, s.dispatchWebhooks)
+	run(s.eventInterval, s.dispatchEvents)
+	if s.backupInterval > 0 {
+		run(s.backupInterval, s.createBackup)
+	}
+
+	wg.Wait()
+}
+
+// every runs job at interval until Stop is called, a run that is still going is waited for
+func (s *Scheduler) every(interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			job()
+		}
+	}
+}
+
+func (s *Scheduler) Stop(ctx context.Context) error {
+	close(s.stop)
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) publishScheduledMovies() {
+	tx, ctx := tracing.StartTransaction(context.Background(), "PublishScheduledMovies", tracing.TypeScheduler)
+	defer tx.End()
+
+	resp := s.app.Usecases.MovieUsecase.PublishScheduledMovies(ctx)
+	if resp.Code != "00" {
+		log.Ctx(ctx).Errorw("failed to publish scheduled movies", "code", resp.Code, "desc", resp.Desc, "data", resp.Data)
+		return
+	}
+
+	log.LogDebug("scheduled movies published")
+}
+
+func (s *Scheduler) dispatchWebhooks() {
+	tx, ctx := tracing.StartTransaction(context.Background(), "DispatchWebhooks", tracing.TypeScheduler)
+	defer tx.End()
+
+	resp := s.app.Usecases.WebhookUsecase.DispatchWebhooks(ctx)
+	if resp.Code != "00" {
+		log.Ctx(ctx).Errorw("failed to dispatch webhooks", "code", resp.Code, "desc", resp.Desc, "data", resp.Data)
+		return
+	}
+
+	log.LogDebug("webhooks dispatched")
+}
+
+func (s *Scheduler) dispatchEvents() {
+	tx, ctx := tracing.StartTransaction(context.Background(), "DispatchEvents", tracing.TypeScheduler)
+	defer tx.End()
+
+	resp := s.app.Usecases.EventBus.DispatchEvents(ctx)
+	if resp.Code != "00" {
+		log.Ctx(ctx).Errorw("failed to dispatch events", "code", resp.Code, "desc", resp.Desc, "data", resp.Data)
+		return
+	}
+
+	log.LogDebug("events dispatched")
+}
+
+func (s *Scheduler) createBackup() {
+	tx, ctx := tracing.StartTransaction(context.Background(), "CreateBackup", tracing.TypeScheduler)
+	defer tx.End()
+
+	resp := s.app.Usecases.SystemUsecase.CreateBackup(ctx)
+	if resp.Code != "00" {
+		log.Ctx(ctx).Errorw("failed to create backup", "code", resp.Code, "desc", resp.Desc, "data", resp.Data)
+		return
+	}
+
+	log.Ctx(ctx).Infow("backup created", "data", resp.Data)
+}
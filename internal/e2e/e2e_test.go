@@ -0,0 +1,1216 @@
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/e2e"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const api = constant.RouteApiV1
+
+func TestAuthentication(t *testing.T) {
+	h := e2e.New(t)
+
+	h.Register("alice@example.com", "alice")
+	h.Expect(h.Request(http.MethodPost, api+"/register", "", map[string]string{"email": "alice@example.com", "name": "alice"}), http.StatusNotFound, "FD")
+	h.Expect(h.Request(http.MethodPost, api+"/login", "", map[string]string{"email": "nobody@example.com"}), http.StatusNotFound, "NA")
+
+	user := h.Login("alice@example.com")
+	admin := h.AdminToken("admin")
+
+	// the middlewares answer with their own {"error": ...}
+	for _, tc := range []struct {
+		name  string
+		path  string
+		token string
+	}{
+		{"anonymous user route", api + "/movies/votes", ""},
+		{"anonymous admin route", api + "/admin/movies", ""},
+		{"user on an admin route", api + "/admin/movies", user},
+		{"tampered token", api + "/movies/votes", user + "x"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if resp := h.Request(http.MethodGet, tc.path, tc.token, nil); resp.HttpCode != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d %s", resp.HttpCode, resp.Data)
+			}
+		})
+	}
+
+	h.Expect(h.Request(http.MethodGet, api+"/movies/votes", user, nil), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies", admin, nil), http.StatusOK, "00")
+
+	resp := h.Do(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("unexpected healthz %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestMovieLifecycle(t *testing.T) {
+	h := e2e.New(t)
+	admin := h.AdminToken("admin")
+
+	movie := h.UploadMovie(admin, "Arrival", constant.MovieStatusDraft)
+	if movie.Status != constant.MovieStatusDraft || movie.Version != 1 {
+		t.Fatalf("unexpected uploaded movie %+v", movie)
+	}
+
+	alice := h.UserToken("alice")
+	movieId, _ := strconv.Atoi(movie.Id)
+
+	// the upload is stored in the storage dir and served from there, only to admins until it is published
+	file := movie.WatchUrl[strings.Index(movie.WatchUrl, "/movies/"):]
+	for token, want := range map[string]int{"": http.StatusNotFound, alice: http.StatusNotFound, admin: http.StatusOK} {
+		if code, body := movieFile(t, h, file, token); code != want || (want == http.StatusOK && body != "movie Arrival") {
+			t.Fatalf("expected the draft file to answer %d, got %d %s from %s", want, code, body, movie.WatchUrl)
+		}
+	}
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": movieId}), http.StatusNotFound, "NA")
+
+	var list struct {
+		Movies []e2e.Movie `json:"movies"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/movies", "", nil), http.StatusOK, "00"), &list)
+	if len(list.Movies) != 0 {
+		t.Fatalf("draft listed publicly %+v", list.Movies)
+	}
+
+	h.Expect(h.Request(http.MethodPut, api+"/admin/movies/"+movie.Id+"/status", admin, map[string]string{"status": "published"}), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodPut, api+"/admin/movies/"+movie.Id+"/status", admin, map[string]string{"status": "gone"}), http.StatusBadRequest, "VE")
+	h.Expect(h.Request(http.MethodPut, api+"/admin/movies/404/status", admin, map[string]string{"status": "draft"}), http.StatusNotFound, "NA")
+
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/movies", "", nil), http.StatusOK, "00"), &list)
+	if len(list.Movies) != 1 || list.Movies[0].Id != movie.Id {
+		t.Fatalf("published movie not listed %+v", list.Movies)
+	}
+	if code, body := movieFile(t, h, file, ""); code != http.StatusOK || body != "movie Arrival" {
+		t.Fatalf("expected the published file, got %d %s", code, body)
+	}
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": movieId}), http.StatusOK, "00")
+	// a path that isn't the file of a movie looks missing
+	if code, _ := movieFile(t, h, "/movies/..%2Fmovies.db", ""); code != http.StatusNotFound {
+		t.Fatalf("expected an unknown file to be missing, got %d", code)
+	}
+
+	var search struct {
+		Movies []e2e.Movie `json:"movies"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/movies/search?keyword=arriv", "", nil), http.StatusOK, "00"), &search)
+	if len(search.Movies) != 1 {
+		t.Fatalf("unexpected search result %+v", search.Movies)
+	}
+
+	var got e2e.Movie
+	resp2 := h.Expect(h.Request(http.MethodGet, api+"/admin/movies/"+movie.Id, admin, nil), http.StatusOK, "00")
+	h.Decode(resp2, &got)
+	if got.Version != 2 || resp2.Header.Get("ETag") != `"2"` {
+		t.Fatalf("expected version 2 after publishing, got %+v %s", got, resp2.Header.Get("ETag"))
+	}
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies/404", admin, nil), http.StatusNotFound, "NA")
+
+	// a full update with a new file, guarded by If-Match
+	fields := e2e.MovieFixture("Arrival (2016)", "")
+	req := h.Multipart(http.MethodPut, api+"/admin/movies/"+movie.Id, fields, "arrival-2016.mp4", []byte("movie Arrival (2016)"))
+	req.Header.Set("If-Match", `"1"`)
+	h.Expect(h.Send(req, admin), http.StatusPreconditionFailed, "VM")
+	req = h.Multipart(http.MethodPut, api+"/admin/movies/404", fields, "arrival-2016.mp4", []byte("movie Arrival (2016)"))
+	h.Expect(h.Send(req, admin), http.StatusNotFound, "NA")
+	// a refused change leaves no file behind
+	uploaded := filepath.Join(h.Config.Storage.MovieDir, "arrival-2016.mp4")
+	if _, err := os.Stat(uploaded); !os.IsNotExist(err) {
+		t.Fatalf("expected the file of the refused updates to be removed, got %v", err)
+	}
+
+	req = h.Multipart(http.MethodPut, api+"/admin/movies/"+movie.Id, fields, "arrival-2016.mp4", []byte("movie Arrival (2016)"))
+	req.Header.Set("If-Match", `"2"`)
+	h.Expect(h.Send(req, admin), http.StatusOK, "00")
+	if _, err := os.Stat(uploaded); err != nil {
+		t.Fatalf("expected the file of the update under its own name: %s", err)
+	}
+
+	// a patch with a file is refused the same way
+	req = h.Multipart(http.MethodPatch, api+"/admin/movies/"+movie.Id, map[string]interface{}{"description": "stale"}, "arrival-patch.mp4", []byte("movie Arrival patch"))
+	req.Header.Set("If-Match", `"2"`)
+	h.Expect(h.Send(req, admin), http.StatusPreconditionFailed, "VM")
+	if _, err := os.Stat(filepath.Join(h.Config.Storage.MovieDir, "arrival-patch.mp4")); !os.IsNotExist(err) {
+		t.Fatalf("expected the file of the refused patch to be removed, got %v", err)
+	}
+
+	// a JSON Merge Patch
+	req = httptest.NewRequest(http.MethodPatch, api+"/admin/movies/"+movie.Id, strings.NewReader(`{"description":"patched","year":2016}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", `"3"`)
+	var patched e2e.Movie
+	h.Decode(h.Expect(h.Send(req, admin), http.StatusOK, "00"), &patched)
+	if patched.Description != "patched" || patched.Year != 2016 || patched.Version != 4 {
+		t.Fatalf("unexpected patched movie %+v", patched)
+	}
+
+	var revisions struct {
+		Revisions []struct {
+			Id     int    `json:"id"`
+			Action string `json:"action"`
+		} `json:"revisions"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/movies/"+movie.Id+"/revisions", admin, nil), http.StatusOK, "00"), &revisions)
+	if len(revisions.Revisions) != 4 || revisions.Revisions[3].Action != constant.MovieRevisionActionCreate {
+		t.Fatalf("unexpected revisions %+v", revisions.Revisions)
+	}
+
+	created := strconv.Itoa(revisions.Revisions[3].Id)
+	var rolledBack e2e.Movie
+	h.Decode(h.Expect(h.Request(http.MethodPost, api+"/admin/movies/"+movie.Id+"/revisions/"+created+"/rollback", admin, nil), http.StatusOK, "00"), &rolledBack)
+	if rolledBack.Title != "Arrival" || rolledBack.Description != "Arrival description" {
+		t.Fatalf("unexpected movie after rollback %+v", rolledBack)
+	}
+	h.Expect(h.Request(http.MethodPost, api+"/admin/movies/"+movie.Id+"/revisions/999/rollback", admin, nil), http.StatusNotFound, "NA")
+
+	var drafts struct {
+		Movies []e2e.Movie `json:"movies"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/movies?status=draft", admin, nil), http.StatusOK, "00"), &drafts)
+	if len(drafts.Movies) != 0 {
+		t.Fatalf("the rollback kept the movie published, no drafts expected %+v", drafts.Movies)
+	}
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies?status=deleted", admin, nil), http.StatusBadRequest, "VE")
+}
+
+// movieFile gets a static movie file as the caller of token, empty for an anonymous one
+func movieFile(t *testing.T, h *e2e.Harness, path string, token string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp := h.Do(req)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestVotes(t *testing.T) {
+	h := e2e.New(t)
+	admin := h.AdminToken("admin")
+	alice := h.UserToken("alice")
+	bob := h.UserToken("bob")
+
+	up := h.UploadMovie(admin, "Up", constant.MovieStatusPublished)
+	coco := h.UploadMovie(admin, "Coco", constant.MovieStatusPublished)
+	upId, _ := strconv.Atoi(up.Id)
+	cocoId, _ := strconv.Atoi(coco.Id)
+
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": upId}), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", bob, map[string]int{"movie_id": upId}), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", bob, map[string]int{"movie_id": cocoId}), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": upId}), http.StatusNotFound, "AV")
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": 9999}), http.StatusNotFound, "NA")
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", alice, map[string]int{}), http.StatusBadRequest, "VE")
+
+	var voted struct {
+		Movies []e2e.Movie `json:"movies"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/movies/votes", bob, nil), http.StatusOK, "00"), &voted)
+	if len(voted.Movies) != 2 {
+		t.Fatalf("expected bob's 2 votes, got %+v", voted.Movies)
+	}
+
+	var mostVoted e2e.Movie
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/movies/most_voted", admin, nil), http.StatusOK, "00"), &mostVoted)
+	if mostVoted.Id != up.Id {
+		t.Fatalf("expected Up to be the most voted, got %+v", mostVoted)
+	}
+
+	var genre struct {
+		VotesCount int    `json:"votes_count"`
+		Genre      string `json:"genre"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/movies/most_voted_genre", admin, nil), http.StatusOK, "00"), &genre)
+	if genre.Genre != "Drama" || genre.VotesCount != 3 {
+		t.Fatalf("unexpected most voted genre %+v", genre)
+	}
+
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies/most_viewed", admin, nil), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies/most_viewed_genre", admin, nil), http.StatusOK, "00")
+
+	h.Expect(h.Request(http.MethodPost, api+"/movies/unvote", alice, map[string]int{"movie_id": upId}), http.StatusOK, "00")
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/movies/votes", alice, nil), http.StatusOK, "00"), &voted)
+	if len(voted.Movies) != 0 {
+		t.Fatalf("vote kept after unvote %+v", voted.Movies)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	h := e2e.New(t)
+	admin := h.AdminToken("admin")
+
+	// imported rows point at files already in the storage dir
+	writeMovieFiles(t, h, "heat.mp4", "ronin.mp4")
+
+	catalog := `{"title":"Heat","description":"heist","duration":170,"artists":"Al Pacino","genres":"Crime","file_name":"heat.mp4","status":"published"}
+{"title":"Ronin","description":"heist","duration":122,"artists":"Robert De Niro","genres":"Crime","file_name":"ronin.mp4","status":"published"}
+`
+	invalid := catalog + `{"title":"Missing","description":"no file","duration":90,"artists":"Nobody","genres":"Crime","file_name":"missing.mp4"}
+`
+
+	importCatalog := func(query, body string) *e2e.Response {
+		req := httptest.NewRequest(http.MethodPost, api+"/admin/movies/import"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		return h.Send(req, admin)
+	}
+
+	var report struct {
+		Total    int `json:"total"`
+		Valid    int `json:"valid"`
+		Imported int `json:"imported"`
+	}
+	h.Decode(h.Expect(importCatalog("?dry_run=true", invalid), http.StatusOK, "00"), &report)
+	if report.Total != 3 || report.Valid != 2 || report.Imported != 0 {
+		t.Fatalf("unexpected dry run %+v", report)
+	}
+	h.Expect(importCatalog("", invalid), http.StatusUnprocessableEntity, "VE")
+
+	h.Decode(h.Expect(importCatalog("", catalog), http.StatusOK, "00"), &report)
+	if report.Imported != 2 {
+		t.Fatalf("unexpected import %+v", report)
+	}
+
+	// the log dir is shared by every run, the request id finds the lines of this one
+	requestId := "export-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	resp := h.Do(func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, api+"/admin/movies/export?format=jsonl", nil)
+		req.Header.Set("Authorization", "Bearer "+admin)
+		req.Header.Set("X-Request-ID", requestId)
+		return req
+	}())
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected export %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(body), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 exported movies, got %s", body)
+	}
+	var exported struct {
+		Title    string `json:"title"`
+		FileName string `json:"file_name"`
+	}
+	if err := jsoniter.Unmarshal(lines[0], &exported); err != nil || exported.FileName == "" {
+		t.Fatalf("unexpected exported row %s: %v", lines[0], err)
+	}
+
+	// the stream runs after the handler returned, it still logs with the request id and user of the request
+	adminId := h.App.Usecases.UserUsecase.PopulateSession(context.Background(), admin).Id
+	var logged []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		logged = logLines(t, h.Config.Log.Dir, admin, requestId)
+		if strings.Contains(strings.Join(logged, "\n"), `"msg":"movies exported"`) {
+			break
+		}
+	}
+	exportLogged := false
+	for _, line := range logged {
+		var entry struct {
+			Msg    string `json:"msg"`
+			UserId int    `json:"user_id"`
+		}
+		if err := jsoniter.UnmarshalFromString(line, &entry); err == nil && entry.Msg == "movies exported" {
+			exportLogged = entry.UserId == adminId
+		}
+	}
+	if !exportLogged {
+		t.Fatalf("expected the export to be logged with the request id and user, got %v", logged)
+	}
+
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies/export?format=xml", admin, nil), http.StatusBadRequest, "VE")
+
+	// an export of every status imports back into another instance as it was
+	ronin := h.FindMovie(admin, "Ronin")
+	h.Expect(h.Request(http.MethodPut, api+"/admin/movies/"+ronin.Id+"/status", admin, map[string]string{"status": constant.MovieStatusArchived}), http.StatusOK, "00")
+
+	for _, format := range []string{"csv", "jsonl"} {
+		t.Run(format, func(t *testing.T) {
+			source := exportCatalog(t, h, admin, format)
+
+			restored := e2e.New(t)
+			restoredAdmin := restored.AdminToken("admin")
+			writeMovieFiles(t, restored, "heat.mp4", "ronin.mp4")
+
+			req := httptest.NewRequest(http.MethodPost, api+"/admin/movies/import?format="+format, bytes.NewReader(source))
+			restored.Decode(restored.Expect(restored.Send(req, restoredAdmin), http.StatusOK, "00"), &report)
+			if report.Imported != 2 {
+				t.Fatalf("expected the export to import back, got %+v", report)
+			}
+
+			for _, title := range []string{"Heat", "Ronin"} {
+				want, got := h.FindMovie(admin, title), restored.FindMovie(restoredAdmin, title)
+				if got.Status != want.Status || got.Description != want.Description || got.Artist != want.Artist || got.Genre != want.Genre || path.Base(got.WatchUrl) != path.Base(want.WatchUrl) {
+					t.Fatalf("expected %+v, got %+v", want, got)
+				}
+			}
+		})
+	}
+}
+
+// exportCatalog returns the whole catalog of h in format
+func exportCatalog(t *testing.T, h *e2e.Harness, token, format string) []byte {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, api+"/admin/movies/export?format="+format, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp := h.Do(req)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected export %d %v", resp.StatusCode, err)
+	}
+
+	return body
+}
+
+// writeMovieFiles puts files in the storage dir of h, for catalogs that point at them
+func writeMovieFiles(t *testing.T, h *e2e.Harness, names ...string) {
+	t.Helper()
+
+	if err := os.MkdirAll(h.Config.Storage.MovieDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(h.Config.Storage.MovieDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEnrichment(t *testing.T) {
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("t") != "Heat" {
+			w.Write([]byte(`{"Response":"False","Error":"Movie not found!"}`))
+			return
+		}
+		w.Write([]byte(`{"Response":"True","Title":"Heat","Year":"1995","Plot":"A heist.","Actors":"Al Pacino","Genre":"Crime","Poster":"http://posters/heat.jpg"}`))
+	}))
+	defer catalog.Close()
+
+	h := e2e.New(t, func(cfg *config.Config) {
+		cfg.Catalog.BaseUrl = catalog.URL
+	})
+	admin := h.AdminToken("admin")
+
+	movie := h.UploadMovie(admin, "Heat", constant.MovieStatusPublished)
+
+	var preview struct {
+		Applied bool `json:"applied"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/movies/"+movie.Id+"/enrichment?fields=year,poster", admin, nil), http.StatusOK, "00"), &preview)
+	if preview.Applied {
+		t.Fatal("a preview must not apply the enrichment")
+	}
+
+	var enriched struct {
+		Applied bool      `json:"applied"`
+		Movie   e2e.Movie `json:"movie"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodPost, api+"/admin/movies/"+movie.Id+"/enrichment", admin, map[string]interface{}{"fields": []string{"year"}}), http.StatusOK, "00"), &enriched)
+	if !enriched.Applied || enriched.Movie.Year != 1995 {
+		t.Fatalf("unexpected enrichment %+v", enriched)
+	}
+
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies/"+movie.Id+"/enrichment?title=Unknown", admin, nil), http.StatusNotFound, "NC")
+}
+
+func TestWebhooks(t *testing.T) {
+	h := e2e.New(t)
+	admin := h.AdminToken("admin")
+
+	var subscription struct {
+		Id     int      `json:"id"`
+		Url    string   `json:"url"`
+		Events []string `json:"events"`
+		Active bool     `json:"active"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodPost, api+"/admin/webhooks", admin, map[string]interface{}{
+		"url":    "http://hooks.example.com/movies",
+		"events": []string{"movie.created"},
+	}), http.StatusCreated, "00"), &subscription)
+	if !subscription.Active {
+		t.Fatalf("unexpected subscription %+v", subscription)
+	}
+	h.Expect(h.Request(http.MethodPost, api+"/admin/webhooks", admin, map[string]interface{}{
+		"url":    "not a url",
+		"events": []string{"movie.created"},
+	}), http.StatusBadRequest, "VE")
+
+	id := strconv.Itoa(subscription.Id)
+
+	var subscriptions struct {
+		Subscriptions []struct {
+			Id int `json:"id"`
+		} `json:"subscriptions"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/webhooks", admin, nil), http.StatusOK, "00"), &subscriptions)
+	if len(subscriptions.Subscriptions) != 1 {
+		t.Fatalf("unexpected subscriptions %+v", subscriptions)
+	}
+
+	h.Decode(h.Expect(h.Request(http.MethodPut, api+"/admin/webhooks/"+id, admin, map[string]interface{}{
+		"url":    "http://hooks.example.com/all",
+		"events": []string{"*"},
+		"active": true,
+	}), http.StatusOK, "00"), &subscription)
+	if subscription.Url != "http://hooks.example.com/all" {
+		t.Fatalf("unexpected updated subscription %+v", subscription)
+	}
+
+	h.Expect(h.Request(http.MethodGet, api+"/admin/webhooks/"+id+"/deliveries", admin, nil), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodPost, api+"/admin/webhooks/"+id+"/deliveries/999/redeliver", admin, nil), http.StatusNotFound, "NA")
+
+	h.Expect(h.Request(http.MethodDelete, api+"/admin/webhooks/"+id, admin, nil), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodDelete, api+"/admin/webhooks/"+id, admin, nil), http.StatusNotFound, "NA")
+}
+
+// receivedWebhook is one request the webhook receiver got
+type receivedWebhook struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver answers with status, and a Retry-After of retryAfter when it is set
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	status     int
+	retryAfter string
+	received   []receivedWebhook
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	r := &webhookReceiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.received = append(r.received, receivedWebhook{at: time.Now(), header: req.Header, body: body})
+		if r.retryAfter != "" {
+			w.Header().Set("Retry-After", r.retryAfter)
+		}
+		w.WriteHeader(r.status)
+		w.Write([]byte("received"))
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func (r *webhookReceiver) answer(status int, retryAfter string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status = status
+	r.retryAfter = retryAfter
+}
+
+// since returns the requests received after the first n
+func (r *webhookReceiver) since(n int) []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]receivedWebhook(nil), r.received[n:]...)
+}
+
+func (r *webhookReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.received)
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	const secret = "e2e-webhook-secret"
+
+	receiver := newWebhookReceiver(t)
+	h := e2e.New(t, func(cfg *config.Config) {
+		cfg.Webhooks.MaxFailures = 2
+
+		breaker := config.CircuitBreakerConfig{
+			// keeps the circuit closed, the failures below are meant to reach the receiver
+			RequestVolumeThreshold: 1000,
+		}
+		breaker.Retry.MaxRetries = 2
+		breaker.Retry.BaseDelay = 10 * time.Millisecond
+		breaker.Retry.MaxDelay = 2 * time.Second
+		breaker.Retry.Methods = []string{http.MethodPost}
+		breaker.Retry.StatusCodes = []int{http.StatusServiceUnavailable}
+		cfg.CircuitBreakers = map[string]config.CircuitBreakerConfig{"webhook": breaker}
+	})
+	admin := h.AdminToken("admin")
+	ctx := context.Background()
+
+	var subscription struct {
+		Id     int    `json:"id"`
+		Secret string `json:"secret"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodPost, api+"/admin/webhooks", admin, map[string]interface{}{
+		"url":    receiver.URL,
+		"events": []string{constant.WebhookEventMovieCreated},
+		"secret": secret,
+	}), http.StatusCreated, "00"), &subscription)
+	id := strconv.Itoa(subscription.Id)
+
+	// the scheduler isn't running, the outbox is handed to the consumers and the deliveries sent by hand
+	dispatch := func() {
+		h.App.Usecases.EventBus.DispatchEvents(ctx)
+		h.App.Usecases.WebhookUsecase.DispatchWebhooks(ctx)
+	}
+
+	type delivery struct {
+		Id             int    `json:"id"`
+		EventId        string `json:"event_id"`
+		Status         string `json:"status"`
+		Attempts       int    `json:"attempts"`
+		ResponseStatus int    `json:"response_status"`
+	}
+	latestDelivery := func() delivery {
+		var deliveries struct {
+			Deliveries []delivery `json:"deliveries"`
+		}
+		h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/webhooks/"+id+"/deliveries", admin, nil), http.StatusOK, "00"), &deliveries)
+		if len(deliveries.Deliveries) == 0 {
+			t.Fatal("expected a delivery")
+		}
+		return deliveries.Deliveries[0]
+	}
+	activeSubscription := func() (bool, int) {
+		var subscriptions struct {
+			Subscriptions []struct {
+				Active              bool `json:"active"`
+				ConsecutiveFailures int  `json:"consecutive_failures"`
+			} `json:"subscriptions"`
+		}
+		h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/webhooks", admin, nil), http.StatusOK, "00"), &subscriptions)
+		return subscriptions.Subscriptions[0].Active, subscriptions.Subscriptions[0].ConsecutiveFailures
+	}
+
+	// a delivery reaches the receiver signed with the secret of the subscription
+	h.UploadMovie(admin, "Heat", constant.MovieStatusDraft)
+	dispatch()
+
+	received := receiver.since(0)
+	if len(received) != 1 {
+		t.Fatalf("expected one delivery, got %d", len(received))
+	}
+	webhook := received[0]
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(webhook.header.Get("X-Webhook-Timestamp") + "."))
+	mac.Write(webhook.body)
+	if signature := "sha256=" + hex.EncodeToString(mac.Sum(nil)); !hmac.Equal([]byte(webhook.header.Get("X-Webhook-Signature")), []byte(signature)) {
+		t.Fatalf("expected the signature %s, got %s", signature, webhook.header.Get("X-Webhook-Signature"))
+	}
+
+	var envelope struct {
+		Id    string `json:"id"`
+		Event string `json:"event"`
+		Data  struct {
+			Movie struct {
+				Title string `json:"title"`
+			} `json:"movie"`
+		} `json:"data"`
+	}
+	if err := jsoniter.Unmarshal(webhook.body, &envelope); err != nil {
+		t.Fatalf("failed to decode %s: %s", webhook.body, err)
+	}
+	if envelope.Event != constant.WebhookEventMovieCreated || envelope.Id != webhook.header.Get("X-Webhook-Id") ||
+		webhook.header.Get("X-Webhook-Event") != constant.WebhookEventMovieCreated || envelope.Data.Movie.Title != "Heat" {
+		t.Fatalf("unexpected webhook %v %s", webhook.header, webhook.body)
+	}
+
+	if got := latestDelivery(); got.Status != constant.WebhookDeliveryStatusSucceeded || got.Attempts != 1 || got.ResponseStatus != http.StatusOK {
+		t.Fatalf("unexpected delivery %+v", got)
+	}
+
+	// a 5xx is retried by the breaker of the subscription after the wait the receiver asks for
+	receiver.answer(http.StatusServiceUnavailable, "1")
+	before := receiver.count()
+	h.UploadMovie(admin, "Up", constant.MovieStatusDraft)
+	dispatch()
+
+	received = receiver.since(before)
+	if len(received) != 3 {
+		t.Fatalf("expected the delivery and 2 retries, got %d requests", len(received))
+	}
+	for i := 1; i < len(received); i++ {
+		if received[i].header.Get("X-Webhook-Delivery") != received[0].header.Get("X-Webhook-Delivery") || !bytes.Equal(received[i].body, received[0].body) {
+			t.Fatalf("expected retry %d to resend the same delivery", i)
+		}
+		if gap := received[i].at.Sub(received[i-1].at); gap < 900*time.Millisecond {
+			t.Fatalf("expected retry %d to wait for Retry-After, waited %s", i, gap)
+		}
+	}
+
+	failed := latestDelivery()
+	if failed.Status != constant.WebhookDeliveryStatusFailed || failed.ResponseStatus != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected failed delivery %+v", failed)
+	}
+	if active, failures := activeSubscription(); !active || failures != 1 {
+		t.Fatalf("expected the subscription to stay active after 1 failure, got %t %d", active, failures)
+	}
+
+	// redelivering sends the same event again and resets the failures
+	receiver.answer(http.StatusOK, "")
+	before = receiver.count()
+	var redelivered delivery
+	h.Decode(h.Expect(h.Request(http.MethodPost, api+"/admin/webhooks/"+id+"/deliveries/"+strconv.Itoa(failed.Id)+"/redeliver", admin, nil), http.StatusOK, "00"), &redelivered)
+	if redelivered.Id != failed.Id || redelivered.Status != constant.WebhookDeliveryStatusSucceeded || redelivered.Attempts != 2 {
+		t.Fatalf("unexpected redelivery %+v", redelivered)
+	}
+	received = receiver.since(before)
+	if len(received) != 1 || received[0].header.Get("X-Webhook-Id") != failed.EventId || received[0].header.Get("X-Webhook-Delivery") != strconv.Itoa(failed.Id) {
+		t.Fatalf("expected the failed delivery to reach the receiver again, got %d requests", len(received))
+	}
+	if active, failures := activeSubscription(); !active || failures != 0 {
+		t.Fatalf("expected the redelivery to reset the failures, got %t %d", active, failures)
+	}
+
+	// max_failures failed deliveries in a row disable the subscription
+	receiver.answer(http.StatusServiceUnavailable, "")
+	h.UploadMovie(admin, "Ran", constant.MovieStatusDraft)
+	h.UploadMovie(admin, "Alien", constant.MovieStatusDraft)
+	dispatch()
+
+	if active, failures := activeSubscription(); active || failures != 2 {
+		t.Fatalf("expected the subscription to be disabled after 2 failures, got %t %d", active, failures)
+	}
+
+	// a disabled subscription isn't notified, and can't be redelivered to until it is enabled again
+	before = receiver.count()
+	h.UploadMovie(admin, "Brazil", constant.MovieStatusDraft)
+	dispatch()
+	if receiver.count() != before {
+		t.Fatalf("expected no delivery to a disabled subscription, got %d", receiver.count()-before)
+	}
+	h.Expect(h.Request(http.MethodPost, api+"/admin/webhooks/"+id+"/deliveries/"+strconv.Itoa(failed.Id)+"/redeliver", admin, nil), http.StatusConflict, "SD")
+}
+
+func TestEventDeadLetters(t *testing.T) {
+	h := e2e.New(t, func(cfg *config.Config) {
+		cfg.Events.MaxAttempts = 3
+	})
+	admin := h.AdminToken("admin")
+	ctx := context.Background()
+
+	// the consumer fails on every event of one movie
+	var handled []string
+	h.App.Usecases.EventBus.Subscribe("poisoned", func(ctx context.Context, event usecase.DomainEvent) error {
+		var created repository.MovieCreatedEvent
+		if err := jsoniter.Unmarshal(event.Payload, &created); err != nil {
+			return err
+		}
+		if created.Movie.Title == "Poison" {
+			return errors.New("cannot handle poison")
+		}
+
+		handled = append(handled, created.Movie.Title)
+		return nil
+	}, constant.EventMovieCreated)
+
+	h.UploadMovie(admin, "Heat", constant.MovieStatusDraft)
+	h.UploadMovie(admin, "Poison", constant.MovieStatusDraft)
+	h.UploadMovie(admin, "Ronin", constant.MovieStatusDraft)
+
+	type consumer struct {
+		Consumer       string `json:"consumer"`
+		LastEventId    int64  `json:"last_event_id"`
+		Lag            int    `json:"lag"`
+		FailingEventId int64  `json:"failing_event_id"`
+		Attempts       int    `json:"attempts"`
+		LastError      string `json:"last_error"`
+	}
+	poisoned := func() consumer {
+		var consumers struct {
+			Consumers []consumer `json:"consumers"`
+		}
+		h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/events/consumers", admin, nil), http.StatusOK, "00"), &consumers)
+		for _, c := range consumers.Consumers {
+			if c.Consumer == "poisoned" {
+				return c
+			}
+		}
+		t.Fatalf("consumer not found in %+v", consumers.Consumers)
+		return consumer{}
+	}
+	dispatch := func() usecase.DispatchEventsResponse {
+		return h.App.Usecases.EventBus.DispatchEvents(ctx).Data.(usecase.DispatchEventsResponse)
+	}
+
+	// the events before the failing one are stored along with the failure, the ones after it wait
+	for attempt := 1; attempt < 3; attempt++ {
+		if result := dispatch(); result.Failed != 1 || result.DeadLettered != 0 {
+			t.Fatalf("unexpected dispatch %+v", result)
+		}
+		if c := poisoned(); c.Attempts != attempt || c.FailingEventId == 0 || c.FailingEventId <= c.LastEventId || c.LastError == "" || c.Lag == 0 {
+			t.Fatalf("expected attempt %d to be recorded, got %+v", attempt, c)
+		}
+	}
+	if strings.Join(handled, ",") != "Heat" {
+		t.Fatalf("expected only the event before the failing one handled, got %v", handled)
+	}
+
+	// max_attempts skips the event and records it
+	failing := poisoned().FailingEventId
+	if result := dispatch(); result.Failed != 0 || result.DeadLettered != 1 {
+		t.Fatalf("unexpected dispatch %+v", result)
+	}
+	if c := poisoned(); c.Lag != 0 || c.Attempts != 0 || c.FailingEventId != 0 || c.LastError != "" {
+		t.Fatalf("expected the consumer to have caught up, got %+v", c)
+	}
+	if strings.Join(handled, ",") != "Heat,Ronin" {
+		t.Fatalf("expected the events after the skipped one handled, got %v", handled)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+h.Config.Database.Sqlite.Path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var eventId int64
+	var event, reason string
+	var attempts int
+	err = db.QueryRow(`SELECT event_id, event, attempts, error FROM event_dead_letters WHERE consumer = 'poisoned';`).Scan(&eventId, &event, &attempts, &reason)
+	if err != nil || eventId != failing || event != constant.EventMovieCreated || attempts != 3 || !strings.Contains(reason, "cannot handle poison") {
+		t.Fatalf("unexpected dead letter %d %s %d %s %v", eventId, event, attempts, reason, err)
+	}
+}
+
+func TestSystem(t *testing.T) {
+	h := e2e.New(t, func(cfg *config.Config) {
+		cfg.Backup.Retention = 1
+	})
+	admin := h.AdminToken("admin")
+
+	h.Expect(h.Request(http.MethodGet, api+"/admin/circuit_breakers", admin, nil), http.StatusOK, "00")
+
+	var consumers struct {
+		Consumers []struct {
+			Name string `json:"name"`
+		} `json:"consumers"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/events/consumers", admin, nil), http.StatusOK, "00"), &consumers)
+	if len(consumers.Consumers) == 0 {
+		t.Fatal("expected the movie webhook consumer")
+	}
+
+	var backups struct {
+		Backups []struct {
+			Name string `json:"name"`
+		} `json:"backups"`
+	}
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/backups", admin, nil), http.StatusOK, "00"), &backups)
+	if len(backups.Backups) != 0 {
+		t.Fatalf("unexpected backups of a fresh database %+v", backups)
+	}
+
+	h.Expect(h.Request(http.MethodPost, api+"/admin/backups", admin, nil), http.StatusCreated, "00")
+	// backup names have millisecond precision
+	time.Sleep(5 * time.Millisecond)
+	h.Expect(h.Request(http.MethodPost, api+"/admin/backups", admin, nil), http.StatusCreated, "00")
+
+	h.Decode(h.Expect(h.Request(http.MethodGet, api+"/admin/backups", admin, nil), http.StatusOK, "00"), &backups)
+	if len(backups.Backups) != 1 {
+		t.Fatalf("expected the retention to keep 1 backup, got %+v", backups)
+	}
+	if _, err := os.Stat(filepath.Join(h.Config.Backup.Dir, backups.Backups[0].Name)); err != nil {
+		t.Fatalf("backup not in the backup dir: %s", err)
+	}
+}
+
+func TestRateLimiting(t *testing.T) {
+	h := e2e.New(t, func(cfg *config.Config) {
+		// the fake connection of the test server has no address of its own
+		cfg.App.ProxyHeader = "X-Forwarded-For"
+		cfg.RateLimit.Lockout.MaxFailures = 2
+		cfg.RateLimit.Lockout.Duration = time.Minute
+	})
+	admin := h.AdminToken("admin")
+	alice := h.UserToken("alice")
+	bob := h.UserToken("bob")
+	up := h.UploadMovie(admin, "Up", constant.MovieStatusPublished)
+	upId, _ := strconv.Atoi(up.Id)
+
+	// the rules are read on every request, the users above weren't counted
+	h.Config.RateLimit.Rules = map[string]config.RateLimitRule{
+		constant.RateLimitRuleRegister: {Key: constant.RateLimitKeyIp, Limit: 2, Period: time.Hour},
+		constant.RateLimitRuleVote:     {Key: constant.RateLimitKeyUser, Limit: 1, Period: time.Hour},
+	}
+
+	from := func(ip, method, path, token string, body interface{}) *e2e.Response {
+		raw, _ := jsoniter.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", ip)
+
+		return h.Send(req, token)
+	}
+	register := func(ip, email string) *e2e.Response {
+		return from(ip, http.MethodPost, api+"/register", "", map[string]string{"email": email, "name": email})
+	}
+
+	resp := h.Expect(register("10.0.0.1", "carol@example.com"), http.StatusOK, "00")
+	if resp.Header.Get("RateLimit-Limit") != "2" || resp.Header.Get("RateLimit-Remaining") != "1" || resp.Header.Get("RateLimit-Policy") != "2;w=3600" {
+		t.Fatalf("unexpected rate limit headers %v", resp.Header)
+	}
+	h.Expect(register("10.0.0.1", "dave@example.com"), http.StatusOK, "00")
+
+	resp = h.Expect(register("10.0.0.1", "erin@example.com"), http.StatusTooManyRequests, "RL")
+	if resp.Header.Get("RateLimit-Remaining") != "0" || resp.Header.Get("Retry-After") != "1800" {
+		t.Fatalf("unexpected rate limit headers %v", resp.Header)
+	}
+
+	// every ip has a bucket of its own, and a route without a rule isn't limited
+	h.Expect(register("10.0.0.2", "erin@example.com"), http.StatusOK, "00")
+	resp = h.Expect(from("10.0.0.1", http.MethodPost, api+"/login", "", map[string]string{"email": "erin@example.com"}), http.StatusOK, "00")
+	if resp.Header.Get("RateLimit-Limit") != "" {
+		t.Fatalf("unexpected rate limit headers on an unlimited route %v", resp.Header)
+	}
+
+	// votes are counted by user, whatever ip they come from
+	h.Expect(from("10.0.0.3", http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": upId}), http.StatusOK, "00")
+	h.Expect(from("10.0.0.4", http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": upId}), http.StatusTooManyRequests, "RL")
+	h.Expect(from("10.0.0.3", http.MethodPost, api+"/movies/vote", bob, map[string]int{"movie_id": upId}), http.StatusOK, "00")
+
+	// failed logins lock the ip out, then the right email is refused too
+	login := func(ip, email string) *e2e.Response {
+		return from(ip, http.MethodPost, api+"/login", "", map[string]string{"email": email})
+	}
+	h.Expect(login("10.0.0.5", "nobody@example.com"), http.StatusNotFound, "NA")
+	h.Expect(login("10.0.0.5", "nobody@example.com"), http.StatusNotFound, "NA")
+
+	resp = h.Expect(login("10.0.0.5", "alice@example.com"), http.StatusTooManyRequests, "LO")
+	if resp.Header.Get("Retry-After") != "60" {
+		t.Fatalf("expected to retry after the lockout, got %v", resp.Header)
+	}
+	h.Expect(login("10.0.0.6", "alice@example.com"), http.StatusOK, "00")
+}
+
+func TestRequestLogs(t *testing.T) {
+	h := e2e.New(t)
+	alice := h.UserToken("alice")
+
+	aliceId := h.App.Usecases.UserUsecase.PopulateSession(context.Background(), alice).Id
+
+	// the log dir is shared by every run, the request id finds the lines of this one
+	requestId := "probe-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	req, _ := http.NewRequest(http.MethodGet, api+"/movies/votes", nil)
+	req.Header.Set("X-Request-ID", requestId)
+	h.Expect(h.Send(req, alice), http.StatusOK, "00")
+
+	// the response is logged from another goroutine
+	var lines []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		lines = logLines(t, h.Config.Log.Dir, alice, requestId)
+		if strings.Contains(strings.Join(lines, "\n"), `"msg":"response"`) {
+			break
+		}
+	}
+
+	var request, response bool
+	for _, line := range lines {
+		var entry struct {
+			Msg           string            `json:"msg"`
+			RequestId     string            `json:"request_id"`
+			UserId        int               `json:"user_id"`
+			RequestHeader map[string]string `json:"request_header"`
+		}
+		if err := jsoniter.UnmarshalFromString(line, &entry); err != nil {
+			t.Fatalf("unreadable log line %s: %s", line, err)
+		}
+		if entry.RequestId != requestId || entry.UserId != aliceId {
+			t.Fatalf("expected the request id and user id on every line: %s", line)
+		}
+
+		switch entry.Msg {
+		case "request":
+			request = true
+			if entry.RequestHeader["Authorization"] != "[REDACTED]" {
+				t.Fatalf("expected the Authorization header to be redacted: %s", line)
+			}
+		case "response":
+			response = true
+		}
+	}
+
+	if !request || !response {
+		t.Fatalf("expected the request and its response to be logged, got %v", lines)
+	}
+}
+
+// logLines are the lines of the logs in dir that contain match, it fails the test when one contains secret
+func logLines(t *testing.T, dir, secret, match string) []string {
+	t.Helper()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+
+	var lines []string
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, line := range strings.Split(string(raw), "\n") {
+			if strings.Contains(line, secret) {
+				t.Fatalf("the secret was logged: %s", line)
+			}
+			if strings.Contains(line, match) {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return lines
+}
+
+func TestRequestIds(t *testing.T) {
+	h := e2e.New(t)
+
+	resp := h.Expect(h.Request(http.MethodGet, api+"/movies", "", nil), http.StatusOK, "00")
+	generated := resp.Header.Get("X-Request-ID")
+	if len(generated) != 32 || resp.RequestId != "" {
+		t.Fatalf("expected a generated request id in the header only, got %q and %q", generated, resp.RequestId)
+	}
+	if next := h.Request(http.MethodGet, api+"/movies", "", nil).Header.Get("X-Request-ID"); next == generated {
+		t.Fatal("expected every request to get an id of its own")
+	}
+
+	send := func(requestId string, body string) *e2e.Response {
+		req, _ := http.NewRequest(http.MethodPost, api+"/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", requestId)
+
+		return h.Send(req, "")
+	}
+
+	// the caller's id is kept and sent back with the errors of the usecases and the handlers
+	resp = h.Expect(send("caller-id-1", `{"email":"nobody@example.com"}`), http.StatusNotFound, "NA")
+	if resp.Header.Get("X-Request-ID") != "caller-id-1" || resp.RequestId != "caller-id-1" {
+		t.Fatalf("expected the caller's request id, got %q and %q", resp.Header.Get("X-Request-ID"), resp.RequestId)
+	}
+
+	resp = h.Expect(send("caller-id-2", `{`), http.StatusUnprocessableEntity, "FM")
+	if resp.RequestId != "caller-id-2" {
+		t.Fatalf("expected the caller's request id in the error, got %q", resp.RequestId)
+	}
+
+	// one that could break a header or a log line is replaced
+	for _, invalid := range []string{"has space", strings.Repeat("a", 129)} {
+		resp = send(invalid, `{"email":"nobody@example.com"}`)
+		if got := resp.Header.Get("X-Request-ID"); got == invalid || len(got) != 32 {
+			t.Fatalf("expected %q to be replaced, got %q", invalid, got)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	h := e2e.New(t)
+
+	// the counters are shared by every app of the process, only what this test adds is checked
+	before := metrics(t, h)
+
+	admin := h.AdminToken("admin")
+	alice := h.UserToken("alice")
+	up := h.UploadMovie(admin, "Up", constant.MovieStatusPublished)
+	upId, _ := strconv.Atoi(up.Id)
+
+	h.Expect(h.Request(http.MethodPost, api+"/movies/vote", alice, map[string]int{"movie_id": upId}), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies/"+up.Id, admin, nil), http.StatusOK, "00")
+	h.Expect(h.Request(http.MethodGet, api+"/admin/movies/9999", admin, nil), http.StatusNotFound, "NA")
+
+	// the watch url is host and path, without a scheme
+	req, _ := http.NewRequest(http.MethodGet, up.WatchUrl[strings.Index(up.WatchUrl, "/movies/"):], nil)
+	resp := h.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the movie file, got %d", resp.StatusCode)
+	}
+
+	after := metrics(t, h)
+
+	for series, want := range map[string]float64{
+		`user_registrations_total`:   2,
+		`movie_votes_total`:          1,
+		`movie_uploads_total`:        1,
+		`movie_upload_bytes_total`:   float64(len("movie Up")),
+		`movie_streamed_bytes_total`: float64(len("movie Up")),
+		`http_requests_total{method="GET",route="/api/v1/admin/movies/:id",status="200"}`:                            1,
+		`http_requests_total{method="GET",route="/api/v1/admin/movies/:id",status="404"}`:                            1,
+		`http_request_duration_seconds_count{method="GET",route="/api/v1/admin/movies/:id",status="200"}`:            1,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/admin/movies/:id",status="200",le="+Inf"}`: 1,
+	} {
+		if got := after[series] - before[series]; got != want {
+			t.Errorf("expected %s to grow by %v, got %v", series, want, got)
+		}
+	}
+
+	if after[`db_query_duration_seconds_count{driver="sqlite",method="QueryRow"}`] == 0 {
+		t.Errorf("expected the queries to be timed, got %v", after)
+	}
+}
+
+// metrics scrapes /metrics into its series and their values
+func metrics(t *testing.T, h *e2e.Harness) map[string]float64 {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	resp := h.Do(req)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected scrape %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	series := map[string]float64{}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("unexpected line %q", line)
+		}
+		series[line[:i]] = value
+	}
+
+	return series
+}
+
+func TestProbes(t *testing.T) {
+	h := e2e.New(t)
+
+	live := probe(t, h, "/livez", http.StatusOK)
+	if len(live.Checks) != 1 || live.Checks[0].Name != "database" {
+		t.Fatalf("expected /livez to check the database only, got %+v", live)
+	}
+
+	ready := probe(t, h, "/readyz", http.StatusOK)
+	var names []string
+	for _, check := range ready.Checks {
+		names = append(names, check.Name)
+	}
+	if got := strings.Join(names, ","); got != "shutdown,database,migrations,storage,disk,circuit_breakers" {
+		t.Fatalf("unexpected checks %s", got)
+	}
+
+	// a file in place of the movie dir can't be written to, even by root
+	if err := os.RemoveAll(h.Config.Storage.MovieDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(h.Config.Storage.MovieDir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+h.Config.Database.Sqlite.Path+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("UPDATE schema_migrations SET dirty = TRUE WHERE version = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	ready = probe(t, h, "/readyz", http.StatusServiceUnavailable)
+	if ready.check("storage").Status != "fail" || ready.check("migrations").Status != "fail" || ready.check("shutdown").Status != "ok" {
+		t.Fatalf("expected storage and migrations to fail, got %+v", ready)
+	}
+	if !strings.Contains(ready.check("migrations").Error, "dirty") {
+		t.Fatalf("expected the dirty version in the error, got %+v", ready.check("migrations"))
+	}
+
+	// the process is still fine, restarting it wouldn't fix the storage
+	probe(t, h, "/livez", http.StatusOK)
+
+	h.App.Health.Drain()
+
+	ready = probe(t, h, "/readyz", http.StatusServiceUnavailable)
+	if ready.check("shutdown").Status != "fail" {
+		t.Fatalf("expected readiness to fail once draining, got %+v", ready)
+	}
+	probe(t, h, "/livez", http.StatusOK)
+}
+
+type probeReport struct {
+	Status string       `json:"status"`
+	Checks []probeCheck `json:"checks"`
+}
+
+type probeCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+func (r probeReport) check(name string) probeCheck {
+	for _, check := range r.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+
+	return probeCheck{}
+}
+
+// probe calls a probe and decodes the report of its checks
+func probe(t *testing.T, h *e2e.Harness, path string, httpCode int) probeReport {
+	t.Helper()
+
+	resp := h.Do(httptest.NewRequest(http.MethodGet, path, nil))
+	defer resp.Body.Close()
+
+	var report probeReport
+	if err := jsoniter.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != httpCode {
+		t.Fatalf("expected %s to answer %d, got %d %+v", path, httpCode, resp.StatusCode, report)
+	}
+
+	return report
+}
@@ -0,0 +1,333 @@
+// Package e2e runs the http server of cmd against a temporary database and storage directory,
+// so tests can go through every route the way a client does
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/app"
+	delivery "lion-parcel-test/internal/delivery/http"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Response is a dto.Response as a client reads it, Data is left raw to be decoded by the caller
+type Response struct {
+	HttpCode int                 `json:"-"`
+	Code     string              `json:"response_code"`
+	Desc     string              `json:"response_desc"`
+	Data     jsoniter.RawMessage `json:"response_data"`
+	// only sent along with an error
+	RequestId string      `json:"request_id"`
+	Header    http.Header `json:"-"`
+}
+
+// Harness is a running app of its own, every harness has a fresh database, backup and movie directory
+type Harness struct {
+	t      *testing.T
+	Config *config.Config
+	App    *app.App
+	Server *delivery.HttpServer
+}
+
+// NewConfig is the config of a harness, everything the app writes goes to dir
+func NewConfig(dir string) *config.Config {
+	cfg := &config.Config{}
+	cfg.App.Name = "lion-parcel-test-e2e"
+	cfg.Database.Driver = constant.DatabaseDriverSqlite
+	cfg.Database.AutoMigrate = true
+	cfg.Database.Sqlite.Path = filepath.Join(dir, "movies.db")
+	cfg.Backup.Dir = filepath.Join(dir, "backups")
+	cfg.Storage.MovieDir = filepath.Join(dir, "movies")
+	// like dev.yaml, so the tests also see the cache being dropped by writes
+	cfg.Cache.Driver = constant.CacheDriverMemory
+	// the logger is set up once per process, a dir of the first test would be gone for the others
+	cfg.Log.Dir = filepath.Join(os.TempDir(), "lion-parcel-test-e2e-logs")
+	cfg.Jwt.SecretKey = "e2e-secret"
+	// there is no collector to send to
+	cfg.Tracing.Backend = constant.TracingBackendNoop
+
+	return cfg
+}
+
+// New starts a harness on NewConfig, configure can change the config before the app is built on it
+func New(t *testing.T, configure ...func(cfg *config.Config)) *Harness {
+	t.Helper()
+
+	cfg := NewConfig(t.TempDir())
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	ctx := context.Background()
+
+	a, err := app.NewAppWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to build the app: %s", err)
+	}
+
+	server, err := delivery.NewHttpServer(a)
+	if err != nil {
+		a.Close(ctx)
+		t.Fatalf("failed to build the http server: %s", err)
+	}
+
+	t.Cleanup(func() {
+		server.Stop(ctx)
+		a.Close(ctx)
+	})
+
+	return &Harness{
+		t:      t,
+		Config: cfg,
+		App:    a,
+		Server: server,
+	}
+}
+
+// Do sends req to the server without a listener and returns the raw response
+func (h *Harness) Do(req *http.Request) *http.Response {
+	h.t.Helper()
+
+	resp, err := h.Server.Test(req, -1)
+	if err != nil {
+		h.t.Fatalf("%s %s failed: %s", req.Method, req.URL, err)
+	}
+
+	return resp
+}
+
+// Request sends body as JSON, token is the jwt of the caller and may be empty
+func (h *Harness) Request(method, path, token string, body interface{}) *Response {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := jsoniter.Marshal(body)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, path, reader)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return h.Send(req, token)
+}
+
+// Send sends req as the owner of token and decodes the dto.Response it answers with
+func (h *Harness) Send(req *http.Request, token string) *Response {
+	h.t.Helper()
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp := h.Do(req)
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	decoded := &Response{}
+	if len(raw) > 0 && jsoniter.Unmarshal(raw, decoded) != nil {
+		// e.g. the {"error": ...} of the auth middlewares or a plain text
+		decoded.Data = raw
+	}
+
+	decoded.HttpCode = resp.StatusCode
+	decoded.Header = resp.Header
+
+	return decoded
+}
+
+// Expect fails the test unless resp has the http and response code, then returns it for chaining
+func (h *Harness) Expect(resp *Response, httpCode int, code string) *Response {
+	h.t.Helper()
+
+	if resp.HttpCode != httpCode || resp.Code != code {
+		h.t.Fatalf("expected %d %q, got %d %q: %s %s", httpCode, code, resp.HttpCode, resp.Code, resp.Desc, resp.Data)
+	}
+
+	return resp
+}
+
+// Decode reads the response_data of resp into out
+func (h *Harness) Decode(resp *Response, out interface{}) {
+	h.t.Helper()
+
+	if err := jsoniter.Unmarshal(resp.Data, out); err != nil {
+		h.t.Fatalf("failed to decode %s: %s", resp.Data, err)
+	}
+}
+
+// Register signs a user up with the email and name
+func (h *Harness) Register(email, name string) {
+	h.t.Helper()
+
+	h.Expect(h.Request(http.MethodPost, constant.RouteApiV1+"/register", "", map[string]string{
+		"email": email,
+		"name":  name,
+	}), http.StatusOK, "00")
+}
+
+// Login returns the jwt of a registered user
+func (h *Harness) Login(email string) string {
+	h.t.Helper()
+
+	resp := h.Expect(h.Request(http.MethodPost, constant.RouteApiV1+"/login", "", map[string]string{
+		"email": email,
+	}), http.StatusOK, "00")
+
+	var login struct {
+		Jwt string `json:"jwt"`
+	}
+	h.Decode(resp, &login)
+
+	return login.Jwt
+}
+
+// UserToken registers a user and returns their jwt
+func (h *Harness) UserToken(name string) string {
+	h.t.Helper()
+
+	email := name + "@example.com"
+	h.Register(email, name)
+
+	return h.Login(email)
+}
+
+// AdminToken registers a user, makes them an admin by hand in the database like it's done in production
+// and returns their jwt
+func (h *Harness) AdminToken(name string) string {
+	h.t.Helper()
+
+	email := name + "@example.com"
+	h.Register(email, name)
+
+	db, err := sql.Open("sqlite3", "file:"+h.Config.Database.Sqlite.Path+"?_busy_timeout=5000")
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("UPDATE users SET is_admin = TRUE WHERE email = ?", email); err != nil {
+		h.t.Fatalf("failed to promote %s: %s", email, err)
+	}
+
+	// the admin flag is read into the jwt on login
+	return h.Login(email)
+}
+
+// Movie is a movie as the api returns it, with the fields the tests look at
+type Movie struct {
+	Id          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Artist      string `json:"artists"`
+	Genre       string `json:"genres"`
+	WatchUrl    string `json:"watch_url"`
+	Status      string `json:"status"`
+	Version     int    `json:"version"`
+	Views       int    `json:"views"`
+	Year        int    `json:"year"`
+}
+
+// MovieFixture is the json of a movie upload, the file content is made up from the title
+func MovieFixture(title string, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"title":       title,
+		"description": title + " description",
+		"duration":    120,
+		"artists":     "Artist",
+		"genres":      "Drama",
+		"status":      status,
+	}
+}
+
+// Multipart builds a multipart request of a "json" field and a "file", the way the movie uploads are sent
+func (h *Harness) Multipart(method, path string, fields map[string]interface{}, fileName string, file []byte) *http.Request {
+	h.t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if fields != nil {
+		raw, err := jsoniter.Marshal(fields)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		if err := writer.WriteField("json", string(raw)); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+
+	if fileName != "" {
+		part, err := writer.CreateFormFile("file", fileName)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		part.Write(file)
+	}
+
+	if err := writer.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+// UploadMovie creates a movie of MovieFixture as the admin of token and returns it
+func (h *Harness) UploadMovie(token, title, status string) Movie {
+	h.t.Helper()
+
+	req := h.Multipart(http.MethodPost, constant.RouteApiV1+"/admin/movies", MovieFixture(title, status), title+".mp4", []byte("movie "+title))
+	h.Expect(h.Send(req, token), http.StatusOK, "00")
+
+	return h.FindMovie(token, title)
+}
+
+// FindMovie looks a movie up by its exact title in the admin list, which has every status
+func (h *Harness) FindMovie(token, title string) Movie {
+	h.t.Helper()
+
+	resp := h.Expect(h.Request(http.MethodGet, constant.RouteApiV1+"/admin/movies?pageSize=100", token, nil), http.StatusOK, "00")
+
+	var list struct {
+		Movies []Movie `json:"movies"`
+	}
+	h.Decode(resp, &list)
+
+	for _, movie := range list.Movies {
+		if movie.Title == title {
+			return movie
+		}
+	}
+
+	h.t.Fatalf("movie %s not found in %+v", title, list.Movies)
+	return Movie{}
+}
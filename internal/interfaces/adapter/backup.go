@@ -0,0 +1,9 @@
+package adapter
+
+import "context"
+
+// BackupClient is implemented by the database clients that can copy themselves while they serve requests
+type BackupClient interface {
+	// Backup writes a snapshot of the database to path, checked with an integrity check
+	Backup(ctx context.Context, path string) error
+}
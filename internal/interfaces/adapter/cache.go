@@ -0,0 +1,20 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCacheMiss is returned by Get when the key is missing or expired
+var ErrCacheMiss = errors.New("cache miss")
+
+type CacheClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value for ttl, 0 keeps it until it is evicted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Incr adds one to the counter of key and returns it, a missing counter starts at 0.
+	// Get reads a counter as its decimal string, like redis does
+	Incr(ctx context.Context, key string) (int64, error)
+	Close() error
+}
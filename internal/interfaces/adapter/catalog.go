@@ -0,0 +1,28 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrCatalogNotFound is returned when the catalog has no movie matching the lookup
+	ErrCatalogNotFound = errors.New("movie not found in catalog")
+	// ErrCatalogUnavailable is returned when the catalog can't be reached or its circuit is open
+	ErrCatalogUnavailable = errors.New("catalog unavailable")
+)
+
+type CatalogClient interface {
+	// LookupMovie finds a movie by title, year narrows the match and 0 means any year
+	LookupMovie(ctx context.Context, title string, year int) (CatalogMovie, error)
+}
+
+// CatalogMovie is the metadata an external catalog knows about a movie
+type CatalogMovie struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Cast        string `json:"cast"`
+	Genres      string `json:"genres"`
+	Year        int    `json:"year"`
+	Poster      string `json:"poster"`
+}
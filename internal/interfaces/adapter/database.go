@@ -7,9 +7,17 @@ import (
 
 type DatabaseClient interface {
 	Close() error
+	// WithTx runs fn in a transaction carried by the ctx it receives, every call made with that ctx joins it.
+	// Nested calls use savepoints, and the outermost call commits or rolls back
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
 	Execute(ctx context.Context, query string, args ...interface{}) ExecuteResult
+	ExecuteBatch(ctx context.Context, statements []Statement) ([]ExecuteResult, error)
 	QueryRows(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row
+	// Ping checks the database answers before ctx is done
+	Ping(ctx context.Context) error
+	// CheckSchema returns nil when the schema is exactly the one of the binary, like the check made on start
+	CheckSchema(ctx context.Context) error
 }
 
 type ExecuteResult struct {
@@ -18,3 +26,9 @@ type ExecuteResult struct {
 	RowsAffected int64
 	Error        error
 }
+
+// Statement is a single query of an ExecuteBatch
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
@@ -0,0 +1,30 @@
+package adapter
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitStore keeps the token buckets, failure counters and locks of the rate limiter.
+// A store shared by every instance of the app makes the limits hold across them
+type RateLimitStore interface {
+	// Take removes a token from the bucket of key, which holds limit tokens and refills them over period
+	Take(ctx context.Context, key string, limit int, period time.Duration) (RateLimitDecision, error)
+	// AddFailure counts a failure of key and returns the failures within window, counted from the first one
+	AddFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	ResetFailures(ctx context.Context, key string) error
+	// Lock locks key for d, LockedFor tells how long it is still locked, 0 when it isn't
+	Lock(ctx context.Context, key string, d time.Duration) error
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	Close() error
+}
+
+type RateLimitDecision struct {
+	Allowed bool
+	// whole tokens left in the bucket
+	Remaining int
+	// until the bucket is full again
+	Reset time.Duration
+	// until the next token when the request isn't allowed
+	RetryAfter time.Duration
+}
@@ -0,0 +1,24 @@
+package adapter
+
+import (
+	"context"
+	"net/http"
+)
+
+type WebhookClient interface {
+	// Send posts the body to the url, a non-2xx answer is returned along with an error
+	Send(ctx context.Context, req WebhookRequest) (WebhookResponse, error)
+}
+
+type WebhookRequest struct {
+	// Command is the circuit breaker of the endpoint, so one dead partner can't open the breaker of the others
+	Command string
+	Url     string
+	Header  http.Header
+	Body    []byte
+}
+
+type WebhookResponse struct {
+	StatusCode int
+	Body       []byte
+}
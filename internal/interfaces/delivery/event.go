@@ -0,0 +1,7 @@
+package delivery
+
+import "github.com/gofiber/fiber/v2"
+
+type EventHandler interface {
+	GetEventConsumers(c *fiber.Ctx) error
+}
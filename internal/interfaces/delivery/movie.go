@@ -14,4 +14,18 @@ type MovieHandler interface {
 	VotedMovies(c *fiber.Ctx) error
 	MostVoted(c *fiber.Ctx) error
 	MostVotedGenre(c *fiber.Ctx) error
+	GetMovie(c *fiber.Ctx) error
+	AdminGetMovies(c *fiber.Ctx) error
+	UpdateMovieStatus(c *fiber.Ctx) error
+	GetMovieRevisions(c *fiber.Ctx) error
+	RollbackMovie(c *fiber.Ctx) error
+	PatchMovie(c *fiber.Ctx) error
+	ImportMovies(c *fiber.Ctx) error
+	ExportMovies(c *fiber.Ctx) error
+	PreviewMovieEnrichment(c *fiber.Ctx) error
+	EnrichMovie(c *fiber.Ctx) error
+	// AuthorizeMovieFile goes in front of the static movie files and hides the ones of unpublished movies
+	AuthorizeMovieFile(c *fiber.Ctx) error
+	// CountStreamedBytes goes in front of the static movie files and counts the bytes they send
+	CountStreamedBytes(c *fiber.Ctx) error
 }
@@ -0,0 +1,8 @@
+package delivery
+
+import "github.com/gofiber/fiber/v2"
+
+type RateLimitHandler interface {
+	// Limit counts the requests of a route against the rate_limit rule of that name
+	Limit(rule string) fiber.Handler
+}
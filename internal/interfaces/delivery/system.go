@@ -0,0 +1,9 @@
+package delivery
+
+import "github.com/gofiber/fiber/v2"
+
+type SystemHandler interface {
+	GetCircuitBreakers(c *fiber.Ctx) error
+	CreateBackup(c *fiber.Ctx) error
+	GetBackups(c *fiber.Ctx) error
+}
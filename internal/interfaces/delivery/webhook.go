@@ -0,0 +1,12 @@
+package delivery
+
+import "github.com/gofiber/fiber/v2"
+
+type WebhookHandler interface {
+	CreateWebhookSubscription(c *fiber.Ctx) error
+	GetWebhookSubscriptions(c *fiber.Ctx) error
+	UpdateWebhookSubscription(c *fiber.Ctx) error
+	DeleteWebhookSubscription(c *fiber.Ctx) error
+	GetWebhookDeliveries(c *fiber.Ctx) error
+	RedeliverWebhook(c *fiber.Ctx) error
+}
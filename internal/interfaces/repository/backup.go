@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"lion-parcel-test/pkg/errs"
+	"time"
+)
+
+type BackupRepository interface {
+	CreateBackup(ctx context.Context) (Backup, errs.MessageErr)
+	// GetBackups lists the backups of backup.dir, newest first
+	GetBackups(ctx context.Context) ([]Backup, errs.MessageErr)
+	DeleteBackup(ctx context.Context, name string) errs.MessageErr
+}
+
+// Backup is a snapshot file in backup.dir
+type Backup struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"lion-parcel-test/pkg/errs"
+)
+
+type CatalogRepository interface {
+	LookupMovieFromCatalog(ctx context.Context, title string, year int) (CatalogMovie, errs.MessageErr)
+}
+
+// CatalogMovie is a movie found in the external catalog
+type CatalogMovie struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Artist      string `json:"artists"`
+	Genre       string `json:"genres"`
+	Year        int    `json:"year"`
+	Poster      string `json:"poster"`
+}
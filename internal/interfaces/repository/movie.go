@@ -3,15 +3,28 @@ package repository
 import (
 	"context"
 	"lion-parcel-test/pkg/errs"
+	"time"
 )
 
 type MovieRepository interface {
-	InsertMovieToDB(ctx context.Context, Title string, Description string, Duration int, Artist string, Genre string, FileName string) errs.MessageErr
-	UpdateMovieToDB(ctx context.Context, Id string, Title string, Description string, Duration int, Artist string, Genre string, FileName string) errs.MessageErr
+	InsertMovieToDB(ctx context.Context, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Status string, PublishAt *time.Time) (string, errs.MessageErr)
+	// UpdateMovieToDB only updates when the stored version equals expectedVersion, 0 skips the check
+	UpdateMovieToDB(ctx context.Context, Id string, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Year int, Poster string, expectedVersion int) errs.MessageErr
 	GetMostViewedMovieFromDB(ctx context.Context) (Movie, errs.MessageErr)
 	GetMostViewedGenreFromDB(ctx context.Context) (Movie, errs.MessageErr)
-	GetMoviesFromDB(ctx context.Context, page int, pageSize int) ([]Movie, MoviePaginationMetadata, errs.MessageErr)
-	SearchMoviesFromDB(ctx context.Context, keyword string) ([]Movie, errs.MessageErr)
+	GetMovieByIdFromDB(ctx context.Context, id string) (Movie, errs.MessageErr)
+	// GetMovieByFileNameFromDB returns the movie the file is the current upload of, NA when there is none
+	GetMovieByFileNameFromDB(ctx context.Context, fileName string) (Movie, errs.MessageErr)
+	GetMoviesFromDB(ctx context.Context, status string, page int, pageSize int) ([]Movie, MoviePaginationMetadata, errs.MessageErr)
+	SearchMoviesFromDB(ctx context.Context, status string, keyword string) ([]Movie, errs.MessageErr)
+	UpdateMovieStatusToDB(ctx context.Context, id string, status string, publishAt *time.Time) errs.MessageErr
+	PublishScheduledMoviesToDB(ctx context.Context, now time.Time) (int64, errs.MessageErr)
+	InsertMoviesToDB(ctx context.Context, movies []NewMovie) ([]string, errs.MessageErr)
+	StreamMoviesFromDB(ctx context.Context, fn func(movie Movie) error) errs.MessageErr
+	InsertMovieRevisionToDB(ctx context.Context, revision MovieRevision) errs.MessageErr
+	GetMovieRevisionsFromDB(ctx context.Context, movieId string) ([]MovieRevision, errs.MessageErr)
+	GetMovieRevisionFromDB(ctx context.Context, movieId string, revisionId int) (MovieRevision, errs.MessageErr)
+	// InsertVoteToDB returns NA when the movie doesn't exist or isn't published, AV when the user already voted
 	InsertVoteToDB(ctx context.Context, userId int, movieId int) errs.MessageErr
 	DeleteVoteFromDB(ctx context.Context, userId int, movieId int) errs.MessageErr
 	GetAllVotedMoviesByUserIdFromDb(ctx context.Context, userId int) ([]Movie, errs.MessageErr)
@@ -21,16 +34,52 @@ type MovieRepository interface {
 }
 
 type Movie struct {
-	Id          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Duration    int    `json:"duration"`
-	Artist      string `json:"artists"`
-	Genre       string `json:"genres"`
-	WatchUrl    string `json:"watch_url"`
-	Views       int    `json:"views"`
-	Vote        int    `json:"vote,omitempty"`
+	Id          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Duration    int        `json:"duration"`
+	Artist      string     `json:"artists"`
+	Genre       string     `json:"genres"`
+	WatchUrl    string     `json:"watch_url"`
+	Views       int        `json:"views"`
+	Vote        int        `json:"vote,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	Version     int        `json:"version,omitempty"`
+	Year        int        `json:"year,omitempty"`
+	Poster      string     `json:"poster,omitempty"`
 }
+
+// NewMovie is a movie to insert, used where several movies are inserted at once
+type NewMovie struct {
+	Title       string
+	Description string
+	Duration    int
+	Artist      string
+	Genre       string
+	FileName    string
+	Status      string
+	PublishAt   *time.Time
+}
+
+// MovieRevision is one recorded create or update of a movie, Snapshot is the movie right after the change
+type MovieRevision struct {
+	Id           int                    `json:"id"`
+	MovieId      string                 `json:"movie_id"`
+	UserId       int                    `json:"user_id"`
+	Action       string                 `json:"action"`
+	Changes      map[string]FieldChange `json:"changes"`
+	Snapshot     Movie                  `json:"snapshot"`
+	FileName     string                 `json:"file_name,omitempty"`
+	RestoredFrom int                    `json:"restored_from,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
 type MoviePaginationMetadata struct {
 	CurrentPage int `json:"currentPage"`
 	PageSize    int `json:"pageSize"`
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"lion-parcel-test/pkg/errs"
+	"time"
+)
+
+// OutboxRepository reads the outbox, events are written by the repository making the change in the same transaction
+type OutboxRepository interface {
+	GetOutboxEventsFromDB(ctx context.Context, afterId int64, limit int) ([]OutboxEvent, errs.MessageErr)
+	CountOutboxEventsFromDB(ctx context.Context, afterId int64) (int, errs.MessageErr)
+	// GetConsumerOffsetFromDB returns 0 for a consumer that never stored an offset
+	GetConsumerOffsetFromDB(ctx context.Context, consumer string) (ConsumerOffset, errs.MessageErr)
+	// UpdateConsumerOffsetToDB stores the offset along with the event the consumer is failing on, if any
+	UpdateConsumerOffsetToDB(ctx context.Context, offset ConsumerOffset) errs.MessageErr
+	// InsertDeadLetterToDB records an event a consumer gave up on, recording it twice keeps the first one
+	InsertDeadLetterToDB(ctx context.Context, letter DeadLetter) errs.MessageErr
+}
+
+type OutboxEvent struct {
+	Id          int64
+	Event       string
+	AggregateId string
+	Payload     string
+	CreatedAt   time.Time
+}
+
+// ConsumerOffset is the id of the last event a consumer handled
+type ConsumerOffset struct {
+	Consumer    string
+	LastEventId int64
+	// FailingEventId is the event after the offset the handler failed on Attempts times, 0 when none failed
+	FailingEventId int64
+	Attempts       int
+	LastError      string
+	UpdatedAt      *time.Time
+}
+
+// DeadLetter is an event a consumer skipped after its handler failed max_attempts times
+type DeadLetter struct {
+	Consumer string
+	EventId  int64
+	Event    string
+	Attempts int
+	Error    string
+}
+
+// MovieCreatedEvent is the payload of constant.EventMovieCreated
+type MovieCreatedEvent struct {
+	Movie Movie `json:"movie"`
+}
+
+// MovieUpdatedEvent is the payload of constant.EventMovieUpdated, both snapshots are taken in the updating transaction
+type MovieUpdatedEvent struct {
+	Before Movie `json:"before"`
+	After  Movie `json:"after"`
+}
+
+// VoteEvent is the payload of constant.EventVoteCast and constant.EventVoteRemoved, Votes is the count right after the change
+type VoteEvent struct {
+	UserId  int `json:"user_id"`
+	MovieId int `json:"movie_id"`
+	Votes   int `json:"votes"`
+}
+
+// UserRegisteredEvent is the payload of constant.EventUserRegistered
+type UserRegisteredEvent struct {
+	UserId int    `json:"user_id"`
+	Name   string `json:"name"`
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"lion-parcel-test/pkg/errs"
+	"time"
+)
+
+type RateLimitRepository interface {
+	// TakeRateLimitToken takes a request of key from the bucket of rule
+	TakeRateLimitToken(ctx context.Context, rule string, key string, limit int, period time.Duration) (RateLimit, errs.MessageErr)
+	// AddLoginFailure returns the failed logins of key within window, key is the ip or the email they were aimed at
+	AddLoginFailure(ctx context.Context, key string, window time.Duration) (int64, errs.MessageErr)
+	ResetLoginFailures(ctx context.Context, key string) errs.MessageErr
+	LockLogin(ctx context.Context, key string, d time.Duration) errs.MessageErr
+	// GetLoginLock is how long logins of key are still locked out, 0 when they aren't
+	GetLoginLock(ctx context.Context, key string) (time.Duration, errs.MessageErr)
+}
+
+// RateLimit is the state of a bucket after a request was taken from it
+type RateLimit struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
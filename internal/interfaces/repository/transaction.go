@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+	"lion-parcel-test/pkg/errs"
+)
+
+// Transactor runs fn as one unit of work, every repository call made with the ctx fn receives joins it
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) errs.MessageErr) errs.MessageErr
+}
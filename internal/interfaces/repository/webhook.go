@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"lion-parcel-test/pkg/errs"
+	"net/http"
+	"time"
+)
+
+type WebhookRepository interface {
+	InsertWebhookSubscriptionToDB(ctx context.Context, subscription WebhookSubscription) (int, errs.MessageErr)
+	// UpdateWebhookSubscriptionToDB saves url, events and active, activating a subscription clears its failures
+	UpdateWebhookSubscriptionToDB(ctx context.Context, subscription WebhookSubscription) errs.MessageErr
+	DeleteWebhookSubscriptionFromDB(ctx context.Context, id int) errs.MessageErr
+	GetWebhookSubscriptionFromDB(ctx context.Context, id int) (WebhookSubscription, errs.MessageErr)
+	GetWebhookSubscriptionsFromDB(ctx context.Context, activeOnly bool) ([]WebhookSubscription, errs.MessageErr)
+	// RecordWebhookResultToDB counts consecutive failures and disables the subscription once they reach maxFailures,
+	// it reports true for the failure that disabled it
+	RecordWebhookResultToDB(ctx context.Context, id int, success bool, maxFailures int) (bool, errs.MessageErr)
+	InsertWebhookDeliveriesToDB(ctx context.Context, deliveries []WebhookDelivery) errs.MessageErr
+	UpdateWebhookDeliveryToDB(ctx context.Context, delivery WebhookDelivery) errs.MessageErr
+	GetWebhookDeliveryFromDB(ctx context.Context, subscriptionId int, id int) (WebhookDelivery, errs.MessageErr)
+	GetWebhookDeliveriesFromDB(ctx context.Context, subscriptionId int, status string, limit int) ([]WebhookDelivery, errs.MessageErr)
+	GetPendingWebhookDeliveriesFromDB(ctx context.Context, limit int) ([]WebhookDelivery, errs.MessageErr)
+	SendWebhookToEndpoint(ctx context.Context, subscription WebhookSubscription, header http.Header, body []byte) (WebhookResponse, errs.MessageErr)
+}
+
+// WebhookSubscription is a partner endpoint, Events holds event names or "*" for all of them
+type WebhookSubscription struct {
+	Id                  int        `json:"id"`
+	Url                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Secret              string     `json:"secret,omitempty"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent to one subscription, Payload is the exact signed body
+type WebhookDelivery struct {
+	Id             int        `json:"id"`
+	SubscriptionId int        `json:"subscription_id"`
+	EventId        string     `json:"event_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+}
+
+type WebhookResponse struct {
+	StatusCode int
+	Body       []byte
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"lion-parcel-test/pkg/dto"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// DomainEvent is an outbox event as subscribers see it, Payload is one of the repository event payloads
+type DomainEvent struct {
+	Id          int64               `json:"id"`
+	Name        string              `json:"name"`
+	AggregateId string              `json:"aggregate_id"`
+	Payload     jsoniter.RawMessage `json:"payload"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// EventHandler returning an error gets the event and everything after it again on the next dispatch,
+// so handlers must be idempotent and only fail on errors worth retrying
+type EventHandler func(ctx context.Context, event DomainEvent) error
+
+type EventBus interface {
+	// Subscribe registers a consumer before the first dispatch, no events means every event
+	Subscribe(consumer string, handler EventHandler, events ...string)
+	DispatchEvents(ctx context.Context) *dto.Response
+	GetEventConsumers(ctx context.Context) *dto.Response
+}
+
+type DispatchEventsResponse struct {
+	Delivered int `json:"delivered"`
+	// DeadLettered is how many events were skipped after their handler failed max_attempts times
+	DeadLettered int `json:"dead_lettered"`
+	Failed       int `json:"failed"`
+}
+
+type EventConsumer struct {
+	Consumer    string   `json:"consumer"`
+	Events      []string `json:"events,omitempty"`
+	LastEventId int64    `json:"last_event_id"`
+	Lag         int      `json:"lag"`
+	// the event after the offset the handler keeps failing on, 0 when it isn't failing
+	FailingEventId int64      `json:"failing_event_id,omitempty"`
+	Attempts       int        `json:"attempts,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+type GetEventConsumersResponse struct {
+	Consumers []EventConsumer `json:"consumers"`
+}
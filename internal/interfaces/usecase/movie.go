@@ -2,7 +2,9 @@ package usecase
 
 import (
 	"context"
+	"io"
 	"lion-parcel-test/pkg/dto"
+	"time"
 )
 
 type MovieUsecase interface {
@@ -17,6 +19,116 @@ type MovieUsecase interface {
 	VotedMovies(ctx context.Context, req *VotedMoviesRequest) *dto.Response
 	MostVoted(ctx context.Context, req *MostVotedRequest) *dto.Response
 	MostVotedGenre(ctx context.Context, req *MostVotedGenreRequest) *dto.Response
+	GetMovie(ctx context.Context, req *GetMovieRequest) *dto.Response
+	AdminGetMovies(ctx context.Context, req *AdminGetMoviesRequest) *dto.Response
+	UpdateMovieStatus(ctx context.Context, req *UpdateMovieStatusRequest) *dto.Response
+	PublishScheduledMovies(ctx context.Context) *dto.Response
+	GetMovieRevisions(ctx context.Context, req *GetMovieRevisionsRequest) *dto.Response
+	RollbackMovie(ctx context.Context, req *RollbackMovieRequest) *dto.Response
+	PatchMovie(ctx context.Context, req *PatchMovieRequest) *dto.Response
+	ImportMovies(ctx context.Context, req *ImportMoviesRequest) *dto.Response
+	ExportMovies(ctx context.Context, req *ExportMoviesRequest) *dto.Response
+	PreviewMovieEnrichment(ctx context.Context, req *EnrichMovieRequest) *dto.Response
+	EnrichMovie(ctx context.Context, req *EnrichMovieRequest) *dto.Response
+	AuthorizeMovieFile(ctx context.Context, req *AuthorizeMovieFileRequest) *dto.Response
+	// HandleMovieEvent is the EventHandler of constant.EventConsumerMovieWebhooks
+	HandleMovieEvent(ctx context.Context, event DomainEvent) error
+}
+
+// ImportMoviesRequest reads a csv or jsonl catalog, "atomic" mode imports every row or none, "batch" mode commits BatchSize rows at a time
+type ImportMoviesRequest struct {
+	Format    string    `validate:"required,oneof=csv jsonl"`
+	Reader    io.Reader `validate:"required"`
+	DryRun    bool
+	Mode      string `validate:"omitempty,oneof=atomic batch"`
+	BatchSize int    `validate:"omitempty,min=1,max=10000"`
+	UserId    int
+}
+type ImportMoviesResponse struct {
+	DryRun   bool             `json:"dry_run"`
+	Mode     string           `json:"mode"`
+	Total    int              `json:"total"`
+	Valid    int              `json:"valid"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError reports a rejected row, Row counts data rows from 1 and skips the csv header
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+type ExportMoviesRequest struct {
+	Format string    `validate:"required,oneof=csv jsonl"`
+	Writer io.Writer `validate:"required"`
+}
+type ExportMoviesResponse struct {
+	Exported int `json:"exported"`
+}
+
+// PatchMovieRequest carries a JSON Merge Patch (RFC 7396) for a movie, FileName is only set when a new file was uploaded
+type PatchMovieRequest struct {
+	Id       string                 `json:"id" validate:"required"`
+	Patch    map[string]interface{} `json:"-"`
+	FileName string                 `json:"file_name"`
+	IfMatch  int                    `json:"-"`
+	UserId   int                    `json:"-" validate:"required"`
+}
+
+// EnrichMovieRequest looks the movie up in the external catalog, Title defaults to the movie title
+// and Fields to every enrichable field. Without Overwrite only empty fields are filled in.
+type EnrichMovieRequest struct {
+	Id        string   `json:"id" validate:"required"`
+	Title     string   `json:"title"`
+	Year      int      `json:"year" validate:"gte=0"`
+	Fields    []string `json:"fields" validate:"dive,oneof=description artists genres year poster"`
+	Overwrite bool     `json:"overwrite"`
+	IfMatch   int      `json:"-"`
+	UserId    int      `json:"-" validate:"required"`
+}
+
+type EnrichMovieResponse struct {
+	Applied bool        `json:"applied"`
+	Match   interface{} `json:"match"`
+	Changes interface{} `json:"changes"`
+	Movie   interface{} `json:"movie"`
+}
+
+type GetMovieRevisionsRequest struct {
+	Id string `json:"id" validate:"required"`
+}
+type GetMovieRevisionsResponse struct {
+	Revisions interface{} `json:"revisions"`
+}
+type RollbackMovieRequest struct {
+	Id         string `json:"id" validate:"required"`
+	RevisionId int    `json:"revision_id" validate:"required"`
+	IfMatch    int    `json:"-"`
+	UserId     int    `json:"-" validate:"required"`
+}
+
+// AuthorizeMovieFileRequest asks whether the caller may stream a file of the movie directory
+type AuthorizeMovieFileRequest struct {
+	FileName string
+	IsAdmin  bool
+}
+
+type GetMovieRequest struct {
+	Id string `json:"id" validate:"required"`
+}
+type AdminGetMoviesRequest struct {
+	Status   string `validate:"omitempty,oneof=draft scheduled published archived"`
+	Page     int
+	PageSize int
+}
+type UpdateMovieStatusRequest struct {
+	Id        string     `json:"id" validate:"required"`
+	Status    string     `json:"status" validate:"required,oneof=draft scheduled published archived"`
+	PublishAt *time.Time `json:"publish_at" validate:"required_if=Status scheduled"`
+	UserId    int        `json:"-" validate:"required"`
+}
+type PublishScheduledMoviesResponse struct {
+	Published int64 `json:"published"`
 }
 
 type VotedMoviesRequest struct {
@@ -67,12 +179,15 @@ type MostVotedGenreResponse struct {
 type MostViewedRequest struct {
 }
 type CreateMovieRequest struct {
-	Title       string `json:"title" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	Duration    int    `json:"duration" validate:"required"`
-	Artist      string `json:"artists" validate:"required"`
-	Genre       string `json:"genres" validate:"required"`
-	FileName    string `json:"file_name" validate:"required"`
+	Title       string     `json:"title" validate:"required"`
+	Description string     `json:"description" validate:"required"`
+	Duration    int        `json:"duration" validate:"required"`
+	Artist      string     `json:"artists" validate:"required"`
+	Genre       string     `json:"genres" validate:"required"`
+	FileName    string     `json:"file_name" validate:"required"`
+	Status      string     `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt   *time.Time `json:"publish_at" validate:"required_if=Status scheduled"`
+	UserId      int        `json:"-" validate:"required"`
 }
 type UpdateMovieRequest struct {
 	Id          string `json:"id" validate:"required"`
@@ -82,4 +197,6 @@ type UpdateMovieRequest struct {
 	Artist      string `json:"artists" validate:"required"`
 	Genre       string `json:"genres" validate:"required"`
 	FileName    string `json:"file_name" validate:"required"`
+	IfMatch     int    `json:"-"`
+	UserId      int    `json:"-" validate:"required"`
 }
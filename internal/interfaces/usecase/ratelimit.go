@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"context"
+	"lion-parcel-test/pkg/dto"
+)
+
+type RateLimitUsecase interface {
+	// Allow takes a request from the bucket of req.Rule, the response carries the RateLimit headers either way
+	Allow(ctx context.Context, req *RateLimitRequest) *dto.Response
+}
+
+// RateLimitRequest is who made a request and where to, the rule picks what it is counted by
+type RateLimitRequest struct {
+	Rule string
+	Ip   string
+	// 0 for anonymous callers
+	UserId int
+	Route  string
+}
@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/dto"
+)
+
+type SystemUsecase interface {
+	GetCircuitBreakers(ctx context.Context) *dto.Response
+	CreateBackup(ctx context.Context) *dto.Response
+	GetBackups(ctx context.Context) *dto.Response
+}
+
+type GetCircuitBreakersResponse struct {
+	CircuitBreakers interface{} `json:"circuit_breakers"`
+}
+
+type CreateBackupResponse struct {
+	Backup repository.Backup `json:"backup"`
+	// Pruned are the older backups removed to keep backup.retention of them
+	Pruned []string `json:"pruned"`
+}
+
+type GetBackupsResponse struct {
+	Backups []repository.Backup `json:"backups"`
+}
@@ -23,6 +23,8 @@ type RegisterResponse struct {
 
 type LoginRequest struct {
 	Email string `json:"email"`
+	// the failed logins of an ip lock it out, set by the handler
+	Ip string `json:"-"`
 }
 type LoginResponse struct {
 	Jwt string `json:"jwt"`
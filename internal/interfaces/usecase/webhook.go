@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+)
+
+// WebhookNotifier queues an event for every active subscription listening to it, id identifies the event
+// to partners and notifying the same id twice queues nothing new
+type WebhookNotifier interface {
+	NotifyWebhooks(ctx context.Context, id string, event string, data interface{}) errs.MessageErr
+}
+
+type WebhookUsecase interface {
+	WebhookNotifier
+	CreateWebhookSubscription(ctx context.Context, req *CreateWebhookSubscriptionRequest) *dto.Response
+	GetWebhookSubscriptions(ctx context.Context) *dto.Response
+	UpdateWebhookSubscription(ctx context.Context, req *UpdateWebhookSubscriptionRequest) *dto.Response
+	DeleteWebhookSubscription(ctx context.Context, req *DeleteWebhookSubscriptionRequest) *dto.Response
+	GetWebhookDeliveries(ctx context.Context, req *GetWebhookDeliveriesRequest) *dto.Response
+	RedeliverWebhook(ctx context.Context, req *RedeliverWebhookRequest) *dto.Response
+	DispatchWebhooks(ctx context.Context) *dto.Response
+}
+
+// CreateWebhookSubscriptionRequest generates the secret when none is given, it is only returned here
+type CreateWebhookSubscriptionRequest struct {
+	Url    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=* movie.created movie.updated movie.published vote.threshold_crossed"`
+	Secret string   `json:"secret" validate:"omitempty,min=16"`
+}
+
+// UpdateWebhookSubscriptionRequest replaces url and events, setting active re-enables a disabled subscription
+type UpdateWebhookSubscriptionRequest struct {
+	Id     int      `json:"id" validate:"required"`
+	Url    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=* movie.created movie.updated movie.published vote.threshold_crossed"`
+	Active bool     `json:"active"`
+}
+
+type DeleteWebhookSubscriptionRequest struct {
+	Id int `json:"id" validate:"required"`
+}
+
+type GetWebhookDeliveriesRequest struct {
+	SubscriptionId int    `json:"subscription_id" validate:"required"`
+	Status         string `json:"status" validate:"omitempty,oneof=pending succeeded failed"`
+}
+
+type RedeliverWebhookRequest struct {
+	SubscriptionId int `json:"subscription_id" validate:"required"`
+	DeliveryId     int `json:"delivery_id" validate:"required"`
+}
+
+type GetWebhookSubscriptionsResponse struct {
+	Subscriptions interface{} `json:"subscriptions"`
+}
+
+type GetWebhookDeliveriesResponse struct {
+	Deliveries interface{} `json:"deliveries"`
+}
+
+type DispatchWebhooksResponse struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
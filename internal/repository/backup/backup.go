@@ -0,0 +1,154 @@
+package backuprepo
+
+import (
+	"context"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupPrefix = "movies-"
+	backupExt    = ".db"
+	// sorts the same way as it reads, so the newest name is the greatest
+	backupTimeFormat = "20060102T150405.000Z"
+)
+
+type backupRepository struct {
+	// backup is nil when the database can't copy itself, e.g. postgres which is backed up with pg_dump
+	backup adapter.BackupClient
+}
+
+func NewBackupRepository(backup adapter.BackupClient) repository.BackupRepository {
+	return &backupRepository{
+		backup: backup,
+	}
+}
+
+func (rp *backupRepository) CreateBackup(ctx context.Context) (repository.Backup, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "CreateBackup", "Repository")
+	defer span.End()
+
+	if rp.backup == nil {
+		return repository.Backup{}, errs.NewCustomErrs(
+			"Backup Not Supported",
+			"BN",
+			"the "+config.Cfg.Database.Driver+" database can't be backed up by the app",
+		)
+	}
+
+	err := os.MkdirAll(config.Cfg.BackupDir(), os.ModePerm)
+	if err != nil {
+		return repository.Backup{}, errs.NewCustomErrs(
+			"Failed Backup",
+			"FB",
+			err.Error(),
+		)
+	}
+
+	name := backupPrefix + time.Now().UTC().Format(backupTimeFormat) + backupExt
+	path := filepath.Join(config.Cfg.BackupDir(), name)
+
+	err = rp.backup.Backup(ctx, path)
+	if err != nil {
+		return repository.Backup{}, errs.NewCustomErrs(
+			"Failed Backup",
+			"FB",
+			err.Error(),
+		)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return repository.Backup{}, errs.NewCustomErrs(
+			"Failed Backup",
+			"FB",
+			err.Error(),
+		)
+	}
+
+	return toBackup(info), nil
+}
+
+func (rp *backupRepository) GetBackups(ctx context.Context) ([]repository.Backup, errs.MessageErr) {
+	span, _ := tracing.StartSpan(ctx, "GetBackups", "Repository")
+	defer span.End()
+
+	backups := make([]repository.Backup, 0)
+
+	entries, err := os.ReadDir(config.Cfg.BackupDir())
+	if os.IsNotExist(err) {
+		return backups, nil
+	}
+	if err != nil {
+		return nil, errs.NewCustomErrs(
+			"Failed Get Backups",
+			"FB",
+			err.Error(),
+		)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackup(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// removed since the directory was read
+			continue
+		}
+
+		backups = append(backups, toBackup(info))
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name > backups[j].Name
+	})
+
+	return backups, nil
+}
+
+func (rp *backupRepository) DeleteBackup(ctx context.Context, name string) errs.MessageErr {
+	span, _ := tracing.StartSpan(ctx, "DeleteBackup", "Repository")
+	defer span.End()
+
+	if !isBackup(name) || filepath.Base(name) != name {
+		return errs.NewCustomErrs(
+			"Backup Not Found",
+			"NA",
+			name+" is not a backup",
+		)
+	}
+
+	err := os.Remove(filepath.Join(config.Cfg.BackupDir(), name))
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Delete Backup",
+			"FB",
+			err.Error(),
+		)
+	}
+
+	return nil
+}
+
+func isBackup(name string) bool {
+	return strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupExt)
+}
+
+func toBackup(info os.FileInfo) repository.Backup {
+	return repository.Backup{
+		Name:      info.Name(),
+		Path:      filepath.Join(config.Cfg.BackupDir(), info.Name()),
+		Size:      info.Size(),
+		CreatedAt: info.ModTime().UTC(),
+	}
+}
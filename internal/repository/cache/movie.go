@@ -0,0 +1,247 @@
+// Package cacherepo caches the movie lists and statistics in front of another MovieRepository.
+// Every write bumps a generation counter the cached entries are keyed by, so a write drops them all at once
+package cacherepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultTTL    = 30 * time.Second
+	keyPrefix     = "movies:"
+	generationKey = keyPrefix + "generation"
+)
+
+type movieRepository struct {
+	// the methods that aren't cached go straight to it
+	repository.MovieRepository
+	cache adapter.CacheClient
+	ttl   time.Duration
+	// loads lets one caller query a missing entry while the others wait for its result
+	loads singleflight.Group
+}
+
+func NewMovieRepository(movies repository.MovieRepository, cache adapter.CacheClient) repository.MovieRepository {
+	ttl := config.Cfg.Cache.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &movieRepository{
+		MovieRepository: movies,
+		cache:           cache,
+		ttl:             ttl,
+	}
+}
+
+// moviesPage is a cached GetMoviesFromDB
+type moviesPage struct {
+	Movies     []repository.Movie                 `json:"movies"`
+	Pagination repository.MoviePaginationMetadata `json:"pagination"`
+}
+
+func (rp *movieRepository) GetMoviesFromDB(ctx context.Context, status string, page int, pageSize int) ([]repository.Movie, repository.MoviePaginationMetadata, errs.MessageErr) {
+	cached, err := readThrough(ctx, rp, "GetMoviesFromDB", func(ctx context.Context) (moviesPage, errs.MessageErr) {
+		movies, pagination, err := rp.MovieRepository.GetMoviesFromDB(ctx, status, page, pageSize)
+		return moviesPage{Movies: movies, Pagination: pagination}, err
+	}, status, page, pageSize)
+
+	return cached.Movies, cached.Pagination, err
+}
+
+func (rp *movieRepository) SearchMoviesFromDB(ctx context.Context, status string, keyword string) ([]repository.Movie, errs.MessageErr) {
+	return readThrough(ctx, rp, "SearchMoviesFromDB", func(ctx context.Context) ([]repository.Movie, errs.MessageErr) {
+		return rp.MovieRepository.SearchMoviesFromDB(ctx, status, keyword)
+	}, status, keyword)
+}
+
+func (rp *movieRepository) GetMostViewedMovieFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	return readThrough(ctx, rp, "GetMostViewedMovieFromDB", rp.MovieRepository.GetMostViewedMovieFromDB)
+}
+
+func (rp *movieRepository) GetMostViewedGenreFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	return readThrough(ctx, rp, "GetMostViewedGenreFromDB", rp.MovieRepository.GetMostViewedGenreFromDB)
+}
+
+func (rp *movieRepository) GetMostVotedMovieFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	return readThrough(ctx, rp, "GetMostVotedMovieFromDB", rp.MovieRepository.GetMostVotedMovieFromDB)
+}
+
+func (rp *movieRepository) GetMostVotedGenreFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	return readThrough(ctx, rp, "GetMostVotedGenreFromDB", rp.MovieRepository.GetMostVotedGenreFromDB)
+}
+
+func (rp *movieRepository) InsertMovieToDB(ctx context.Context, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Status string, PublishAt *time.Time) (string, errs.MessageErr) {
+	id, err := rp.MovieRepository.InsertMovieToDB(ctx, Title, Description, Duration, Artist, Genre, FileName, Status, PublishAt)
+	if err == nil {
+		invalidate(ctx, rp.cache)
+	}
+
+	return id, err
+}
+
+func (rp *movieRepository) UpdateMovieToDB(ctx context.Context, Id string, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Year int, Poster string, expectedVersion int) errs.MessageErr {
+	err := rp.MovieRepository.UpdateMovieToDB(ctx, Id, Title, Description, Duration, Artist, Genre, FileName, Year, Poster, expectedVersion)
+	if err == nil {
+		invalidate(ctx, rp.cache)
+	}
+
+	return err
+}
+
+func (rp *movieRepository) UpdateMovieStatusToDB(ctx context.Context, id string, status string, publishAt *time.Time) errs.MessageErr {
+	err := rp.MovieRepository.UpdateMovieStatusToDB(ctx, id, status, publishAt)
+	if err == nil {
+		invalidate(ctx, rp.cache)
+	}
+
+	return err
+}
+
+func (rp *movieRepository) PublishScheduledMoviesToDB(ctx context.Context, now time.Time) (int64, errs.MessageErr) {
+	published, err := rp.MovieRepository.PublishScheduledMoviesToDB(ctx, now)
+	if err == nil && published > 0 {
+		invalidate(ctx, rp.cache)
+	}
+
+	return published, err
+}
+
+func (rp *movieRepository) InsertMoviesToDB(ctx context.Context, movies []repository.NewMovie) ([]string, errs.MessageErr) {
+	ids, err := rp.MovieRepository.InsertMoviesToDB(ctx, movies)
+	if err == nil {
+		invalidate(ctx, rp.cache)
+	}
+
+	return ids, err
+}
+
+func (rp *movieRepository) InsertVoteToDB(ctx context.Context, userId int, movieId int) errs.MessageErr {
+	err := rp.MovieRepository.InsertVoteToDB(ctx, userId, movieId)
+	if err == nil {
+		invalidate(ctx, rp.cache)
+	}
+
+	return err
+}
+
+func (rp *movieRepository) DeleteVoteFromDB(ctx context.Context, userId int, movieId int) errs.MessageErr {
+	err := rp.MovieRepository.DeleteVoteFromDB(ctx, userId, movieId)
+	if err == nil {
+		invalidate(ctx, rp.cache)
+	}
+
+	return err
+}
+
+// readThrough serves name(params) from the cache, or loads and caches it. Errors aren't cached and a cache
+// that can't be reached only costs the query. In a transaction the cache is skipped, it can't see the writes made so far
+func readThrough[T any](ctx context.Context, rp *movieRepository, name string, load func(ctx context.Context) (T, errs.MessageErr), params ...interface{}) (T, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, name, "Repository.Cache")
+	defer span.End()
+
+	if inTx(ctx) {
+		return load(ctx)
+	}
+
+	// read before the query, an entry loaded while a write commits is stored under the generation it bumps away from
+	generation, err := currentGeneration(ctx, rp.cache)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		return load(ctx)
+	}
+
+	key := cacheKey(generation, name, params)
+
+	var value T
+
+	raw, err := rp.cache.Get(ctx, key)
+	if err == nil && jsoniter.Unmarshal(raw, &value) == nil {
+		return value, nil
+	}
+	if err != nil && !errors.Is(err, adapter.ErrCacheMiss) {
+		tracing.CaptureError(ctx, err)
+		return load(ctx)
+	}
+
+	// the load outlives a caller that gives up, the others waiting for it still want the result
+	loadCtx := context.WithoutCancel(ctx)
+
+	shared, loadErr, _ := rp.loads.Do(key, func() (interface{}, error) {
+		loaded, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, marshalErr := jsoniter.Marshal(loaded)
+		if marshalErr != nil {
+			return nil, errs.NewCustomErrs("Failed Cache Movies", "FC", marshalErr.Error())
+		}
+
+		if setErr := rp.cache.Set(loadCtx, key, raw, rp.ttl); setErr != nil {
+			tracing.CaptureError(loadCtx, setErr)
+		}
+
+		return raw, nil
+	})
+	if loadErr != nil {
+		return value, loadErr.(errs.MessageErr)
+	}
+
+	// every caller decodes its own copy, so none of them can change the others' movies
+	err = jsoniter.Unmarshal(shared.([]byte), &value)
+	if err != nil {
+		return value, errs.NewCustomErrs("Failed Cache Movies", "FC", err.Error())
+	}
+
+	return value, nil
+}
+
+func cacheKey(generation int64, name string, params []interface{}) string {
+	key := keyPrefix + strconv.FormatInt(generation, 10) + ":" + name
+	for _, param := range params {
+		key += ":" + strings.ReplaceAll(fmt.Sprint(param), ":", `\:`)
+	}
+
+	return key
+}
+
+// currentGeneration is 0 until the first write
+func currentGeneration(ctx context.Context, cache adapter.CacheClient) (int64, error) {
+	raw, err := cache.Get(ctx, generationKey)
+	if errors.Is(err, adapter.ErrCacheMiss) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(string(raw), 10, 64)
+}
+
+// invalidate drops every cached entry, a write made in a transaction does it once the transaction committed.
+// When the cache can't be reached the entries expire after their ttl
+func invalidate(ctx context.Context, cache adapter.CacheClient) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.written.Store(true)
+		return
+	}
+
+	_, err := cache.Incr(ctx, generationKey)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+	}
+}
@@ -0,0 +1,265 @@
+package cacherepo_test
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/adapters/cache/lru"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	cacherepo "lion-parcel-test/internal/repository/cache"
+	"lion-parcel-test/internal/repository/contract"
+	memoryrepo "lion-parcel-test/internal/repository/memory"
+	"lion-parcel-test/pkg/errs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	config.Cfg = &config.Config{}
+	config.Cfg.Cache.TTL = time.Minute
+
+	os.Exit(m.Run())
+}
+
+// the cached repository has to behave like the one it wraps
+func TestRepositoryContract(t *testing.T) {
+	contract.Run(t, func(t *testing.T) contract.Repositories {
+		store := memoryrepo.NewStore()
+
+		return contract.Repositories{
+			Movies: cacherepo.NewMovieRepository(memoryrepo.NewMovieRepository(store), lru.NewLruClient()),
+			Users:  memoryrepo.NewUserRepository(store),
+		}
+	})
+}
+
+// countingMovies counts the list queries that reach the database, gate holds them until it is closed
+type countingMovies struct {
+	repository.MovieRepository
+	loads atomic.Int32
+	gate  chan struct{}
+}
+
+func (rp *countingMovies) GetMoviesFromDB(ctx context.Context, status string, page int, pageSize int) ([]repository.Movie, repository.MoviePaginationMetadata, errs.MessageErr) {
+	rp.loads.Add(1)
+	if rp.gate != nil {
+		<-rp.gate
+	}
+
+	return rp.MovieRepository.GetMoviesFromDB(ctx, status, page, pageSize)
+}
+
+type fixture struct {
+	store      *memoryrepo.Store
+	counting   *countingMovies
+	movies     repository.MovieRepository
+	transactor repository.Transactor
+	userId     int
+}
+
+func newFixture(t *testing.T, cache adapter.CacheClient) *fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	store := memoryrepo.NewStore()
+	users := memoryrepo.NewUserRepository(store)
+	if err := users.InsertUserToDB(ctx, "alice@example.com", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	user, _ := users.GetUserFromDbByEmail(ctx, "alice@example.com")
+
+	counting := &countingMovies{MovieRepository: memoryrepo.NewMovieRepository(store)}
+
+	return &fixture{
+		store:      store,
+		counting:   counting,
+		movies:     cacherepo.NewMovieRepository(counting, cache),
+		transactor: cacherepo.NewTransactor(memoryrepo.NewTransactor(store), cache),
+		userId:     user.ID,
+	}
+}
+
+func (f *fixture) insertMovie(t *testing.T, ctx context.Context, title string) string {
+	t.Helper()
+
+	id, err := f.movies.InsertMovieToDB(ctx, title, title, 100, "Artist", "Drama", title+".mp4", constant.MovieStatusPublished, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// list reads the first page and expects the titles in it and the list queries made so far
+func (f *fixture) list(t *testing.T, ctx context.Context, loads int32, titles ...string) {
+	t.Helper()
+
+	movies, _, err := f.movies.GetMoviesFromDB(ctx, constant.MovieStatusPublished, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0, len(movies))
+	for _, movie := range movies {
+		got = append(got, movie.Title)
+	}
+	if strings.Join(got, ",") != strings.Join(titles, ",") {
+		t.Fatalf("expected movies %v, got %v", titles, got)
+	}
+
+	if f.counting.loads.Load() != loads {
+		t.Fatalf("expected %d list queries, got %d", loads, f.counting.loads.Load())
+	}
+}
+
+func TestReadsAreCachedUntilAWrite(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, lru.NewLruClient())
+
+	f.insertMovie(t, ctx, "Heat")
+
+	f.list(t, ctx, 1, "Heat")
+	f.list(t, ctx, 1, "Heat")
+
+	// every parameter has an entry of its own
+	if _, _, err := f.movies.GetMoviesFromDB(ctx, constant.MovieStatusPublished, 2, 10); err != nil {
+		t.Fatal(err)
+	}
+	f.list(t, ctx, 2, "Heat")
+
+	id := f.insertMovie(t, ctx, "Ronin")
+	f.list(t, ctx, 3, "Heat", "Ronin")
+
+	movieId, _ := strconv.Atoi(id)
+	if err := f.movies.InsertVoteToDB(ctx, f.userId, movieId); err != nil {
+		t.Fatal(err)
+	}
+	f.list(t, ctx, 4, "Heat", "Ronin")
+
+	// a failed write changes nothing, the entry is kept
+	if err := f.movies.InsertVoteToDB(ctx, f.userId, movieId); err == nil {
+		t.Fatal("expected the second vote to be refused")
+	}
+	f.list(t, ctx, 4, "Heat", "Ronin")
+
+	if err := f.movies.UpdateMovieStatusToDB(ctx, id, constant.MovieStatusArchived, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.list(t, ctx, 5, "Heat")
+}
+
+func TestTransactionInvalidatesOnCommit(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, lru.NewLruClient())
+
+	f.insertMovie(t, ctx, "Heat")
+	f.list(t, ctx, 1, "Heat")
+
+	err := f.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		f.insertMovie(t, ctx, "Ronin")
+
+		// the transaction sees its own write, the cache doesn't know of it yet
+		f.list(t, ctx, 2, "Heat", "Ronin")
+		f.list(t, context.Background(), 2, "Heat")
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.list(t, ctx, 3, "Heat", "Ronin")
+
+	err = f.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		f.insertMovie(t, ctx, "Tenet")
+		return errs.NewCustomErrs("Failed Insert Database", "FD", "rolled back")
+	})
+	if err == nil {
+		t.Fatal("expected the transaction to fail")
+	}
+
+	// nothing was committed, so the cached list is still right
+	f.list(t, ctx, 3, "Heat", "Ronin")
+}
+
+// countingCache counts the reads of entries, the generation counter aside
+type countingCache struct {
+	adapter.CacheClient
+	gets atomic.Int32
+}
+
+func (c *countingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if !strings.HasSuffix(key, "generation") {
+		c.gets.Add(1)
+	}
+
+	return c.CacheClient.Get(ctx, key)
+}
+
+func TestConcurrentMissesQueryOnce(t *testing.T) {
+	ctx := context.Background()
+	cache := &countingCache{CacheClient: lru.NewLruClient()}
+	f := newFixture(t, cache)
+
+	f.insertMovie(t, ctx, "Heat")
+	f.counting.gate = make(chan struct{})
+
+	const callers = 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			movies, _, err := f.movies.GetMoviesFromDB(ctx, constant.MovieStatusPublished, 1, 10)
+			if err != nil || len(movies) != 1 {
+				t.Errorf("unexpected result %+v %v", movies, err)
+			}
+		}()
+	}
+
+	// every caller missed before the first query is let through
+	for cache.gets.Load() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(f.counting.gate)
+	wg.Wait()
+
+	if loads := f.counting.loads.Load(); loads != 1 {
+		t.Fatalf("expected a single query for %d concurrent misses, got %d", callers, loads)
+	}
+}
+
+// downCache is a cache that can't be reached
+type downCache struct{}
+
+var errDown = errors.New("connection refused")
+
+func (downCache) Get(ctx context.Context, key string) ([]byte, error) { return nil, errDown }
+func (downCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errDown
+}
+func (downCache) Incr(ctx context.Context, key string) (int64, error) { return 0, errDown }
+func (downCache) Close() error                                        { return nil }
+
+func TestUnreachableCacheFallsBackToTheDatabase(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, downCache{})
+
+	f.insertMovie(t, ctx, "Heat")
+
+	f.list(t, ctx, 1, "Heat")
+	f.list(t, ctx, 2, "Heat")
+
+	f.insertMovie(t, ctx, "Ronin")
+	f.list(t, ctx, 3, "Heat", "Ronin")
+}
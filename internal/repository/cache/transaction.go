@@ -0,0 +1,51 @@
+package cacherepo
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"sync/atomic"
+)
+
+type txKey struct{}
+
+// txState is carried by the ctx of a transaction, written tells the cache has to be dropped on commit
+type txState struct {
+	written atomic.Bool
+}
+
+func inTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+type transactor struct {
+	inner repository.Transactor
+	cache adapter.CacheClient
+}
+
+// NewTransactor wraps the transactor of the repository NewMovieRepository wraps
+func NewTransactor(inner repository.Transactor, cache adapter.CacheClient) repository.Transactor {
+	return &transactor{
+		inner: inner,
+		cache: cache,
+	}
+}
+
+// WithTx drops the cache after the outermost transaction committed, not when the write was made:
+// a reader could otherwise cache the rows the transaction is about to change
+func (rp *transactor) WithTx(ctx context.Context, fn func(ctx context.Context) errs.MessageErr) errs.MessageErr {
+	if inTx(ctx) {
+		return rp.inner.WithTx(ctx, fn)
+	}
+
+	state := &txState{}
+
+	err := rp.inner.WithTx(context.WithValue(ctx, txKey{}, state), fn)
+	if err == nil && state.written.Load() {
+		invalidate(ctx, rp.cache)
+	}
+
+	return err
+}
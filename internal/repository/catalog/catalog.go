@@ -0,0 +1,51 @@
+package catalogrepo
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+)
+
+type catalogRepository struct {
+	catalog adapter.CatalogClient
+}
+
+func NewCatalogRepository(catalog adapter.CatalogClient) repository.CatalogRepository {
+	return &catalogRepository{
+		catalog: catalog,
+	}
+}
+
+func (rp *catalogRepository) LookupMovieFromCatalog(ctx context.Context, title string, year int) (repository.CatalogMovie, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "LookupMovieFromCatalog", "Repository")
+	defer span.End()
+
+	movie, err := rp.catalog.LookupMovie(ctx, title, year)
+	if err != nil {
+		if errors.Is(err, adapter.ErrCatalogNotFound) {
+			return repository.CatalogMovie{}, errs.NewCustomErrs(
+				"Not Found In Catalog",
+				"NC",
+				err.Error(),
+			)
+		}
+
+		return repository.CatalogMovie{}, errs.NewCustomErrs(
+			"Catalog Unavailable",
+			"CU",
+			err.Error(),
+		)
+	}
+
+	return repository.CatalogMovie{
+		Title:       movie.Title,
+		Description: movie.Description,
+		Artist:      movie.Cast,
+		Genre:       movie.Genres,
+		Year:        movie.Year,
+		Poster:      movie.Poster,
+	}, nil
+}
@@ -0,0 +1,351 @@
+// Package contract is the behaviour every implementation of the movie and user repositories has to share,
+// run it from the tests of an implementation with Run
+package contract
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Repositories is what the contract runs against, every call of the factory has to return empty ones
+type Repositories struct {
+	Movies repository.MovieRepository
+	Users  repository.UserRepository
+}
+
+// Run runs every contract test with fresh repositories from newRepositories
+func Run(t *testing.T, newRepositories func(t *testing.T) Repositories) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repos Repositories)
+	}{
+		{"users", testUsers},
+		{"insert and get movie", testInsertAndGetMovie},
+		{"update movie", testUpdateMovie},
+		{"movie status", testMovieStatus},
+		{"publish scheduled movies", testPublishScheduledMovies},
+		{"list and search movies", testListAndSearchMovies},
+		{"insert movies", testInsertMovies},
+		{"revisions", testRevisions},
+		{"votes", testVotes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepositories(t))
+		})
+	}
+}
+
+func expectCode(t *testing.T, err errs.MessageErr, code string) {
+	t.Helper()
+
+	if code == "" {
+		if err != nil {
+			t.Fatalf("unexpected error %s: %s (%s)", err.Status(), err.Message(), err.Error())
+		}
+		return
+	}
+
+	if err == nil {
+		t.Fatalf("expected error %s, got none", code)
+	}
+	if err.Status() != code {
+		t.Fatalf("expected error %s, got %s: %s (%s)", code, err.Status(), err.Message(), err.Error())
+	}
+}
+
+func insertMovie(t *testing.T, repos Repositories, title string, genre string) string {
+	t.Helper()
+
+	id, err := repos.Movies.InsertMovieToDB(context.Background(), title, title+" description", 120, "Artist", genre, title+".mp4", constant.MovieStatusPublished, nil)
+	expectCode(t, err, "")
+
+	return id
+}
+
+func insertUser(t *testing.T, repos Repositories, name string) repository.User {
+	t.Helper()
+	ctx := context.Background()
+
+	expectCode(t, repos.Users.InsertUserToDB(ctx, name+"@example.com", name), "")
+
+	user, err := repos.Users.GetUserFromDbByEmail(ctx, name+"@example.com")
+	expectCode(t, err, "")
+
+	return user
+}
+
+func testUsers(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	user := insertUser(t, repos, "alice")
+	if user.ID == 0 || user.Name != "alice" || user.Email != "alice@example.com" || user.IsAdmin {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	if err := repos.Users.InsertUserToDB(ctx, "alice@example.com", "alice2"); err == nil {
+		t.Fatal("expected an error for a duplicate email")
+	}
+
+	_, err := repos.Users.GetUserFromDbByEmail(ctx, "nobody@example.com")
+	expectCode(t, err, "NA")
+}
+
+func testInsertAndGetMovie(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	id := insertMovie(t, repos, "Arrival", "Sci-Fi")
+
+	movie, err := repos.Movies.GetMovieByIdFromDB(ctx, id)
+	expectCode(t, err, "")
+
+	if movie.Id != id || movie.Title != "Arrival" || movie.Genre != "Sci-Fi" || movie.Duration != 120 {
+		t.Fatalf("unexpected movie %+v", movie)
+	}
+	if movie.Status != constant.MovieStatusPublished || movie.Version != 1 || movie.WatchUrl != "localhost:8080/movies/Arrival.mp4" {
+		t.Fatalf("unexpected defaults %+v", movie)
+	}
+
+	_, err = repos.Movies.GetMovieByIdFromDB(ctx, "999999")
+	expectCode(t, err, "NA")
+}
+
+func testUpdateMovie(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	id := insertMovie(t, repos, "Heat", "Crime")
+
+	err := repos.Movies.UpdateMovieToDB(ctx, id, "Heat (1995)", "updated", 170, "Pacino", "Crime", "heat.mp4", 1995, "poster.jpg", 1)
+	expectCode(t, err, "")
+
+	movie, err := repos.Movies.GetMovieByIdFromDB(ctx, id)
+	expectCode(t, err, "")
+	if movie.Title != "Heat (1995)" || movie.Year != 1995 || movie.Poster != "poster.jpg" || movie.Version != 2 {
+		t.Fatalf("unexpected movie after update %+v", movie)
+	}
+
+	// the file of the movie is the one of its last update
+	byFile, err := repos.Movies.GetMovieByFileNameFromDB(ctx, "heat.mp4")
+	expectCode(t, err, "")
+	if byFile.Id != id {
+		t.Fatalf("expected movie %s by its file, got %+v", id, byFile)
+	}
+	_, err = repos.Movies.GetMovieByFileNameFromDB(ctx, "Heat.mp4")
+	expectCode(t, err, "NA")
+
+	err = repos.Movies.UpdateMovieToDB(ctx, id, "stale", "", 1, "", "", "heat.mp4", 0, "", 1)
+	expectCode(t, err, "VM")
+
+	// 0 skips the version check
+	err = repos.Movies.UpdateMovieToDB(ctx, id, "Heat", "", 170, "", "Crime", "heat.mp4", 0, "", 0)
+	expectCode(t, err, "")
+
+	err = repos.Movies.UpdateMovieToDB(ctx, "999999", "missing", "", 1, "", "", "", 0, "", 0)
+	expectCode(t, err, "NA")
+}
+
+func testMovieStatus(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	id := insertMovie(t, repos, "Dune", "Sci-Fi")
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	err := repos.Movies.UpdateMovieStatusToDB(ctx, id, constant.MovieStatusScheduled, &publishAt)
+	expectCode(t, err, "")
+
+	movie, err := repos.Movies.GetMovieByIdFromDB(ctx, id)
+	expectCode(t, err, "")
+	if movie.Status != constant.MovieStatusScheduled || movie.PublishAt == nil || !movie.PublishAt.Equal(publishAt) || movie.Version != 2 {
+		t.Fatalf("unexpected movie after status change %+v", movie)
+	}
+
+	err = repos.Movies.UpdateMovieStatusToDB(ctx, "999999", constant.MovieStatusDraft, nil)
+	expectCode(t, err, "NA")
+}
+
+func testPublishScheduledMovies(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	due := insertMovie(t, repos, "Due", "Drama")
+	later := insertMovie(t, repos, "Later", "Drama")
+
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	expectCode(t, repos.Movies.UpdateMovieStatusToDB(ctx, due, constant.MovieStatusScheduled, &past), "")
+	expectCode(t, repos.Movies.UpdateMovieStatusToDB(ctx, later, constant.MovieStatusScheduled, &future), "")
+
+	published, err := repos.Movies.PublishScheduledMoviesToDB(ctx, now)
+	expectCode(t, err, "")
+	if published != 1 {
+		t.Fatalf("expected 1 published movie, got %d", published)
+	}
+
+	movie, _ := repos.Movies.GetMovieByIdFromDB(ctx, due)
+	if movie.Status != constant.MovieStatusPublished || movie.Version != 3 {
+		t.Fatalf("due movie not published %+v", movie)
+	}
+
+	movie, _ = repos.Movies.GetMovieByIdFromDB(ctx, later)
+	if movie.Status != constant.MovieStatusScheduled {
+		t.Fatalf("future movie published %+v", movie)
+	}
+
+	published, err = repos.Movies.PublishScheduledMoviesToDB(ctx, now)
+	expectCode(t, err, "")
+	if published != 0 {
+		t.Fatalf("expected nothing left to publish, got %d", published)
+	}
+}
+
+func testListAndSearchMovies(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		insertMovie(t, repos, "Movie "+strconv.Itoa(i), "Drama")
+	}
+	draft := insertMovie(t, repos, "Hidden Gem", "Mystery")
+	expectCode(t, repos.Movies.UpdateMovieStatusToDB(ctx, draft, constant.MovieStatusDraft, nil), "")
+
+	movies, page, err := repos.Movies.GetMoviesFromDB(ctx, constant.MovieStatusPublished, 1, 2)
+	expectCode(t, err, "")
+	if len(movies) != 2 || page.TotalItems != 3 || page.TotalPages != 2 {
+		t.Fatalf("unexpected page %+v with %d movies", page, len(movies))
+	}
+
+	_, page, err = repos.Movies.GetMoviesFromDB(ctx, "", 1, 10)
+	expectCode(t, err, "")
+	if page.TotalItems != 4 {
+		t.Fatalf("expected every status, got %d movies", page.TotalItems)
+	}
+
+	// search is case-insensitive on every backend
+	movies, err = repos.Movies.SearchMoviesFromDB(ctx, "", "hidden")
+	expectCode(t, err, "")
+	if len(movies) != 1 || movies[0].Id != draft {
+		t.Fatalf("unexpected search result %+v", movies)
+	}
+
+	movies, err = repos.Movies.SearchMoviesFromDB(ctx, constant.MovieStatusPublished, "GEM")
+	expectCode(t, err, "")
+	if len(movies) != 0 {
+		t.Fatalf("draft found by a published search %+v", movies)
+	}
+}
+
+func testInsertMovies(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	ids, err := repos.Movies.InsertMoviesToDB(ctx, []repository.NewMovie{
+		{Title: "One", Duration: 90, Genre: "Comedy", FileName: "one.mp4", Status: constant.MovieStatusPublished},
+		{Title: "Two", Duration: 95, Genre: "Comedy", FileName: "two.mp4", Status: constant.MovieStatusDraft},
+	})
+	expectCode(t, err, "")
+	if len(ids) != 2 || ids[0] == ids[1] {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+
+	movie, err := repos.Movies.GetMovieByIdFromDB(ctx, ids[1])
+	expectCode(t, err, "")
+	if movie.Title != "Two" || movie.Status != constant.MovieStatusDraft {
+		t.Fatalf("ids don't follow the input order, got %+v", movie)
+	}
+
+	var streamed []string
+	err = repos.Movies.StreamMoviesFromDB(ctx, func(movie repository.Movie) error {
+		streamed = append(streamed, movie.Title)
+		return nil
+	})
+	expectCode(t, err, "")
+	if len(streamed) != 2 || streamed[0] != "One" || streamed[1] != "Two" {
+		t.Fatalf("unexpected stream %v", streamed)
+	}
+}
+
+func testRevisions(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	id := insertMovie(t, repos, "Memento", "Thriller")
+	movie, _ := repos.Movies.GetMovieByIdFromDB(ctx, id)
+
+	err := repos.Movies.InsertMovieRevisionToDB(ctx, repository.MovieRevision{
+		MovieId:  id,
+		UserId:   1,
+		Action:   "create",
+		Changes:  map[string]repository.FieldChange{"title": {From: nil, To: "Memento"}},
+		Snapshot: movie,
+		FileName: "Memento.mp4",
+	})
+	expectCode(t, err, "")
+
+	revisions, err := repos.Movies.GetMovieRevisionsFromDB(ctx, id)
+	expectCode(t, err, "")
+	if len(revisions) != 1 || revisions[0].Action != "create" || revisions[0].Snapshot.Title != "Memento" || revisions[0].FileName != "Memento.mp4" {
+		t.Fatalf("unexpected revisions %+v", revisions)
+	}
+
+	revision, err := repos.Movies.GetMovieRevisionFromDB(ctx, id, revisions[0].Id)
+	expectCode(t, err, "")
+	if revision.Changes["title"].To != "Memento" {
+		t.Fatalf("unexpected revision %+v", revision)
+	}
+
+	_, err = repos.Movies.GetMovieRevisionFromDB(ctx, id, revisions[0].Id+1000)
+	expectCode(t, err, "NA")
+}
+
+func testVotes(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	alice := insertUser(t, repos, "alice")
+	bob := insertUser(t, repos, "bob")
+	first, _ := strconv.Atoi(insertMovie(t, repos, "First", "Drama"))
+	second, _ := strconv.Atoi(insertMovie(t, repos, "Second", "Horror"))
+
+	expectCode(t, repos.Movies.InsertVoteToDB(ctx, alice.ID, second), "")
+	expectCode(t, repos.Movies.InsertVoteToDB(ctx, bob.ID, second), "")
+	expectCode(t, repos.Movies.InsertVoteToDB(ctx, alice.ID, first), "")
+	expectCode(t, repos.Movies.InsertVoteToDB(ctx, alice.ID, first), "AV")
+	expectCode(t, repos.Movies.InsertVoteToDB(ctx, alice.ID, second+1000), "NA")
+
+	// drafts and scheduled movies can't be voted on until they are published
+	draft, err := repos.Movies.InsertMovieToDB(ctx, "Draft", "", 90, "", "Drama", "Draft.mp4", constant.MovieStatusDraft, nil)
+	expectCode(t, err, "")
+	draftId, _ := strconv.Atoi(draft)
+	expectCode(t, repos.Movies.InsertVoteToDB(ctx, alice.ID, draftId), "NA")
+	expectCode(t, repos.Movies.UpdateMovieStatusToDB(ctx, draft, constant.MovieStatusPublished, nil), "")
+	expectCode(t, repos.Movies.InsertVoteToDB(ctx, alice.ID, draftId), "")
+	expectCode(t, repos.Movies.DeleteVoteFromDB(ctx, alice.ID, draftId), "")
+
+	voted, err := repos.Movies.GetAllVotedMoviesByUserIdFromDb(ctx, alice.ID)
+	expectCode(t, err, "")
+	if len(voted) != 2 {
+		t.Fatalf("expected 2 voted movies, got %+v", voted)
+	}
+
+	top, err := repos.Movies.GetMostVotedMovieFromDB(ctx)
+	expectCode(t, err, "")
+	if top.Title != "Second" || top.Vote != 2 {
+		t.Fatalf("unexpected most voted movie %+v", top)
+	}
+
+	genre, err := repos.Movies.GetMostVotedGenreFromDB(ctx)
+	expectCode(t, err, "")
+	if genre.Genre != "Horror" || genre.Vote != 2 {
+		t.Fatalf("unexpected most voted genre %+v", genre)
+	}
+
+	expectCode(t, repos.Movies.DeleteVoteFromDB(ctx, alice.ID, first), "")
+	// removing a vote that doesn't exist is not an error
+	expectCode(t, repos.Movies.DeleteVoteFromDB(ctx, alice.ID, first), "")
+
+	voted, _ = repos.Movies.GetAllVotedMoviesByUserIdFromDb(ctx, alice.ID)
+	if len(voted) != 1 || voted[0].Title != "Second" {
+		t.Fatalf("unexpected voted movies after unvote %+v", voted)
+	}
+}
@@ -0,0 +1,451 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/adapters/database/postgres"
+	"lion-parcel-test/internal/adapters/database/sqlite"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/repository/contract"
+	movierepo "lion-parcel-test/internal/repository/movie"
+	outboxrepo "lion-parcel-test/internal/repository/outbox"
+	userrepo "lion-parcel-test/internal/repository/user"
+	webhookrepo "lion-parcel-test/internal/repository/webhook"
+	"lion-parcel-test/pkg/log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// the postgres backend only runs when TEST_POSTGRES_HOST is set, its database is emptied by every test
+var postgresEnv = []string{"TEST_POSTGRES_HOST", "TEST_POSTGRES_PORT", "TEST_POSTGRES_USER", "TEST_POSTGRES_PASSWORD", "TEST_POSTGRES_DB"}
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "repository-contract")
+	if err != nil {
+		panic(err)
+	}
+
+	// the logger writes to ./logs
+	if err = os.Chdir(dir); err != nil {
+		panic(err)
+	}
+
+	config.Cfg = &config.Config{}
+	config.Cfg.Database.AutoMigrate = true
+
+	if err = log.Initialize(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type backend struct {
+	name string
+	open func(t *testing.T) adapter.DatabaseClient
+}
+
+func backends() []backend {
+	return []backend{
+		{constant.DatabaseDriverSqlite, openSqlite},
+		{constant.DatabaseDriverPostgres, openPostgres},
+	}
+}
+
+// openSqlite creates a database file in a new directory
+func openSqlite(t *testing.T) adapter.DatabaseClient {
+	path := config.Cfg.Database.Sqlite.Path
+	config.Cfg.Database.Sqlite.Path = filepath.Join(t.TempDir(), "movies.db")
+	t.Cleanup(func() { config.Cfg.Database.Sqlite.Path = path })
+
+	db, err := sqlite.NewSqliteClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func openPostgres(t *testing.T) adapter.DatabaseClient {
+	env := map[string]string{}
+	for _, key := range postgresEnv {
+		env[key] = os.Getenv(key)
+	}
+
+	if env["TEST_POSTGRES_HOST"] == "" {
+		t.Skip("TEST_POSTGRES_HOST is not set")
+	}
+
+	port, _ := strconv.Atoi(env["TEST_POSTGRES_PORT"])
+	if port == 0 {
+		port = 5432
+	}
+
+	cfg := &config.Cfg.Database
+	cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Name = env["TEST_POSTGRES_HOST"], port, env["TEST_POSTGRES_USER"], env["TEST_POSTGRES_PASSWORD"], env["TEST_POSTGRES_DB"]
+
+	db, err := postgres.NewPostgresClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	result := db.Execute(context.Background(), `TRUNCATE users, movies, votes, movie_revisions, webhook_subscriptions, webhook_deliveries, outbox_events, event_consumer_offsets, event_dead_letters RESTART IDENTITY CASCADE;`)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	return db
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, db adapter.DatabaseClient)) {
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, b.open(t))
+		})
+	}
+}
+
+func TestRepositoryContract(t *testing.T) {
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			contract.Run(t, func(t *testing.T) contract.Repositories {
+				db := b.open(t)
+
+				return contract.Repositories{
+					Movies: movierepo.NewMovieRepository(db),
+					Users:  userrepo.NewUserRepository(db),
+				}
+			})
+		})
+	}
+}
+
+func TestOutboxEvents(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db adapter.DatabaseClient) {
+		ctx := context.Background()
+		movies := movierepo.NewMovieRepository(db)
+		users := userrepo.NewUserRepository(db)
+		outbox := outboxrepo.NewOutboxRepository(db)
+
+		if err := users.InsertUserToDB(ctx, "voter@example.com", "voter"); err != nil {
+			t.Fatal(err.Error())
+		}
+		user, _ := users.GetUserFromDbByEmail(ctx, "voter@example.com")
+
+		id, err := movies.InsertMovieToDB(ctx, "Alien", "", 117, "", "Horror", "alien.mp4", constant.MovieStatusDraft, nil)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		movieId, _ := strconv.Atoi(id)
+
+		steps := []errorFunc{
+			func() error {
+				return asError(movies.UpdateMovieToDB(ctx, id, "Alien", "", 117, "", "Horror", "alien.mp4", 1979, "", 1))
+			},
+			// a version conflict writes nothing
+			func() error {
+				if err := movies.UpdateMovieToDB(ctx, id, "stale", "", 1, "", "", "", 0, "", 1); err == nil || err.Status() != "VM" {
+					return errors.New("expected a version conflict")
+				}
+				return nil
+			},
+			func() error {
+				return asError(movies.UpdateMovieStatusToDB(ctx, id, constant.MovieStatusPublished, nil))
+			},
+			func() error { return asError(movies.InsertVoteToDB(ctx, user.ID, movieId)) },
+			func() error { return asError(movies.DeleteVoteFromDB(ctx, user.ID, movieId)) },
+			// removing a vote that doesn't exist writes nothing
+			func() error { return asError(movies.DeleteVoteFromDB(ctx, user.ID, movieId)) },
+		}
+		for i, step := range steps {
+			if err := step(); err != nil {
+				t.Fatalf("step %d: %s", i, err)
+			}
+		}
+
+		events, errs := outbox.GetOutboxEventsFromDB(ctx, 0, 100)
+		if errs != nil {
+			t.Fatal(errs.Error())
+		}
+
+		var names []string
+		for _, event := range events {
+			names = append(names, event.Event)
+		}
+
+		expected := []string{constant.EventUserRegistered, constant.EventMovieCreated, constant.EventMovieUpdated, constant.EventMovieUpdated, constant.EventVoteCast, constant.EventVoteRemoved}
+		if len(names) != len(expected) {
+			t.Fatalf("expected events %v, got %v", expected, names)
+		}
+		for i := range expected {
+			if names[i] != expected[i] {
+				t.Fatalf("expected events %v, got %v", expected, names)
+			}
+		}
+
+		var registered repository.UserRegisteredEvent
+		decode(t, events[0], &registered)
+		if registered.UserId != user.ID || registered.Name != "voter" || events[0].AggregateId != strconv.Itoa(user.ID) {
+			t.Fatalf("unexpected UserRegistered %+v", registered)
+		}
+
+		var created repository.MovieCreatedEvent
+		decode(t, events[1], &created)
+		if created.Movie.Id != id || created.Movie.Title != "Alien" || events[1].AggregateId != id {
+			t.Fatalf("unexpected MovieCreated %+v", created)
+		}
+
+		var published repository.MovieUpdatedEvent
+		decode(t, events[3], &published)
+		if published.Before.Status != constant.MovieStatusDraft || published.After.Status != constant.MovieStatusPublished ||
+			published.Before.Version != 2 || published.After.Version != 3 || published.After.Year != 1979 {
+			t.Fatalf("unexpected MovieUpdated %+v", published)
+		}
+
+		var cast, removed repository.VoteEvent
+		decode(t, events[4], &cast)
+		decode(t, events[5], &removed)
+		if cast.Votes != 1 || removed.Votes != 0 || cast.UserId != user.ID || removed.MovieId != movieId {
+			t.Fatalf("unexpected vote events %+v %+v", cast, removed)
+		}
+
+		count, errs := outbox.CountOutboxEventsFromDB(ctx, events[1].Id)
+		if errs != nil || count != 4 {
+			t.Fatalf("expected 4 events after the second one, got %d", count)
+		}
+
+		failing := repository.ConsumerOffset{Consumer: "test", LastEventId: events[2].Id, FailingEventId: events[3].Id, Attempts: 2, LastError: "boom"}
+		if errs = outbox.UpdateConsumerOffsetToDB(ctx, failing); errs != nil {
+			t.Fatal(errs.Error())
+		}
+		offset, errs := outbox.GetConsumerOffsetFromDB(ctx, "test")
+		offset.UpdatedAt = nil
+		if errs != nil || offset != failing {
+			t.Fatalf("unexpected offset %+v", offset)
+		}
+
+		// a dead letter stored twice keeps the first one
+		letter := repository.DeadLetter{Consumer: "test", EventId: events[3].Id, Event: events[3].Event, Attempts: 3, Error: "boom"}
+		for i := 0; i < 2; i++ {
+			if errs = outbox.InsertDeadLetterToDB(ctx, letter); errs != nil {
+				t.Fatal(errs.Error())
+			}
+		}
+		var letters int
+		if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM event_dead_letters WHERE consumer = ?;`, "test").Scan(&letters); err != nil || letters != 1 {
+			t.Fatalf("expected one dead letter, got %d %v", letters, err)
+		}
+	})
+}
+
+// TestOutboxCommitOrder commits a later outbox insert while an earlier one is still in its transaction. The dispatcher
+// moves the offsets of the consumers by id, so no event may be seen before every lower id committed
+func TestOutboxCommitOrder(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db adapter.DatabaseClient) {
+		ctx := context.Background()
+		outbox := outboxrepo.NewOutboxRepository(db)
+		insert := `INSERT INTO outbox_events (event, aggregate_id, payload) VALUES (?, ?, '{}');`
+
+		inserted := make(chan struct{})
+		release := make(chan struct{})
+		first := make(chan error, 1)
+		go func() {
+			first <- db.WithTx(ctx, func(ctx context.Context) error {
+				if result := db.Execute(ctx, insert, "first", "1"); result.Error != nil {
+					close(inserted)
+					return result.Error
+				}
+				close(inserted)
+				<-release
+				return nil
+			})
+		}()
+		<-inserted
+
+		second := make(chan error, 1)
+		go func() {
+			second <- db.WithTx(ctx, func(ctx context.Context) error {
+				return db.Execute(ctx, insert, "second", "2").Error
+			})
+		}()
+
+		// the second insert would have committed by now if it didn't wait for the first transaction
+		select {
+		case err := <-second:
+			t.Fatalf("expected the second insert to wait for the first transaction, it returned %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		events, errs := outbox.GetOutboxEventsFromDB(ctx, 0, 10)
+		if errs != nil || len(events) != 0 {
+			t.Fatalf("expected no event before the first one committed, got %+v %v", events, errs)
+		}
+
+		close(release)
+		if err := <-first; err != nil {
+			t.Fatal(err)
+		}
+		if err := <-second; err != nil {
+			t.Fatal(err)
+		}
+
+		events, errs = outbox.GetOutboxEventsFromDB(ctx, 0, 10)
+		if errs != nil || len(events) != 2 || events[0].Event != "first" || events[1].Event != "second" {
+			t.Fatalf("expected both events in commit order, got %+v %v", events, errs)
+		}
+	})
+}
+
+func TestWebhookRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db adapter.DatabaseClient) {
+		ctx := context.Background()
+		webhooks := webhookrepo.NewWebhookRepository(db, nil)
+
+		id, err := webhooks.InsertWebhookSubscriptionToDB(ctx, repository.WebhookSubscription{Url: "http://localhost/hook", Events: []string{"*"}, Secret: "secret"})
+		if err != nil || id == 0 {
+			t.Fatalf("insert subscription: %v", err)
+		}
+
+		delivery := repository.WebhookDelivery{SubscriptionId: id, EventId: "1", Event: "movie.created", Payload: "{}"}
+		for i := 0; i < 2; i++ {
+			// the same event is only queued once
+			if err = webhooks.InsertWebhookDeliveriesToDB(ctx, []repository.WebhookDelivery{delivery}); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		pending, err := webhooks.GetPendingWebhookDeliveriesFromDB(ctx, 10)
+		if err != nil || len(pending) != 1 {
+			t.Fatalf("expected 1 pending delivery, got %d (%v)", len(pending), err)
+		}
+
+		for i := 1; i <= 2; i++ {
+			disabled, err := webhooks.RecordWebhookResultToDB(ctx, id, false, 2)
+			if err != nil || disabled != (i == 2) {
+				t.Fatalf("failure %d: disabled %t, %v", i, disabled, err)
+			}
+		}
+
+		active, err := webhooks.GetWebhookSubscriptionsFromDB(ctx, true)
+		if err != nil || len(active) != 0 {
+			t.Fatalf("expected the subscription to be disabled, got %+v", active)
+		}
+
+		subscription, err := webhooks.GetWebhookSubscriptionFromDB(ctx, id)
+		if err != nil || subscription.Active || subscription.DisabledAt == nil || subscription.ConsecutiveFailures != 2 {
+			t.Fatalf("unexpected subscription %+v", subscription)
+		}
+
+		subscription.Active = true
+		if err = webhooks.UpdateWebhookSubscriptionToDB(ctx, subscription); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		subscription, _ = webhooks.GetWebhookSubscriptionFromDB(ctx, id)
+		if !subscription.Active || subscription.DisabledAt != nil || subscription.ConsecutiveFailures != 0 {
+			t.Fatalf("unexpected subscription after re-enabling %+v", subscription)
+		}
+
+		if err = webhooks.DeleteWebhookSubscriptionFromDB(ctx, id); err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, err = webhooks.GetWebhookSubscriptionFromDB(ctx, id); err == nil || err.Status() != "NA" {
+			t.Fatalf("expected the subscription to be gone, got %v", err)
+		}
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db adapter.DatabaseClient) {
+		ctx := context.Background()
+		insert := `INSERT INTO users (name, email) VALUES (?, ?);`
+
+		err := db.WithTx(ctx, func(ctx context.Context) error {
+			if result := db.Execute(ctx, insert, "kept", "kept@example.com"); result.Error != nil {
+				return result.Error
+			}
+
+			// the failing savepoint only undoes its own insert and leaves the transaction usable
+			err := db.WithTx(ctx, func(ctx context.Context) error {
+				if result := db.Execute(ctx, insert, "undone", "undone@example.com"); result.Error != nil {
+					return result.Error
+				}
+				return db.Execute(ctx, insert, "kept", "kept@example.com").Error
+			})
+			if err == nil || err.Error() != constant.DuplicateConstraintError {
+				return errors.New("expected a duplicate constraint error, got " + errorString(err))
+			}
+
+			return db.Execute(ctx, insert, "after", "after@example.com").Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rollback := errors.New("rollback")
+		err = db.WithTx(ctx, func(ctx context.Context) error {
+			db.Execute(ctx, insert, "rolled back", "rolledback@example.com")
+			return rollback
+		})
+		if err != rollback {
+			t.Fatalf("expected the error of fn, got %v", err)
+		}
+
+		var names []string
+		rows, err := db.QueryRows(ctx, `SELECT name FROM users ORDER BY id;`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name string
+			rows.Scan(&name)
+			names = append(names, name)
+		}
+
+		if len(names) != 2 || names[0] != "kept" || names[1] != "after" {
+			t.Fatalf("unexpected users %v", names)
+		}
+	})
+}
+
+type errorFunc func() error
+
+// asError keeps a nil errs.MessageErr from turning into a non-nil error
+func asError(err interface{ Error() string }) error {
+	if err == nil {
+		return nil
+	}
+
+	return errors.New(err.Error())
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return "nil"
+	}
+
+	return err.Error()
+}
+
+func decode(t *testing.T, event repository.OutboxEvent, payload interface{}) {
+	t.Helper()
+
+	if err := jsoniter.UnmarshalFromString(event.Payload, payload); err != nil {
+		t.Fatalf("decode %s: %s", event.Event, err)
+	}
+}
@@ -0,0 +1,18 @@
+package memoryrepo_test
+
+import (
+	"lion-parcel-test/internal/repository/contract"
+	memoryrepo "lion-parcel-test/internal/repository/memory"
+	"testing"
+)
+
+func TestRepositoryContract(t *testing.T) {
+	contract.Run(t, func(t *testing.T) contract.Repositories {
+		store := memoryrepo.NewStore()
+
+		return contract.Repositories{
+			Movies: memoryrepo.NewMovieRepository(store),
+			Users:  memoryrepo.NewUserRepository(store),
+		}
+	})
+}
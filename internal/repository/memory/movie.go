@@ -0,0 +1,496 @@
+package memoryrepo
+
+import (
+	"context"
+	"database/sql"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+type movieRepository struct {
+	store *Store
+}
+
+func NewMovieRepository(store *Store) repository.MovieRepository {
+	return &movieRepository{
+		store: store,
+	}
+}
+
+func notExist() errs.MessageErr {
+	return errs.NewCustomErrs(
+		"Not Exist",
+		"NA",
+		sql.ErrNoRows.Error(),
+	)
+}
+
+func (rp *movieRepository) InsertMovieToDB(ctx context.Context, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Status string, PublishAt *time.Time) (string, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	return rp.store.tables.insertMovie(repository.NewMovie{
+		Title:       Title,
+		Description: Description,
+		Duration:    Duration,
+		Artist:      Artist,
+		Genre:       Genre,
+		FileName:    FileName,
+		Status:      Status,
+		PublishAt:   PublishAt,
+	}), nil
+}
+
+func (t *tables) insertMovie(movie repository.NewMovie) string {
+	t.lastMovieId++
+	id := strconv.Itoa(t.lastMovieId)
+
+	t.movies = append(t.movies, repository.Movie{
+		Id:          id,
+		Title:       movie.Title,
+		Description: movie.Description,
+		Duration:    movie.Duration,
+		Artist:      movie.Artist,
+		Genre:       movie.Genre,
+		WatchUrl:    "localhost:8080/movies/" + movie.FileName,
+		Status:      movie.Status,
+		PublishAt:   copyTime(movie.PublishAt),
+		Version:     1,
+	})
+
+	return id
+}
+
+func (rp *movieRepository) UpdateMovieToDB(ctx context.Context, Id string, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Year int, Poster string, expectedVersion int) errs.MessageErr {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	i := rp.store.tables.movieIndex(Id)
+	if i < 0 {
+		return notExist()
+	}
+
+	movie := &rp.store.tables.movies[i]
+	if expectedVersion != 0 && movie.Version != expectedVersion {
+		return errs.NewCustomErrs(
+			"Version Mismatch",
+			"VM",
+			"movie "+Id+" is at version "+strconv.Itoa(movie.Version),
+		)
+	}
+
+	movie.Title = Title
+	movie.Description = Description
+	movie.Duration = Duration
+	movie.Artist = Artist
+	movie.Genre = Genre
+	movie.WatchUrl = "localhost:8080/movies/" + FileName
+	movie.Year = Year
+	movie.Poster = Poster
+	movie.Version++
+
+	return nil
+}
+
+func (rp *movieRepository) GetMostViewedMovieFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	if len(rp.store.tables.movies) == 0 {
+		return repository.Movie{}, notExist()
+	}
+
+	top := rp.store.tables.movies[0]
+	for _, movie := range rp.store.tables.movies[1:] {
+		if movie.Views > top.Views {
+			top = movie
+		}
+	}
+
+	return copyMovie(top), nil
+}
+
+func (rp *movieRepository) GetMostViewedGenreFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	views := map[string]int{}
+	genres := make([]string, 0)
+	for _, movie := range rp.store.tables.movies {
+		if _, ok := views[movie.Genre]; !ok {
+			genres = append(genres, movie.Genre)
+		}
+		views[movie.Genre] += movie.Views
+	}
+
+	genre, ok := top(genres, views)
+	if !ok {
+		return repository.Movie{}, notExist()
+	}
+
+	return repository.Movie{Genre: genre, Views: views[genre]}, nil
+}
+
+// top is the key with the greatest count, the first one of keys on a tie
+func top(keys []string, counts map[string]int) (string, bool) {
+	if len(keys) == 0 {
+		return "", false
+	}
+
+	best := keys[0]
+	for _, key := range keys[1:] {
+		if counts[key] > counts[best] {
+			best = key
+		}
+	}
+
+	return best, true
+}
+
+func (rp *movieRepository) GetMovieByIdFromDB(ctx context.Context, id string) (repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	i := rp.store.tables.movieIndex(id)
+	if i < 0 {
+		return repository.Movie{}, notExist()
+	}
+
+	return copyMovie(rp.store.tables.movies[i]), nil
+}
+
+func (rp *movieRepository) GetMovieByFileNameFromDB(ctx context.Context, fileName string) (repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	movies := rp.store.tables.moviesWhere(func(movie repository.Movie) bool {
+		return movie.WatchUrl == "localhost:8080/movies/"+fileName
+	})
+	if len(movies) == 0 {
+		return repository.Movie{}, notExist()
+	}
+
+	return movies[0], nil
+}
+
+func (rp *movieRepository) GetMoviesFromDB(ctx context.Context, status string, page int, pageSize int) ([]repository.Movie, repository.MoviePaginationMetadata, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	matching := rp.store.tables.moviesWhere(func(movie repository.Movie) bool {
+		return status == "" || movie.Status == status
+	})
+
+	movies := make([]repository.Movie, 0)
+	for i := (page - 1) * pageSize; i >= 0 && i < len(matching) && len(movies) < pageSize; i++ {
+		movies = append(movies, matching[i])
+	}
+
+	return movies, repository.MoviePaginationMetadata{
+		CurrentPage: page,
+		PageSize:    pageSize,
+		TotalItems:  len(matching),
+		TotalPages:  int(math.Ceil(float64(len(matching)) / float64(pageSize))),
+	}, nil
+}
+
+func (rp *movieRepository) SearchMoviesFromDB(ctx context.Context, status string, keyword string) ([]repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	keyword = strings.ToLower(keyword)
+
+	return rp.store.tables.moviesWhere(func(movie repository.Movie) bool {
+		if status != "" && movie.Status != status {
+			return false
+		}
+
+		for _, field := range []string{movie.Title, movie.Description, movie.Artist, movie.Genre} {
+			if strings.Contains(strings.ToLower(field), keyword) {
+				return true
+			}
+		}
+
+		return false
+	}), nil
+}
+
+// moviesWhere copies the movies matching fn in id order
+func (t *tables) moviesWhere(fn func(movie repository.Movie) bool) []repository.Movie {
+	movies := make([]repository.Movie, 0)
+	for _, movie := range t.movies {
+		if fn(movie) {
+			movies = append(movies, copyMovie(movie))
+		}
+	}
+
+	return movies
+}
+
+func (rp *movieRepository) UpdateMovieStatusToDB(ctx context.Context, id string, status string, publishAt *time.Time) errs.MessageErr {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	i := rp.store.tables.movieIndex(id)
+	if i < 0 {
+		return notExist()
+	}
+
+	movie := &rp.store.tables.movies[i]
+	movie.Status = status
+	movie.PublishAt = copyTime(publishAt)
+	movie.Version++
+
+	return nil
+}
+
+func (rp *movieRepository) PublishScheduledMoviesToDB(ctx context.Context, now time.Time) (int64, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	var published int64
+	for i := range rp.store.tables.movies {
+		movie := &rp.store.tables.movies[i]
+		if movie.Status != constant.MovieStatusScheduled || movie.PublishAt == nil || movie.PublishAt.After(now) {
+			continue
+		}
+
+		movie.Status = constant.MovieStatusPublished
+		movie.Version++
+		published++
+	}
+
+	return published, nil
+}
+
+func (rp *movieRepository) InsertMoviesToDB(ctx context.Context, movies []repository.NewMovie) ([]string, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	ids := make([]string, 0, len(movies))
+	for _, movie := range movies {
+		ids = append(ids, rp.store.tables.insertMovie(movie))
+	}
+
+	return ids, nil
+}
+
+// StreamMoviesFromDB calls fn without holding the store, so fn may use the repositories
+func (rp *movieRepository) StreamMoviesFromDB(ctx context.Context, fn func(movie repository.Movie) error) errs.MessageErr {
+	rp.store.mu.Lock()
+	movies := rp.store.tables.moviesWhere(func(movie repository.Movie) bool { return true })
+	for i := range movies {
+		movies[i].Vote = rp.store.tables.voteCount(movies[i].Id)
+	}
+	rp.store.mu.Unlock()
+
+	for _, movie := range movies {
+		err := fn(movie)
+		if err != nil {
+			return errs.NewCustomErrs(
+				"Failed Stream",
+				"FS",
+				err.Error(),
+			)
+		}
+	}
+
+	return nil
+}
+
+// InsertMovieRevisionToDB stores changes and snapshot through JSON like the sql columns do, so numbers come back as float64
+func (rp *movieRepository) InsertMovieRevisionToDB(ctx context.Context, revision repository.MovieRevision) errs.MessageErr {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	if rp.store.tables.movieIndex(revision.MovieId) < 0 {
+		return errs.NewCustomErrs(
+			"Failed Insert Database",
+			"FD",
+			constant.ForeignKeyConstraintError,
+		)
+	}
+
+	stored := revision
+	stored.Changes = nil
+	stored.Snapshot = repository.Movie{}
+
+	err := roundTrip(revision.Changes, &stored.Changes)
+	if err == nil {
+		err = roundTrip(revision.Snapshot, &stored.Snapshot)
+	}
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Marshal",
+			"FM",
+			err.Error(),
+		)
+	}
+
+	rp.store.tables.lastRevisionId++
+	stored.Id = rp.store.tables.lastRevisionId
+	stored.CreatedAt = time.Now().UTC()
+
+	rp.store.tables.revisions = append(rp.store.tables.revisions, stored)
+
+	return nil
+}
+
+func roundTrip(value interface{}, dest interface{}) error {
+	data, err := jsoniter.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return jsoniter.Unmarshal(data, dest)
+}
+
+func (rp *movieRepository) GetMovieRevisionsFromDB(ctx context.Context, movieId string) ([]repository.MovieRevision, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	revisions := make([]repository.MovieRevision, 0)
+	for i := len(rp.store.tables.revisions) - 1; i >= 0; i-- {
+		if rp.store.tables.revisions[i].MovieId == movieId {
+			revisions = append(revisions, rp.store.tables.revisions[i])
+		}
+	}
+
+	return revisions, nil
+}
+
+func (rp *movieRepository) GetMovieRevisionFromDB(ctx context.Context, movieId string, revisionId int) (repository.MovieRevision, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	for _, revision := range rp.store.tables.revisions {
+		if revision.MovieId == movieId && revision.Id == revisionId {
+			return revision, nil
+		}
+	}
+
+	return repository.MovieRevision{}, notExist()
+}
+
+func (rp *movieRepository) InsertVoteToDB(ctx context.Context, userId int, movieId int) errs.MessageErr {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	i := rp.store.tables.movieIndex(strconv.Itoa(movieId))
+	if !rp.store.tables.userExists(userId) || i < 0 || rp.store.tables.movies[i].Status != constant.MovieStatusPublished {
+		return errs.NewCustomErrs(
+			"Movie Not Found",
+			"NA",
+			constant.ForeignKeyConstraintError,
+		)
+	}
+
+	for _, v := range rp.store.tables.votes {
+		if v.userId == userId && v.movieId == movieId {
+			return errs.NewCustomErrs(
+				"Already Voted",
+				"AV",
+				constant.DuplicateConstraintError,
+			)
+		}
+	}
+
+	rp.store.tables.votes = append(rp.store.tables.votes, vote{userId: userId, movieId: movieId})
+
+	return nil
+}
+
+func (rp *movieRepository) DeleteVoteFromDB(ctx context.Context, userId int, movieId int) errs.MessageErr {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	votes := rp.store.tables.votes[:0]
+	for _, v := range rp.store.tables.votes {
+		if v.userId != userId || v.movieId != movieId {
+			votes = append(votes, v)
+		}
+	}
+	rp.store.tables.votes = votes
+
+	return nil
+}
+
+func (rp *movieRepository) GetAllVotedMoviesByUserIdFromDb(ctx context.Context, userId int) ([]repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	voted := map[string]bool{}
+	for _, v := range rp.store.tables.votes {
+		if v.userId == userId {
+			voted[strconv.Itoa(v.movieId)] = true
+		}
+	}
+
+	return rp.store.tables.moviesWhere(func(movie repository.Movie) bool {
+		return voted[movie.Id]
+	}), nil
+}
+
+func (rp *movieRepository) GetMostVotedMovieFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	ids, votes := rp.store.tables.votesBy(func(movie repository.Movie) string { return movie.Id })
+
+	id, ok := top(ids, votes)
+	if !ok {
+		return repository.Movie{}, notExist()
+	}
+
+	movie := copyMovie(rp.store.tables.movies[rp.store.tables.movieIndex(id)])
+	movie.Vote = votes[id]
+
+	return movie, nil
+}
+
+func (rp *movieRepository) GetMostVotedGenreFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	genres, votes := rp.store.tables.votesBy(func(movie repository.Movie) string { return movie.Genre })
+
+	genre, ok := top(genres, votes)
+	if !ok {
+		return repository.Movie{}, notExist()
+	}
+
+	return repository.Movie{Genre: genre, Vote: votes[genre]}, nil
+}
+
+// votesBy counts the votes grouped by key of the voted movie, keys are in the order of the movies
+func (t *tables) votesBy(key func(movie repository.Movie) string) ([]string, map[string]int) {
+	keys := make([]string, 0)
+	votes := map[string]int{}
+
+	for _, movie := range t.movies {
+		count := t.voteCount(movie.Id)
+		if count == 0 {
+			continue
+		}
+
+		k := key(movie)
+		if _, ok := votes[k]; !ok {
+			keys = append(keys, k)
+		}
+		votes[k] += count
+	}
+
+	return keys, votes
+}
+
+func copyMovie(movie repository.Movie) repository.Movie {
+	movie.PublishAt = copyTime(movie.PublishAt)
+	return movie
+}
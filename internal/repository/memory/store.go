@@ -0,0 +1,115 @@
+// Package memoryrepo implements the movie and user repositories in memory, for tests that shouldn't touch disk.
+// They pass the same contract as the sql repositories, see internal/repository/contract
+package memoryrepo
+
+import (
+	"lion-parcel-test/internal/interfaces/repository"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store is what the in-memory repositories share, the tables of their database. Every call locks it,
+// a transaction of NewTransactor is atomic but not isolated from concurrent callers
+type Store struct {
+	mu     sync.Mutex
+	tables tables
+}
+
+type tables struct {
+	movies    []repository.Movie
+	users     []repository.User
+	votes     []vote
+	revisions []repository.MovieRevision
+
+	lastMovieId    int
+	lastUserId     int
+	lastRevisionId int
+}
+
+type vote struct {
+	userId  int
+	movieId int
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// PromoteAdmin turns the user into an admin, the way an admin is made by hand in the database
+func (s *Store) PromoteAdmin(email string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.tables.users {
+		if s.tables.users[i].Email == email {
+			s.tables.users[i].IsAdmin = true
+			return true
+		}
+	}
+
+	return false
+}
+
+// snapshot copies the tables, restore puts a copy back when a transaction fails
+func (s *Store) snapshot() tables {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := s.tables
+	copied.movies = append([]repository.Movie(nil), s.tables.movies...)
+	copied.users = append([]repository.User(nil), s.tables.users...)
+	copied.votes = append([]vote(nil), s.tables.votes...)
+	copied.revisions = append([]repository.MovieRevision(nil), s.tables.revisions...)
+
+	return copied
+}
+
+func (s *Store) restore(snapshot tables) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tables = snapshot
+}
+
+// movieIndex is the position of the movie in movies, -1 when there is none
+func (t *tables) movieIndex(id string) int {
+	for i := range t.movies {
+		if t.movies[i].Id == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (t *tables) userExists(id int) bool {
+	for _, user := range t.users {
+		if user.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *tables) voteCount(movieId string) int {
+	count := 0
+	for _, v := range t.votes {
+		if strconv.Itoa(v.movieId) == movieId {
+			count++
+		}
+	}
+
+	return count
+}
+
+// copyTime keeps a stored movie from sharing its publish_at with the caller
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	copied := *t
+	return &copied
+}
@@ -0,0 +1,29 @@
+package memoryrepo
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+)
+
+type transactor struct {
+	store *Store
+}
+
+func NewTransactor(store *Store) repository.Transactor {
+	return &transactor{
+		store: store,
+	}
+}
+
+// WithTx puts the store back the way it was when fn fails, a nested call only undoes its own fn like a savepoint
+func (rp *transactor) WithTx(ctx context.Context, fn func(ctx context.Context) errs.MessageErr) errs.MessageErr {
+	snapshot := rp.store.snapshot()
+
+	err := fn(ctx)
+	if err != nil {
+		rp.store.restore(snapshot)
+	}
+
+	return err
+}
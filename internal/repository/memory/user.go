@@ -0,0 +1,57 @@
+package memoryrepo
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"time"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{
+		store: store,
+	}
+}
+
+func (rp *userRepository) InsertUserToDB(ctx context.Context, email string, name string) errs.MessageErr {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	for _, user := range rp.store.tables.users {
+		if user.Email == email {
+			return errs.NewCustomErrs(
+				"Failed Insert Database",
+				"FD",
+				constant.DuplicateConstraintError,
+			)
+		}
+	}
+
+	rp.store.tables.lastUserId++
+	rp.store.tables.users = append(rp.store.tables.users, repository.User{
+		ID:        rp.store.tables.lastUserId,
+		Name:      name,
+		Email:     email,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	return nil
+}
+
+func (rp *userRepository) GetUserFromDbByEmail(ctx context.Context, email string) (repository.User, errs.MessageErr) {
+	rp.store.mu.Lock()
+	defer rp.store.mu.Unlock()
+
+	for _, user := range rp.store.tables.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return repository.User{}, notExist()
+}
@@ -0,0 +1,97 @@
+package movierepo
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+)
+
+// InsertMoviesToDB inserts every movie in one transaction and returns their ids in the same order
+func (rp *movieRepository) InsertMoviesToDB(ctx context.Context, movies []repository.NewMovie) ([]string, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "InsertMoviesToDB", "Repository")
+	defer span.End()
+
+	ids := make([]string, 0, len(movies))
+
+	err := rp.database.WithTx(ctx, func(ctx context.Context) error {
+		// a busy retry runs this again from scratch
+		ids = ids[:0]
+
+		for _, movie := range movies {
+			id, err := rp.insertMovie(ctx, movie)
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errs.NewCustomErrs(
+			"Failed Insert Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return ids, nil
+}
+
+// StreamMoviesFromDB calls fn for every movie of every status with its vote count, stopping at the first error of fn
+func (rp *movieRepository) StreamMoviesFromDB(ctx context.Context, fn func(movie repository.Movie) error) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "StreamMoviesFromDB", "Repository")
+	defer span.End()
+
+	streamMoviesQuery := `
+	SELECT m.id, m.title, m.description, m.duration, m.artists, m.genres, m.watch_url, m.views_count, m.status, m.publish_at, m.version, m.year, m.poster_url, COUNT(v.id) AS vote_count
+	FROM movies m
+	LEFT JOIN votes v ON m.id = v.movie_id
+	GROUP BY m.id
+	ORDER BY m.id;`
+
+	rows, err := rp.database.QueryRows(ctx, streamMoviesQuery)
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var vote int
+
+		movie, err := scanMovie(rows, &vote)
+		if err != nil {
+			return errs.NewCustomErrs(
+				"Failed Scan",
+				"FD",
+				err.Error(),
+			)
+		}
+		movie.Vote = vote
+
+		err = fn(movie)
+		if err != nil {
+			return errs.NewCustomErrs(
+				"Failed Stream",
+				"FS",
+				err.Error(),
+			)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package movierepo
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const insertOutboxEventQuery = `INSERT INTO outbox_events (event, aggregate_id, payload) VALUES (?, ?, ?);`
+
+// watchUrlOf is the url a movie file is served at
+func watchUrlOf(fileName string) string {
+	return "localhost:8080/movies/" + fileName
+}
+
+// insertEvent writes an outbox event, it has to run in the transaction of the change it describes
+func (rp *movieRepository) insertEvent(ctx context.Context, event string, aggregateId string, payload interface{}) error {
+	data, err := jsoniter.MarshalToString(payload)
+	if err != nil {
+		return err
+	}
+
+	return rp.database.Execute(ctx, insertOutboxEventQuery, event, aggregateId, data).Error
+}
+
+// getMoviesWhere reads the movies matching where ordered by id
+func (rp *movieRepository) getMoviesWhere(ctx context.Context, where string, args ...interface{}) ([]repository.Movie, error) {
+	rows, err := rp.database.QueryRows(ctx, `SELECT `+movieColumns+` FROM movies WHERE `+where+` ORDER BY id;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := make([]repository.Movie, 0)
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, movie)
+	}
+
+	return movies, rows.Err()
+}
+
+// insertMovie inserts the movie with its MovieCreated event, call it inside a transaction
+func (rp *movieRepository) insertMovie(ctx context.Context, movie repository.NewMovie) (string, error) {
+	watchUrl := watchUrlOf(movie.FileName)
+
+	var id string
+
+	row := rp.database.QueryRow(ctx, insertMovieQuery, movie.Title, movie.Description, movie.Duration, movie.Artist, movie.Genre, watchUrl, movie.Status, movie.PublishAt)
+	err := row.Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	created, err := rp.getMoviesWhere(ctx, `id = ?`, id)
+	if err != nil {
+		return "", err
+	}
+
+	return id, rp.insertEvent(ctx, constant.EventMovieCreated, id, repository.MovieCreatedEvent{Movie: created[0]})
+}
+
+// updateMovies applies set to the movies matching where, bumps their version and writes a MovieUpdated event
+// with both snapshots for each of them. It returns how many movies were updated
+func (rp *movieRepository) updateMovies(ctx context.Context, set string, setArgs []interface{}, where string, whereArgs []interface{}) (int64, error) {
+	var updated int64
+
+	err := rp.database.WithTx(ctx, func(ctx context.Context) error {
+		// a no-op write locks the rows before they are read, a portable SELECT ... FOR UPDATE
+		lock := rp.database.Execute(ctx, `UPDATE movies SET version = version WHERE `+where+`;`, whereArgs...)
+		if lock.Error != nil || lock.RowsAffected == 0 {
+			return lock.Error
+		}
+
+		before, err := rp.getMoviesWhere(ctx, where, whereArgs...)
+		if err != nil {
+			return err
+		}
+
+		// the update may change what where matches, so the rows are picked by id from here on
+		ids := make([]interface{}, 0, len(before))
+		for _, movie := range before {
+			ids = append(ids, movie.Id)
+		}
+		byId := `id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+
+		result := rp.database.Execute(ctx, `UPDATE movies SET `+set+`, version = version + 1 WHERE `+byId+`;`, append(setArgs, ids...)...)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		after, err := rp.getMoviesWhere(ctx, byId, ids...)
+		if err != nil {
+			return err
+		}
+
+		for i := range before {
+			err = rp.insertEvent(ctx, constant.EventMovieUpdated, before[i].Id, repository.MovieUpdatedEvent{Before: before[i], After: after[i]})
+			if err != nil {
+				return err
+			}
+		}
+
+		updated = result.RowsAffected
+		return nil
+	})
+
+	return updated, err
+}
+
+// countVotes is the vote count of the movie as seen by the current transaction
+func (rp *movieRepository) countVotes(ctx context.Context, movieId int) (int, error) {
+	var votes int
+
+	err := rp.database.QueryRow(ctx, `SELECT COUNT(*) FROM votes WHERE movie_id = ?;`, movieId).Scan(&votes)
+
+	return votes, err
+}
@@ -8,11 +8,44 @@ import (
 	"lion-parcel-test/internal/interfaces/adapter"
 	"lion-parcel-test/internal/interfaces/repository"
 	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
 	"math"
-
-	"go.elastic.co/apm/v2"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// movieColumns is the column list every full movie select uses, read back with scanMovie
+const movieColumns = `id, title, description, duration, artists, genres, watch_url, views_count, status, publish_at, version, year, poster_url`
+
+const insertMovieQuery = `INSERT INTO movies (title, description, duration, artists, genres, watch_url, status, publish_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+
+// errMovieNotPublished ends the transaction of a vote on a movie that is missing or not published
+var errMovieNotPublished = errors.New("movie not found or not published")
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMovie(row rowScanner, extra ...interface{}) (repository.Movie, error) {
+	var movie repository.Movie
+	var publishAt sql.NullTime
+
+	dest := []interface{}{&movie.Id, &movie.Title, &movie.Description, &movie.Duration, &movie.Artist, &movie.Genre, &movie.WatchUrl, &movie.Views, &movie.Status, &publishAt, &movie.Version, &movie.Year, &movie.Poster}
+	dest = append(dest, extra...)
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return repository.Movie{}, err
+	}
+
+	if publishAt.Valid {
+		movie.PublishAt = &publishAt.Time
+	}
+
+	return movie, nil
+}
+
 type movieRepository struct {
 	database adapter.DatabaseClient
 }
@@ -23,56 +56,101 @@ func NewMovieRepository(database adapter.DatabaseClient) repository.MovieReposit
 	}
 }
 
-func (rp *movieRepository) InsertMovieToDB(ctx context.Context, Title string, Description string, Duration int, Artist string, Genre string, FileName string) errs.MessageErr {
-	apmSpan, ctx := apm.StartSpan(ctx, "InsertUserToDB", "Repository")
-	defer apmSpan.End()
+func (rp *movieRepository) InsertMovieToDB(ctx context.Context, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Status string, PublishAt *time.Time) (string, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "InsertMovieToDB", "Repository")
+	defer span.End()
+
+	var id string
+
+	err := rp.database.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = rp.insertMovie(ctx, repository.NewMovie{
+			Title:       Title,
+			Description: Description,
+			Duration:    Duration,
+			Artist:      Artist,
+			Genre:       Genre,
+			FileName:    FileName,
+			Status:      Status,
+			PublishAt:   PublishAt,
+		})
+		return err
+	})
+	if err != nil {
+		return "", errs.NewCustomErrs(
+			"Failed Insert Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return id, nil
+}
 
-	insertMovieQuery := `INSERT INTO movies (title, description, duration, artists, genres, watch_url) VALUES (?, ?, ?, ?, ?, ?);`
+func (rp *movieRepository) UpdateMovieToDB(ctx context.Context, Id string, Title string, Description string, Duration int, Artist string, Genre string, FileName string, Year int, Poster string, expectedVersion int) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "UpdateMovieToDB", "Repository")
+	defer span.End()
 
-	watchUrl := "localhost:8080/movies/" + FileName
+	watchUrl := watchUrlOf(FileName)
 
-	result := rp.database.Execute(ctx, insertMovieQuery, Title, Description, Duration, Artist, Genre, watchUrl)
-	if result.Error != nil {
+	updated, err := rp.updateMovies(ctx,
+		`title = ?, description = ?, duration = ?, artists = ?, genres = ?, watch_url = ?, year = ?, poster_url = ?`,
+		[]interface{}{Title, Description, Duration, Artist, Genre, watchUrl, Year, Poster},
+		`id = ? AND (? = 0 OR version = ?)`,
+		[]interface{}{Id, expectedVersion, expectedVersion},
+	)
+	if err != nil {
 		return errs.NewCustomErrs(
 			"Failed Insert Database",
 			"FD",
-			result.Error.Error(),
+			err.Error(),
 		)
 	}
 
+	if updated == 0 {
+		return rp.missingOrConflict(ctx, Id)
+	}
+
 	return nil
 }
 
-func (rp *movieRepository) UpdateMovieToDB(ctx context.Context, Id string, Title string, Description string, Duration int, Artist string, Genre string, FileName string) errs.MessageErr {
-	apmSpan, ctx := apm.StartSpan(ctx, "UpdateMovieToDB", "Repository")
-	defer apmSpan.End()
+// missingOrConflict explains why an update by id and version touched no rows
+func (rp *movieRepository) missingOrConflict(ctx context.Context, id string) errs.MessageErr {
+	var version int
 
-	updateMovieQuery := `UPDATE movies SET title = ?, description = ?, duration = ?, artists = ?, genres = ?, watch_url = ? WHERE id = ?;`
-
-	watchUrl := "localhost:8080/movies/" + FileName
+	row := rp.database.QueryRow(ctx, `SELECT version FROM movies WHERE id = ?;`, id)
+	err := row.Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.NewCustomErrs(
+				"Not Exist",
+				"NA",
+				err.Error(),
+			)
+		}
 
-	result := rp.database.Execute(ctx, updateMovieQuery, Title, Description, Duration, Artist, Genre, watchUrl, Id)
-	if result.Error != nil {
 		return errs.NewCustomErrs(
-			"Failed Insert Database",
+			"Failed Get Database",
 			"FD",
-			result.Error.Error(),
+			err.Error(),
 		)
 	}
 
-	return nil
+	return errs.NewCustomErrs(
+		"Version Mismatch",
+		"VM",
+		"movie "+id+" is at version "+strconv.Itoa(version),
+	)
 }
 
 func (rp *movieRepository) GetMostViewedMovieFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "GetMostViewedMovieFromDB", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "GetMostViewedMovieFromDB", "Repository")
+	defer span.End()
 
-	getTopMovieQuery := `SELECT id, title, description, duration, artists, genres, watch_url, views_count FROM movies ORDER BY views_count DESC LIMIT 1;`
-
-	var movie repository.Movie
+	getTopMovieQuery := `SELECT ` + movieColumns + ` FROM movies ORDER BY views_count DESC LIMIT 1;`
 
 	row := rp.database.QueryRow(ctx, getTopMovieQuery)
-	err := row.Scan(&movie.Id, &movie.Title, &movie.Description, &movie.Duration, &movie.Artist, &movie.Genre, &movie.WatchUrl, &movie.Views)
+	movie, err := scanMovie(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return repository.Movie{}, errs.NewCustomErrs(
@@ -92,8 +170,8 @@ func (rp *movieRepository) GetMostViewedMovieFromDB(ctx context.Context) (reposi
 }
 
 func (rp *movieRepository) GetMostViewedGenreFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "GetMostViewedGenreFromDB", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "GetMostViewedGenreFromDB", "Repository")
+	defer span.End()
 
 	getTopMovieQuery := `SELECT genres, SUM(views_count) AS total_views FROM movies GROUP BY genres ORDER BY total_views DESC LIMIT 1;`
 
@@ -119,16 +197,68 @@ func (rp *movieRepository) GetMostViewedGenreFromDB(ctx context.Context) (reposi
 	return movie, nil
 }
 
-func (rp *movieRepository) GetMoviesFromDB(ctx context.Context, page int, pageSize int) ([]repository.Movie, repository.MoviePaginationMetadata, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "GetMoviesFromDB", "Repository")
-	defer apmSpan.End()
+// GetMovieByFileNameFromDB returns the movie whose current file is fileName, older files of its revisions don't match
+func (rp *movieRepository) GetMovieByFileNameFromDB(ctx context.Context, fileName string) (repository.Movie, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetMovieByFileNameFromDB", "Repository")
+	defer span.End()
+
+	movies, err := rp.getMoviesWhere(ctx, `watch_url = ?`, watchUrlOf(fileName))
+	if err != nil {
+		return repository.Movie{}, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	if len(movies) == 0 {
+		return repository.Movie{}, errs.NewCustomErrs(
+			"Not Exist",
+			"NA",
+			sql.ErrNoRows.Error(),
+		)
+	}
+
+	return movies[0], nil
+}
+
+func (rp *movieRepository) GetMovieByIdFromDB(ctx context.Context, id string) (repository.Movie, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetMovieByIdFromDB", "Repository")
+	defer span.End()
+
+	getMovieQuery := `SELECT ` + movieColumns + ` FROM movies WHERE id = ?;`
+
+	row := rp.database.QueryRow(ctx, getMovieQuery, id)
+	movie, err := scanMovie(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.Movie{}, errs.NewCustomErrs(
+				"Not Exist",
+				"NA",
+				err.Error(),
+			)
+		}
+
+		return repository.Movie{}, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+	return movie, nil
+}
+
+// GetMoviesFromDB returns a page of movies, an empty status returns movies of every status
+func (rp *movieRepository) GetMoviesFromDB(ctx context.Context, status string, page int, pageSize int) ([]repository.Movie, repository.MoviePaginationMetadata, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetMoviesFromDB", "Repository")
+	defer span.End()
 
 	offset := (page - 1) * pageSize
 
 	// Get total number of movies
 	var totalItems int
-	countQuery := `SELECT COUNT(*) FROM movies`
-	row := rp.database.QueryRow(ctx, countQuery)
+	countQuery := `SELECT COUNT(*) FROM movies WHERE (? = '' OR status = ?)`
+	row := rp.database.QueryRow(ctx, countQuery, status, status)
 	err := row.Scan(&totalItems)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -146,10 +276,10 @@ func (rp *movieRepository) GetMoviesFromDB(ctx context.Context, page int, pageSi
 		)
 	}
 
-	getMoviesQuery := `SELECT id, title, description, duration, artists, genres, watch_url, views_count FROM movies LIMIT ? OFFSET ?`
+	getMoviesQuery := `SELECT ` + movieColumns + ` FROM movies WHERE (? = '' OR status = ?) LIMIT ? OFFSET ?`
 	movies := make([]repository.Movie, 0)
 
-	rows, err := rp.database.QueryRows(ctx, getMoviesQuery, pageSize, offset)
+	rows, err := rp.database.QueryRows(ctx, getMoviesQuery, status, status, pageSize, offset)
 	if err != nil {
 		return nil, repository.MoviePaginationMetadata{}, errs.NewCustomErrs(
 			"Failed Insert Database",
@@ -159,9 +289,7 @@ func (rp *movieRepository) GetMoviesFromDB(ctx context.Context, page int, pageSi
 	}
 
 	for rows.Next() {
-		var movie repository.Movie
-
-		err = rows.Scan(&movie.Id, &movie.Title, &movie.Description, &movie.Duration, &movie.Artist, &movie.Genre, &movie.WatchUrl, &movie.Views)
+		movie, err := scanMovie(rows)
 		if err != nil {
 			return nil, repository.MoviePaginationMetadata{}, errs.NewCustomErrs(
 				"Failed Scan",
@@ -185,18 +313,19 @@ func (rp *movieRepository) GetMoviesFromDB(ctx context.Context, page int, pageSi
 
 }
 
-func (rp *movieRepository) SearchMoviesFromDB(ctx context.Context, keyword string) ([]repository.Movie, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "SearchMoviesFromDB", "Repository")
-	defer apmSpan.End()
+func (rp *movieRepository) SearchMoviesFromDB(ctx context.Context, status string, keyword string) ([]repository.Movie, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "SearchMoviesFromDB", "Repository")
+	defer span.End()
 
 	getMoviesQuery := `
-    SELECT id, title, description, duration, artists, genres, watch_url, views_count
+    SELECT ` + movieColumns + `
     FROM movies
-    WHERE title LIKE ? OR description LIKE ? OR artists LIKE ? OR genres LIKE ?;`
+    WHERE (? = '' OR status = ?) AND (LOWER(title) LIKE ? OR LOWER(description) LIKE ? OR LOWER(artists) LIKE ? OR LOWER(genres) LIKE ?);`
 	movies := make([]repository.Movie, 0)
 
-	searchTerm := "%" + keyword + "%"
-	rows, err := rp.database.QueryRows(ctx, getMoviesQuery, searchTerm, searchTerm, searchTerm, searchTerm)
+	// LIKE is case-insensitive on sqlite only, so both sides are lowercased
+	searchTerm := "%" + strings.ToLower(keyword) + "%"
+	rows, err := rp.database.QueryRows(ctx, getMoviesQuery, status, status, searchTerm, searchTerm, searchTerm, searchTerm)
 	if err != nil {
 		return nil, errs.NewCustomErrs(
 			"Failed Insert Database",
@@ -206,9 +335,7 @@ func (rp *movieRepository) SearchMoviesFromDB(ctx context.Context, keyword strin
 	}
 
 	for rows.Next() {
-		var movie repository.Movie
-
-		err = rows.Scan(&movie.Id, &movie.Title, &movie.Description, &movie.Duration, &movie.Artist, &movie.Genre, &movie.WatchUrl, &movie.Views)
+		movie, err := scanMovie(rows)
 		if err != nil {
 			return nil, errs.NewCustomErrs(
 				"Failed Scan",
@@ -225,26 +352,55 @@ func (rp *movieRepository) SearchMoviesFromDB(ctx context.Context, keyword strin
 }
 
 func (rp *movieRepository) InsertVoteToDB(ctx context.Context, userId int, movieId int) errs.MessageErr {
-	apmSpan, ctx := apm.StartSpan(ctx, "InsertVoteToDB", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "InsertVoteToDB", "Repository")
+	defer span.End()
 
 	insertVoteQuery := `INSERT INTO votes (user_id, movie_id) VALUES (?, ?);`
 
-	result := rp.database.Execute(ctx, insertVoteQuery, userId, movieId)
-	if result.Error != nil {
+	err := rp.database.WithTx(ctx, func(ctx context.Context) error {
+		// locks the movie like updateMovies, so it can't be unpublished while the vote is cast
+		lock := rp.database.Execute(ctx, `UPDATE movies SET version = version WHERE id = ? AND status = ?;`, movieId, constant.MovieStatusPublished)
+		if lock.Error != nil {
+			return lock.Error
+		}
+		if lock.RowsAffected == 0 {
+			return errMovieNotPublished
+		}
 
-		if result.Error.Error() == constant.DuplicateConstraintError {
+		result := rp.database.Execute(ctx, insertVoteQuery, userId, movieId)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		votes, err := rp.countVotes(ctx, movieId)
+		if err != nil {
+			return err
+		}
+
+		return rp.insertEvent(ctx, constant.EventVoteCast, strconv.Itoa(movieId), repository.VoteEvent{UserId: userId, MovieId: movieId, Votes: votes})
+	})
+	if err != nil {
+
+		if err.Error() == constant.DuplicateConstraintError {
 			return errs.NewCustomErrs(
 				"Already Voted",
 				"AV",
-				result.Error.Error(),
+				err.Error(),
+			)
+		}
+
+		if err.Error() == constant.ForeignKeyConstraintError || errors.Is(err, errMovieNotPublished) {
+			return errs.NewCustomErrs(
+				"Movie Not Found",
+				"NA",
+				err.Error(),
 			)
 		}
 
 		return errs.NewCustomErrs(
 			"Failed Insert Database",
 			"FD",
-			result.Error.Error(),
+			err.Error(),
 		)
 	}
 
@@ -252,17 +408,29 @@ func (rp *movieRepository) InsertVoteToDB(ctx context.Context, userId int, movie
 }
 
 func (rp *movieRepository) DeleteVoteFromDB(ctx context.Context, userId int, movieId int) errs.MessageErr {
-	apmSpan, ctx := apm.StartSpan(ctx, "InsertVoteToDB", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "InsertVoteToDB", "Repository")
+	defer span.End()
 
 	deleteVoteQuery := `DELETE FROM votes WHERE user_id = ? AND movie_id = ?;`
 
-	result := rp.database.Execute(ctx, deleteVoteQuery, userId, movieId)
-	if result.Error != nil {
+	err := rp.database.WithTx(ctx, func(ctx context.Context) error {
+		result := rp.database.Execute(ctx, deleteVoteQuery, userId, movieId)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		votes, err := rp.countVotes(ctx, movieId)
+		if err != nil {
+			return err
+		}
+
+		return rp.insertEvent(ctx, constant.EventVoteRemoved, strconv.Itoa(movieId), repository.VoteEvent{UserId: userId, MovieId: movieId, Votes: votes})
+	})
+	if err != nil {
 		return errs.NewCustomErrs(
 			"Failed Insert Database",
 			"FD",
-			result.Error.Error(),
+			err.Error(),
 		)
 	}
 
@@ -270,11 +438,11 @@ func (rp *movieRepository) DeleteVoteFromDB(ctx context.Context, userId int, mov
 }
 
 func (rp *movieRepository) GetAllVotedMoviesByUserIdFromDb(ctx context.Context, userId int) ([]repository.Movie, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "GetAllVotedMoviesByUserIdFromDb", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "GetAllVotedMoviesByUserIdFromDb", "Repository")
+	defer span.End()
 
 	getVotedMoviesQuery := `
-    SELECT m.id, m.title, m.description, m.duration, m.artists, m.genres, m.watch_url, m.views_count
+    SELECT m.id, m.title, m.description, m.duration, m.artists, m.genres, m.watch_url, m.views_count, m.status, m.publish_at, m.version, m.year, m.poster_url
 	FROM movies m
 	JOIN votes v ON m.id = v.movie_id
 	WHERE v.user_id = ?;`
@@ -291,9 +459,7 @@ func (rp *movieRepository) GetAllVotedMoviesByUserIdFromDb(ctx context.Context,
 	}
 
 	for rows.Next() {
-		var movie repository.Movie
-
-		err = rows.Scan(&movie.Id, &movie.Title, &movie.Description, &movie.Duration, &movie.Artist, &movie.Genre, &movie.WatchUrl, &movie.Views)
+		movie, err := scanMovie(rows)
 		if err != nil {
 			return nil, errs.NewCustomErrs(
 				"Failed Scan",
@@ -310,11 +476,11 @@ func (rp *movieRepository) GetAllVotedMoviesByUserIdFromDb(ctx context.Context,
 }
 
 func (rp *movieRepository) GetMostVotedMovieFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "GetMostVotedMovieFromDB", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "GetMostVotedMovieFromDB", "Repository")
+	defer span.End()
 
 	getTopMovieQuery := `
-	SELECT m.id, m.title, m.description, m.duration, m.artists, m.genres, m.watch_url, m.views_count, COUNT(v.movie_id) AS vote_count
+	SELECT m.id, m.title, m.description, m.duration, m.artists, m.genres, m.watch_url, m.views_count, m.status, m.publish_at, m.version, m.year, m.poster_url, COUNT(v.movie_id) AS vote_count
 	FROM movies m
 	JOIN votes v ON m.id = v.movie_id
 	GROUP BY m.id
@@ -322,10 +488,10 @@ func (rp *movieRepository) GetMostVotedMovieFromDB(ctx context.Context) (reposit
 	LIMIT 1;
 	`
 
-	var movie repository.Movie
+	var vote int
 
 	row := rp.database.QueryRow(ctx, getTopMovieQuery)
-	err := row.Scan(&movie.Id, &movie.Title, &movie.Description, &movie.Duration, &movie.Artist, &movie.Genre, &movie.WatchUrl, &movie.Views, &movie.Vote)
+	movie, err := scanMovie(row, &vote)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return repository.Movie{}, errs.NewCustomErrs(
@@ -341,12 +507,14 @@ func (rp *movieRepository) GetMostVotedMovieFromDB(ctx context.Context) (reposit
 			err.Error(),
 		)
 	}
+	movie.Vote = vote
+
 	return movie, nil
 }
 
 func (rp *movieRepository) GetMostVotedGenreFromDB(ctx context.Context) (repository.Movie, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "GetMostVotedGenreFromDB", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "GetMostVotedGenreFromDB", "Repository")
+	defer span.End()
 
 	getTopMovieQuery := `
 	SELECT m.genres, COUNT(v.movie_id) AS vote_count
@@ -378,3 +546,50 @@ func (rp *movieRepository) GetMostVotedGenreFromDB(ctx context.Context) (reposit
 	}
 	return movie, nil
 }
+
+func (rp *movieRepository) UpdateMovieStatusToDB(ctx context.Context, id string, status string, publishAt *time.Time) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "UpdateMovieStatusToDB", "Repository")
+	defer span.End()
+
+	updated, err := rp.updateMovies(ctx,
+		`status = ?, publish_at = ?`, []interface{}{status, publishAt},
+		`id = ?`, []interface{}{id},
+	)
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Update Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	if updated == 0 {
+		return errs.NewCustomErrs(
+			"Not Exist",
+			"NA",
+			sql.ErrNoRows.Error(),
+		)
+	}
+
+	return nil
+}
+
+// PublishScheduledMoviesToDB publishes every scheduled movie whose publish_at is due and returns how many were published
+func (rp *movieRepository) PublishScheduledMoviesToDB(ctx context.Context, now time.Time) (int64, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "PublishScheduledMoviesToDB", "Repository")
+	defer span.End()
+
+	published, err := rp.updateMovies(ctx,
+		`status = ?`, []interface{}{constant.MovieStatusPublished},
+		`status = ? AND publish_at IS NOT NULL AND publish_at <= ?`, []interface{}{constant.MovieStatusScheduled, now},
+	)
+	if err != nil {
+		return 0, errs.NewCustomErrs(
+			"Failed Update Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return published, nil
+}
@@ -0,0 +1,151 @@
+package movierepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func (rp *movieRepository) InsertMovieRevisionToDB(ctx context.Context, revision repository.MovieRevision) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "InsertMovieRevisionToDB", "Repository")
+	defer span.End()
+
+	changes, err := jsoniter.MarshalToString(revision.Changes)
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Marshal",
+			"FM",
+			err.Error(),
+		)
+	}
+
+	snapshot, err := jsoniter.MarshalToString(revision.Snapshot)
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Marshal",
+			"FM",
+			err.Error(),
+		)
+	}
+
+	insertRevisionQuery := `INSERT INTO movie_revisions (movie_id, user_id, action, changes, snapshot, file_name, restored_from) VALUES (?, ?, ?, ?, ?, ?, ?);`
+
+	result := rp.database.Execute(ctx, insertRevisionQuery, revision.MovieId, revision.UserId, revision.Action, changes, snapshot, nullString(revision.FileName), nullInt(revision.RestoredFrom))
+	if result.Error != nil {
+		return errs.NewCustomErrs(
+			"Failed Insert Database",
+			"FD",
+			result.Error.Error(),
+		)
+	}
+
+	return nil
+}
+
+func (rp *movieRepository) GetMovieRevisionsFromDB(ctx context.Context, movieId string) ([]repository.MovieRevision, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetMovieRevisionsFromDB", "Repository")
+	defer span.End()
+
+	getRevisionsQuery := `
+	SELECT id, movie_id, user_id, action, changes, snapshot, file_name, restored_from, created_at
+	FROM movie_revisions
+	WHERE movie_id = ?
+	ORDER BY id DESC;`
+
+	revisions := make([]repository.MovieRevision, 0)
+
+	rows, err := rp.database.QueryRows(ctx, getRevisionsQuery, movieId)
+	if err != nil {
+		return nil, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		revision, err := scanMovieRevision(rows)
+		if err != nil {
+			return nil, errs.NewCustomErrs(
+				"Failed Scan",
+				"FD",
+				err.Error(),
+			)
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+func (rp *movieRepository) GetMovieRevisionFromDB(ctx context.Context, movieId string, revisionId int) (repository.MovieRevision, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetMovieRevisionFromDB", "Repository")
+	defer span.End()
+
+	getRevisionQuery := `
+	SELECT id, movie_id, user_id, action, changes, snapshot, file_name, restored_from, created_at
+	FROM movie_revisions
+	WHERE movie_id = ? AND id = ?;`
+
+	row := rp.database.QueryRow(ctx, getRevisionQuery, movieId, revisionId)
+	revision, err := scanMovieRevision(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.MovieRevision{}, errs.NewCustomErrs(
+				"Not Exist",
+				"NA",
+				err.Error(),
+			)
+		}
+
+		return repository.MovieRevision{}, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return revision, nil
+}
+
+func scanMovieRevision(row rowScanner) (repository.MovieRevision, error) {
+	var revision repository.MovieRevision
+	var changes, snapshot string
+	var fileName sql.NullString
+	var restoredFrom sql.NullInt64
+
+	err := row.Scan(&revision.Id, &revision.MovieId, &revision.UserId, &revision.Action, &changes, &snapshot, &fileName, &restoredFrom, &revision.CreatedAt)
+	if err != nil {
+		return repository.MovieRevision{}, err
+	}
+
+	err = jsoniter.UnmarshalFromString(changes, &revision.Changes)
+	if err != nil {
+		return repository.MovieRevision{}, err
+	}
+
+	err = jsoniter.UnmarshalFromString(snapshot, &revision.Snapshot)
+	if err != nil {
+		return repository.MovieRevision{}, err
+	}
+
+	revision.FileName = fileName.String
+	revision.RestoredFrom = int(restoredFrom.Int64)
+
+	return revision, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
+}
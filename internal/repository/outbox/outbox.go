@@ -0,0 +1,152 @@
+package outboxrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+)
+
+type outboxRepository struct {
+	database adapter.DatabaseClient
+}
+
+func NewOutboxRepository(database adapter.DatabaseClient) repository.OutboxRepository {
+	return &outboxRepository{
+		database: database,
+	}
+}
+
+// GetOutboxEventsFromDB returns the events written after afterId, oldest first
+func (rp *outboxRepository) GetOutboxEventsFromDB(ctx context.Context, afterId int64, limit int) ([]repository.OutboxEvent, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetOutboxEventsFromDB", "Repository")
+	defer span.End()
+
+	query := `SELECT id, event, aggregate_id, payload, created_at FROM outbox_events WHERE id > ? ORDER BY id LIMIT ?;`
+
+	rows, err := rp.database.QueryRows(ctx, query, afterId, limit)
+	if err != nil {
+		return nil, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+	defer rows.Close()
+
+	events := []repository.OutboxEvent{}
+	for rows.Next() {
+		var event repository.OutboxEvent
+
+		err = rows.Scan(&event.Id, &event.Event, &event.AggregateId, &event.Payload, &event.CreatedAt)
+		if err != nil {
+			return nil, errs.NewCustomErrs(
+				"Failed Get Database",
+				"FD",
+				err.Error(),
+			)
+		}
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return events, nil
+}
+
+func (rp *outboxRepository) CountOutboxEventsFromDB(ctx context.Context, afterId int64) (int, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "CountOutboxEventsFromDB", "Repository")
+	defer span.End()
+
+	var count int
+
+	row := rp.database.QueryRow(ctx, `SELECT COUNT(*) FROM outbox_events WHERE id > ?;`, afterId)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return count, nil
+}
+
+func (rp *outboxRepository) GetConsumerOffsetFromDB(ctx context.Context, consumer string) (repository.ConsumerOffset, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetConsumerOffsetFromDB", "Repository")
+	defer span.End()
+
+	offset := repository.ConsumerOffset{Consumer: consumer}
+	var updatedAt sql.NullTime
+
+	row := rp.database.QueryRow(ctx, `SELECT last_event_id, failing_event_id, attempts, last_error, updated_at FROM event_consumer_offsets WHERE consumer = ?;`, consumer)
+	err := row.Scan(&offset.LastEventId, &offset.FailingEventId, &offset.Attempts, &offset.LastError, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return offset, nil
+		}
+
+		return repository.ConsumerOffset{}, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	if updatedAt.Valid {
+		offset.UpdatedAt = &updatedAt.Time
+	}
+
+	return offset, nil
+}
+
+func (rp *outboxRepository) UpdateConsumerOffsetToDB(ctx context.Context, offset repository.ConsumerOffset) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "UpdateConsumerOffsetToDB", "Repository")
+	defer span.End()
+
+	upsertQuery := `INSERT INTO event_consumer_offsets (consumer, last_event_id, failing_event_id, attempts, last_error, updated_at)
+	VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT (consumer) DO UPDATE SET last_event_id = excluded.last_event_id, failing_event_id = excluded.failing_event_id,
+	attempts = excluded.attempts, last_error = excluded.last_error, updated_at = excluded.updated_at;`
+
+	result := rp.database.Execute(ctx, upsertQuery, offset.Consumer, offset.LastEventId, offset.FailingEventId, offset.Attempts, offset.LastError)
+	if result.Error != nil {
+		return errs.NewCustomErrs(
+			"Failed Update Database",
+			"FD",
+			result.Error.Error(),
+		)
+	}
+
+	return nil
+}
+
+func (rp *outboxRepository) InsertDeadLetterToDB(ctx context.Context, letter repository.DeadLetter) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "InsertDeadLetterToDB", "Repository")
+	defer span.End()
+
+	insertQuery := `INSERT INTO event_dead_letters (consumer, event_id, event, attempts, error) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (consumer, event_id) DO NOTHING;`
+
+	result := rp.database.Execute(ctx, insertQuery, letter.Consumer, letter.EventId, letter.Event, letter.Attempts, letter.Error)
+	if result.Error != nil {
+		return errs.NewCustomErrs(
+			"Failed Insert Database",
+			"FD",
+			result.Error.Error(),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package ratelimitrepo
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"time"
+)
+
+const (
+	bucketPrefix  = "ratelimit:"
+	failurePrefix = "lockout:failures:"
+	lockPrefix    = "lockout:lock:"
+)
+
+type rateLimitRepository struct {
+	store adapter.RateLimitStore
+}
+
+func NewRateLimitRepository(store adapter.RateLimitStore) repository.RateLimitRepository {
+	return &rateLimitRepository{
+		store: store,
+	}
+}
+
+func (rp *rateLimitRepository) TakeRateLimitToken(ctx context.Context, rule string, key string, limit int, period time.Duration) (repository.RateLimit, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "TakeRateLimitToken", "Repository")
+	defer span.End()
+
+	decision, err := rp.store.Take(ctx, bucketPrefix+rule+":"+key, limit, period)
+	if err != nil {
+		return repository.RateLimit{}, errs.NewCustomErrs(
+			"Rate Limit Unavailable",
+			"RU",
+			err.Error(),
+		)
+	}
+
+	return repository.RateLimit{
+		Allowed:    decision.Allowed,
+		Limit:      limit,
+		Remaining:  decision.Remaining,
+		Reset:      decision.Reset,
+		RetryAfter: decision.RetryAfter,
+	}, nil
+}
+
+func (rp *rateLimitRepository) AddLoginFailure(ctx context.Context, key string, window time.Duration) (int64, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "AddLoginFailure", "Repository")
+	defer span.End()
+
+	count, err := rp.store.AddFailure(ctx, failurePrefix+key, window)
+	if err != nil {
+		return 0, errs.NewCustomErrs(
+			"Rate Limit Unavailable",
+			"RU",
+			err.Error(),
+		)
+	}
+
+	return count, nil
+}
+
+func (rp *rateLimitRepository) ResetLoginFailures(ctx context.Context, key string) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "ResetLoginFailures", "Repository")
+	defer span.End()
+
+	err := rp.store.ResetFailures(ctx, failurePrefix+key)
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Rate Limit Unavailable",
+			"RU",
+			err.Error(),
+		)
+	}
+
+	return nil
+}
+
+func (rp *rateLimitRepository) LockLogin(ctx context.Context, key string, d time.Duration) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "LockLogin", "Repository")
+	defer span.End()
+
+	err := rp.store.Lock(ctx, lockPrefix+key, d)
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Rate Limit Unavailable",
+			"RU",
+			err.Error(),
+		)
+	}
+
+	return nil
+}
+
+func (rp *rateLimitRepository) GetLoginLock(ctx context.Context, key string) (time.Duration, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetLoginLock", "Repository")
+	defer span.End()
+
+	lockedFor, err := rp.store.LockedFor(ctx, lockPrefix+key)
+	if err != nil {
+		return 0, errs.NewCustomErrs(
+			"Rate Limit Unavailable",
+			"RU",
+			err.Error(),
+		)
+	}
+
+	return lockedFor, nil
+}
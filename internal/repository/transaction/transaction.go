@@ -0,0 +1,49 @@
+package txrepo
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+)
+
+type transactor struct {
+	database adapter.DatabaseClient
+}
+
+func NewTransactor(database adapter.DatabaseClient) repository.Transactor {
+	return &transactor{
+		database: database,
+	}
+}
+
+// WithTx returns the error of fn as it is, so callers can still tell a not found from a conflict
+func (rp *transactor) WithTx(ctx context.Context, fn func(ctx context.Context) errs.MessageErr) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "WithTx", "Repository")
+	defer span.End()
+
+	var fnErr errs.MessageErr
+
+	err := rp.database.WithTx(ctx, func(ctx context.Context) error {
+		fnErr = fn(ctx)
+		if fnErr != nil {
+			return fnErr
+		}
+
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Transaction",
+			"FT",
+			err.Error(),
+		)
+	}
+
+	return nil
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"lion-parcel-test/constant"
 	"lion-parcel-test/internal/interfaces/adapter"
 	"lion-parcel-test/internal/interfaces/repository"
 	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"strconv"
 
-	"go.elastic.co/apm/v2"
+	jsoniter "github.com/json-iterator/go"
 )
 
 type userRepository struct {
@@ -22,25 +25,41 @@ func NewUserRepository(database adapter.DatabaseClient) repository.UserRepositor
 }
 
 func (rp *userRepository) InsertUserToDB(ctx context.Context, email string, name string) errs.MessageErr {
-	apmSpan, ctx := apm.StartSpan(ctx, "InsertUserToDB", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "InsertUserToDB", "Repository")
+	defer span.End()
 
-	query := `INSERT INTO users (name, email, is_admin) VALUES (?, ?, false);`
+	query := `INSERT INTO users (name, email, is_admin) VALUES (?, ?, FALSE) RETURNING id;`
 
-	result := rp.database.Execute(ctx, query, name, email)
-	if result.Error != nil {
+	err := rp.database.WithTx(ctx, func(ctx context.Context) error {
+		var id int
+
+		err := rp.database.QueryRow(ctx, query, name, email).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		payload, err := jsoniter.MarshalToString(repository.UserRegisteredEvent{UserId: id, Name: name})
+		if err != nil {
+			return err
+		}
+
+		eventQuery := `INSERT INTO outbox_events (event, aggregate_id, payload) VALUES (?, ?, ?);`
+
+		return rp.database.Execute(ctx, eventQuery, constant.EventUserRegistered, strconv.Itoa(id), payload).Error
+	})
+	if err != nil {
 		return errs.NewCustomErrs(
 			"Failed Insert Database",
 			"FD",
-			result.Error.Error(),
+			err.Error(),
 		)
 	}
 
 	return nil
 }
 func (rp *userRepository) GetUserFromDbByEmail(ctx context.Context, email string) (repository.User, errs.MessageErr) {
-	apmSpan, ctx := apm.StartSpan(ctx, "GetUserFromDbByEmail", "Repository")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "GetUserFromDbByEmail", "Repository")
+	defer span.End()
 
 	query := `SELECT * FROM users WHERE email = ?`
 
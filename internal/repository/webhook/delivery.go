@@ -0,0 +1,152 @@
+package webhookrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+)
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event, payload, status, attempts, response_status, response_body, error, created_at, last_attempt_at`
+
+func scanWebhookDelivery(row rowScanner) (repository.WebhookDelivery, error) {
+	var delivery repository.WebhookDelivery
+	var responseStatus sql.NullInt64
+	var responseBody, deliveryErr sql.NullString
+	var lastAttemptAt sql.NullTime
+
+	err := row.Scan(&delivery.Id, &delivery.SubscriptionId, &delivery.EventId, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts, &responseStatus, &responseBody, &deliveryErr, &delivery.CreatedAt, &lastAttemptAt)
+	if err != nil {
+		return repository.WebhookDelivery{}, err
+	}
+
+	delivery.ResponseStatus = int(responseStatus.Int64)
+	delivery.ResponseBody = responseBody.String
+	delivery.Error = deliveryErr.String
+	if lastAttemptAt.Valid {
+		delivery.LastAttemptAt = &lastAttemptAt.Time
+	}
+
+	return delivery, nil
+}
+
+func (rp *webhookRepository) InsertWebhookDeliveriesToDB(ctx context.Context, deliveries []repository.WebhookDelivery) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "InsertWebhookDeliveriesToDB", "Repository")
+	defer span.End()
+
+	// an event already queued for a subscription is skipped, so notifying twice is harmless
+	insertQuery := `INSERT INTO webhook_deliveries (subscription_id, event_id, event, payload, status) VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING;`
+
+	statements := make([]adapter.Statement, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		statements = append(statements, adapter.Statement{
+			Query: insertQuery,
+			Args:  []interface{}{delivery.SubscriptionId, delivery.EventId, delivery.Event, delivery.Payload, constant.WebhookDeliveryStatusPending},
+		})
+	}
+
+	_, err := rp.database.ExecuteBatch(ctx, statements)
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Insert Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return nil
+}
+
+func (rp *webhookRepository) UpdateWebhookDeliveryToDB(ctx context.Context, delivery repository.WebhookDelivery) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "UpdateWebhookDeliveryToDB", "Repository")
+	defer span.End()
+
+	updateQuery := `UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, response_body = ?, error = ?, last_attempt_at = ? WHERE id = ?;`
+
+	result := rp.database.Execute(ctx, updateQuery, delivery.Status, delivery.Attempts, nullInt(delivery.ResponseStatus), nullString(delivery.ResponseBody), nullString(delivery.Error), delivery.LastAttemptAt, delivery.Id)
+	if result.Error != nil {
+		return errs.NewCustomErrs(
+			"Failed Update Database",
+			"FD",
+			result.Error.Error(),
+		)
+	}
+
+	return nil
+}
+
+func (rp *webhookRepository) GetWebhookDeliveryFromDB(ctx context.Context, subscriptionId int, id int) (repository.WebhookDelivery, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetWebhookDeliveryFromDB", "Repository")
+	defer span.End()
+
+	getQuery := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = ? AND id = ?;`
+
+	row := rp.database.QueryRow(ctx, getQuery, subscriptionId, id)
+	delivery, err := scanWebhookDelivery(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.WebhookDelivery{}, errs.NewCustomErrs(
+				"Not Exist",
+				"NA",
+				err.Error(),
+			)
+		}
+
+		return repository.WebhookDelivery{}, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return delivery, nil
+}
+
+func (rp *webhookRepository) GetWebhookDeliveriesFromDB(ctx context.Context, subscriptionId int, status string, limit int) ([]repository.WebhookDelivery, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetWebhookDeliveriesFromDB", "Repository")
+	defer span.End()
+
+	getQuery := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = ? AND (? = '' OR status = ?) ORDER BY id DESC LIMIT ?;`
+
+	return rp.queryWebhookDeliveries(ctx, getQuery, subscriptionId, status, status, limit)
+}
+
+func (rp *webhookRepository) GetPendingWebhookDeliveriesFromDB(ctx context.Context, limit int) ([]repository.WebhookDelivery, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetPendingWebhookDeliveriesFromDB", "Repository")
+	defer span.End()
+
+	getQuery := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE status = ? ORDER BY id LIMIT ?;`
+
+	return rp.queryWebhookDeliveries(ctx, getQuery, constant.WebhookDeliveryStatusPending, limit)
+}
+
+func (rp *webhookRepository) queryWebhookDeliveries(ctx context.Context, query string, args ...interface{}) ([]repository.WebhookDelivery, errs.MessageErr) {
+	rows, err := rp.database.QueryRows(ctx, query, args...)
+	if err != nil {
+		return nil, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+	defer rows.Close()
+
+	deliveries := []repository.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, errs.NewCustomErrs(
+				"Failed Get Database",
+				"FD",
+				err.Error(),
+			)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
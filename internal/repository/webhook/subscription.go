@@ -0,0 +1,213 @@
+package webhookrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"strings"
+)
+
+const webhookSubscriptionColumns = `id, url, events, secret, active, consecutive_failures, disabled_at, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhookSubscription(row rowScanner) (repository.WebhookSubscription, error) {
+	var subscription repository.WebhookSubscription
+	var events string
+	var disabledAt sql.NullTime
+
+	err := row.Scan(&subscription.Id, &subscription.Url, &events, &subscription.Secret, &subscription.Active, &subscription.ConsecutiveFailures, &disabledAt, &subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		return repository.WebhookSubscription{}, err
+	}
+
+	subscription.Events = strings.Split(events, ",")
+	if disabledAt.Valid {
+		subscription.DisabledAt = &disabledAt.Time
+	}
+
+	return subscription, nil
+}
+
+func (rp *webhookRepository) InsertWebhookSubscriptionToDB(ctx context.Context, subscription repository.WebhookSubscription) (int, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "InsertWebhookSubscriptionToDB", "Repository")
+	defer span.End()
+
+	insertQuery := `INSERT INTO webhook_subscriptions (url, events, secret, active) VALUES (?, ?, ?, TRUE) RETURNING id;`
+
+	var id int
+
+	row := rp.database.QueryRow(ctx, insertQuery, subscription.Url, strings.Join(subscription.Events, ","), subscription.Secret)
+	err := row.Scan(&id)
+	if err != nil {
+		return 0, errs.NewCustomErrs(
+			"Failed Insert Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return id, nil
+}
+
+func (rp *webhookRepository) UpdateWebhookSubscriptionToDB(ctx context.Context, subscription repository.WebhookSubscription) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "UpdateWebhookSubscriptionToDB", "Repository")
+	defer span.End()
+
+	updateQuery := `UPDATE webhook_subscriptions SET url = ?, events = ?, active = ?,
+	consecutive_failures = CASE WHEN ? THEN 0 ELSE consecutive_failures END,
+	disabled_at = CASE WHEN ? THEN NULL WHEN active AND NOT ? THEN CURRENT_TIMESTAMP ELSE disabled_at END,
+	updated_at = CURRENT_TIMESTAMP
+	WHERE id = ?;`
+
+	active := subscription.Active
+
+	result := rp.database.Execute(ctx, updateQuery, subscription.Url, strings.Join(subscription.Events, ","), active, active, active, active, subscription.Id)
+	if result.Error != nil {
+		return errs.NewCustomErrs(
+			"Failed Update Database",
+			"FD",
+			result.Error.Error(),
+		)
+	}
+
+	if result.RowsAffected == 0 {
+		return errs.NewCustomErrs(
+			"Not Exist",
+			"NA",
+			"webhook subscription doesn't exist",
+		)
+	}
+
+	return nil
+}
+
+func (rp *webhookRepository) DeleteWebhookSubscriptionFromDB(ctx context.Context, id int) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "DeleteWebhookSubscriptionFromDB", "Repository")
+	defer span.End()
+
+	results, err := rp.database.ExecuteBatch(ctx, []adapter.Statement{
+		{Query: `DELETE FROM webhook_deliveries WHERE subscription_id = ?;`, Args: []interface{}{id}},
+		{Query: `DELETE FROM webhook_subscriptions WHERE id = ?;`, Args: []interface{}{id}},
+	})
+	if err != nil {
+		return errs.NewCustomErrs(
+			"Failed Delete Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	if results[1].RowsAffected == 0 {
+		return errs.NewCustomErrs(
+			"Not Exist",
+			"NA",
+			"webhook subscription doesn't exist",
+		)
+	}
+
+	return nil
+}
+
+func (rp *webhookRepository) GetWebhookSubscriptionFromDB(ctx context.Context, id int) (repository.WebhookSubscription, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetWebhookSubscriptionFromDB", "Repository")
+	defer span.End()
+
+	getQuery := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = ?;`
+
+	row := rp.database.QueryRow(ctx, getQuery, id)
+	subscription, err := scanWebhookSubscription(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.WebhookSubscription{}, errs.NewCustomErrs(
+				"Not Exist",
+				"NA",
+				err.Error(),
+			)
+		}
+
+		return repository.WebhookSubscription{}, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	return subscription, nil
+}
+
+func (rp *webhookRepository) GetWebhookSubscriptionsFromDB(ctx context.Context, activeOnly bool) ([]repository.WebhookSubscription, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "GetWebhookSubscriptionsFromDB", "Repository")
+	defer span.End()
+
+	getQuery := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE (NOT ? OR active) ORDER BY id;`
+
+	rows, err := rp.database.QueryRows(ctx, getQuery, activeOnly)
+	if err != nil {
+		return nil, errs.NewCustomErrs(
+			"Failed Get Database",
+			"FD",
+			err.Error(),
+		)
+	}
+	defer rows.Close()
+
+	subscriptions := []repository.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, errs.NewCustomErrs(
+				"Failed Get Database",
+				"FD",
+				err.Error(),
+			)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+func (rp *webhookRepository) RecordWebhookResultToDB(ctx context.Context, id int, success bool, maxFailures int) (bool, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "RecordWebhookResultToDB", "Repository")
+	defer span.End()
+
+	if success {
+		result := rp.database.Execute(ctx, `UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = ?;`, id)
+		if result.Error != nil {
+			return false, errs.NewCustomErrs(
+				"Failed Update Database",
+				"FD",
+				result.Error.Error(),
+			)
+		}
+		return false, nil
+	}
+
+	failQuery := `UPDATE webhook_subscriptions SET consecutive_failures = consecutive_failures + 1,
+	active = CASE WHEN consecutive_failures + 1 >= ? THEN FALSE ELSE active END,
+	disabled_at = CASE WHEN active AND consecutive_failures + 1 >= ? THEN CURRENT_TIMESTAMP ELSE disabled_at END,
+	updated_at = CURRENT_TIMESTAMP
+	WHERE id = ? RETURNING consecutive_failures;`
+
+	var failures int
+
+	row := rp.database.QueryRow(ctx, failQuery, maxFailures, maxFailures, id)
+	err := row.Scan(&failures)
+	if err != nil {
+		return false, errs.NewCustomErrs(
+			"Failed Update Database",
+			"FD",
+			err.Error(),
+		)
+	}
+
+	// only the failure reaching the limit disables it, later ones find it disabled already
+	return failures == maxFailures, nil
+}
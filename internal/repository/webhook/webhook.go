@@ -0,0 +1,54 @@
+package webhookrepo
+
+import (
+	"context"
+	"database/sql"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/adapter"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"strconv"
+)
+
+type webhookRepository struct {
+	database adapter.DatabaseClient
+	client   adapter.WebhookClient
+}
+
+func NewWebhookRepository(database adapter.DatabaseClient, client adapter.WebhookClient) repository.WebhookRepository {
+	return &webhookRepository{
+		database: database,
+		client:   client,
+	}
+}
+
+func (rp *webhookRepository) SendWebhookToEndpoint(ctx context.Context, subscription repository.WebhookSubscription, header http.Header, body []byte) (repository.WebhookResponse, errs.MessageErr) {
+	span, ctx := tracing.StartSpan(ctx, "SendWebhookToEndpoint", "Repository")
+	defer span.End()
+
+	response, err := rp.client.Send(ctx, adapter.WebhookRequest{
+		Command: constant.WebhookCommandPrefix + strconv.Itoa(subscription.Id),
+		Url:     subscription.Url,
+		Header:  header,
+		Body:    body,
+	})
+	if err != nil {
+		return repository.WebhookResponse{StatusCode: response.StatusCode, Body: response.Body}, errs.NewCustomErrs(
+			"Failed Send Webhook",
+			"WF",
+			err.Error(),
+		)
+	}
+
+	return repository.WebhookResponse{StatusCode: response.StatusCode, Body: response.Body}, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
+}
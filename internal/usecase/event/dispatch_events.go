@@ -0,0 +1,156 @@
+package eventuc
+
+import (
+	"context"
+	"fmt"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// DispatchEvents hands every consumer the events after its offset in outbox order, the offset only moves past
+// an event once the handler succeeded so a crash or a failing handler means redelivery, never loss.
+// An event the handler failed on max_attempts times is recorded as a dead letter and skipped
+func (uc *eventBus) DispatchEvents(ctx context.Context) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "DispatchEvents", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	uc.dispatching.Lock()
+	defer uc.dispatching.Unlock()
+
+	var result usecase.DispatchEventsResponse
+	for _, sub := range uc.getSubscribers() {
+		delivered, deadLettered, err := uc.dispatch(ctx, sub)
+		result.Delivered += delivered
+		result.DeadLettered += deadLettered
+		if err != nil {
+			log.Ctx(ctx).Errorw("failed to dispatch events", "consumer", sub.consumer, "error", err.Error())
+			result.Failed++
+		}
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success dispatch events", result)
+
+	return resp
+}
+
+// dispatch stops at the first failing event of the consumer, the others keep going
+func (uc *eventBus) dispatch(ctx context.Context, sub subscriber) (int, int, errs.MessageErr) {
+	offset, err := uc.outboxRepository.GetConsumerOffsetFromDB(ctx, sub.consumer)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	delivered, deadLettered := 0, 0
+
+	for {
+		events, err := uc.outboxRepository.GetOutboxEventsFromDB(ctx, offset.LastEventId, dispatchBatchSize)
+		if err != nil {
+			return delivered, deadLettered, err
+		}
+
+		if len(events) == 0 {
+			return delivered, deadLettered, nil
+		}
+
+		lastEventId := offset.LastEventId
+
+		for _, event := range events {
+			if sub.wants(event.Event) {
+				handleErr := uc.handle(ctx, sub, event)
+				if handleErr != nil {
+					if !uc.giveUp(ctx, &offset, event, handleErr) {
+						// stores the events handled before this one along with the failure
+						if err = uc.outboxRepository.UpdateConsumerOffsetToDB(ctx, offset); err != nil {
+							return delivered, deadLettered, err
+						}
+
+						return delivered, deadLettered, errs.NewCustomErrs(
+							"Failed Handle Event",
+							"FH",
+							handleErr.Error(),
+						)
+					}
+
+					deadLettered++
+				} else {
+					delivered++
+				}
+			}
+
+			offset.LastEventId = event.Id
+			offset.FailingEventId, offset.Attempts, offset.LastError = 0, 0, ""
+		}
+
+		if offset.LastEventId != lastEventId {
+			if err = uc.outboxRepository.UpdateConsumerOffsetToDB(ctx, offset); err != nil {
+				return delivered, deadLettered, err
+			}
+		}
+
+		if len(events) < dispatchBatchSize {
+			return delivered, deadLettered, nil
+		}
+	}
+}
+
+// giveUp counts the failure of event in offset and records the event as a dead letter once it reached max_attempts.
+// It is false while the event should be retried, and when the dead letter couldn't be stored
+func (uc *eventBus) giveUp(ctx context.Context, offset *repository.ConsumerOffset, event repository.OutboxEvent, handleErr error) bool {
+	attempts := 1
+	if offset.FailingEventId == event.Id {
+		attempts = offset.Attempts + 1
+	}
+
+	offset.FailingEventId = event.Id
+	offset.Attempts = attempts
+	offset.LastError = handleErr.Error()
+
+	if attempts < maxAttempts() {
+		return false
+	}
+
+	err := uc.outboxRepository.InsertDeadLetterToDB(ctx, repository.DeadLetter{
+		Consumer: offset.Consumer,
+		EventId:  event.Id,
+		Event:    event.Event,
+		Attempts: attempts,
+		Error:    offset.LastError,
+	})
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+		return false
+	}
+
+	log.Ctx(ctx).Errorw("event handler failed too often, the event is skipped",
+		"consumer", offset.Consumer, "event_id", event.Id, "event", event.Event, "attempts", attempts, "error", offset.LastError)
+
+	return true
+}
+
+// handle runs the handler in its own span and turns a panic into an error so the event is retried
+func (uc *eventBus) handle(ctx context.Context, sub subscriber, event repository.OutboxEvent) (err error) {
+	span, ctx := tracing.StartSpan(ctx, sub.consumer+" "+event.Event, "event")
+	defer span.End()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errs.NewCustomErrs("Event Handler Panic", "EP", fmt.Sprint(r))
+		}
+	}()
+
+	return sub.handler(ctx, usecase.DomainEvent{
+		Id:          event.Id,
+		Name:        event.Event,
+		AggregateId: event.AggregateId,
+		Payload:     jsoniter.RawMessage(event.Payload),
+		CreatedAt:   event.CreatedAt,
+	})
+}
@@ -0,0 +1,74 @@
+package eventuc
+
+import (
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"sync"
+)
+
+const (
+	// dispatchBatchSize is how many outbox events are read at once for a consumer, its offset is stored once per batch
+	dispatchBatchSize  = 100
+	defaultMaxAttempts = 5
+)
+
+type subscriber struct {
+	consumer string
+	handler  usecase.EventHandler
+	events   []string
+}
+
+type eventBus struct {
+	outboxRepository repository.OutboxRepository
+	mu               sync.RWMutex
+	subscribers      []subscriber
+	// dispatching keeps two dispatches from handing the same events to a consumer at once
+	dispatching sync.Mutex
+}
+
+func NewEventBus(outboxRepository repository.OutboxRepository) usecase.EventBus {
+	return &eventBus{
+		outboxRepository: outboxRepository,
+	}
+}
+
+func (uc *eventBus) Subscribe(consumer string, handler usecase.EventHandler, events ...string) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.subscribers = append(uc.subscribers, subscriber{
+		consumer: consumer,
+		handler:  handler,
+		events:   events,
+	})
+}
+
+func maxAttempts() int {
+	if config.Cfg.Events.MaxAttempts > 0 {
+		return config.Cfg.Events.MaxAttempts
+	}
+
+	return defaultMaxAttempts
+}
+
+func (s subscriber) wants(event string) bool {
+	if len(s.events) == 0 {
+		return true
+	}
+
+	for _, wanted := range s.events {
+		if wanted == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (uc *eventBus) getSubscribers() []subscriber {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+
+	return append([]subscriber(nil), uc.subscribers...)
+}
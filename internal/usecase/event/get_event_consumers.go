@@ -0,0 +1,50 @@
+package eventuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// GetEventConsumers reports each consumer's offset and how many outbox events it hasn't been through yet
+func (uc *eventBus) GetEventConsumers(ctx context.Context) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "GetEventConsumers", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	subscribers := uc.getSubscribers()
+
+	consumers := make([]usecase.EventConsumer, 0, len(subscribers))
+	for _, sub := range subscribers {
+		offset, err := uc.outboxRepository.GetConsumerOffsetFromDB(ctx, sub.consumer)
+		if err != nil {
+			resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+			return resp
+		}
+
+		lag, err := uc.outboxRepository.CountOutboxEventsFromDB(ctx, offset.LastEventId)
+		if err != nil {
+			resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+			return resp
+		}
+
+		consumers = append(consumers, usecase.EventConsumer{
+			Consumer:       sub.consumer,
+			Events:         sub.events,
+			LastEventId:    offset.LastEventId,
+			Lag:            lag,
+			FailingEventId: offset.FailingEventId,
+			Attempts:       offset.Attempts,
+			LastError:      offset.LastError,
+			UpdatedAt:      offset.UpdatedAt,
+		})
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success get event consumers", usecase.GetEventConsumersResponse{
+		Consumers: consumers,
+	})
+
+	return resp
+}
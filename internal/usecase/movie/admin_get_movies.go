@@ -0,0 +1,29 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// AdminGetMovies lists movies of any status so admins can preview drafts and scheduled releases
+func (uc *movieUsecase) AdminGetMovies(ctx context.Context, req *usecase.AdminGetMoviesRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "AdminGetMovies", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	movie, paginationMetadata, err := uc.movieRepository.GetMoviesFromDB(ctx, req.Status, req.Page, req.PageSize)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success get movie", usecase.GetMoviesResponse{
+		Movies:         movie,
+		PaginationData: paginationMetadata,
+	})
+
+	return resp
+}
@@ -0,0 +1,40 @@
+package movieuc
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// AuthorizeMovieFile lets anyone stream the file of a published movie, admins also preview the other ones.
+// A file that isn't the current one of a movie looks missing, like a movie that isn't published
+func (uc *movieUsecase) AuthorizeMovieFile(ctx context.Context, req *usecase.AuthorizeMovieFileRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "AuthorizeMovieFile", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	if req.IsAdmin {
+		resp.SetSuccess(http.StatusOK, "00", "Success authorize movie file", nil)
+		return resp
+	}
+
+	movie, err := uc.movieRepository.GetMovieByFileNameFromDB(ctx, req.FileName)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	if movie.Status != constant.MovieStatusPublished {
+		resp.SetError(http.StatusNotFound, "NA", "Not Exist", errors.New("movie "+movie.Id+" is not published"))
+		return resp
+	}
+
+	resp.SetSuccess(http.StatusOK, "00", "Success authorize movie file", nil)
+
+	return resp
+}
@@ -0,0 +1,317 @@
+package movieuc
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	catalogFormatCSV   = "csv"
+	catalogFormatJSONL = "jsonl"
+)
+
+// catalogColumns is the csv header of an export, imports only need the columns in requiredCatalogColumns
+var catalogColumns = []string{"id", "title", "description", "duration", "artists", "genres", "file_name", "watch_url", "status", "publish_at", "views", "votes", "version"}
+
+var requiredCatalogColumns = []string{"title", "description", "duration", "artists", "genres", "file_name"}
+
+// catalogRow is one movie of an imported or exported catalog, the counters are ignored on import
+type catalogRow struct {
+	Id          string     `json:"id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Duration    int        `json:"duration"`
+	Artist      string     `json:"artists"`
+	Genre       string     `json:"genres"`
+	FileName    string     `json:"file_name"`
+	WatchUrl    string     `json:"watch_url,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	Views       int        `json:"views"`
+	Votes       int        `json:"votes"`
+	Version     int        `json:"version,omitempty"`
+}
+
+type parsedCatalogRow struct {
+	Row   int
+	Movie repository.NewMovie
+	Err   error
+}
+
+// readCatalog parses every row, row level problems are kept on the row so the whole file can be reported at once
+func readCatalog(format string, reader io.Reader) ([]parsedCatalogRow, error) {
+	switch format {
+	case catalogFormatCSV:
+		return readCatalogCSV(reader)
+	case catalogFormatJSONL:
+		return readCatalogJSONL(reader)
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+func readCatalogCSV(reader io.Reader) ([]parsedCatalogRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.TrimSpace(strings.ToLower(column))] = i
+	}
+
+	for _, column := range requiredCatalogColumns {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("csv header is missing column %s", column)
+		}
+	}
+
+	rows := make([]parsedCatalogRow, 0)
+
+	for rowNumber := 1; ; rowNumber++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			rows = append(rows, parsedCatalogRow{Row: rowNumber, Err: err})
+			continue
+		}
+
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := parsedCatalogRow{
+			Row: rowNumber,
+			Movie: repository.NewMovie{
+				Title:       value("title"),
+				Description: value("description"),
+				Artist:      value("artists"),
+				Genre:       value("genres"),
+				FileName:    value("file_name"),
+				Status:      value("status"),
+			},
+		}
+
+		row.Movie.Duration, err = strconv.Atoi(value("duration"))
+		if err != nil {
+			row.Err = fmt.Errorf("duration must be an integer")
+			rows = append(rows, row)
+			continue
+		}
+
+		if publishAt := value("publish_at"); publishAt != "" {
+			parsed, err := time.Parse(time.RFC3339, publishAt)
+			if err != nil {
+				row.Err = fmt.Errorf("publish_at must be an RFC 3339 timestamp")
+				rows = append(rows, row)
+				continue
+			}
+			row.Movie.PublishAt = &parsed
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readCatalogJSONL(reader io.Reader) ([]parsedCatalogRow, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	rows := make([]parsedCatalogRow, 0)
+	rowNumber := 0
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		rowNumber++
+
+		var record catalogRow
+
+		err := jsoniter.UnmarshalFromString(line, &record)
+		if err != nil {
+			rows = append(rows, parsedCatalogRow{Row: rowNumber, Err: fmt.Errorf("invalid json: %w", err)})
+			continue
+		}
+
+		rows = append(rows, parsedCatalogRow{
+			Row: rowNumber,
+			Movie: repository.NewMovie{
+				Title:       record.Title,
+				Description: record.Description,
+				Duration:    record.Duration,
+				Artist:      record.Artist,
+				Genre:       record.Genre,
+				FileName:    record.FileName,
+				Status:      record.Status,
+				PublishAt:   record.PublishAt,
+			},
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jsonl: %w", err)
+	}
+
+	return rows, nil
+}
+
+// validateCatalogMovie applies the CreateMovie rules and checks that the referenced file was uploaded to the movies directory
+func validateCatalogMovie(movie *repository.NewMovie) error {
+	switch {
+	case movie.Title == "":
+		return fmt.Errorf("title is required")
+	case movie.Description == "":
+		return fmt.Errorf("description is required")
+	case movie.Duration <= 0:
+		return fmt.Errorf("duration must be positive")
+	case movie.Artist == "":
+		return fmt.Errorf("artists is required")
+	case movie.Genre == "":
+		return fmt.Errorf("genres is required")
+	case movie.FileName == "":
+		return fmt.Errorf("file_name is required")
+	case movie.FileName != filepath.Base(movie.FileName):
+		return fmt.Errorf("file_name must not contain a directory")
+	}
+
+	if _, err := os.Stat(filepath.Join(config.Cfg.MovieDir(), movie.FileName)); err != nil {
+		return fmt.Errorf("file %s doesn't exist in the movies directory", movie.FileName)
+	}
+
+	if movie.Status == "" {
+		movie.Status = constant.MovieStatusDraft
+	}
+
+	// archived is accepted too, an export carries every movie and has to import back as it was
+	switch movie.Status {
+	case constant.MovieStatusDraft, constant.MovieStatusPublished, constant.MovieStatusArchived:
+		movie.PublishAt = nil
+	case constant.MovieStatusScheduled:
+		if movie.PublishAt == nil {
+			return fmt.Errorf("publish_at is required for scheduled movies")
+		}
+		utc := movie.PublishAt.UTC()
+		movie.PublishAt = &utc
+	default:
+		return fmt.Errorf("status must be one of draft, scheduled, published or archived")
+	}
+
+	return nil
+}
+
+// catalogWriter writes exported movies in the requested format
+type catalogWriter interface {
+	Write(row catalogRow) error
+	Flush() error
+}
+
+func newCatalogWriter(format string, writer io.Writer) (catalogWriter, error) {
+	switch format {
+	case catalogFormatCSV:
+		csvWriter := csv.NewWriter(writer)
+		if err := csvWriter.Write(catalogColumns); err != nil {
+			return nil, err
+		}
+		return &csvCatalogWriter{writer: csvWriter}, nil
+	case catalogFormatJSONL:
+		return &jsonlCatalogWriter{writer: writer}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+func newCatalogRow(movie repository.Movie) catalogRow {
+	return catalogRow{
+		Id:          movie.Id,
+		Title:       movie.Title,
+		Description: movie.Description,
+		Duration:    movie.Duration,
+		Artist:      movie.Artist,
+		Genre:       movie.Genre,
+		FileName:    path.Base(movie.WatchUrl),
+		WatchUrl:    movie.WatchUrl,
+		Status:      movie.Status,
+		PublishAt:   movie.PublishAt,
+		Views:       movie.Views,
+		Votes:       movie.Vote,
+		Version:     movie.Version,
+	}
+}
+
+type csvCatalogWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvCatalogWriter) Write(row catalogRow) error {
+	publishAt := ""
+	if row.PublishAt != nil {
+		publishAt = row.PublishAt.UTC().Format(time.RFC3339)
+	}
+
+	return w.writer.Write([]string{
+		row.Id,
+		row.Title,
+		row.Description,
+		strconv.Itoa(row.Duration),
+		row.Artist,
+		row.Genre,
+		row.FileName,
+		row.WatchUrl,
+		row.Status,
+		publishAt,
+		strconv.Itoa(row.Views),
+		strconv.Itoa(row.Votes),
+		strconv.Itoa(row.Version),
+	})
+}
+
+func (w *csvCatalogWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type jsonlCatalogWriter struct {
+	writer io.Writer
+}
+
+func (w *jsonlCatalogWriter) Write(row catalogRow) error {
+	line, err := jsoniter.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.writer.Write(append(line, '\n'))
+	return err
+}
+
+func (w *jsonlCatalogWriter) Flush() error {
+	return nil
+}
@@ -2,22 +2,50 @@ package movieuc
 
 import (
 	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
-
-	"go.elastic.co/apm/v2"
+	"time"
 )
 
 func (uc *movieUsecase) CreateMovie(ctx context.Context, req *usecase.CreateMovieRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "CreateMovie", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "CreateMovie", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
-	err := uc.movieRepository.InsertMovieToDB(ctx, req.Title, req.Description, req.Duration, req.Artist, req.Genre, req.FileName)
+	// new movies stay hidden from public reads until an admin publishes them
+	status := req.Status
+	if status == "" {
+		status = constant.MovieStatusDraft
+	}
+
+	var publishAt *time.Time
+	if status == constant.MovieStatusScheduled {
+		utc := req.PublishAt.UTC()
+		publishAt = &utc
+	}
+
+	// the movie and its first revision exist together or not at all
+	err := uc.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		id, err := uc.movieRepository.InsertMovieToDB(ctx, req.Title, req.Description, req.Duration, req.Artist, req.Genre, req.FileName, status, publishAt)
+		if err != nil {
+			return err
+		}
+
+		movie, err := uc.movieRepository.GetMovieByIdFromDB(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return uc.recordRevision(ctx, constant.MovieRevisionActionCreate, req.UserId, repository.Movie{}, movie, req.FileName, 0)
+	})
 	if err != nil {
-		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		resp.SetError(writeErrorCode(err), err.Status(), err.Message(), err)
 		return resp
 	}
 	resp.SetSuccess(http.StatusOK, "00", "Success Create Movie", nil)
@@ -0,0 +1,74 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"path"
+)
+
+// EnrichMovie applies the catalog metadata to the movie, the lookup is repeated so it may differ from an earlier preview
+func (uc *movieUsecase) EnrichMovie(ctx context.Context, req *usecase.EnrichMovieRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "EnrichMovie", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	before, enriched, match, code, err := uc.lookupEnrichment(ctx, req)
+	if err != nil {
+		resp.SetError(code, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	err = checkIfMatch(req.IfMatch, before.Version)
+	if err != nil {
+		resp.SetError(http.StatusPreconditionFailed, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	changes := diffMovies(before, enriched)
+	if len(changes) == 0 {
+		resp.SetHeader("ETag", movieETag(before.Version))
+		resp.SetSuccess(http.StatusOK, "00", "Movie already up to date", usecase.EnrichMovieResponse{
+			Applied: false,
+			Match:   match,
+			Changes: changes,
+			Movie:   before,
+		})
+		return resp
+	}
+
+	var after repository.Movie
+
+	err = uc.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		err := uc.movieRepository.UpdateMovieToDB(ctx, req.Id, enriched.Title, enriched.Description, enriched.Duration, enriched.Artist, enriched.Genre, path.Base(before.WatchUrl), enriched.Year, enriched.Poster, before.Version)
+		if err != nil {
+			return err
+		}
+
+		after, err = uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+		if err != nil {
+			return err
+		}
+
+		return uc.recordRevision(ctx, constant.MovieRevisionActionEnrich, req.UserId, before, after, "", 0)
+	})
+	if err != nil {
+		resp.SetError(writeErrorCode(err), err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetHeader("ETag", movieETag(after.Version))
+	resp.SetSuccess(http.StatusOK, "00", "Success enrich movie", usecase.EnrichMovieResponse{
+		Applied: true,
+		Match:   match,
+		Changes: diffMovies(before, after),
+		Movie:   after,
+	})
+
+	return resp
+}
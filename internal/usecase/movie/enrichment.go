@@ -0,0 +1,67 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/errs"
+	"net/http"
+)
+
+var enrichableFields = []string{"description", "artists", "genres", "year", "poster"}
+
+// lookupEnrichment fetches the movie and its catalog match, returning the movie with the match merged in
+func (uc *movieUsecase) lookupEnrichment(ctx context.Context, req *usecase.EnrichMovieRequest) (repository.Movie, repository.Movie, repository.CatalogMovie, int, errs.MessageErr) {
+	before, err := uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+	if err != nil {
+		return repository.Movie{}, repository.Movie{}, repository.CatalogMovie{}, http.StatusNotFound, err
+	}
+
+	title := req.Title
+	if title == "" {
+		title = before.Title
+	}
+
+	match, err := uc.catalogRepository.LookupMovieFromCatalog(ctx, title, req.Year)
+	if err != nil {
+		code := http.StatusServiceUnavailable
+		if err.Status() == "NC" {
+			code = http.StatusNotFound
+		}
+		return repository.Movie{}, repository.Movie{}, repository.CatalogMovie{}, code, err
+	}
+
+	return before, enrichMovie(before, match, req.Fields, req.Overwrite), match, http.StatusOK, nil
+}
+
+// enrichMovie copies the catalog values of the given fields, by default only into fields that are still empty
+func enrichMovie(movie repository.Movie, match repository.CatalogMovie, fields []string, overwrite bool) repository.Movie {
+	if len(fields) == 0 {
+		fields = enrichableFields
+	}
+
+	fill := func(current *string, value string) {
+		if value != "" && (overwrite || *current == "") {
+			*current = value
+		}
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "description":
+			fill(&movie.Description, match.Description)
+		case "artists":
+			fill(&movie.Artist, match.Artist)
+		case "genres":
+			fill(&movie.Genre, match.Genre)
+		case "poster":
+			fill(&movie.Poster, match.Poster)
+		case "year":
+			if match.Year != 0 && (overwrite || movie.Year == 0) {
+				movie.Year = match.Year
+			}
+		}
+	}
+
+	return movie
+}
@@ -0,0 +1,45 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// ExportMovies streams the whole catalog, including votes and view counts, to req.Writer
+func (uc *movieUsecase) ExportMovies(ctx context.Context, req *usecase.ExportMoviesRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "ExportMovies", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	writer, writeErr := newCatalogWriter(req.Format, req.Writer)
+	if writeErr != nil {
+		resp.SetError(http.StatusInternalServerError, "FS", "Failed Stream", writeErr)
+		return resp
+	}
+
+	exported := 0
+
+	err := uc.movieRepository.StreamMoviesFromDB(ctx, func(movie repository.Movie) error {
+		exported++
+		return writer.Write(newCatalogRow(movie))
+	})
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	if writeErr := writer.Flush(); writeErr != nil {
+		resp.SetError(http.StatusInternalServerError, "FS", "Failed Stream", writeErr)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success export movies", usecase.ExportMoviesResponse{
+		Exported: exported,
+	})
+
+	return resp
+}
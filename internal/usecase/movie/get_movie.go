@@ -0,0 +1,26 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+func (uc *movieUsecase) GetMovie(ctx context.Context, req *usecase.GetMovieRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "GetMovie", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	movie, err := uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetHeader("ETag", movieETag(movie.Version))
+	resp.SetSuccess(http.StatusOK, "00", "Success get movie", movie)
+
+	return resp
+}
@@ -0,0 +1,33 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+func (uc *movieUsecase) GetMovieRevisions(ctx context.Context, req *usecase.GetMovieRevisionsRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "GetMovieRevisions", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	_, err := uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	revisions, err := uc.movieRepository.GetMovieRevisionsFromDB(ctx, req.Id)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success get movie revisions", usecase.GetMovieRevisionsResponse{
+		Revisions: revisions,
+	})
+
+	return resp
+}
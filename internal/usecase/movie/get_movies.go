@@ -2,20 +2,20 @@ package movieuc
 
 import (
 	"context"
+	"lion-parcel-test/constant"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
-
-	"go.elastic.co/apm/v2"
 )
 
 func (uc *movieUsecase) GetMovies(ctx context.Context, req *usecase.GetMoviesRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "CreateMovie", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "CreateMovie", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
-	movie, paginationMetadata, err := uc.movieRepository.GetMoviesFromDB(ctx, req.Page, req.PageSize)
+	movie, paginationMetadata, err := uc.movieRepository.GetMoviesFromDB(ctx, constant.MovieStatusPublished, req.Page, req.PageSize)
 	if err != nil {
 		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
 		return resp
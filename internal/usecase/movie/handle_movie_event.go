@@ -0,0 +1,110 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/tracing"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var defaultVoteThresholds = []int{10, 100, 1000}
+
+type movieWebhookData struct {
+	Movie   repository.Movie                  `json:"movie"`
+	Changes map[string]repository.FieldChange `json:"changes,omitempty"`
+}
+
+type voteThresholdWebhookData struct {
+	MovieId   int `json:"movie_id"`
+	Votes     int `json:"votes"`
+	Threshold int `json:"threshold"`
+}
+
+// HandleMovieEvent turns movie and vote domain events into webhook events, the outbox id doubles as the webhook id
+// so a redelivered event is queued only once
+func (uc *movieUsecase) HandleMovieEvent(ctx context.Context, event usecase.DomainEvent) error {
+	span, ctx := tracing.StartSpan(ctx, "HandleMovieEvent", "usecase")
+	defer span.End()
+
+	id := strconv.FormatInt(event.Id, 10)
+
+	var webhookEvent string
+	var data interface{}
+
+	switch event.Name {
+	case constant.EventMovieCreated:
+		var payload repository.MovieCreatedEvent
+		if !decodeEvent(event, &payload) {
+			return nil
+		}
+
+		webhookEvent = constant.WebhookEventMovieCreated
+		data = movieWebhookData{Movie: payload.Movie}
+	case constant.EventMovieUpdated:
+		var payload repository.MovieUpdatedEvent
+		if !decodeEvent(event, &payload) {
+			return nil
+		}
+
+		webhookEvent = constant.WebhookEventMovieUpdated
+		if payload.After.Status == constant.MovieStatusPublished && payload.Before.Status != constant.MovieStatusPublished {
+			webhookEvent = constant.WebhookEventMoviePublished
+		}
+		data = movieWebhookData{Movie: payload.After, Changes: diffMovies(payload.Before, payload.After)}
+	case constant.EventVoteCast:
+		var payload repository.VoteEvent
+		if !decodeEvent(event, &payload) {
+			return nil
+		}
+
+		threshold, crossed := voteThreshold(payload.Votes)
+		if !crossed {
+			return nil
+		}
+
+		webhookEvent = constant.WebhookEventVoteThresholdCrossed
+		data = voteThresholdWebhookData{MovieId: payload.MovieId, Votes: payload.Votes, Threshold: threshold}
+	default:
+		return nil
+	}
+
+	err := uc.webhookNotifier.NotifyWebhooks(ctx, id, webhookEvent, data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// decodeEvent logs and skips a payload it can't read, retrying it would never succeed
+func decodeEvent(event usecase.DomainEvent, payload interface{}) bool {
+	err := jsoniter.Unmarshal(event.Payload, payload)
+	if err != nil {
+		log.Sugar.Errorw("skipping unreadable domain event", "id", event.Id, "event", event.Name, "error", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// voteThreshold reports whether votes landed exactly on a threshold, unvote and revote can cross it again
+func voteThreshold(votes int) (int, bool) {
+	thresholds := config.Cfg.Webhooks.VoteThresholds
+	if len(thresholds) == 0 {
+		thresholds = defaultVoteThresholds
+	}
+
+	for _, threshold := range thresholds {
+		if votes == threshold {
+			return threshold, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,154 @@
+package movieuc
+
+import (
+	"context"
+	"fmt"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+const (
+	importModeAtomic       = "atomic"
+	importModeBatch        = "batch"
+	defaultImportBatchSize = 100
+	maxImportBatchSize     = 10000
+)
+
+func (uc *movieUsecase) ImportMovies(ctx context.Context, req *usecase.ImportMoviesRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "ImportMovies", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	mode := req.Mode
+	if mode == "" {
+		mode = importModeAtomic
+	}
+
+	batchSize := req.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	// the cli doesn't go through the validator of the handler
+	if mode != importModeAtomic && mode != importModeBatch {
+		resp.SetError(http.StatusBadRequest, "VE", "Validation Error", fmt.Errorf("mode must be %s or %s, got %q", importModeAtomic, importModeBatch, mode))
+		return resp
+	}
+	if batchSize < 1 || batchSize > maxImportBatchSize {
+		resp.SetError(http.StatusBadRequest, "VE", "Validation Error", fmt.Errorf("batch size must be between 1 and %d, got %d", maxImportBatchSize, batchSize))
+		return resp
+	}
+
+	rows, readErr := readCatalog(req.Format, req.Reader)
+	if readErr != nil {
+		resp.SetError(http.StatusBadRequest, "VE", "Invalid Catalog", readErr)
+		return resp
+	}
+
+	report := usecase.ImportMoviesResponse{
+		DryRun: req.DryRun,
+		Mode:   mode,
+		Total:  len(rows),
+		Errors: make([]usecase.ImportRowError, 0),
+	}
+
+	validRows := make([]parsedCatalogRow, 0, len(rows))
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Err = validateCatalogMovie(&row.Movie)
+		}
+
+		if row.Err != nil {
+			report.Errors = append(report.Errors, usecase.ImportRowError{Row: row.Row, Error: row.Err.Error()})
+			continue
+		}
+
+		validRows = append(validRows, row)
+	}
+	report.Valid = len(validRows)
+
+	if req.DryRun {
+		resp.SetSuccess(http.StatusOK, "00", "Success validate movies", report)
+		return resp
+	}
+
+	// atomic imports are all or nothing, so a single invalid row rejects the file
+	if mode == importModeAtomic {
+		if len(report.Errors) > 0 {
+			resp.SetSuccess(http.StatusUnprocessableEntity, "VE", "Catalog has invalid rows", report)
+			return resp
+		}
+		batchSize = len(validRows)
+	}
+
+	for start := 0; start < len(validRows); start += batchSize {
+		end := min(start+batchSize, len(validRows))
+		batch := validRows[start:end]
+
+		ids, err := uc.insertCatalogBatch(ctx, req.UserId, batch)
+		if err != nil {
+			if mode == importModeAtomic {
+				resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+				return resp
+			}
+
+			for _, row := range batch {
+				report.Errors = append(report.Errors, usecase.ImportRowError{Row: row.Row, Error: "batch failed: " + err.Error()})
+			}
+			continue
+		}
+		report.Imported += len(ids)
+	}
+
+	if len(report.Errors) > 0 {
+		resp.SetSuccess(http.StatusOK, "PI", "Movies partially imported", report)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success import movies", report)
+
+	return resp
+}
+
+// insertCatalogBatch inserts the movies of a batch together with their revisions, a failure leaves nothing behind
+func (uc *movieUsecase) insertCatalogBatch(ctx context.Context, userId int, batch []parsedCatalogRow) ([]string, errs.MessageErr) {
+	movies := make([]repository.NewMovie, 0, len(batch))
+	for _, row := range batch {
+		movies = append(movies, row.Movie)
+	}
+
+	var ids []string
+
+	err := uc.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		var err errs.MessageErr
+
+		ids, err = uc.movieRepository.InsertMoviesToDB(ctx, movies)
+		if err != nil {
+			return err
+		}
+
+		for i, id := range ids {
+			movie, err := uc.movieRepository.GetMovieByIdFromDB(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			err = uc.recordRevision(ctx, constant.MovieRevisionActionCreate, userId, repository.Movie{}, movie, movies[i].FileName, 0)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -6,11 +6,17 @@ import (
 )
 
 type movieUsecase struct {
-	movieRepository repository.MovieRepository
+	movieRepository   repository.MovieRepository
+	catalogRepository repository.CatalogRepository
+	transactor        repository.Transactor
+	webhookNotifier   usecase.WebhookNotifier
 }
 
-func NewMovieUsecase(movieRepository repository.MovieRepository) usecase.MovieUsecase {
+func NewMovieUsecase(movieRepository repository.MovieRepository, catalogRepository repository.CatalogRepository, transactor repository.Transactor, webhookNotifier usecase.WebhookNotifier) usecase.MovieUsecase {
 	return &movieUsecase{
-		movieRepository: movieRepository,
+		movieRepository:   movieRepository,
+		catalogRepository: catalogRepository,
+		transactor:        transactor,
+		webhookNotifier:   webhookNotifier,
 	}
 }
@@ -0,0 +1,269 @@
+package movieuc_test
+
+import (
+	"context"
+	"errors"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	memoryrepo "lion-parcel-test/internal/repository/memory"
+	movieuc "lion-parcel-test/internal/usecase/movie"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type fixture struct {
+	store   *memoryrepo.Store
+	movies  repository.MovieRepository
+	users   repository.UserRepository
+	usecase usecase.MovieUsecase
+}
+
+// newFixture wires the movie usecase to in-memory repositories, the catalog and webhooks aren't used by these tests
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	store := memoryrepo.NewStore()
+	f := &fixture{
+		store:  store,
+		movies: memoryrepo.NewMovieRepository(store),
+		users:  memoryrepo.NewUserRepository(store),
+	}
+	f.usecase = movieuc.NewMovieUsecase(f.movies, nil, memoryrepo.NewTransactor(store), nil)
+
+	return f
+}
+
+func (f *fixture) createMovie(t *testing.T, title string, status string) string {
+	t.Helper()
+
+	resp := f.usecase.CreateMovie(context.Background(), &usecase.CreateMovieRequest{
+		Title:       title,
+		Description: title + " description",
+		Duration:    100,
+		Artist:      "Artist",
+		Genre:       "Drama",
+		FileName:    title + ".mp4",
+		Status:      status,
+		UserId:      1,
+	})
+	expectResponse(t, resp, http.StatusOK, "00")
+
+	movies, _ := f.movies.SearchMoviesFromDB(context.Background(), "", title)
+	if len(movies) != 1 {
+		t.Fatalf("expected the created movie %s, found %+v", title, movies)
+	}
+
+	return movies[0].Id
+}
+
+func (f *fixture) createUser(t *testing.T, name string) int {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := f.users.InsertUserToDB(ctx, name+"@example.com", name); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := f.users.GetUserFromDbByEmail(ctx, name+"@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user.ID
+}
+
+func expectResponse(t *testing.T, resp *dto.Response, httpCode int, code string) {
+	t.Helper()
+
+	if resp.HttpCode != httpCode || resp.Code != code {
+		t.Fatalf("expected %d %s, got %d %s: %s %+v", httpCode, code, resp.HttpCode, resp.Code, resp.Desc, resp.Data)
+	}
+}
+
+func TestCreateMovie(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	id := f.createMovie(t, "Arrival", "")
+
+	movie, _ := f.movies.GetMovieByIdFromDB(ctx, id)
+	if movie.Status != constant.MovieStatusDraft {
+		t.Fatalf("expected a new movie to be a draft, got %s", movie.Status)
+	}
+
+	// drafts stay out of the public list
+	resp := f.usecase.GetMovies(ctx, &usecase.GetMoviesRequest{Page: 1, PageSize: 10})
+	expectResponse(t, resp, http.StatusOK, "00")
+	if movies := resp.Data.(usecase.GetMoviesResponse).Movies.([]repository.Movie); len(movies) != 0 {
+		t.Fatalf("draft listed publicly %+v", movies)
+	}
+
+	revisions, _ := f.movies.GetMovieRevisionsFromDB(ctx, id)
+	if len(revisions) != 1 || revisions[0].Action != constant.MovieRevisionActionCreate || revisions[0].Changes["title"].To != "Arrival" {
+		t.Fatalf("unexpected revisions %+v", revisions)
+	}
+}
+
+// failingRevisions fails every revision insert, so the write it belongs to has to be undone
+type failingRevisions struct {
+	repository.MovieRepository
+}
+
+func (r failingRevisions) InsertMovieRevisionToDB(ctx context.Context, revision repository.MovieRevision) errs.MessageErr {
+	return errs.NewCustomErrs("Failed Insert Database", "FD", errors.New("disk full").Error())
+}
+
+func TestCreateMovieWithoutRevisionIsRolledBack(t *testing.T) {
+	store := memoryrepo.NewStore()
+	movies := memoryrepo.NewMovieRepository(store)
+	uc := movieuc.NewMovieUsecase(failingRevisions{movies}, nil, memoryrepo.NewTransactor(store), nil)
+
+	resp := uc.CreateMovie(context.Background(), &usecase.CreateMovieRequest{
+		Title:    "Lost",
+		Duration: 100,
+		FileName: "lost.mp4",
+		UserId:   1,
+	})
+	expectResponse(t, resp, http.StatusInternalServerError, "FD")
+
+	_, page, _ := movies.GetMoviesFromDB(context.Background(), "", 1, 10)
+	if page.TotalItems != 0 {
+		t.Fatalf("movie kept without its revision, %d movies", page.TotalItems)
+	}
+}
+
+func TestPatchMovie(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	id := f.createMovie(t, "Heat", constant.MovieStatusPublished)
+
+	resp := f.usecase.PatchMovie(ctx, &usecase.PatchMovieRequest{
+		Id:      id,
+		Patch:   map[string]interface{}{"title": "Heat (1995)", "year": float64(1995)},
+		IfMatch: 1,
+		UserId:  1,
+	})
+	expectResponse(t, resp, http.StatusOK, "00")
+
+	movie := resp.Data.(repository.Movie)
+	if movie.Title != "Heat (1995)" || movie.Year != 1995 || movie.Version != 2 || resp.Headers["ETag"] != `"2"` {
+		t.Fatalf("unexpected patched movie %+v %v", movie, resp.Headers)
+	}
+
+	// a stale If-Match is refused before anything is written
+	resp = f.usecase.PatchMovie(ctx, &usecase.PatchMovieRequest{
+		Id:      id,
+		Patch:   map[string]interface{}{"title": "stale"},
+		IfMatch: 1,
+		UserId:  1,
+	})
+	expectResponse(t, resp, http.StatusPreconditionFailed, "VM")
+
+	resp = f.usecase.PatchMovie(ctx, &usecase.PatchMovieRequest{
+		Id:     id,
+		Patch:  map[string]interface{}{"title": nil},
+		UserId: 1,
+	})
+	expectResponse(t, resp, http.StatusBadRequest, "VE")
+
+	revisions, _ := f.movies.GetMovieRevisionsFromDB(ctx, id)
+	if len(revisions) != 2 || revisions[0].Action != constant.MovieRevisionActionUpdate {
+		t.Fatalf("unexpected revisions %+v", revisions)
+	}
+	// the changes went through JSON, like they do in the database
+	if change := revisions[0].Changes["year"]; change.From != float64(0) || change.To != float64(1995) {
+		t.Fatalf("unexpected year change %+v", change)
+	}
+
+	resp = f.usecase.PatchMovie(ctx, &usecase.PatchMovieRequest{Id: "404", Patch: map[string]interface{}{}, UserId: 1})
+	expectResponse(t, resp, http.StatusNotFound, "NA")
+}
+
+func TestRollbackMovie(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	id := f.createMovie(t, "Memento", constant.MovieStatusPublished)
+	created, _ := f.movies.GetMovieRevisionsFromDB(ctx, id)
+
+	resp := f.usecase.PatchMovie(ctx, &usecase.PatchMovieRequest{Id: id, Patch: map[string]interface{}{"title": "Tnemem"}, UserId: 1})
+	expectResponse(t, resp, http.StatusOK, "00")
+
+	resp = f.usecase.RollbackMovie(ctx, &usecase.RollbackMovieRequest{Id: id, RevisionId: created[0].Id, IfMatch: 2, UserId: 1})
+	expectResponse(t, resp, http.StatusOK, "00")
+
+	movie := resp.Data.(repository.Movie)
+	if movie.Title != "Memento" || movie.Version != 3 {
+		t.Fatalf("unexpected movie after rollback %+v", movie)
+	}
+
+	revisions, _ := f.movies.GetMovieRevisionsFromDB(ctx, id)
+	if revisions[0].Action != constant.MovieRevisionActionRollback || revisions[0].RestoredFrom != created[0].Id {
+		t.Fatalf("unexpected rollback revision %+v", revisions[0])
+	}
+
+	resp = f.usecase.RollbackMovie(ctx, &usecase.RollbackMovieRequest{Id: id, RevisionId: 999, UserId: 1})
+	expectResponse(t, resp, http.StatusNotFound, "NA")
+}
+
+func TestScheduledMovieIsPublished(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	id := f.createMovie(t, "Dune", constant.MovieStatusDraft)
+
+	publishAt := time.Now().Add(-time.Minute)
+	resp := f.usecase.UpdateMovieStatus(ctx, &usecase.UpdateMovieStatusRequest{Id: id, Status: constant.MovieStatusScheduled, PublishAt: &publishAt, UserId: 1})
+	expectResponse(t, resp, http.StatusOK, "00")
+
+	resp = f.usecase.PublishScheduledMovies(ctx)
+	expectResponse(t, resp, http.StatusOK, "00")
+	if published := resp.Data.(usecase.PublishScheduledMoviesResponse).Published; published != 1 {
+		t.Fatalf("expected 1 published movie, got %d", published)
+	}
+
+	resp = f.usecase.GetMovies(ctx, &usecase.GetMoviesRequest{Page: 1, PageSize: 10})
+	movies := resp.Data.(usecase.GetMoviesResponse).Movies.([]repository.Movie)
+	if len(movies) != 1 || movies[0].Id != id {
+		t.Fatalf("published movie not listed %+v", movies)
+	}
+
+	resp = f.usecase.UpdateMovieStatus(ctx, &usecase.UpdateMovieStatusRequest{Id: "404", Status: constant.MovieStatusDraft, UserId: 1})
+	expectResponse(t, resp, http.StatusNotFound, "NA")
+}
+
+func TestVoteMovie(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	userId := f.createUser(t, "alice")
+	movieId, _ := strconv.Atoi(f.createMovie(t, "Up", constant.MovieStatusPublished))
+
+	resp := f.usecase.VoteMovie(ctx, &usecase.VoteMovieRequest{UserId: userId, MovieId: movieId})
+	expectResponse(t, resp, http.StatusOK, "00")
+
+	resp = f.usecase.VoteMovie(ctx, &usecase.VoteMovieRequest{UserId: userId, MovieId: movieId})
+	expectResponse(t, resp, http.StatusNotFound, "AV")
+
+	resp = f.usecase.VoteMovie(ctx, &usecase.VoteMovieRequest{UserId: userId, MovieId: movieId + 1})
+	expectResponse(t, resp, http.StatusNotFound, "NA")
+
+	resp = f.usecase.VotedMovies(ctx, &usecase.VotedMoviesRequest{UserId: userId})
+	if movies := resp.Data.(usecase.VotedMoviesResponse).Movies.([]repository.Movie); len(movies) != 1 || movies[0].Title != "Up" {
+		t.Fatalf("unexpected voted movies %+v", movies)
+	}
+
+	resp = f.usecase.UnvoteMovie(ctx, &usecase.UnvoteMovieRequest{UserId: userId, MovieId: movieId})
+	expectResponse(t, resp, http.StatusOK, "00")
+
+	resp = f.usecase.VotedMovies(ctx, &usecase.VotedMoviesRequest{UserId: userId})
+	if movies := resp.Data.(usecase.VotedMoviesResponse).Movies.([]repository.Movie); len(movies) != 0 {
+		t.Fatalf("vote kept after unvote %+v", movies)
+	}
+}
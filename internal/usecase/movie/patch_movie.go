@@ -0,0 +1,148 @@
+package movieuc
+
+import (
+	"context"
+	"fmt"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"math"
+	"net/http"
+	"path"
+)
+
+// PatchMovie applies a JSON Merge Patch to the movie metadata, the file is only replaced when a new one was uploaded
+func (uc *movieUsecase) PatchMovie(ctx context.Context, req *usecase.PatchMovieRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "PatchMovie", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	before, err := uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	err = checkIfMatch(req.IfMatch, before.Version)
+	if err != nil {
+		resp.SetError(http.StatusPreconditionFailed, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	patched, err := applyMoviePatch(before, req.Patch)
+	if err != nil {
+		resp.SetError(http.StatusBadRequest, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	fileName := req.FileName
+	if fileName == "" {
+		fileName = path.Base(before.WatchUrl)
+	}
+
+	var after repository.Movie
+
+	err = uc.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		err := uc.movieRepository.UpdateMovieToDB(ctx, req.Id, patched.Title, patched.Description, patched.Duration, patched.Artist, patched.Genre, fileName, patched.Year, patched.Poster, before.Version)
+		if err != nil {
+			return err
+		}
+
+		after, err = uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+		if err != nil {
+			return err
+		}
+
+		return uc.recordRevision(ctx, constant.MovieRevisionActionUpdate, req.UserId, before, after, req.FileName, 0)
+	})
+	if err != nil {
+		resp.SetError(writeErrorCode(err), err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetHeader("ETag", movieETag(after.Version))
+	resp.SetSuccess(http.StatusOK, "00", "Success Patch Movie", after)
+
+	return resp
+}
+
+// applyMoviePatch merges the patch into the movie, a null removes an optional field
+func applyMoviePatch(movie repository.Movie, patch map[string]interface{}) (repository.Movie, errs.MessageErr) {
+	for field, value := range patch {
+		var err error
+
+		switch field {
+		case "title":
+			movie.Title, err = patchString(field, value, true)
+		case "description":
+			movie.Description, err = patchString(field, value, false)
+		case "artists":
+			movie.Artist, err = patchString(field, value, false)
+		case "genres":
+			movie.Genre, err = patchString(field, value, false)
+		case "duration":
+			movie.Duration, err = patchDuration(value)
+		case "year":
+			movie.Year, err = patchYear(value)
+		case "poster":
+			movie.Poster, err = patchString(field, value, false)
+		default:
+			err = fmt.Errorf("field %s can't be patched", field)
+		}
+
+		if err != nil {
+			return repository.Movie{}, errs.NewCustomErrs(
+				"Validation Error",
+				"VE",
+				err.Error(),
+			)
+		}
+	}
+
+	return movie, nil
+}
+
+func patchString(field string, value interface{}, required bool) (string, error) {
+	if value == nil {
+		if required {
+			return "", fmt.Errorf("field %s can't be removed", field)
+		}
+		return "", nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s must be a string", field)
+	}
+
+	if required && str == "" {
+		return "", fmt.Errorf("field %s can't be empty", field)
+	}
+
+	return str, nil
+}
+
+func patchDuration(value interface{}) (int, error) {
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) || number <= 0 {
+		return 0, fmt.Errorf("field duration must be a positive integer")
+	}
+
+	return int(number), nil
+}
+
+func patchYear(value interface{}) (int, error) {
+	if value == nil {
+		return 0, nil
+	}
+
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) || number < 0 {
+		return 0, fmt.Errorf("field year must be a positive integer")
+	}
+
+	return int(number), nil
+}
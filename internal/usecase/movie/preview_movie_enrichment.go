@@ -0,0 +1,32 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// PreviewMovieEnrichment shows what EnrichMovie would change without writing anything
+func (uc *movieUsecase) PreviewMovieEnrichment(ctx context.Context, req *usecase.EnrichMovieRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "PreviewMovieEnrichment", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	before, enriched, match, code, err := uc.lookupEnrichment(ctx, req)
+	if err != nil {
+		resp.SetError(code, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetHeader("ETag", movieETag(before.Version))
+	resp.SetSuccess(http.StatusOK, "00", "Success preview movie enrichment", usecase.EnrichMovieResponse{
+		Applied: false,
+		Match:   match,
+		Changes: diffMovies(before, enriched),
+		Movie:   enriched,
+	})
+
+	return resp
+}
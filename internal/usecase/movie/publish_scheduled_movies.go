@@ -0,0 +1,29 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"time"
+)
+
+// PublishScheduledMovies publishes every scheduled movie whose publish_at has passed, it is run by the scheduler
+func (uc *movieUsecase) PublishScheduledMovies(ctx context.Context) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "PublishScheduledMovies", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	published, err := uc.movieRepository.PublishScheduledMoviesToDB(ctx, time.Now().UTC())
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success publish scheduled movies", usecase.PublishScheduledMoviesResponse{
+		Published: published,
+	})
+
+	return resp
+}
@@ -0,0 +1,53 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"time"
+)
+
+// recordRevision stores the change from before to after, before is the zero Movie for a create
+func (uc *movieUsecase) recordRevision(ctx context.Context, action string, userId int, before repository.Movie, after repository.Movie, fileName string, restoredFrom int) errs.MessageErr {
+	return uc.movieRepository.InsertMovieRevisionToDB(ctx, repository.MovieRevision{
+		MovieId:      after.Id,
+		UserId:       userId,
+		Action:       action,
+		Changes:      diffMovies(before, after),
+		Snapshot:     after,
+		FileName:     fileName,
+		RestoredFrom: restoredFrom,
+	})
+}
+
+// diffMovies returns the editable fields that differ between both movies, keyed by their json name
+func diffMovies(before repository.Movie, after repository.Movie) map[string]repository.FieldChange {
+	changes := make(map[string]repository.FieldChange)
+
+	addChange := func(field string, from interface{}, to interface{}) {
+		if from != to {
+			changes[field] = repository.FieldChange{From: from, To: to}
+		}
+	}
+
+	addChange("title", before.Title, after.Title)
+	addChange("description", before.Description, after.Description)
+	addChange("duration", before.Duration, after.Duration)
+	addChange("artists", before.Artist, after.Artist)
+	addChange("genres", before.Genre, after.Genre)
+	addChange("year", before.Year, after.Year)
+	addChange("poster", before.Poster, after.Poster)
+	addChange("watch_url", before.WatchUrl, after.WatchUrl)
+	addChange("status", before.Status, after.Status)
+	addChange("publish_at", timeOrNil(before.PublishAt), timeOrNil(after.PublishAt))
+
+	return changes
+}
+
+func timeOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,72 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"path"
+)
+
+// RollbackMovie restores the metadata and file of a revision, the status workflow is left as it is
+func (uc *movieUsecase) RollbackMovie(ctx context.Context, req *usecase.RollbackMovieRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "RollbackMovie", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	before, err := uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	err = checkIfMatch(req.IfMatch, before.Version)
+	if err != nil {
+		resp.SetError(http.StatusPreconditionFailed, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	revision, err := uc.movieRepository.GetMovieRevisionFromDB(ctx, req.Id, req.RevisionId)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	target := revision.Snapshot
+	fileName := path.Base(target.WatchUrl)
+
+	var after repository.Movie
+
+	err = uc.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		err := uc.movieRepository.UpdateMovieToDB(ctx, req.Id, target.Title, target.Description, target.Duration, target.Artist, target.Genre, fileName, target.Year, target.Poster, before.Version)
+		if err != nil {
+			return err
+		}
+
+		after, err = uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+		if err != nil {
+			return err
+		}
+
+		// only point at the file when the rollback actually switched it
+		revisionFileName := ""
+		if after.WatchUrl != before.WatchUrl {
+			revisionFileName = fileName
+		}
+
+		return uc.recordRevision(ctx, constant.MovieRevisionActionRollback, req.UserId, before, after, revisionFileName, revision.Id)
+	})
+	if err != nil {
+		resp.SetError(writeErrorCode(err), err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetHeader("ETag", movieETag(after.Version))
+	resp.SetSuccess(http.StatusOK, "00", "Success Rollback Movie", after)
+
+	return resp
+}
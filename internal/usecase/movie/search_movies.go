@@ -2,20 +2,20 @@ package movieuc
 
 import (
 	"context"
+	"lion-parcel-test/constant"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
-
-	"go.elastic.co/apm/v2"
 )
 
 func (uc *movieUsecase) SearchMovies(ctx context.Context, req *usecase.SearchMoviesRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "CreateMovie", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "CreateMovie", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
-	movies, err := uc.movieRepository.SearchMoviesFromDB(ctx, req.Keyword)
+	movies, err := uc.movieRepository.SearchMoviesFromDB(ctx, constant.MovieStatusPublished, req.Keyword)
 	if err != nil {
 		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
 		return resp
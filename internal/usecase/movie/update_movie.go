@@ -2,25 +2,54 @@ package movieuc
 
 import (
 	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
-
-	"go.elastic.co/apm/v2"
 )
 
 func (uc *movieUsecase) UpdateMovie(ctx context.Context, req *usecase.UpdateMovieRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "UpdateMovie", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "UpdateMovie", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
-	err := uc.movieRepository.UpdateMovieToDB(ctx, req.Id, req.Title, req.Description, req.Duration, req.Artist, req.Genre, req.FileName)
+	before, err := uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
 	if err != nil {
 		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
 		return resp
 	}
-	resp.SetSuccess(http.StatusOK, "00", "Success Update Movie", nil)
+
+	err = checkIfMatch(req.IfMatch, before.Version)
+	if err != nil {
+		resp.SetError(http.StatusPreconditionFailed, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	var after repository.Movie
+
+	err = uc.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		err := uc.movieRepository.UpdateMovieToDB(ctx, req.Id, req.Title, req.Description, req.Duration, req.Artist, req.Genre, req.FileName, before.Year, before.Poster, before.Version)
+		if err != nil {
+			return err
+		}
+
+		after, err = uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+		if err != nil {
+			return err
+		}
+
+		return uc.recordRevision(ctx, constant.MovieRevisionActionUpdate, req.UserId, before, after, req.FileName, 0)
+	})
+	if err != nil {
+		resp.SetError(writeErrorCode(err), err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetHeader("ETag", movieETag(after.Version))
+	resp.SetSuccess(http.StatusOK, "00", "Success Update Movie", after)
 
 	return resp
 }
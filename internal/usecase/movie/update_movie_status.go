@@ -0,0 +1,52 @@
+package movieuc
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"time"
+)
+
+func (uc *movieUsecase) UpdateMovieStatus(ctx context.Context, req *usecase.UpdateMovieStatusRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "UpdateMovieStatus", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	before, err := uc.movieRepository.GetMovieByIdFromDB(ctx, req.Id)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	// publish_at only matters while the movie waits for the scheduler
+	var publishAt *time.Time
+	if req.Status == constant.MovieStatusScheduled {
+		utc := req.PublishAt.UTC()
+		publishAt = &utc
+	}
+
+	err = uc.transactor.WithTx(ctx, func(ctx context.Context) errs.MessageErr {
+		err := uc.movieRepository.UpdateMovieStatusToDB(ctx, req.Id, req.Status, publishAt)
+		if err != nil {
+			return err
+		}
+
+		after := before
+		after.Status = req.Status
+		after.PublishAt = publishAt
+
+		return uc.recordRevision(ctx, constant.MovieRevisionActionStatus, req.UserId, before, after, "", 0)
+	})
+	if err != nil {
+		resp.SetError(writeErrorCode(err), err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success Update Movie Status", nil)
+
+	return resp
+}
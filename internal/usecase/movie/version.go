@@ -0,0 +1,37 @@
+package movieuc
+
+import (
+	"lion-parcel-test/pkg/errs"
+	"net/http"
+	"strconv"
+)
+
+// movieETag is the ETag of a movie at the given version
+func movieETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// checkIfMatch fails when the client asked for a version other than the current one, 0 means no If-Match was sent
+func checkIfMatch(ifMatch int, current int) errs.MessageErr {
+	if ifMatch == 0 || ifMatch == current {
+		return nil
+	}
+
+	return errs.NewCustomErrs(
+		"Version Mismatch",
+		"VM",
+		"movie is at version "+strconv.Itoa(current)+", not "+strconv.Itoa(ifMatch),
+	)
+}
+
+// writeErrorCode maps the error of a movie write to its http code
+func writeErrorCode(err errs.MessageErr) int {
+	switch err.Status() {
+	case "NA":
+		return http.StatusNotFound
+	case "VM":
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -4,14 +4,16 @@ import (
 	"context"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/metrics"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
-
-	"go.elastic.co/apm/v2"
 )
 
+var votesCast = metrics.NewCounter("movie_votes_total", "Votes cast on movies.")
+
 func (uc *movieUsecase) VoteMovie(ctx context.Context, req *usecase.VoteMovieRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "VoteMovie", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "VoteMovie", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
@@ -21,6 +23,8 @@ func (uc *movieUsecase) VoteMovie(ctx context.Context, req *usecase.VoteMovieReq
 		return resp
 	}
 
+	votesCast.Inc()
+
 	resp.SetSuccess(http.StatusOK, "00", "Success vote", nil)
 
 	return resp
@@ -4,14 +4,13 @@ import (
 	"context"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
-
-	"go.elastic.co/apm/v2"
 )
 
 func (uc *movieUsecase) VotedMovies(ctx context.Context, req *usecase.VotedMoviesRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "UnvoteMovie", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "UnvoteMovie", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
@@ -0,0 +1,62 @@
+package ratelimituc
+
+import (
+	"context"
+	"lion-parcel-test/config"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"strconv"
+)
+
+func (uc *rateLimitUsecase) Allow(ctx context.Context, req *usecase.RateLimitRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "Allow", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	rule, ok := config.Cfg.RateLimit.Rules[req.Rule]
+	if !ok || rule.Limit <= 0 || rule.Period <= 0 {
+		resp.SetSuccess(http.StatusOK, "00", "Not Limited", nil)
+		return resp
+	}
+
+	limit, err := uc.rateLimitRepository.TakeRateLimitToken(ctx, req.Rule, rateLimitKey(rule.Key, req), rule.Limit, rule.Period)
+	if err != nil {
+		// a store that can't be reached lets the requests through, the limits are a protection not a dependency
+		tracing.CaptureError(ctx, err)
+		resp.SetSuccess(http.StatusOK, "00", "Not Limited", nil)
+		return resp
+	}
+
+	resp.SetHeader("RateLimit-Limit", strconv.Itoa(limit.Limit))
+	resp.SetHeader("RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+	resp.SetHeader("RateLimit-Reset", seconds(limit.Reset))
+	resp.SetHeader("RateLimit-Policy", strconv.Itoa(rule.Limit)+";w="+seconds(rule.Period))
+
+	if !limit.Allowed {
+		resp.SetHeader("Retry-After", seconds(limit.RetryAfter))
+		resp.SetError(http.StatusTooManyRequests, "RL", "Too Many Requests", nil)
+		return resp
+	}
+
+	resp.SetSuccess(http.StatusOK, "00", "Allowed", nil)
+
+	return resp
+}
+
+// rateLimitKey is what the requests are counted by, the ip when the rule counts by user and the caller is anonymous
+func rateLimitKey(key string, req *usecase.RateLimitRequest) string {
+	switch key {
+	case constant.RateLimitKeyRoute:
+		return "route:" + req.Route
+	case constant.RateLimitKeyUser:
+		if req.UserId != 0 {
+			return "user:" + strconv.Itoa(req.UserId)
+		}
+	}
+
+	return "ip:" + req.Ip
+}
@@ -0,0 +1,24 @@
+package ratelimituc
+
+import (
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"math"
+	"strconv"
+	"time"
+)
+
+type rateLimitUsecase struct {
+	rateLimitRepository repository.RateLimitRepository
+}
+
+func NewRateLimitUsecase(rateLimitRepository repository.RateLimitRepository) usecase.RateLimitUsecase {
+	return &rateLimitUsecase{
+		rateLimitRepository: rateLimitRepository,
+	}
+}
+
+// seconds rounds d up, the RateLimit and Retry-After headers count whole seconds
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
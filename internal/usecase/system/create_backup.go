@@ -0,0 +1,65 @@
+package systemuc
+
+import (
+	"context"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// CreateBackup takes a snapshot of the database, then removes the ones past backup.retention
+func (uc *systemUsecase) CreateBackup(ctx context.Context) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "CreateBackup", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	backup, err := uc.backupRepository.CreateBackup(ctx)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if err.Status() == "BN" {
+			code = http.StatusNotImplemented
+		}
+
+		resp.SetError(code, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	resp.SetSuccess(http.StatusCreated, "00", "Success create backup", usecase.CreateBackupResponse{
+		Backup: backup,
+		Pruned: uc.pruneBackups(ctx),
+	})
+
+	return resp
+}
+
+// pruneBackups keeps the newest backup.retention backups, a failure only costs disk space so it is logged
+func (uc *systemUsecase) pruneBackups(ctx context.Context) []string {
+	pruned := make([]string, 0)
+
+	retention := config.Cfg.Backup.Retention
+	if retention <= 0 {
+		return pruned
+	}
+
+	backups, err := uc.backupRepository.GetBackups(ctx)
+	if err != nil {
+		log.Ctx(ctx).Errorw("failed to list backups", "error", err.Error())
+		return pruned
+	}
+
+	for i := retention; i < len(backups); i++ {
+		err = uc.backupRepository.DeleteBackup(ctx, backups[i].Name)
+		if err != nil {
+			log.Ctx(ctx).Errorw("failed to remove backup", "backup", backups[i].Name, "error", err.Error())
+			continue
+		}
+
+		pruned = append(pruned, backups[i].Name)
+	}
+
+	return pruned
+}
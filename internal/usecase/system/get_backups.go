@@ -0,0 +1,29 @@
+package systemuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// GetBackups lists the backups, newest first
+func (uc *systemUsecase) GetBackups(ctx context.Context) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "GetBackups", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	backups, err := uc.backupRepository.GetBackups(ctx)
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	resp.SetSuccess(http.StatusOK, "00", "Success get backups", usecase.GetBackupsResponse{
+		Backups: backups,
+	})
+
+	return resp
+}
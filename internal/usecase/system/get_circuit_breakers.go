@@ -0,0 +1,23 @@
+package systemuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/httpclient"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+func (uc *systemUsecase) GetCircuitBreakers(ctx context.Context) *dto.Response {
+	span, _ := tracing.StartSpan(ctx, "GetCircuitBreakers", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	resp.SetSuccess(http.StatusOK, "00", "Success get circuit breakers", usecase.GetCircuitBreakersResponse{
+		CircuitBreakers: httpclient.Client.CircuitBreakers(),
+	})
+
+	return resp
+}
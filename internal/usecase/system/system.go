@@ -0,0 +1,16 @@
+package systemuc
+
+import (
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+)
+
+type systemUsecase struct {
+	backupRepository repository.BackupRepository
+}
+
+func NewSystemUsecase(backupRepository repository.BackupRepository) usecase.SystemUsecase {
+	return &systemUsecase{
+		backupRepository: backupRepository,
+	}
+}
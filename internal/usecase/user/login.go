@@ -6,25 +6,50 @@ import (
 	"lion-parcel-test/internal/interfaces/repository"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
-	"go.elastic.co/apm/v2"
+)
+
+const (
+	defaultLockoutWindow      = 15 * time.Minute
+	defaultLockoutDuration    = time.Minute
+	defaultLockoutMaxDuration = time.Hour
 )
 
 func (uc *userUsecase) Login(ctx context.Context, req *usecase.LoginRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "Register", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "Login", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
+	keys := loginKeys(req)
+
+	if lockedFor := uc.loginLock(ctx, keys); lockedFor > 0 {
+		resp.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(lockedFor.Seconds()))))
+		resp.SetError(http.StatusTooManyRequests, "LO", "Too Many Failed Logins", nil)
+		return resp
+	}
+
 	user, err := uc.userRepository.GetUserFromDbByEmail(ctx, req.Email)
 	if err != nil {
+		if err.Status() == "NA" {
+			uc.addLoginFailure(ctx, keys)
+		}
+
 		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
 		return resp
 	}
 
+	// only the failures aimed at this account are forgotten, the ones of the ip run out with the window,
+	// otherwise logging in to an account of one's own between guesses would reset them
+	uc.resetLoginFailures(ctx, emailLoginKey(req.Email))
+
 	token := generateToken(user)
 
 	resp.SetSuccess(http.StatusOK, "00", "Success Login", usecase.LoginResponse{
@@ -46,3 +71,106 @@ func generateToken(u repository.User) string {
 
 	return tokenString
 }
+
+// lockoutEnabled is off without rate_limit.lockout.max_failures
+func lockoutEnabled() bool {
+	return config.Cfg.RateLimit.Lockout.MaxFailures > 0
+}
+
+// loginKeys are the counters a failed login adds to, the caller's ip when it is known and the email it was aimed at
+func loginKeys(req *usecase.LoginRequest) []string {
+	if !lockoutEnabled() {
+		return nil
+	}
+
+	keys := []string{emailLoginKey(req.Email)}
+	if req.Ip != "" {
+		keys = append(keys, "ip:"+req.Ip)
+	}
+
+	return keys
+}
+
+func emailLoginKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// loginLock is how long the longest lock of keys still lasts. The store failing lets the login through
+func (uc *userUsecase) loginLock(ctx context.Context, keys []string) time.Duration {
+	var longest time.Duration
+
+	for _, key := range keys {
+		lockedFor, err := uc.rateLimitRepository.GetLoginLock(ctx, key)
+		if err != nil {
+			tracing.CaptureError(ctx, err)
+			continue
+		}
+
+		longest = max(longest, lockedFor)
+	}
+
+	return longest
+}
+
+// addLoginFailure locks every key out once it reached max_failures, for twice as long with every failure after that
+func (uc *userUsecase) addLoginFailure(ctx context.Context, keys []string) {
+	cfg := config.Cfg.RateLimit.Lockout
+
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultLockoutWindow
+	}
+
+	for _, key := range keys {
+		failures, err := uc.rateLimitRepository.AddLoginFailure(ctx, key, window)
+		if err != nil {
+			tracing.CaptureError(ctx, err)
+			continue
+		}
+
+		if failures < int64(cfg.MaxFailures) {
+			continue
+		}
+
+		err = uc.rateLimitRepository.LockLogin(ctx, key, lockoutDuration(failures-int64(cfg.MaxFailures)))
+		if err != nil {
+			tracing.CaptureError(ctx, err)
+		}
+	}
+}
+
+func (uc *userUsecase) resetLoginFailures(ctx context.Context, key string) {
+	if !lockoutEnabled() {
+		return
+	}
+
+	err := uc.rateLimitRepository.ResetLoginFailures(ctx, key)
+	if err != nil {
+		tracing.CaptureError(ctx, err)
+	}
+}
+
+// lockoutDuration doubles lockout.duration for every failure past max_failures, up to lockout.max_duration
+func lockoutDuration(extraFailures int64) time.Duration {
+	cfg := config.Cfg.RateLimit.Lockout
+
+	duration := cfg.Duration
+	if duration <= 0 {
+		duration = defaultLockoutDuration
+	}
+
+	maxDuration := cfg.MaxDuration
+	if maxDuration <= 0 {
+		maxDuration = defaultLockoutMaxDuration
+	}
+
+	for ; extraFailures > 0 && duration < maxDuration; extraFailures-- {
+		duration *= 2
+	}
+
+	if duration > maxDuration {
+		return maxDuration
+	}
+
+	return duration
+}
@@ -5,14 +5,14 @@ import (
 	"errors"
 	"lion-parcel-test/config"
 	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/tracing"
 
 	"github.com/golang-jwt/jwt"
-	"go.elastic.co/apm/v2"
 )
 
 func (uc *userUsecase) PopulateSession(ctx context.Context, token string) *usecase.UserSession {
-	apmSpan, ctx := apm.StartSpan(ctx, "IsAdmin", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "IsAdmin", "usecase")
+	defer span.End()
 
 	tokenString, errp := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -4,14 +4,16 @@ import (
 	"context"
 	"lion-parcel-test/internal/interfaces/usecase"
 	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/metrics"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
-
-	"go.elastic.co/apm/v2"
 )
 
+var registrations = metrics.NewCounter("user_registrations_total", "Users registered.")
+
 func (uc *userUsecase) Register(ctx context.Context, req *usecase.RegisterRequest) *dto.Response {
-	apmSpan, ctx := apm.StartSpan(ctx, "Register", "usecase")
-	defer apmSpan.End()
+	span, ctx := tracing.StartSpan(ctx, "Register", "usecase")
+	defer span.End()
 
 	resp := dto.New()
 
@@ -20,6 +22,8 @@ func (uc *userUsecase) Register(ctx context.Context, req *usecase.RegisterReques
 		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
 		return resp
 	}
+	registrations.Inc()
+
 	resp.SetSuccess(http.StatusOK, "00", "Success Register", nil)
 
 	return resp
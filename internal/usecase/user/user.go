@@ -6,11 +6,13 @@ import (
 )
 
 type userUsecase struct {
-	userRepository repository.UserRepository
+	userRepository      repository.UserRepository
+	rateLimitRepository repository.RateLimitRepository
 }
 
-func NewUserUsecase(userRepository repository.UserRepository) usecase.UserUsecase {
+func NewUserUsecase(userRepository repository.UserRepository, rateLimitRepository repository.RateLimitRepository) usecase.UserUsecase {
 	return &userUsecase{
-		userRepository: userRepository,
+		userRepository:      userRepository,
+		rateLimitRepository: rateLimitRepository,
 	}
 }
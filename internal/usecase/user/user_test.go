@@ -0,0 +1,131 @@
+package useruc_test
+
+import (
+	"context"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/adapters/ratelimit/memory"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	memoryrepo "lion-parcel-test/internal/repository/memory"
+	ratelimitrepo "lion-parcel-test/internal/repository/ratelimit"
+	useruc "lion-parcel-test/internal/usecase/user"
+	"lion-parcel-test/pkg/dto"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	config.Cfg = &config.Config{}
+	config.Cfg.Jwt.SecretKey = "test-secret"
+
+	os.Exit(m.Run())
+}
+
+func newRateLimitRepository() repository.RateLimitRepository {
+	return ratelimitrepo.NewRateLimitRepository(memory.NewMemoryStore())
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	uc := useruc.NewUserUsecase(memoryrepo.NewUserRepository(memoryrepo.NewStore()), newRateLimitRepository())
+
+	resp := uc.Register(ctx, &usecase.RegisterRequest{Email: "alice@example.com", Name: "alice"})
+	if resp.Code != "00" {
+		t.Fatalf("unexpected register response %+v", resp)
+	}
+
+	resp = uc.Register(ctx, &usecase.RegisterRequest{Email: "alice@example.com", Name: "alice again"})
+	if resp.Code != "FD" {
+		t.Fatalf("expected a duplicate email to be refused, got %+v", resp)
+	}
+
+	resp = uc.Login(ctx, &usecase.LoginRequest{Email: "alice@example.com"})
+	if resp.Code != "00" || resp.HttpCode != http.StatusOK {
+		t.Fatalf("unexpected login response %+v", resp)
+	}
+
+	session := uc.PopulateSession(ctx, resp.Data.(usecase.LoginResponse).Jwt)
+	if session == nil || session.Id == 0 || session.IsAdmin {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	resp = uc.Login(ctx, &usecase.LoginRequest{Email: "nobody@example.com"})
+	if resp.Code != "NA" || resp.HttpCode != http.StatusNotFound {
+		t.Fatalf("expected an unknown email to be refused, got %+v", resp)
+	}
+}
+
+func TestPopulateSession(t *testing.T) {
+	ctx := context.Background()
+	store := memoryrepo.NewStore()
+	uc := useruc.NewUserUsecase(memoryrepo.NewUserRepository(store), newRateLimitRepository())
+
+	uc.Register(ctx, &usecase.RegisterRequest{Email: "admin@example.com", Name: "admin"})
+	store.PromoteAdmin("admin@example.com")
+
+	token := uc.Login(ctx, &usecase.LoginRequest{Email: "admin@example.com"}).Data.(usecase.LoginResponse).Jwt
+
+	session := uc.PopulateSession(ctx, token)
+	if session == nil || !session.IsAdmin {
+		t.Fatalf("expected an admin session, got %+v", session)
+	}
+
+	if session := uc.PopulateSession(ctx, token+"x"); session != nil {
+		t.Fatalf("tampered token accepted %+v", session)
+	}
+
+	// a token of someone else's secret
+	config.Cfg.Jwt.SecretKey = "other-secret"
+	defer func() { config.Cfg.Jwt.SecretKey = "test-secret" }()
+
+	if session := uc.PopulateSession(ctx, token); session != nil {
+		t.Fatalf("token signed with another secret accepted %+v", session)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	uc := useruc.NewUserUsecase(memoryrepo.NewUserRepository(memoryrepo.NewStore()), newRateLimitRepository())
+
+	config.Cfg.RateLimit.Lockout.MaxFailures = 2
+	config.Cfg.RateLimit.Lockout.Duration = time.Minute
+	config.Cfg.RateLimit.Lockout.MaxDuration = 90 * time.Second
+	defer func() { config.Cfg.RateLimit.Lockout.MaxFailures = 0 }()
+
+	uc.Register(ctx, &usecase.RegisterRequest{Email: "alice@example.com", Name: "alice"})
+	uc.Register(ctx, &usecase.RegisterRequest{Email: "mallory@example.com", Name: "mallory"})
+
+	login := func(email string, ip string) *dto.Response {
+		return uc.Login(ctx, &usecase.LoginRequest{Email: email, Ip: ip})
+	}
+
+	// logging in to an account of one's own between guesses doesn't forget them
+	for i := 0; i < 2; i++ {
+		if resp := login("nobody@example.com", "10.0.0.1"); resp.Code != "NA" {
+			t.Fatalf("expected failure %d to be let through, got %+v", i+1, resp)
+		}
+		if resp := login("mallory@example.com", "10.0.0.1"); i == 0 && resp.Code != "00" {
+			t.Fatalf("unexpected login response %+v", resp)
+		}
+	}
+
+	// locked out after max_failures, even for a known email
+	resp := login("alice@example.com", "10.0.0.1")
+	if resp.Code != "LO" || resp.HttpCode != http.StatusTooManyRequests {
+		t.Fatalf("expected the ip to be locked out, got %+v", resp)
+	}
+	if resp.Headers["Retry-After"] != "60" {
+		t.Fatalf("expected to retry after 60s, got %q", resp.Headers["Retry-After"])
+	}
+
+	if resp := login("alice@example.com", "10.0.0.2"); resp.Code != "00" {
+		t.Fatalf("expected another ip to log in, got %+v", resp)
+	}
+
+	// the guessed email is locked from every ip
+	if resp := login("Nobody@example.com", "10.0.0.3"); resp.Code != "LO" {
+		t.Fatalf("expected the guessed email to be locked out, got %+v", resp)
+	}
+}
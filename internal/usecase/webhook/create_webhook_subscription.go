@@ -0,0 +1,49 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// CreateWebhookSubscription is the only response that carries the secret
+func (uc *webhookUsecase) CreateWebhookSubscription(ctx context.Context, req *usecase.CreateWebhookSubscriptionRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "CreateWebhookSubscription", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	secret := req.Secret
+	if secret == "" {
+		generated, randErr := randomHex(32)
+		if randErr != nil {
+			err := errs.NewCustomErrs("Failed Generate Secret", "FG", randErr.Error())
+			resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+			return resp
+		}
+		secret = generated
+	}
+
+	id, err := uc.webhookRepository.InsertWebhookSubscriptionToDB(ctx, repository.WebhookSubscription{
+		Url:    req.Url,
+		Events: req.Events,
+		Secret: secret,
+	})
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	subscription, err := uc.webhookRepository.GetWebhookSubscriptionFromDB(ctx, id)
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetSuccess(http.StatusCreated, "00", "Success create webhook subscription", subscription)
+
+	return resp
+}
@@ -0,0 +1,30 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// DeleteWebhookSubscription removes the subscription together with its delivery log
+func (uc *webhookUsecase) DeleteWebhookSubscription(ctx context.Context, req *usecase.DeleteWebhookSubscriptionRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "DeleteWebhookSubscription", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	err := uc.webhookRepository.DeleteWebhookSubscriptionFromDB(ctx, req.Id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if err.Status() == "NA" {
+			code = http.StatusNotFound
+		}
+		resp.SetError(code, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success delete webhook subscription", nil)
+
+	return resp
+}
@@ -0,0 +1,53 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/log"
+	"time"
+)
+
+// deliver sends one delivery and records the attempt on it and on its subscription
+func (uc *webhookUsecase) deliver(ctx context.Context, subscription repository.WebhookSubscription, delivery repository.WebhookDelivery) (repository.WebhookDelivery, errs.MessageErr) {
+	now := time.Now().UTC()
+
+	response, sendErr := uc.webhookRepository.SendWebhookToEndpoint(ctx, subscription, webhookHeader(subscription, delivery, now), []byte(delivery.Payload))
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = response.StatusCode
+	delivery.ResponseBody = truncate(string(response.Body), maxResponseBodySize)
+	delivery.Status = constant.WebhookDeliveryStatusSucceeded
+	delivery.Error = ""
+
+	if sendErr != nil {
+		delivery.Status = constant.WebhookDeliveryStatusFailed
+		delivery.Error = sendErr.Error()
+	}
+
+	err := uc.webhookRepository.UpdateWebhookDeliveryToDB(ctx, delivery)
+	if err != nil {
+		return delivery, err
+	}
+
+	disabled, err := uc.webhookRepository.RecordWebhookResultToDB(ctx, subscription.Id, sendErr == nil, maxFailures())
+	if err != nil {
+		return delivery, err
+	}
+
+	if disabled {
+		log.Ctx(ctx).Warnw("webhook subscription disabled after repeated failures", "subscription_id", subscription.Id, "url", subscription.Url)
+	}
+
+	return delivery, nil
+}
+
+func truncate(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+
+	return s[:size]
+}
@@ -0,0 +1,94 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DispatchWebhooks sends the oldest pending deliveries, it is run by the scheduler
+func (uc *webhookUsecase) DispatchWebhooks(ctx context.Context) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "DispatchWebhooks", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	deliveries, err := uc.webhookRepository.GetPendingWebhookDeliveriesFromDB(ctx, dispatchBatchSize)
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	subscriptions := make(map[int]repository.WebhookSubscription)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var result usecase.DispatchWebhooksResponse
+
+	sem := make(chan struct{}, dispatchConcurrency)
+
+	for _, delivery := range deliveries {
+		subscription, ok := subscriptions[delivery.SubscriptionId]
+		if !ok {
+			subscription, err = uc.webhookRepository.GetWebhookSubscriptionFromDB(ctx, delivery.SubscriptionId)
+			if err != nil {
+				log.Ctx(ctx).Errorw("failed to get webhook subscription", "subscription_id", delivery.SubscriptionId, "error", err.Error())
+				continue
+			}
+			subscriptions[delivery.SubscriptionId] = subscription
+		}
+
+		// deliveries queued before the subscription got disabled are not sent, they can be redelivered later
+		if !subscription.Active {
+			now := time.Now().UTC()
+			delivery.Status = constant.WebhookDeliveryStatusFailed
+			delivery.Error = "subscription is disabled"
+			delivery.LastAttemptAt = &now
+
+			err = uc.webhookRepository.UpdateWebhookDeliveryToDB(ctx, delivery)
+			if err != nil {
+				log.Ctx(ctx).Errorw("failed to update webhook delivery", "delivery_id", delivery.Id, "error", err.Error())
+			}
+
+			mu.Lock()
+			result.Failed++
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(subscription repository.WebhookSubscription, delivery repository.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			delivered, err := uc.deliver(ctx, subscription, delivery)
+			if err != nil {
+				log.Ctx(ctx).Errorw("failed to record webhook delivery", "delivery_id", delivery.Id, "error", err.Error())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if delivered.Status == constant.WebhookDeliveryStatusSucceeded {
+				result.Succeeded++
+			} else {
+				result.Failed++
+			}
+		}(subscription, delivery)
+	}
+
+	wg.Wait()
+
+	resp.SetSuccess(http.StatusOK, "00", "Success dispatch webhooks", result)
+
+	return resp
+}
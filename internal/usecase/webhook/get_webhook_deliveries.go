@@ -0,0 +1,34 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// GetWebhookDeliveries returns the newest deliveries of a subscription
+func (uc *webhookUsecase) GetWebhookDeliveries(ctx context.Context, req *usecase.GetWebhookDeliveriesRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "GetWebhookDeliveries", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	_, err := uc.webhookRepository.GetWebhookSubscriptionFromDB(ctx, req.SubscriptionId)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	deliveries, err := uc.webhookRepository.GetWebhookDeliveriesFromDB(ctx, req.SubscriptionId, req.Status, deliveriesLimit)
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success get webhook deliveries", usecase.GetWebhookDeliveriesResponse{
+		Deliveries: deliveries,
+	})
+
+	return resp
+}
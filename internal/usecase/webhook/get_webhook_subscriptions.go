@@ -0,0 +1,31 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+func (uc *webhookUsecase) GetWebhookSubscriptions(ctx context.Context) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "GetWebhookSubscriptions", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	subscriptions, err := uc.webhookRepository.GetWebhookSubscriptionsFromDB(ctx, false)
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success get webhook subscriptions", usecase.GetWebhookSubscriptionsResponse{
+		Subscriptions: subscriptions,
+	})
+
+	return resp
+}
@@ -0,0 +1,78 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// webhookEnvelope is the body every endpoint receives, Id is shared by the deliveries of one event
+type webhookEnvelope struct {
+	Id        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// NotifyWebhooks only stores pending deliveries, DispatchWebhooks sends them so callers never wait on partners
+func (uc *webhookUsecase) NotifyWebhooks(ctx context.Context, id string, event string, data interface{}) errs.MessageErr {
+	span, ctx := tracing.StartSpan(ctx, "NotifyWebhooks", "usecase")
+	defer span.End()
+
+	subscriptions, err := uc.webhookRepository.GetWebhookSubscriptionsFromDB(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	var listeners []repository.WebhookSubscription
+	for _, subscription := range subscriptions {
+		if subscribedTo(subscription, event) {
+			listeners = append(listeners, subscription)
+		}
+	}
+
+	if len(listeners) == 0 {
+		return nil
+	}
+
+	payload, marshalErr := jsoniter.MarshalToString(webhookEnvelope{
+		Id:        id,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if marshalErr != nil {
+		return errs.NewCustomErrs(
+			"Failed Encode Payload",
+			"FE",
+			marshalErr.Error(),
+		)
+	}
+
+	deliveries := make([]repository.WebhookDelivery, 0, len(listeners))
+	for _, subscription := range listeners {
+		deliveries = append(deliveries, repository.WebhookDelivery{
+			SubscriptionId: subscription.Id,
+			EventId:        id,
+			Event:          event,
+			Payload:        payload,
+		})
+	}
+
+	return uc.webhookRepository.InsertWebhookDeliveriesToDB(ctx, deliveries)
+}
+
+func subscribedTo(subscription repository.WebhookSubscription, event string) bool {
+	for _, subscribed := range subscription.Events {
+		if subscribed == event || subscribed == constant.WebhookEventAll {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/constant"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/errs"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+// RedeliverWebhook sends a delivery again right away, whatever its status, and returns the new attempt
+func (uc *webhookUsecase) RedeliverWebhook(ctx context.Context, req *usecase.RedeliverWebhookRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "RedeliverWebhook", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	subscription, err := uc.webhookRepository.GetWebhookSubscriptionFromDB(ctx, req.SubscriptionId)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	if !subscription.Active {
+		err = errs.NewCustomErrs(
+			"Subscription Disabled",
+			"SD",
+			"enable the subscription before redelivering",
+		)
+		resp.SetError(http.StatusConflict, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	delivery, err := uc.webhookRepository.GetWebhookDeliveryFromDB(ctx, req.SubscriptionId, req.DeliveryId)
+	if err != nil {
+		resp.SetError(http.StatusNotFound, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	delivery, err = uc.deliver(ctx, subscription, delivery)
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	if delivery.Status != constant.WebhookDeliveryStatusSucceeded {
+		resp.SetSuccess(http.StatusBadGateway, "WF", "Webhook redelivery failed", delivery)
+		return resp
+	}
+	resp.SetSuccess(http.StatusOK, "00", "Success redeliver webhook", delivery)
+
+	return resp
+}
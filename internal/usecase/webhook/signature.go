@@ -0,0 +1,50 @@
+package webhookuc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/repository"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookSignature is hex(HMAC-SHA256(secret, "<timestamp>.<body>")), signing the timestamp lets partners reject replays
+func webhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookHeader(subscription repository.WebhookSubscription, delivery repository.WebhookDelivery, now time.Time) http.Header {
+	timestamp := now.Unix()
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("User-Agent", config.Cfg.App.Name+"-webhooks")
+	header.Set("X-Webhook-Event", delivery.Event)
+	header.Set("X-Webhook-Id", delivery.EventId)
+	header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.Id))
+	header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	header.Set("X-Webhook-Signature", webhookSignature(subscription.Secret, timestamp, []byte(delivery.Payload)))
+
+	return header
+}
+
+// randomHex returns n random bytes hex encoded, used for generated secrets
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,43 @@
+package webhookuc
+
+import (
+	"context"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+	"lion-parcel-test/pkg/dto"
+	"lion-parcel-test/pkg/tracing"
+	"net/http"
+)
+
+func (uc *webhookUsecase) UpdateWebhookSubscription(ctx context.Context, req *usecase.UpdateWebhookSubscriptionRequest) *dto.Response {
+	span, ctx := tracing.StartSpan(ctx, "UpdateWebhookSubscription", "usecase")
+	defer span.End()
+
+	resp := dto.New()
+
+	err := uc.webhookRepository.UpdateWebhookSubscriptionToDB(ctx, repository.WebhookSubscription{
+		Id:     req.Id,
+		Url:    req.Url,
+		Events: req.Events,
+		Active: req.Active,
+	})
+	if err != nil {
+		code := http.StatusInternalServerError
+		if err.Status() == "NA" {
+			code = http.StatusNotFound
+		}
+		resp.SetError(code, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	subscription, err := uc.webhookRepository.GetWebhookSubscriptionFromDB(ctx, req.Id)
+	if err != nil {
+		resp.SetError(http.StatusInternalServerError, err.Status(), err.Message(), err)
+		return resp
+	}
+
+	subscription.Secret = ""
+	resp.SetSuccess(http.StatusOK, "00", "Success update webhook subscription", subscription)
+
+	return resp
+}
@@ -0,0 +1,36 @@
+package webhookuc
+
+import (
+	"lion-parcel-test/config"
+	"lion-parcel-test/internal/interfaces/repository"
+	"lion-parcel-test/internal/interfaces/usecase"
+)
+
+const (
+	defaultMaxFailures = 5
+	// dispatchBatchSize is how many pending deliveries one DispatchWebhooks run sends
+	dispatchBatchSize = 100
+	// dispatchConcurrency is how many deliveries are sent at once, so one slow endpoint doesn't hold up the rest
+	dispatchConcurrency = 4
+	// maxResponseBodySize caps how much of an endpoint's answer is stored in the delivery log
+	maxResponseBodySize = 1024
+	deliveriesLimit     = 100
+)
+
+type webhookUsecase struct {
+	webhookRepository repository.WebhookRepository
+}
+
+func NewWebhookUsecase(webhookRepository repository.WebhookRepository) usecase.WebhookUsecase {
+	return &webhookUsecase{
+		webhookRepository: webhookRepository,
+	}
+}
+
+func maxFailures() int {
+	if config.Cfg.Webhooks.MaxFailures > 0 {
+		return config.Cfg.Webhooks.MaxFailures
+	}
+
+	return defaultMaxFailures
+}
@@ -1,10 +1,13 @@
 package dto
 
 type Response struct {
-	Code     string      `json:"response_code"`
-	Desc     string      `json:"response_desc"`
-	Data     interface{} `json:"response_data"`
-	HttpCode int         `json:"-"`
+	Code string      `json:"response_code"`
+	Desc string      `json:"response_desc"`
+	Data interface{} `json:"response_data"`
+	// RequestId is only sent along with an error, the X-Request-ID header carries it either way
+	RequestId string            `json:"request_id,omitempty"`
+	HttpCode  int               `json:"-"`
+	Headers   map[string]string `json:"-"`
 }
 
 // Initialization of Response
@@ -34,6 +37,15 @@ func (r *Response) SetSuccess(httpcode int, code, desc string, data interface{})
 	r.HttpCode = httpcode
 }
 
+// SetHeader adds a header the delivery layer should send along with the response
+func (r *Response) SetHeader(key, value string) {
+	if r.Headers == nil {
+		r.Headers = make(map[string]string)
+	}
+
+	r.Headers[key] = value
+}
+
 func NewError(httpcode int, code, desc string, err error) *Response {
 	r := &Response{
 		Code:     code,
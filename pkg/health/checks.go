@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errUnsupported is returned by freeSpace where the free space can't be read, DiskSpace passes then
+var errUnsupported = errors.New("free space not supported on this platform")
+
+// Writable checks a file can be created in each of dirs, a missing dir is created like the app would
+func Writable(dirs ...string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, dir := range dirs {
+			err := os.MkdirAll(dir, os.ModePerm)
+			if err != nil {
+				return err
+			}
+
+			file, err := os.CreateTemp(dir, ".healthcheck-*")
+			if err != nil {
+				return err
+			}
+
+			file.Close()
+			os.Remove(file.Name())
+		}
+
+		return nil
+	}
+}
+
+// DiskSpace checks the file system of dir has at least minFree bytes free for the app
+func DiskSpace(dir string, minFree uint64) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		free, err := freeSpace(dir)
+		if errors.Is(err, errUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if free < minFree {
+			return fmt.Errorf("%s has %d MB free, expected at least %d MB", dir, free>>20, minFree>>20)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package health
+
+import "syscall"
+
+// freeSpace is the space of the file system of dir an unprivileged process can use
+func freeSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+
+	err := syscall.Statfs(dir, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,185 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the status of a check and of a whole report
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+	// StatusWarn is a failed Warning check, the probe still passes
+	StatusWarn = "warn"
+)
+
+// ShutdownCheck is the check /readyz fails from the moment Drain is called
+const ShutdownCheck = "shutdown"
+
+const defaultTimeout = 2 * time.Second
+
+type Options struct {
+	// of each check, 2s when empty
+	Timeout time.Duration
+}
+
+type Check struct {
+	Name string
+	// Run returns nil when the check passes, ctx carries the timeout of the check
+	Run func(ctx context.Context) error
+	// Live checks run on /livez too, the others only on /readyz
+	Live bool
+	// Warning checks are reported but don't fail the probe, e.g. an open circuit breaker of another service
+	Warning bool
+}
+
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ok is false when a check that isn't a Warning failed
+func (r Report) Ok() bool {
+	return r.Status == StatusOk
+}
+
+// Checker runs the checks of the liveness and readiness probes
+type Checker struct {
+	timeout  time.Duration
+	mu       sync.RWMutex
+	checks   []Check
+	draining atomic.Bool
+}
+
+func NewChecker(opts Options) *Checker {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	return &Checker{
+		timeout: opts.Timeout,
+	}
+}
+
+// Add registers a check, reports list them in the order they were added
+func (c *Checker) Add(check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, check)
+}
+
+// Drain makes Ready fail from now on, so load balancers stop sending requests before the server stops
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Draining is true once Drain was called
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Live runs the Live checks
+func (c *Checker) Live(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make([]Check, 0, len(c.checks))
+	for _, check := range c.checks {
+		if check.Live {
+			checks = append(checks, check)
+		}
+	}
+	c.mu.RUnlock()
+
+	return c.run(ctx, checks)
+}
+
+// Ready runs every check after the shutdown one
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make([]Check, 0, len(c.checks)+1)
+	checks = append(checks, Check{
+		Name: ShutdownCheck,
+		Run: func(ctx context.Context) error {
+			if c.Draining() {
+				return fmt.Errorf("shutting down")
+			}
+			return nil
+		},
+	})
+	checks = append(checks, c.checks...)
+	c.mu.RUnlock()
+
+	return c.run(ctx, checks)
+}
+
+// run runs the checks concurrently, a check that overruns its timeout fails even when it ignores ctx
+func (c *Checker) run(ctx context.Context, checks []Check) Report {
+	report := Report{
+		Status: StatusOk,
+		Checks: make([]Result, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = c.runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusFail {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) runCheck(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	// buffered, the check can still finish after it timed out
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	result := Result{
+		Name:     check.Name,
+		Status:   StatusOk,
+		Duration: time.Since(start).Round(time.Microsecond).String(),
+	}
+
+	if err != nil {
+		result.Status = StatusFail
+		if check.Warning {
+			result.Status = StatusWarn
+		}
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker(Options{Timeout: 50 * time.Millisecond})
+
+	block := make(chan struct{})
+	defer close(block)
+
+	checker.Add(Check{Name: "live", Live: true, Run: func(ctx context.Context) error { return nil }})
+	// ignores ctx, the checker has to give up on it by itself
+	checker.Add(Check{Name: "stuck", Run: func(ctx context.Context) error { <-block; return nil }})
+	checker.Add(Check{Name: "warning", Warning: true, Run: func(ctx context.Context) error { return errors.New("open") }})
+
+	live := checker.Live(context.Background())
+	if !live.Ok() || len(live.Checks) != 1 || live.Checks[0].Name != "live" {
+		t.Fatalf("expected only the live check, got %+v", live)
+	}
+
+	start := time.Now()
+	ready := checker.Ready(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the stuck check to time out, took %s", elapsed)
+	}
+
+	want := []Result{
+		{Name: ShutdownCheck, Status: StatusOk},
+		{Name: "live", Status: StatusOk},
+		{Name: "stuck", Status: StatusFail, Error: "timed out after 50ms"},
+		{Name: "warning", Status: StatusWarn, Error: "open"},
+	}
+	if ready.Ok() || len(ready.Checks) != len(want) {
+		t.Fatalf("expected the stuck check to fail the report, got %+v", ready)
+	}
+	for i, result := range ready.Checks {
+		result.Duration = ""
+		if result != want[i] {
+			t.Errorf("check %d: expected %+v, got %+v", i, want[i], result)
+		}
+	}
+
+	checker.Drain()
+
+	ready = checker.Ready(context.Background())
+	if ready.Checks[0].Status != StatusFail || ready.Checks[0].Error != "shutting down" {
+		t.Fatalf("expected readiness to fail once draining, got %+v", ready.Checks[0])
+	}
+	if !checker.Live(context.Background()).Ok() {
+		t.Fatal("expected liveness to pass while draining")
+	}
+}
+
+func TestWritable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "movies")
+
+	if err := Writable(dir)(context.Background()); err != nil {
+		t.Fatalf("expected the missing dir to be created, got %s", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("expected the probe file to be removed, got %v", entries)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Writable(dir, file)(context.Background()); err == nil {
+		t.Fatal("expected a file in place of a dir to fail")
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	if _, err := freeSpace(t.TempDir()); errors.Is(err, errUnsupported) {
+		t.Skip(err)
+	}
+
+	if err := DiskSpace(t.TempDir(), 1)(context.Background()); err != nil {
+		t.Fatalf("expected a byte to be free, got %s", err)
+	}
+
+	err := DiskSpace(t.TempDir(), math.MaxUint64)(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "MB free") {
+		t.Fatalf("expected the disk to be too small, got %v", err)
+	}
+}
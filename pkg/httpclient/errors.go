@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"errors"
+
+	"github.com/afex/hystrix-go/hystrix"
+)
+
+// the reasons a circuit breaker rejects a call, match them with errors.Is
+var (
+	ErrCircuitOpen    = errors.New("circuit open")
+	ErrTimeout        = errors.New("timeout")
+	ErrMaxConcurrency = errors.New("max concurrency")
+)
+
+// CircuitError is returned when the breaker of Command didn't let the call through or gave up waiting for it
+type CircuitError struct {
+	Command string
+	// Message is the fallback message configured for the command
+	Message string
+	Err     error
+}
+
+func (e *CircuitError) Error() string {
+	return e.Command + ": " + e.Message + " (" + e.Err.Error() + ")"
+}
+
+func (e *CircuitError) Unwrap() error {
+	return e.Err
+}
+
+// newCircuitError turns a hystrix rejection into a CircuitError, other errors come from the call itself and are returned as is
+func newCircuitError(source hystrixSource, err error) error {
+	var reason error
+
+	switch err {
+	case hystrix.ErrCircuitOpen:
+		reason = ErrCircuitOpen
+	case hystrix.ErrTimeout:
+		reason = ErrTimeout
+	case hystrix.ErrMaxConcurrency:
+		reason = ErrMaxConcurrency
+	default:
+		return err
+	}
+
+	return &CircuitError{
+		Command: source.command,
+		Message: source.fallbackMsg,
+		Err:     reason,
+	}
+}
@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitErrors(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		received <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	for _, tc := range []struct {
+		name   string
+		opts   []func(c *HttpClient) Option
+		reject func(t *testing.T, c *HttpClient, command string) error
+		reason error
+	}{
+		{
+			name: "open",
+			opts: []func(c *HttpClient) Option{
+				func(c *HttpClient) Option { return c.CbWithRequestVolumeThreshold(2) },
+				func(c *HttpClient) Option { return c.CbWithSleepWindow(time.Minute) },
+			},
+			reject: func(t *testing.T, c *HttpClient, command string) error {
+				openCircuit(t, c, command, server.URL+"/fail")
+
+				_, err := c.Get(context.Background(), command, server.URL+"/fail", nil, nil)
+				return err
+			},
+			reason: ErrCircuitOpen,
+		},
+		{
+			name: "timeout",
+			opts: []func(c *HttpClient) Option{
+				func(c *HttpClient) Option { return c.CbWithTimeout(20 * time.Millisecond) },
+			},
+			reject: func(t *testing.T, c *HttpClient, command string) error {
+				_, err := c.Get(context.Background(), command, server.URL, nil, nil)
+				<-received
+				return err
+			},
+			reason: ErrTimeout,
+		},
+		{
+			name: "max concurrency",
+			opts: []func(c *HttpClient) Option{
+				func(c *HttpClient) Option { return c.CbWithMaxConcurrentRequests(1) },
+			},
+			reject: func(t *testing.T, c *HttpClient, command string) error {
+				// holds the only ticket of the command until release is closed
+				go c.Get(context.Background(), command, server.URL, nil, nil)
+				<-received
+
+				_, err := c.Get(context.Background(), command, server.URL, nil, nil)
+				return err
+			},
+			reason: ErrMaxConcurrency,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append(tc.opts, func(c *HttpClient) Option { return c.CbWithFallbackMsg("movies unavailable") })
+			c, command := newClient(t, opts...)
+
+			err := tc.reject(t, c, command)
+
+			var circuitErr *CircuitError
+			if !errors.As(err, &circuitErr) {
+				t.Fatalf("expected a CircuitError, got %v", err)
+			}
+			if circuitErr.Command != command || circuitErr.Message != "movies unavailable" || circuitErr.Err != tc.reason {
+				t.Fatalf("unexpected CircuitError %+v", circuitErr)
+			}
+			for _, reason := range []error{ErrCircuitOpen, ErrTimeout, ErrMaxConcurrency} {
+				if errors.Is(err, reason) != (reason == tc.reason) {
+					t.Fatalf("expected errors.Is to match only %v, matched %v", tc.reason, reason)
+				}
+			}
+			if !strings.Contains(err.Error(), "movies unavailable ("+tc.reason.Error()+")") {
+				t.Fatalf("unexpected message %s", err.Error())
+			}
+		})
+	}
+}
+
+func TestCircuitErrorPassesCallErrors(t *testing.T) {
+	if err := newCircuitError(hystrixSource{command: "movies"}, io.EOF); err != io.EOF {
+		t.Fatalf("expected the error of the call as is, got %v", err)
+	}
+}
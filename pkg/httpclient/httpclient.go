@@ -1,32 +1,33 @@
 package httpclient
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"lion-parcel-test/pkg/tracing"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/afex/hystrix-go/hystrix"
-	"go.elastic.co/apm/module/apmhttp"
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
 )
 
 type HttpClient struct {
-	client      *http.Client
-	fallbackMsg map[string]string
-	source      hystrixSource
+	client  *http.Client
+	mu      sync.RWMutex
+	sources map[string]hystrixSource
 }
 
+// hystrixSource is the configuration of one command, durations are converted to milliseconds for hystrix
 type hystrixSource struct {
 	command                string
 	timeout                time.Duration
 	maxConcurrentRequests  int
 	errorPercentThreshold  int
-	sleepWindow            int
+	sleepWindow            time.Duration
 	requestVolumeThreshold int
 	fallbackMsg            string
+	retryPolicy            RetryPolicy
 }
 
 const (
@@ -35,154 +36,173 @@ const (
 	defaultHystrixTimeout         = 30 * time.Second
 	defaultMaxConcurrentRequests  = 100
 	defaultErrorPercentThreshold  = 25
-	defaultSleepWindow            = 10
+	defaultSleepWindow            = 10 * time.Second
 	defaultRequestVolumeThreshold = 10
 	defaultFallbackMsg            = "Timeout External (State: Open)"
 	defaultCommand                = "http-call"
 )
 
-type Option func(*HttpClient)
+type Option func(*hystrixSource)
 
 var Client *HttpClient
 
+var registerCollector sync.Once
+
 func Init() {
+	// must be registered before the first circuit is created, circuits pick their collectors up once
+	registerCollector.Do(func() {
+		metricCollector.Registry.Register(newWindowCollector)
+	})
+
 	Client = &HttpClient{
-		client: apmhttp.WrapClient(&http.Client{
+		client: &http.Client{
 			Timeout: 20 * time.Second,
-		}),
-		fallbackMsg: make(map[string]string),
+			// every call is a span of the caller's trace and sends its traceparent along
+			Transport: tracing.WrapTransport(nil),
+		},
+		sources: make(map[string]hystrixSource),
 	}
 }
 
+// NewCbSource configures one command, calling it again for the same command replaces its configuration
 func (c *HttpClient) NewCbSource(opts ...Option) {
-
-	c.source.errorPercentThreshold = defaultErrorPercentThreshold
-	c.source.maxConcurrentRequests = defaultMaxConcurrentRequests
-	c.source.sleepWindow = defaultSleepWindow
-	c.source.requestVolumeThreshold = defaultRequestVolumeThreshold
-	c.source.timeout = defaultHystrixTimeout
-	c.source.fallbackMsg = defaultFallbackMsg
-	c.source.command = defaultCommand
+	source := hystrixSource{
+		command:                defaultCommand,
+		timeout:                defaultHystrixTimeout,
+		maxConcurrentRequests:  defaultMaxConcurrentRequests,
+		errorPercentThreshold:  defaultErrorPercentThreshold,
+		sleepWindow:            defaultSleepWindow,
+		requestVolumeThreshold: defaultRequestVolumeThreshold,
+		fallbackMsg:            defaultFallbackMsg,
+		retryPolicy:            RetryPolicy{MaxRetries: defaultHystrixRetryCount},
+	}
 
 	for _, opt := range opts {
-		opt(c)
+		opt(&source)
+	}
+
+	source.retryPolicy = source.retryPolicy.withDefaults()
+
+	c.configure(source)
+}
+
+// NewCbSourceFrom configures command like the template command unless it is configured already,
+// for commands named at runtime that shouldn't share one breaker, e.g. one per webhook endpoint
+func (c *HttpClient) NewCbSourceFrom(template string, command string) {
+	c.mu.RLock()
+	_, exists := c.sources[command]
+	source, found := c.sources[template]
+	c.mu.RUnlock()
+
+	if exists {
+		return
 	}
 
-	hystrix.ConfigureCommand(c.source.command, hystrix.CommandConfig{
-		Timeout:                int(c.source.timeout),
-		MaxConcurrentRequests:  c.source.maxConcurrentRequests,
-		ErrorPercentThreshold:  c.source.errorPercentThreshold,
-		RequestVolumeThreshold: c.source.requestVolumeThreshold,
-		SleepWindow:            c.source.sleepWindow,
+	if !found {
+		c.NewCbSource(c.CbWithCommand(command))
+		return
+	}
+
+	source.command = command
+	c.configure(source)
+}
+
+func (c *HttpClient) configure(source hystrixSource) {
+	hystrix.ConfigureCommand(source.command, hystrix.CommandConfig{
+		Timeout:                int(source.timeout / time.Millisecond),
+		MaxConcurrentRequests:  source.maxConcurrentRequests,
+		ErrorPercentThreshold:  source.errorPercentThreshold,
+		RequestVolumeThreshold: source.requestVolumeThreshold,
+		SleepWindow:            int(source.sleepWindow / time.Millisecond),
 	})
 
-	c.fallbackMsg[c.source.command] = c.source.fallbackMsg
+	c.mu.Lock()
+	c.sources[source.command] = source
+	c.mu.Unlock()
+}
+
+// source returns the configuration of a command, unknown commands get hystrix defaults and no retries
+func (c *HttpClient) source(command string) hystrixSource {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	source, ok := c.sources[command]
+	if !ok {
+		return hystrixSource{command: command, fallbackMsg: defaultFallbackMsg}
+	}
+
+	return source
 }
 
 func (c *HttpClient) CbWithTimeout(timeout time.Duration) Option {
-	return func(c *HttpClient) {
-		c.source.timeout = timeout
+	return func(s *hystrixSource) {
+		s.timeout = timeout
 	}
 }
 func (c *HttpClient) CbWithCommand(command string) Option {
-	return func(c *HttpClient) {
-		c.source.command = command
+	return func(s *hystrixSource) {
+		s.command = command
 	}
 }
 func (c *HttpClient) CbWithMaxConcurrentRequests(maxConcurrentRequests int) Option {
-	return func(c *HttpClient) {
-		c.source.maxConcurrentRequests = maxConcurrentRequests
+	return func(s *hystrixSource) {
+		s.maxConcurrentRequests = maxConcurrentRequests
 	}
 }
 func (c *HttpClient) CbWithErrorPercentThreshold(errorPercentThreshold int) Option {
-	return func(c *HttpClient) {
-		c.source.errorPercentThreshold = errorPercentThreshold
+	return func(s *hystrixSource) {
+		s.errorPercentThreshold = errorPercentThreshold
 	}
 }
 func (c *HttpClient) CbWithRequestVolumeThreshold(requestVolumeThreshold int) Option {
-	return func(c *HttpClient) {
-		c.source.requestVolumeThreshold = requestVolumeThreshold
+	return func(s *hystrixSource) {
+		s.requestVolumeThreshold = requestVolumeThreshold
 	}
 }
-func (c *HttpClient) CbWithSleepWindow(sleepWindow int) Option {
-	return func(c *HttpClient) {
-		c.source.sleepWindow = sleepWindow
+func (c *HttpClient) CbWithSleepWindow(sleepWindow time.Duration) Option {
+	return func(s *hystrixSource) {
+		s.sleepWindow = sleepWindow
 	}
 }
-func (c *HttpClient) CbWithFallbackMsg(fallbackMsg string) Option {
-	return func(c *HttpClient) {
-		c.source.fallbackMsg = fallbackMsg
+func (c *HttpClient) CbWithRetryPolicy(retryPolicy RetryPolicy) Option {
+	return func(s *hystrixSource) {
+		s.retryPolicy = retryPolicy
 	}
 }
-
-func (c *HttpClient) Call(ctx context.Context, requestBody map[string]interface{}, header http.Header, endpoint string, source string) (context.Context, []byte, http.Header, error) {
-	var responseCtx context.Context
-	var responseBody []byte
-	var responseHeader http.Header
-	var responseErr error
-
-	err := hystrix.Do(source,
-		func() error {
-			var err error
-			responseCtx, responseBody, responseHeader, err = c.makeHttpCall(ctx, requestBody, header, endpoint)
-			if err != nil {
-				// log.LogDebug(fmt.Sprint("Main call failed for %s: %v", source, err))
-			}
-			return err
-		},
-		func(err error) error {
-			// log.LogDebug(fmt.Sprint("Fallback triggered for %s. Error: %v", source, err))
-			fmt.Println("fallback")
-
-			responseCtx = ctx
-			responseBody = nil
-			responseHeader = make(http.Header)
-			responseHeader.Set("Content-Type", "application/json")
-			responseErr = fmt.Errorf(c.fallbackMsg[source])
-
-			return nil
-		})
-
-	if err != nil {
-		// log.LogDebug(fmt.Sprint("Complete failure in Call to %s: %v", source, err))
-
-		return ctx, nil, nil, fmt.Errorf("service completely unavailable: %v", err)
+func (c *HttpClient) CbWithFallbackMsg(fallbackMsg string) Option {
+	return func(s *hystrixSource) {
+		s.fallbackMsg = fallbackMsg
 	}
-
-	return responseCtx, responseBody, responseHeader, responseErr
 }
 
-func (c *HttpClient) makeHttpCall(ctx context.Context, requestBody map[string]interface{}, header http.Header, endpoint string) (context.Context, []byte, http.Header, error) {
-	jsonRequest, _ := json.Marshal(requestBody)
-
-	payload := bytes.NewReader(jsonRequest)
-
-	request, err := http.NewRequest("POST", endpoint, payload)
+// Call posts requestBody as json and only accepts a json object back, new callers should use Do or DoJSON
+func (c *HttpClient) Call(ctx context.Context, requestBody map[string]interface{}, header http.Header, endpoint string, source string) (context.Context, []byte, http.Header, error) {
+	body, err := JSONBody(requestBody)
 	if err != nil {
 		return ctx, nil, nil, err
 	}
 
-	request.Header = header
-
-	response, err := c.client.Do(request.WithContext(ctx))
-	if err != nil {
-		return ctx, nil, nil, err
+	if header == nil {
+		header = make(http.Header)
 	}
 
-	defer response.Body.Close()
-
-	responseByte, err := ioutil.ReadAll(response.Body)
+	response, err := c.Do(ctx, &Request{
+		Method:   http.MethodPost,
+		Endpoint: endpoint,
+		Header:   header,
+		Body:     body,
+		Command:  source,
+	})
 	if err != nil {
 		return ctx, nil, nil, err
 	}
 
 	// check for valid json response
 	var js map[string]interface{}
-	err = json.Unmarshal(responseByte, &js)
+	err = json.Unmarshal(response.Body, &js)
 	if err != nil {
-		//log.LogWarn("invalid json", "invalid json")
 		return ctx, nil, nil, err
 	}
 
-	return ctx, responseByte, response.Header, nil
+	return ctx, response.Body, response.Header, nil
 }
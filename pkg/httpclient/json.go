@@ -0,0 +1,64 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// JSONBody encodes v as a request body, a nil v means no body
+func JSONBody(v interface{}) (io.Reader, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	body, err := jsoniter.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(body), nil
+}
+
+// Decode unmarshals a json response into T
+func Decode[T any](response *Response) (T, error) {
+	var result T
+
+	err := jsoniter.Unmarshal(response.Body, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// DoJSON sends body as json, nil sends none, and decodes the 2xx response into T
+func DoJSON[T any](ctx context.Context, c *HttpClient, req *Request, body interface{}) (T, error) {
+	var result T
+
+	payload, err := JSONBody(body)
+	if err != nil {
+		return result, err
+	}
+
+	request := *req
+	request.Header = req.Header.Clone()
+	if request.Header == nil {
+		request.Header = make(http.Header)
+	}
+	request.Header.Set("Accept", "application/json")
+	if payload != nil {
+		request.Body = payload
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.Do(ctx, &request)
+	if err != nil {
+		return result, err
+	}
+
+	return Decode[T](response)
+}
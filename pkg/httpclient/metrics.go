@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"lion-parcel-test/pkg/metrics"
+)
+
+// the circuit breakers are read on every scrape, the same way CircuitBreakers reports them
+func init() {
+	metrics.NewGaugeFunc("circuit_breaker_open",
+		"1 while the circuit of the command is open and calls fail fast, 0 while it is closed.",
+		[]string{"command"}, func() []metrics.Sample {
+			return circuitBreakerSamples(func(status CircuitBreakerStatus) float64 {
+				if status.State == CircuitStateOpen {
+					return 1
+				}
+				return 0
+			})
+		})
+
+	metrics.NewGaugeFunc("circuit_breaker_error_percent",
+		"Share of the calls of the command that failed over the last 10 seconds.",
+		[]string{"command"}, func() []metrics.Sample {
+			return circuitBreakerSamples(func(status CircuitBreakerStatus) float64 {
+				return float64(status.ErrorPercent)
+			})
+		})
+
+	metrics.NewGaugeFunc("circuit_breaker_requests",
+		"Calls of the command over the last 10 seconds.",
+		[]string{"command"}, func() []metrics.Sample {
+			return circuitBreakerSamples(func(status CircuitBreakerStatus) float64 {
+				return float64(status.RequestVolume)
+			})
+		})
+}
+
+func circuitBreakerSamples(value func(CircuitBreakerStatus) float64) []metrics.Sample {
+	if Client == nil {
+		return nil
+	}
+
+	statuses := Client.CircuitBreakers()
+
+	samples := make([]metrics.Sample, 0, len(statuses))
+	for _, status := range statuses {
+		samples = append(samples, metrics.Sample{
+			Values: []string{status.Command},
+			Value:  value(status),
+		})
+	}
+
+	return samples
+}
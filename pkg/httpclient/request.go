@@ -0,0 +1,288 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+)
+
+// maxErrorBodySize caps how much of a non-2xx body is kept in a StatusError
+const maxErrorBodySize = 64 * 1024
+
+// Request is one call to another service, Body may be any reader and is streamed as is
+type Request struct {
+	Method   string
+	Endpoint string
+	Query    url.Values
+	Header   http.Header
+	Body     io.Reader
+	// Command is the circuit breaker guarding the call, defaultCommand when empty
+	Command string
+}
+
+// Response is a fully read 2xx response
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// StreamResponse is a 2xx response whose body is still to be read, the caller must close Body
+type StreamResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       io.ReadCloser
+}
+
+// StatusError is returned for a non-2xx response, Body holds at most maxErrorBodySize bytes of it
+type StatusError struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d: %s", e.Method, e.Endpoint, e.StatusCode, strings.TrimSpace(string(e.Body)))
+}
+
+// Get, Post, Put, Patch and Delete are shorthands for Do
+func (c *HttpClient) Get(ctx context.Context, command string, endpoint string, query url.Values, header http.Header) (*Response, error) {
+	return c.Do(ctx, &Request{Method: http.MethodGet, Endpoint: endpoint, Query: query, Header: header, Command: command})
+}
+
+func (c *HttpClient) Post(ctx context.Context, command string, endpoint string, body io.Reader, header http.Header) (*Response, error) {
+	return c.Do(ctx, &Request{Method: http.MethodPost, Endpoint: endpoint, Body: body, Header: header, Command: command})
+}
+
+func (c *HttpClient) Put(ctx context.Context, command string, endpoint string, body io.Reader, header http.Header) (*Response, error) {
+	return c.Do(ctx, &Request{Method: http.MethodPut, Endpoint: endpoint, Body: body, Header: header, Command: command})
+}
+
+func (c *HttpClient) Patch(ctx context.Context, command string, endpoint string, body io.Reader, header http.Header) (*Response, error) {
+	return c.Do(ctx, &Request{Method: http.MethodPatch, Endpoint: endpoint, Body: body, Header: header, Command: command})
+}
+
+func (c *HttpClient) Delete(ctx context.Context, command string, endpoint string, header http.Header) (*Response, error) {
+	return c.Do(ctx, &Request{Method: http.MethodDelete, Endpoint: endpoint, Header: header, Command: command})
+}
+
+// Do sends the request through its circuit breaker and reads the whole response.
+// 5xx responses and transport errors count against the breaker, 4xx responses don't.
+func (c *HttpClient) Do(ctx context.Context, req *Request) (*Response, error) {
+	stream, err := c.DoStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer stream.Body.Close()
+
+	body, err := ioutil.ReadAll(stream.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode: stream.StatusCode,
+		Header:     stream.Header,
+		Body:       body,
+	}, nil
+}
+
+// DoStream is Do without reading the body, the breaker only covers the call up to the response headers.
+// Failed attempts are retried following the command's RetryPolicy.
+func (c *HttpClient) DoStream(ctx context.Context, req *Request) (*StreamResponse, error) {
+	command := req.Command
+	if command == "" {
+		command = defaultCommand
+	}
+
+	source := c.source(command)
+
+	request, err := newHttpRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.attempt(ctx, source, req, request)
+		if err == nil {
+			return response, nil
+		}
+
+		delay, retry := source.retryPolicy.retryDelay(ctx, command, request, attempt, err)
+		if !retry {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+
+		// the body of the previous attempt was consumed, replayable() made sure it can be read again
+		request, err = replayRequest(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends the request once through the circuit breaker. hystrix runs the call in a goroutine of its own
+// that outlives attempt when the breaker times it out, so its result is handed over under a lock and a response
+// that arrives after the breaker gave up is closed
+func (c *HttpClient) attempt(ctx context.Context, source hystrixSource, req *Request, request *http.Request) (*StreamResponse, error) {
+	// cancelled when the breaker gives up on the call, or once the body is closed
+	callCtx, cancel := context.WithCancel(ctx)
+
+	var mu sync.Mutex
+	var abandoned bool
+	var response *http.Response
+	var clientErr error
+	var fallbackErr error
+
+	err := hystrix.Do(source.command,
+		func() error {
+			res, err := c.client.Do(request.WithContext(callCtx))
+			if err != nil {
+				return err
+			}
+
+			if res.StatusCode >= 500 {
+				return newStatusError(req, request, res)
+			}
+
+			// read before taking the lock, the fallback shouldn't wait for the body
+			var statusErr error
+			if res.StatusCode < 200 || res.StatusCode > 299 {
+				statusErr = newStatusError(req, request, res)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			// the breaker already timed the call out, nobody will read this response
+			if abandoned {
+				res.Body.Close()
+				return nil
+			}
+
+			if statusErr != nil {
+				clientErr = statusErr
+				return nil
+			}
+
+			response = res
+			return nil
+		},
+		func(err error) error {
+			cancel()
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			abandoned = true
+			// the call may have finished right as the breaker timed it out
+			if response != nil {
+				response.Body.Close()
+				response = nil
+			}
+
+			fallbackErr = newCircuitError(source, err)
+			return nil
+		})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("service completely unavailable: %v", err)
+	}
+
+	if fallbackErr != nil {
+		return nil, fallbackErr
+	}
+
+	if clientErr != nil {
+		cancel()
+		return nil, clientErr
+	}
+
+	return &StreamResponse{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       &cancelOnClose{ReadCloser: response.Body, cancel: cancel},
+	}, nil
+}
+
+func newHttpRequest(req *Request) (*http.Request, error) {
+	endpoint := req.Endpoint
+	if len(req.Query) > 0 {
+		separator := "?"
+		if strings.Contains(endpoint, "?") {
+			separator = "&"
+		}
+		endpoint = endpoint + separator + req.Query.Encode()
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	request, err := http.NewRequest(method, endpoint, req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Header != nil {
+		request.Header = req.Header.Clone()
+	}
+
+	return request, nil
+}
+
+// newStatusError reads what's left of a non-2xx response and closes it
+func newStatusError(req *Request, request *http.Request, res *http.Response) *StatusError {
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+
+	return &StatusError{
+		Method:     request.Method,
+		Endpoint:   req.Endpoint,
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// RawBody wraps already encoded bytes as a request body
+func RawBody(body []byte) io.Reader {
+	return bytes.NewReader(body)
+}
@@ -0,0 +1,256 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestMain(m *testing.M) {
+	Init()
+
+	os.Exit(m.Run())
+}
+
+// newClient is a client of its own with a command named after the test, so tests don't share a circuit
+func newClient(t *testing.T, opts ...func(c *HttpClient) Option) (*HttpClient, string) {
+	t.Helper()
+
+	c := &HttpClient{
+		client:  &http.Client{Timeout: 5 * time.Second},
+		sources: make(map[string]hystrixSource),
+	}
+	command := strings.NewReplacer("/", "-", " ", "-").Replace(t.Name())
+
+	options := []Option{c.CbWithCommand(command)}
+	for _, opt := range opts {
+		options = append(options, opt(c))
+	}
+	c.NewCbSource(options...)
+
+	return c, command
+}
+
+// echo is what the echo server saw of a request
+type echo struct {
+	Method string      `json:"method"`
+	Query  url.Values  `json:"query"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+func newEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		jsoniter.NewEncoder(w).Encode(echo{Method: r.Method, Query: r.URL.Query(), Header: r.Header, Body: string(body)})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVerbs(t *testing.T) {
+	server := newEchoServer(t)
+	c, command := newClient(t)
+	ctx := context.Background()
+	header := http.Header{"X-Test": {"yes"}}
+
+	for _, tc := range []struct {
+		method string
+		call   func() (*Response, error)
+		body   string
+	}{
+		{http.MethodGet, func() (*Response, error) {
+			return c.Get(ctx, command, server.URL+"/?a=1", url.Values{"b": {"2"}}, header)
+		}, ""},
+		{http.MethodPost, func() (*Response, error) {
+			return c.Post(ctx, command, server.URL, strings.NewReader("post"), header)
+		}, "post"},
+		{http.MethodPut, func() (*Response, error) {
+			return c.Put(ctx, command, server.URL, RawBody([]byte("put")), header)
+		}, "put"},
+		{http.MethodPatch, func() (*Response, error) {
+			return c.Patch(ctx, command, server.URL, strings.NewReader("patch"), header)
+		}, "patch"},
+		{http.MethodDelete, func() (*Response, error) {
+			return c.Delete(ctx, command, server.URL, header)
+		}, ""},
+	} {
+		t.Run(tc.method, func(t *testing.T) {
+			response, err := tc.call()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Decode[echo](response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.StatusCode != http.StatusOK || got.Method != tc.method || got.Body != tc.body || got.Header.Get("X-Test") != "yes" {
+				t.Fatalf("unexpected echo %d %+v", response.StatusCode, got)
+			}
+			if tc.method == http.MethodGet && (got.Query.Get("a") != "1" || got.Query.Get("b") != "2") {
+				t.Fatalf("expected the query of the endpoint and the one given, got %v", got.Query)
+			}
+		})
+	}
+
+	// the caller's header isn't changed by the request
+	if len(header) != 1 {
+		t.Fatalf("unexpected header after the calls %v", header)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("got " + string(body) + ","))
+		w.(http.Flusher).Flush()
+
+		<-release
+		w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	c, command := newClient(t)
+
+	// a pipe can't be read twice, it is streamed as is
+	reader, writer := io.Pipe()
+	go func() {
+		writer.Write([]byte("streamed"))
+		writer.Close()
+	}()
+
+	stream, err := c.DoStream(context.Background(), &Request{Method: http.MethodPost, Endpoint: server.URL, Body: reader, Command: command})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	// the response is handed over before the server finished it
+	first := make([]byte, len("got streamed,"))
+	if _, err = io.ReadFull(stream.Body, first); err != nil || string(first) != "got streamed," {
+		t.Fatalf("unexpected start of the body %q %v", first, err)
+	}
+
+	close(release)
+	rest, err := io.ReadAll(stream.Body)
+	if err != nil || string(rest) != "done" {
+		t.Fatalf("unexpected rest of the body %q %v", rest, err)
+	}
+}
+
+func TestDoJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Accept") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var in struct {
+			Name string `json:"name"`
+		}
+		jsoniter.NewDecoder(r.Body).Decode(&in)
+		w.Write([]byte(`{"greeting":"hello ` + in.Name + `"}`))
+	}))
+	defer server.Close()
+
+	c, command := newClient(t)
+
+	type greeting struct {
+		Greeting string `json:"greeting"`
+	}
+	got, err := DoJSON[greeting](context.Background(), c, &Request{Method: http.MethodPost, Endpoint: server.URL, Command: command}, map[string]string{"name": "alice"})
+	if err != nil || got.Greeting != "hello alice" {
+		t.Fatalf("unexpected greeting %+v %v", got, err)
+	}
+
+	_, err = Decode[greeting](&Response{Body: []byte("not json")})
+	if err == nil {
+		t.Fatal("expected an error decoding a body that isn't json")
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Reason", "missing")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(strings.Repeat("x", maxErrorBodySize+10)))
+	}))
+	defer server.Close()
+
+	c, command := newClient(t)
+
+	_, err := c.Get(context.Background(), command, server.URL+"/movies", nil, nil)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected a StatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound || statusErr.Method != http.MethodGet || statusErr.Endpoint != server.URL+"/movies" ||
+		statusErr.Header.Get("X-Reason") != "missing" || len(statusErr.Body) != maxErrorBodySize {
+		t.Fatalf("unexpected StatusError %d %s %s %v %d", statusErr.StatusCode, statusErr.Method, statusErr.Endpoint, statusErr.Header, len(statusErr.Body))
+	}
+	if !strings.Contains(err.Error(), "unexpected status 404") {
+		t.Fatalf("unexpected message %s", err.Error())
+	}
+}
+
+// slowTransport answers after delay whatever the context of the request says, and records when the body is closed
+type slowTransport struct {
+	delay  time.Duration
+	closed atomic.Bool
+}
+
+func (s *slowTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	time.Sleep(s.delay)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       &closeRecorder{ReadCloser: io.NopCloser(strings.NewReader("late")), closed: &s.closed},
+		Request:    r,
+	}, nil
+}
+
+type closeRecorder struct {
+	io.ReadCloser
+	closed *atomic.Bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed.Store(true)
+	return c.ReadCloser.Close()
+}
+
+func TestLateResponseIsClosed(t *testing.T) {
+	transport := &slowTransport{delay: 150 * time.Millisecond}
+	c, command := newClient(t, func(c *HttpClient) Option { return c.CbWithTimeout(30 * time.Millisecond) })
+	c.client.Transport = transport
+
+	_, err := c.Get(context.Background(), command, "http://example.invalid", nil, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected the breaker to time the call out, got %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !transport.closed.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("the body of the response that came after the timeout was never closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
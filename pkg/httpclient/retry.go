@@ -0,0 +1,163 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+)
+
+const (
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+// RetryPolicy decides which failed calls of a command are sent again.
+// The zero value never retries, empty fields fall back to the defaults below.
+type RetryPolicy struct {
+	MaxRetries int
+	// BaseDelay doubles on every retry up to MaxDelay, the actual wait is a random value below it (full jitter)
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Methods that are safe to send twice, GET, HEAD, OPTIONS, PUT and DELETE by default
+	Methods []string
+	// StatusCodes worth retrying, 429, 502, 503 and 504 by default. Transport errors and timeouts are always retried.
+	StatusCodes []int
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryMaxDelay
+	}
+	if len(p.Methods) == 0 {
+		p.Methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete}
+	}
+	if len(p.StatusCodes) == 0 {
+		p.StatusCodes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+
+	return p
+}
+
+// retryDelay returns how long to wait before retrying the failed attempt, false means give up
+func (p RetryPolicy) retryDelay(ctx context.Context, command string, request *http.Request, attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+
+	if !containsString(p.Methods, request.Method) || !replayable(request) {
+		return 0, false
+	}
+
+	// an open circuit or a full command already reject calls, retrying would only add load
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrMaxConcurrency) {
+		return 0, false
+	}
+
+	circuit, _, circuitErr := hystrix.GetCircuit(command)
+	if circuitErr == nil && circuit.IsOpen() {
+		return 0, false
+	}
+
+	delay := p.backoff(attempt)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if !containsInt(p.StatusCodes, statusErr.StatusCode) {
+			return 0, false
+		}
+
+		if retryAfter, ok := parseRetryAfter(statusErr.Header.Get("Retry-After")); ok {
+			// the server asked for a longer pause than we are willing to wait
+			if retryAfter > p.MaxDelay {
+				return 0, false
+			}
+			delay = retryAfter
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// backoff is exponential backoff with full jitter, a random wait between 0 and BaseDelay * 2^attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if attempt < 32 {
+		if exp := p.BaseDelay << uint(attempt); exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an http date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// replayable tells whether the request body can be sent again, streamed bodies can't
+func replayable(request *http.Request) bool {
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+func replayRequest(request *http.Request) (*http.Request, error) {
+	retry := request.Clone(request.Context())
+
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+
+	return retry, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,382 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reply is one response of the counting server
+type reply struct {
+	status     int
+	retryAfter string
+}
+
+// countingServer answers with replies in order, then 200 once they run out, and records every attempt
+type countingServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	replies  []reply
+	attempts []time.Time
+	bodies   []string
+}
+
+func newCountingServer(t *testing.T, replies ...reply) *countingServer {
+	s := &countingServer{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		n := len(s.attempts)
+		s.attempts = append(s.attempts, time.Now())
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+
+		if n >= len(s.replies) {
+			w.Write([]byte("ok"))
+			return
+		}
+		if s.replies[n].retryAfter != "" {
+			w.Header().Set("Retry-After", s.replies[n].retryAfter)
+		}
+		w.WriteHeader(s.replies[n].status)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *countingServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.attempts)
+}
+
+// gap is the time between the first two attempts
+func (s *countingServer) gap() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts[1].Sub(s.attempts[0])
+}
+
+func repeat(r reply, n int) []reply {
+	replies := make([]reply, n)
+	for i := range replies {
+		replies[i] = r
+	}
+
+	return replies
+}
+
+func withRetryPolicy(policy RetryPolicy) func(c *HttpClient) Option {
+	return func(c *HttpClient) Option { return c.CbWithRetryPolicy(policy) }
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 100 * time.Millisecond}
+
+	for _, tc := range []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, 10 * time.Millisecond},
+		{1, 20 * time.Millisecond},
+		{3, 80 * time.Millisecond},
+		{4, 100 * time.Millisecond},
+		// BaseDelay << attempt overflows
+		{40, 100 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	} {
+		var longest time.Duration
+		for i := 0; i < 1000; i++ {
+			delay := policy.backoff(tc.attempt)
+			if delay < 0 || delay > tc.ceiling {
+				t.Fatalf("attempt %d: expected a delay within [0, %s], got %s", tc.attempt, tc.ceiling, delay)
+			}
+			longest = max(longest, delay)
+		}
+
+		// full jitter spreads the delays over the whole range
+		if longest < tc.ceiling/2 {
+			t.Errorf("attempt %d: expected delays up to %s, the longest was %s", tc.attempt, tc.ceiling, longest)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+		ok    bool
+	}{
+		{"", 0, 0, false},
+		{"soon", 0, 0, false},
+		{"-1", 0, 0, false},
+		{"0", 0, 0, true},
+		{"3", 3 * time.Second, 3 * time.Second, true},
+		// http dates have a precision of a second
+		{time.Now().Add(3 * time.Second).UTC().Format(http.TimeFormat), 2 * time.Second, 3 * time.Second, true},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0, true},
+	} {
+		delay, ok := parseRetryAfter(tc.value)
+		if ok != tc.ok || delay < tc.min || delay > tc.max {
+			t.Errorf("%q: expected a delay within [%s, %s] %t, got %s %t", tc.value, tc.min, tc.max, tc.ok, delay, ok)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := reply{status: http.StatusServiceUnavailable}
+
+	for _, tc := range []struct {
+		name     string
+		policy   RetryPolicy
+		method   string
+		body     func() io.Reader
+		replies  []reply
+		attempts int
+		ok       bool
+	}{
+		{
+			name:     "retries until it succeeds",
+			policy:   RetryPolicy{MaxRetries: 3},
+			method:   http.MethodGet,
+			replies:  repeat(unavailable, 2),
+			attempts: 3,
+			ok:       true,
+		},
+		{
+			name:     "gives up after MaxRetries",
+			policy:   RetryPolicy{MaxRetries: 2},
+			method:   http.MethodGet,
+			replies:  repeat(unavailable, 5),
+			attempts: 3,
+		},
+		{
+			name:     "zero policy never retries",
+			method:   http.MethodGet,
+			replies:  repeat(unavailable, 1),
+			attempts: 1,
+		},
+		{
+			name:     "retries 429 with Retry-After",
+			policy:   RetryPolicy{MaxRetries: 3},
+			method:   http.MethodGet,
+			replies:  []reply{{status: http.StatusTooManyRequests, retryAfter: "0"}},
+			attempts: 2,
+			ok:       true,
+		},
+		{
+			name:     "doesn't retry a status outside StatusCodes",
+			policy:   RetryPolicy{MaxRetries: 3},
+			method:   http.MethodGet,
+			replies:  repeat(reply{status: http.StatusInternalServerError}, 1),
+			attempts: 1,
+		},
+		{
+			name:     "doesn't retry a client error",
+			policy:   RetryPolicy{MaxRetries: 3},
+			method:   http.MethodGet,
+			replies:  repeat(reply{status: http.StatusNotFound}, 1),
+			attempts: 1,
+		},
+		{
+			name:     "retries the configured status codes",
+			policy:   RetryPolicy{MaxRetries: 3, StatusCodes: []int{http.StatusInternalServerError}},
+			method:   http.MethodGet,
+			replies:  repeat(reply{status: http.StatusInternalServerError}, 1),
+			attempts: 2,
+			ok:       true,
+		},
+		{
+			name:     "doesn't retry a method outside Methods",
+			policy:   RetryPolicy{MaxRetries: 3},
+			method:   http.MethodPost,
+			body:     func() io.Reader { return RawBody([]byte("vote")) },
+			replies:  repeat(unavailable, 1),
+			attempts: 1,
+		},
+		{
+			name:     "retries the configured methods with the same body",
+			policy:   RetryPolicy{MaxRetries: 3, Methods: []string{http.MethodPost}},
+			method:   http.MethodPost,
+			body:     func() io.Reader { return RawBody([]byte("vote")) },
+			replies:  repeat(unavailable, 2),
+			attempts: 3,
+			ok:       true,
+		},
+		{
+			name:     "doesn't retry a streamed body",
+			policy:   RetryPolicy{MaxRetries: 3},
+			method:   http.MethodPut,
+			body:     func() io.Reader { return io.MultiReader(strings.NewReader("vote")) },
+			replies:  repeat(unavailable, 1),
+			attempts: 1,
+		},
+		{
+			name:     "doesn't wait longer than MaxDelay for Retry-After",
+			policy:   RetryPolicy{MaxRetries: 3, MaxDelay: time.Second},
+			method:   http.MethodGet,
+			replies:  []reply{{status: http.StatusServiceUnavailable, retryAfter: "120"}},
+			attempts: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newCountingServer(t, tc.replies...)
+			tc.policy.BaseDelay = time.Millisecond
+			c, command := newClient(t, withRetryPolicy(tc.policy))
+
+			req := &Request{Method: tc.method, Endpoint: server.URL, Command: command}
+			if tc.body != nil {
+				req.Body = tc.body()
+			}
+
+			_, err := c.Do(context.Background(), req)
+			if (err == nil) != tc.ok {
+				t.Fatalf("expected ok %t, got %v", tc.ok, err)
+			}
+			if server.count() != tc.attempts {
+				t.Fatalf("expected %d attempts, got %d", tc.attempts, server.count())
+			}
+			if tc.body != nil {
+				for i, body := range server.bodies {
+					if body != "vote" {
+						t.Fatalf("attempt %d: expected the body to be sent again, got %q", i, body)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		retryAfter func() string
+		wait       time.Duration
+	}{
+		{"seconds", func() string { return "1" }, time.Second},
+		// rounded down to the second, so the wait is somewhere between 1s and 2s
+		{"http date", func() string { return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat) }, time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newCountingServer(t, reply{status: http.StatusServiceUnavailable, retryAfter: tc.retryAfter()})
+			// the backoff alone would retry right away
+			c, command := newClient(t, withRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Nanosecond, MaxDelay: 5 * time.Second}))
+
+			if _, err := c.Get(context.Background(), command, server.URL, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			// the server sees the attempts a little apart from when the client sent them
+			if gap := server.gap(); gap < tc.wait-50*time.Millisecond {
+				t.Fatalf("expected to wait %s before retrying, waited %s", tc.wait, gap)
+			}
+		})
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		replies  []reply
+		policy   RetryPolicy
+		attempts func(n int) bool
+	}{
+		{
+			name:     "backoff",
+			replies:  repeat(reply{status: http.StatusServiceUnavailable}, 1000),
+			policy:   RetryPolicy{MaxRetries: 1000, BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond},
+			attempts: func(n int) bool { return n > 1 && n < 1000 },
+		},
+		{
+			// a wait past the deadline isn't even started
+			name:     "retry after",
+			replies:  []reply{{status: http.StatusServiceUnavailable, retryAfter: "2"}},
+			policy:   RetryPolicy{MaxRetries: 3, MaxDelay: 5 * time.Second},
+			attempts: func(n int) bool { return n == 1 },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newCountingServer(t, tc.replies...)
+			// keeps the circuit closed, every call fails
+			c, command := newClient(t, withRetryPolicy(tc.policy), func(c *HttpClient) Option { return c.CbWithRequestVolumeThreshold(10000) })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := c.Get(ctx, command, server.URL, nil, nil)
+
+			// the last attempt either failed or was cut short by the deadline
+			var statusErr *StatusError
+			if !errors.Is(err, context.DeadlineExceeded) && (!errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable) {
+				t.Fatalf("expected the error of the last attempt, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+				t.Fatalf("expected to give up by the deadline, took %s", elapsed)
+			}
+			if !tc.attempts(server.count()) {
+				t.Fatalf("unexpected number of attempts %d", server.count())
+			}
+		})
+	}
+}
+
+// openCircuit fails calls of command until its breaker opens
+func openCircuit(t *testing.T, c *HttpClient, command string, endpoint string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := c.Get(context.Background(), command, endpoint, nil, nil)
+		if errors.Is(err, ErrCircuitOpen) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the circuit of %s didn't open, last error %v", command, err)
+		}
+		// hystrix updates the metrics of the circuit in the background
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNoRetryWhenCircuitOpen(t *testing.T) {
+	server := newCountingServer(t, repeat(reply{status: http.StatusServiceUnavailable}, 1000)...)
+	c, command := newClient(t,
+		withRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}),
+		func(c *HttpClient) Option { return c.CbWithRequestVolumeThreshold(2) },
+		func(c *HttpClient) Option { return c.CbWithErrorPercentThreshold(50) },
+		func(c *HttpClient) Option { return c.CbWithSleepWindow(time.Minute) },
+	)
+
+	openCircuit(t, c, command, server.URL)
+
+	before := server.count()
+	_, err := c.Get(context.Background(), command, server.URL, nil, nil)
+
+	var circuitErr *CircuitError
+	if !errors.As(err, &circuitErr) || circuitErr.Command != command || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the open circuit to reject the call, got %v", err)
+	}
+	if server.count() != before {
+		t.Fatalf("expected no attempt to reach the server, got %d", server.count()-before)
+	}
+
+	// a failure of a call let through just before the circuit opened isn't retried either
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	policy := RetryPolicy{MaxRetries: 3}.withDefaults()
+	if _, retry := policy.retryDelay(context.Background(), command, request, 0, &StatusError{StatusCode: http.StatusServiceUnavailable}); retry {
+		t.Fatal("expected no retry while the circuit is open")
+	}
+}
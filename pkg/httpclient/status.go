@@ -0,0 +1,158 @@
+package httpclient
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"github.com/afex/hystrix-go/hystrix/rolling"
+)
+
+// CircuitBreakerStatus is the state of one command over the last 10 seconds, the window hystrix itself uses
+type CircuitBreakerStatus struct {
+	Command                string `json:"command"`
+	State                  string `json:"state"`
+	ErrorPercent           int    `json:"error_percent"`
+	RequestVolume          int    `json:"request_volume"`
+	Successes              int    `json:"successes"`
+	Failures               int    `json:"failures"`
+	Timeouts               int    `json:"timeouts"`
+	ShortCircuits          int    `json:"short_circuits"`
+	Rejects                int    `json:"rejects"`
+	Timeout                string `json:"timeout"`
+	SleepWindow            string `json:"sleep_window"`
+	MaxConcurrentRequests  int    `json:"max_concurrent_requests"`
+	ErrorPercentThreshold  int    `json:"error_percent_threshold"`
+	RequestVolumeThreshold int    `json:"request_volume_threshold"`
+	MaxRetries             int    `json:"max_retries"`
+}
+
+const (
+	CircuitStateClosed = "closed"
+	CircuitStateOpen   = "open"
+)
+
+// CircuitBreakers reports every configured command, sorted by name
+func (c *HttpClient) CircuitBreakers() []CircuitBreakerStatus {
+	c.mu.RLock()
+	sources := make([]hystrixSource, 0, len(c.sources))
+	for _, source := range c.sources {
+		sources = append(sources, source)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].command < sources[j].command
+	})
+
+	now := time.Now()
+	statuses := make([]CircuitBreakerStatus, 0, len(sources))
+
+	for _, source := range sources {
+		status := CircuitBreakerStatus{
+			Command:                source.command,
+			State:                  CircuitStateClosed,
+			Timeout:                source.timeout.String(),
+			SleepWindow:            source.sleepWindow.String(),
+			MaxConcurrentRequests:  source.maxConcurrentRequests,
+			ErrorPercentThreshold:  source.errorPercentThreshold,
+			RequestVolumeThreshold: source.requestVolumeThreshold,
+			MaxRetries:             source.retryPolicy.MaxRetries,
+		}
+
+		circuit, _, err := hystrix.GetCircuit(source.command)
+		if err == nil && circuit.IsOpen() {
+			status.State = CircuitStateOpen
+		}
+
+		if collector := windowCollectorFor(source.command); collector != nil {
+			collector.fill(&status, now)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+var (
+	windowCollectorsMu sync.Mutex
+	windowCollectors   = make(map[string]*windowCollector)
+)
+
+// windowCollector keeps the rolling counters of one circuit, hystrix only exposes its own through the event stream
+type windowCollector struct {
+	mu            sync.RWMutex
+	attempts      *rolling.Number
+	errors        *rolling.Number
+	successes     *rolling.Number
+	failures      *rolling.Number
+	timeouts      *rolling.Number
+	shortCircuits *rolling.Number
+	rejects       *rolling.Number
+}
+
+func newWindowCollector(name string) metricCollector.MetricCollector {
+	collector := &windowCollector{}
+	collector.Reset()
+
+	windowCollectorsMu.Lock()
+	windowCollectors[name] = collector
+	windowCollectorsMu.Unlock()
+
+	return collector
+}
+
+func windowCollectorFor(name string) *windowCollector {
+	windowCollectorsMu.Lock()
+	defer windowCollectorsMu.Unlock()
+
+	return windowCollectors[name]
+}
+
+func (w *windowCollector) Update(r metricCollector.MetricResult) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	w.attempts.Increment(r.Attempts)
+	w.errors.Increment(r.Errors)
+	w.successes.Increment(r.Successes)
+	w.failures.Increment(r.Failures)
+	w.timeouts.Increment(r.Timeouts)
+	w.shortCircuits.Increment(r.ShortCircuits)
+	w.rejects.Increment(r.Rejects)
+}
+
+func (w *windowCollector) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.attempts = rolling.NewNumber()
+	w.errors = rolling.NewNumber()
+	w.successes = rolling.NewNumber()
+	w.failures = rolling.NewNumber()
+	w.timeouts = rolling.NewNumber()
+	w.shortCircuits = rolling.NewNumber()
+	w.rejects = rolling.NewNumber()
+}
+
+func (w *windowCollector) fill(status *CircuitBreakerStatus, now time.Time) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	attempts := w.attempts.Sum(now)
+	errors := w.errors.Sum(now)
+
+	status.RequestVolume = int(attempts)
+	status.Successes = int(w.successes.Sum(now))
+	status.Failures = int(w.failures.Sum(now))
+	status.Timeouts = int(w.timeouts.Sum(now))
+	status.ShortCircuits = int(w.shortCircuits.Sum(now))
+	status.Rejects = int(w.rejects.Sum(now))
+
+	if attempts > 0 {
+		status.ErrorPercent = int(errors/attempts*100 + 0.5)
+	}
+}
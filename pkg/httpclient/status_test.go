@@ -0,0 +1,80 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	server := newCountingServer(t, reply{status: http.StatusNotFound}, reply{status: http.StatusServiceUnavailable})
+
+	c, command := newClient(t,
+		func(c *HttpClient) Option { return c.CbWithTimeout(time.Second) },
+		func(c *HttpClient) Option { return c.CbWithSleepWindow(time.Minute) },
+		func(c *HttpClient) Option { return c.CbWithMaxConcurrentRequests(7) },
+		func(c *HttpClient) Option { return c.CbWithErrorPercentThreshold(50) },
+		func(c *HttpClient) Option { return c.CbWithRequestVolumeThreshold(4) },
+		// 503 isn't retried, every call is one attempt
+		withRetryPolicy(RetryPolicy{MaxRetries: 2, StatusCodes: []int{http.StatusTeapot}}),
+	)
+	c.NewCbSource(c.CbWithCommand(command + "-a"))
+
+	// a 404 isn't a failure of the breaker, a 503 is
+	for i := 0; i < 3; i++ {
+		c.Get(context.Background(), command, server.URL, nil, nil)
+	}
+
+	status := waitForStatus(t, c, command, func(s CircuitBreakerStatus) bool { return s.RequestVolume == 3 })
+
+	want := CircuitBreakerStatus{
+		Command:                command,
+		State:                  CircuitStateClosed,
+		ErrorPercent:           33,
+		RequestVolume:          3,
+		Successes:              2,
+		Failures:               1,
+		Timeout:                "1s",
+		SleepWindow:            "1m0s",
+		MaxConcurrentRequests:  7,
+		ErrorPercentThreshold:  50,
+		RequestVolumeThreshold: 4,
+		MaxRetries:             2,
+	}
+	if status != want {
+		t.Fatalf("expected %+v, got %+v", want, status)
+	}
+
+	statuses := c.CircuitBreakers()
+	if len(statuses) != 2 || statuses[0].Command != command || statuses[1].Command != command+"-a" {
+		t.Fatalf("expected both commands sorted by name, got %+v", statuses)
+	}
+	if statuses[1].Timeout != defaultHystrixTimeout.String() || statuses[1].RequestVolume != 0 || statuses[1].State != CircuitStateClosed {
+		t.Fatalf("expected the unused command with the defaults, got %+v", statuses[1])
+	}
+
+	failing := newCountingServer(t, repeat(reply{status: http.StatusServiceUnavailable}, 1000)...)
+	openCircuit(t, c, command, failing.URL)
+
+	waitForStatus(t, c, command, func(s CircuitBreakerStatus) bool { return s.State == CircuitStateOpen && s.ShortCircuits > 0 })
+}
+
+// waitForStatus polls the status of command until ok, hystrix counts calls in the background
+func waitForStatus(t *testing.T, c *HttpClient, command string, ok func(CircuitBreakerStatus) bool) CircuitBreakerStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, status := range c.CircuitBreakers() {
+			if status.Command == command && ok(status) {
+				return status
+			}
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status of %s %+v", command, c.CircuitBreakers())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"context"
+	"lion-parcel-test/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// keys of the request values Ctx adds to a log line. Set them with fiber's c.Locals,
+// the fasthttp ctx of a request and every ctx derived from it hand them to ctx.Value
+const (
+	RequestIdKey = "request_id"
+	UserIdKey    = "user_id"
+)
+
+// Ctx is Sugar with the request id, the trace id and the user id of ctx, the ones ctx doesn't have are left out
+func Ctx(ctx context.Context) *zap.SugaredLogger {
+	fields := make([]interface{}, 0, 6)
+
+	if requestId := RequestIdFromContext(ctx); requestId != "" {
+		fields = append(fields, zap.String("request_id", requestId))
+	}
+
+	if traceId := tracing.TraceId(ctx); traceId != "" {
+		fields = append(fields, zap.String("trace_id", traceId))
+	}
+
+	if userId, ok := ctx.Value(UserIdKey).(int); ok && userId != 0 {
+		fields = append(fields, zap.Int("user_id", userId))
+	}
+
+	if len(fields) == 0 {
+		return Sugar
+	}
+
+	return Sugar.With(fields...)
+}
+
+// Detach is a context.Background with the request id, the user id and the span of ctx,
+// for work that outlives the request of ctx like a streamed response
+func Detach(ctx context.Context) context.Context {
+	detached := tracing.Detach(ctx)
+
+	if requestId := RequestIdFromContext(ctx); requestId != "" {
+		detached = context.WithValue(detached, RequestIdKey, requestId)
+	}
+
+	if userId, ok := ctx.Value(UserIdKey).(int); ok {
+		detached = context.WithValue(detached, UserIdKey, userId)
+	}
+
+	return detached
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(RequestIdKey).(string)
+	return requestId
+}
@@ -0,0 +1,241 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxBodySize = 4096
+	redacted           = "[REDACTED]"
+)
+
+var (
+	defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+	defaultRedactFields  = []string{"password", "jwt", "token", "secret"}
+
+	// numbers are kept as they were written, a float64 would round large ids
+	numberJSON = jsoniter.Config{UseNumber: true, SortMapKeys: true}.Froze()
+)
+
+// HttpOptions says what the request and response logs leave out, zero values use the defaults
+type HttpOptions struct {
+	// headers whose value is replaced, case insensitive
+	RedactHeaders []string
+	// JSON fields whose value is replaced at any depth, case insensitive
+	RedactFields []string
+	// bytes of a body logged before it is truncated, negative leaves the bodies out
+	MaxBodySize int
+	Sampling    []HttpSampling
+}
+
+// HttpSampling logs a share of the requests of a route
+type HttpSampling struct {
+	// "GET /api/v1/movies", or "/api/v1/movies" for every method, as the route is declared, e.g. /api/v1/admin/movies/:id
+	Route string
+	// 0 logs none of them, 1 all of them
+	Rate float64
+}
+
+type HttpLogger struct {
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+	maxBodySize   int
+	// rates by "METHOD route", or " route" for every method
+	sampling map[string]float64
+}
+
+func NewHttpLogger(opts HttpOptions) *HttpLogger {
+	if len(opts.RedactHeaders) == 0 {
+		opts.RedactHeaders = defaultRedactHeaders
+	}
+	if len(opts.RedactFields) == 0 {
+		opts.RedactFields = defaultRedactFields
+	}
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = defaultMaxBodySize
+	}
+
+	l := &HttpLogger{
+		redactHeaders: make(map[string]bool),
+		redactFields:  make(map[string]bool),
+		maxBodySize:   opts.MaxBodySize,
+		sampling:      make(map[string]float64),
+	}
+
+	for _, header := range opts.RedactHeaders {
+		l.redactHeaders[strings.ToLower(header)] = true
+	}
+	for _, field := range opts.RedactFields {
+		l.redactFields[strings.ToLower(field)] = true
+	}
+	for _, sampling := range opts.Sampling {
+		method, route, found := strings.Cut(strings.TrimSpace(sampling.Route), " ")
+		if !found {
+			method, route = "", method
+		}
+		l.sampling[strings.ToUpper(method)+" "+strings.TrimSpace(route)] = sampling.Rate
+	}
+
+	return l
+}
+
+// Sampled tells whether a request to the declared route is logged, failed requests always are
+func (l *HttpLogger) Sampled(method, route string, status int) bool {
+	if status >= fiber.StatusBadRequest {
+		return true
+	}
+
+	rate, ok := l.sampling[method+" "+route]
+	if !ok {
+		rate, ok = l.sampling[" "+route]
+	}
+	if !ok || rate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < rate
+}
+
+// LogRequest logs the request of c and returns the logger its response is logged with,
+// the response is logged from another goroutine, once c can't be read anymore
+func (l *HttpLogger) LogRequest(c *fiber.Ctx, timestamp time.Time) *zap.SugaredLogger {
+	reqHeaders := make(map[string]string)
+	for k, v := range c.GetReqHeaders() {
+		if l.redactHeaders[strings.ToLower(k)] {
+			reqHeaders[k] = redacted
+			continue
+		}
+		reqHeaders[k] = strings.Join(v, ",")
+	}
+
+	logger := Ctx(c.Context())
+
+	reqLog := logger.With(
+		zap.Time("timestamp", timestamp),
+		zap.String("method", c.Method()),
+		zap.String("path", c.Path()),
+		zap.String("remote_addr", c.IP()),
+		zap.String("request_body", l.body(c.Get(fiber.HeaderContentType), c.Body())),
+		zap.Any("request_header", reqHeaders),
+	)
+
+	reqLog.Info("request")
+
+	return logger
+}
+
+// ResponseBody is what the logs show of the response body, a copy that outlives the request
+func (l *HttpLogger) ResponseBody(c *fiber.Ctx) string {
+	// reading a streamed body would buffer the whole stream in memory
+	if c.Response().IsBodyStream() {
+		return "<stream>"
+	}
+
+	return l.body(string(c.Response().Header.ContentType()), c.Response().Body())
+}
+
+func LogResponse(logger *zap.SugaredLogger, responseStatus int, responseBody string, responseTime time.Duration) {
+
+	respLog := logger.With(
+		zap.Int("status", responseStatus),
+		zap.String("response_body", responseBody),
+		zap.Duration("duration", responseTime),
+	)
+
+	respLog.Info("response")
+
+}
+
+// body leaves out multipart and binary bodies, redacts JSON ones and truncates the rest at maxBodySize
+func (l *HttpLogger) body(contentType string, body []byte) string {
+	if l.maxBodySize < 0 || len(body) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return fmt.Sprintf("<multipart body, %d bytes>", len(body))
+	case isBinary(mediaType, body):
+		return fmt.Sprintf("<binary body, %d bytes>", len(body))
+	case strings.Contains(mediaType, "json") || (mediaType == "" && looksLikeJSON(body)):
+		redactedBody, err := l.redactJSON(body)
+		if err != nil {
+			// it can't be redacted, so none of it is logged
+			return fmt.Sprintf("<unparsed json body, %d bytes>", len(body))
+		}
+		body = redactedBody
+	}
+
+	return l.truncate(body)
+}
+
+func (l *HttpLogger) redactJSON(body []byte) ([]byte, error) {
+	var document interface{}
+
+	err := numberJSON.Unmarshal(body, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	l.redact(document)
+
+	return numberJSON.Marshal(document)
+}
+
+func (l *HttpLogger) redact(value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if l.redactFields[strings.ToLower(key)] {
+				value[key] = redacted
+				continue
+			}
+			l.redact(field)
+		}
+	case []interface{}:
+		for _, item := range value {
+			l.redact(item)
+		}
+	}
+}
+
+// truncate cuts body at maxBodySize, on a character boundary
+func (l *HttpLogger) truncate(body []byte) string {
+	if len(body) <= l.maxBodySize {
+		return string(body)
+	}
+
+	cut := l.maxBodySize
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+
+	return string(body[:cut]) + fmt.Sprintf("...<truncated %d bytes>", len(body)-cut)
+}
+
+func isBinary(mediaType string, body []byte) bool {
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/", "application/octet-stream", "application/zip", "application/gzip", "application/pdf"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+
+	return bytes.IndexByte(body, 0) >= 0 || !utf8.Valid(body)
+}
+
+func looksLikeJSON(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBody(t *testing.T) {
+	l := NewHttpLogger(HttpOptions{MaxBodySize: 100})
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"empty", "application/json", "", ""},
+		{"nested json fields", "application/json; charset=utf-8", `{"response_data":{"jwt":"abc","Password":"x","user":{"name":"alice"}}}`, `{"response_data":{"Password":"[REDACTED]","jwt":"[REDACTED]","user":{"name":"alice"}}}`},
+		{"json arrays", "application/json", `[{"token":"abc","id":12345678901234567890}]`, `[{"id":12345678901234567890,"token":"[REDACTED]"}]`},
+		{"json without a content type", "", `{"secret":"abc"}`, `{"secret":"[REDACTED]"}`},
+		{"invalid json", "application/json", `{"jwt":"abc"`, "<unparsed json body, 12 bytes>"},
+		{"multipart", "multipart/form-data; boundary=x", "--x\r\nfile", "<multipart body, 9 bytes>"},
+		{"binary content type", "video/mp4", "ftyp", "<binary body, 4 bytes>"},
+		{"binary text", "text/plain", "a\x00b", "<binary body, 3 bytes>"},
+		{"text", "text/plain", "ok", "ok"},
+		{"truncated", "text/plain", strings.Repeat("a", 106), strings.Repeat("a", 100) + "...<truncated 6 bytes>"},
+		// é is 2 bytes and would be cut in half at 100
+		{"truncated on a character", "text/plain", strings.Repeat("a", 99) + "é", strings.Repeat("a", 99) + "...<truncated 2 bytes>"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := l.body(tc.contentType, []byte(tc.body)); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	if got := NewHttpLogger(HttpOptions{MaxBodySize: -1}).body("text/plain", []byte("ok")); got != "" {
+		t.Fatalf("expected the body to be left out, got %q", got)
+	}
+
+	custom := NewHttpLogger(HttpOptions{RedactFields: []string{"email"}})
+	if got := custom.body("application/json", []byte(`{"email":"a@b.c","jwt":"abc"}`)); got != `{"email":"[REDACTED]","jwt":"abc"}` {
+		t.Fatalf("expected only the configured fields to be redacted, got %q", got)
+	}
+}
+
+func TestSampled(t *testing.T) {
+	l := NewHttpLogger(HttpOptions{Sampling: []HttpSampling{
+		{Route: "GET /api/v1/movies", Rate: 0},
+		{Route: "/api/v1/movies/search", Rate: 0},
+		{Route: "post /api/v1/login", Rate: 1},
+	}})
+
+	for _, tc := range []struct {
+		method string
+		route  string
+		status int
+		want   bool
+	}{
+		{"GET", "/api/v1/movies", 200, false},
+		{"GET", "/api/v1/movies", 500, true},
+		{"POST", "/api/v1/movies", 200, true},
+		{"GET", "/api/v1/movies/search", 200, false},
+		{"POST", "/api/v1/movies/search", 200, false},
+		{"POST", "/api/v1/login", 200, true},
+		{"GET", "/healthz", 200, true},
+	} {
+		if got := l.Sampled(tc.method, tc.route, tc.status); got != tc.want {
+			t.Errorf("expected %s %s %d sampled %v, got %v", tc.method, tc.route, tc.status, tc.want, got)
+		}
+	}
+}
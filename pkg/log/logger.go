@@ -4,11 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -17,126 +15,100 @@ var (
 	Logger *zap.Logger
 	Sugar  *zap.SugaredLogger
 
-	once sync.Once
+	once   sync.Once
+	writer *rotatingWriter
 )
 
 const folder = "logs"
 
-func Initialize() error {
-	var err error
-	once.Do(func() {
-
-		dir, _ := os.Getwd()
-		logDir := dir + "/" + folder
-
-		// Ensure log directory exists
-		err = os.MkdirAll(logDir, os.ModePerm)
-		if err != nil {
-			return
-		}
+const (
+	OutputFile   = "file"
+	OutputStdout = "stdout"
+	OutputBoth   = "both"
+)
 
-		debug := false
+// Options of the logger, the zero value logs info and above to ./logs and keeps every file
+type Options struct {
+	// where the log files go, ./logs when empty
+	Dir string
+	// debug, info, warn or error, info when empty
+	Level string
+	// file, stdout or both, file when empty
+	Output string
+	// megabytes of a log file before it is rotated, 0 rotates daily only
+	MaxSize int
+	// how long rotated files are kept, 0 keeps them forever
+	MaxAge time.Duration
+	// how many rotated files are kept, 0 keeps them all
+	MaxBackups int
+	// gzips the rotated files
+	Compress bool
+}
 
-		debugStr := os.Getenv("DEBUG")
-		if debugStr == "" || debugStr == "true" {
-			debug = true
-		}
+// Initialize writes the logs to ./logs
+func Initialize() error {
+	return InitializeOptions(Options{})
+}
 
-		level := zap.InfoLevel
+// InitializeDir writes the logs to logDir
+func InitializeDir(logDir string) error {
+	return InitializeOptions(Options{Dir: logDir})
+}
 
-		if debug {
-			level = zap.DebugLevel
-		}
-		// Create a custom log writer with daily rotation
-		logWriter := newDailyLogWriter(logDir)
-
-		// Create encoder config
-		encoderConfig := getEncoderConfig()
-
-		// Create a core that writes to both file and console
-		core := zapcore.NewTee(
-			zapcore.NewCore(
-				zapcore.NewJSONEncoder(encoderConfig),
-				logWriter,
-				level,
-			),
-			zapcore.NewCore(
-				zapcore.NewConsoleEncoder(encoderConfig),
-				logWriter,
-				level,
-			),
-		)
-
-		// Create the logger
-		Logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))
-		Sugar = Logger.Sugar()
+// InitializeOptions sets the logger up, only the first call of Initialize, InitializeDir or InitializeOptions does
+func InitializeOptions(opts Options) error {
+	var err error
+	once.Do(func() {
+		err = initialize(opts)
 	})
 
 	return err
 }
 
-// dailyLogWriter implements a custom WriteSyncer that creates a new log file each day
-type dailyLogWriter struct {
-	logDir      string
-	currentFile *os.File
-	currentDate string
-}
+func initialize(opts Options) error {
+	if opts.Dir == "" {
+		dir, _ := os.Getwd()
+		opts.Dir = filepath.Join(dir, folder)
+	}
+	if opts.Level == "" {
+		opts.Level = "info"
+	}
+	if opts.Output == "" {
+		opts.Output = OutputFile
+	}
 
-// newDailyLogWriter creates a new daily log writer
-func newDailyLogWriter(logDir string) *dailyLogWriter {
-	return &dailyLogWriter{
-		logDir: logDir,
+	level, err := zapcore.ParseLevel(opts.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q", opts.Level)
 	}
-}
 
-// Write implements the WriteSyncer interface
-func (d *dailyLogWriter) Write(p []byte) (n int, err error) {
-	today := time.Now().Format("2006-01-02")
+	encoderConfig := getEncoderConfig()
 
-	// Create new file if date changed or no file exists
-	if d.currentFile == nil || d.currentDate != today {
-		// Close existing file if open
-		if d.currentFile != nil {
-			d.currentFile.Close()
-		}
+	// the files are read by machines, stdout by people
+	var cores []zapcore.Core
 
-		// Create new log file
-		filename := filepath.Join(d.logDir, fmt.Sprintf("%s.log", today))
-		d.currentFile, err = os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	switch opts.Output {
+	case OutputFile, OutputBoth:
+		err = os.MkdirAll(opts.Dir, os.ModePerm)
 		if err != nil {
-			return 0, err
+			return err
 		}
-		d.currentDate = today
-	}
-
-	// Write to file
-	return d.currentFile.Write(p)
-}
 
-// Sync implements the WriteSyncer interface
-func (d *dailyLogWriter) Sync() error {
-	if d.currentFile != nil {
-		return d.currentFile.Sync()
+		writer = newRotatingWriter(opts).start()
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), writer, level))
+	case OutputStdout:
+	default:
+		return fmt.Errorf("invalid log output %q, expected file, stdout or both", opts.Output)
 	}
-	return nil
-}
 
-// getLogLevel converts string to zapcore.Level
-func getLogLevel(level string) zapcore.Level {
-	switch level {
-	case "debug":
-		return zap.DebugLevel
-	case "info":
-		return zap.InfoLevel
-	case "warn":
-		return zap.WarnLevel
-	case "error":
-		return zap.ErrorLevel
-	case "fatal":
-		return zap.FatalLevel
-	default:
-		return zap.InfoLevel
+	if opts.Output == OutputStdout || opts.Output == OutputBoth {
+		cores = append(cores, zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), zapcore.Lock(os.Stdout), level))
 	}
+
+	Logger = zap.New(zapcore.NewTee(cores...), zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))
+	Sugar = Logger.Sugar()
+
+	return nil
 }
 
 // getEncoderConfig creates a custom encoder configuration
@@ -161,42 +133,9 @@ func Close() {
 	if Logger != nil {
 		Logger.Sync()
 	}
-}
-
-func LogRequest(c *fiber.Ctx, timestamp time.Time) {
-	var requestBody []byte
-	if c.Body() != nil {
-		requestBody = c.Body()
-	}
-
-	reqHeaders := make(map[string]string)
-	for k, v := range c.GetReqHeaders() {
-		reqHeaders[k] = strings.Join(v, ",")
+	if writer != nil {
+		writer.Close()
 	}
-
-	reqLog := Sugar.With(
-		zap.Time("timestamp", timestamp),
-		zap.String("method", c.Method()),
-		zap.String("path", c.Path()),
-		zap.String("remote_addr", c.IP()),
-		zap.ByteString("request_body", requestBody),
-		zap.Any("request_header", reqHeaders),
-	)
-
-	reqLog.Info("request")
-
-}
-
-func LogResponse(responseStatus int, responseBody string, responseTime time.Duration) {
-
-	respLog := Sugar.With(
-		zap.Int("status", responseStatus),
-		zap.String("response_body", responseBody),
-		zap.Duration("duration", responseTime),
-	)
-
-	respLog.Info("response")
-
 }
 
 func LogDebug(msg string) {
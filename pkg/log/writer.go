@@ -0,0 +1,307 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+// logFileName matches the files of the writer: 2006-01-02.log of the day and 2006-01-02-1.log rotated by size,
+// either of them gzipped once it isn't written anymore
+var logFileName = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-\d+)?\.log(\.gz)?$`)
+
+// rotatingWriter writes to a file per day, and to a new one whenever the file of the day reached maxSize.
+// The files it stopped writing are gzipped and pruned in the background
+type rotatingWriter struct {
+	dir string
+	// bytes, 0 never rotates by size
+	maxSize int64
+	// 0 keeps the files forever
+	maxAge time.Duration
+	// 0 keeps every file
+	maxBackups int
+	compress   bool
+	now        func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	date string
+	size int64
+
+	// millCh wakes the goroutine that compresses and prunes, a pending wake up is enough for several rotations
+	millCh chan struct{}
+	// milled is told every time the goroutine went through the files, tests wait on it
+	milled func()
+}
+
+func newRotatingWriter(opts Options) *rotatingWriter {
+	return &rotatingWriter{
+		dir:        opts.Dir,
+		maxSize:    int64(opts.MaxSize) * 1024 * 1024,
+		maxAge:     opts.MaxAge,
+		maxBackups: opts.MaxBackups,
+		compress:   opts.Compress,
+		now:        time.Now,
+		millCh:     make(chan struct{}, 1),
+		milled:     func() {},
+	}
+}
+
+// start compresses and prunes in the background, beginning with the files of an earlier run
+func (w *rotatingWriter) start() *rotatingWriter {
+	go w.millRun()
+
+	w.mill()
+
+	return w
+}
+
+// Write implements the WriteSyncer interface, zap writes from every goroutine that logs
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	today := w.now().Format(dateFormat)
+
+	if w.file == nil || w.date != today {
+		if err := w.open(today); err != nil {
+			return 0, err
+		}
+	}
+
+	// an entry larger than maxSize still gets a file of its own
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Sync implements the WriteSyncer interface
+func (w *rotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil {
+		return w.file.Sync()
+	}
+
+	return nil
+}
+
+// Close closes the file of the day, a later Write opens it again
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.closeFile()
+}
+
+// open appends to the file of date, the file of another day it replaces is left to the mill
+func (w *rotatingWriter) open(date string) error {
+	if w.file != nil {
+		w.closeFile()
+		w.mill()
+	}
+
+	err := os.MkdirAll(w.dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(w.dir, date+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.date = date
+	w.size = info.Size()
+
+	return nil
+}
+
+// rotate moves the file of the day aside as <date>-<n>.log and starts a new one
+func (w *rotatingWriter) rotate() error {
+	err := w.closeFile()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(filepath.Join(w.dir, w.date+".log"), w.rotatedName(w.date))
+	if err != nil {
+		return err
+	}
+
+	w.mill()
+
+	return w.open(w.date)
+}
+
+// rotatedName is the first <date>-<n>.log that isn't taken, gzipped or not
+func (w *rotatingWriter) rotatedName(date string) string {
+	for n := 1; ; n++ {
+		name := filepath.Join(w.dir, date+"-"+strconv.Itoa(n)+".log")
+
+		_, err := os.Stat(name)
+		_, gzErr := os.Stat(name + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return name
+		}
+	}
+}
+
+func (w *rotatingWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+func (w *rotatingWriter) mill() {
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *rotatingWriter) millRun() {
+	for range w.millCh {
+		err := w.millOnce()
+		if err != nil {
+			// the logger can't log its own failures
+			fmt.Fprintf(os.Stderr, "log: failed to compress or prune %s: %s\n", w.dir, err)
+		}
+
+		w.milled()
+	}
+}
+
+type logFile struct {
+	name    string
+	modTime time.Time
+}
+
+// millOnce gzips the files that aren't written anymore, then removes the ones past maxBackups or maxAge
+func (w *rotatingWriter) millOnce() error {
+	w.mu.Lock()
+	active := w.date + ".log"
+	w.mu.Unlock()
+
+	entries, err := os.ReadDir(w.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	files := make([]logFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == active || !logFileName.MatchString(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		file := logFile{name: entry.Name(), modTime: info.ModTime()}
+
+		if w.compress && filepath.Ext(file.name) == ".log" {
+			err = compressFile(filepath.Join(w.dir, file.name), info)
+			if err != nil {
+				return err
+			}
+			file.name += ".gz"
+		}
+
+		files = append(files, file)
+	}
+
+	// newest first, the gzipped files keep the time of their last entry
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	now := w.now()
+	for i, file := range files {
+		if (w.maxBackups > 0 && i >= w.maxBackups) || (w.maxAge > 0 && now.Sub(file.modTime) > w.maxAge) {
+			err = os.Remove(filepath.Join(w.dir, file.name))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// compressFile replaces name with name.gz, a failed compression leaves name as it was
+func compressFile(name string, info os.FileInfo) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(name + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+
+	_, err = io.Copy(gz, src)
+	if err != nil {
+		return err
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return err
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chtimes(name+".gz", info.ModTime(), info.ModTime())
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
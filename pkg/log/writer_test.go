@@ -0,0 +1,241 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRotatingWriterRotates(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)
+
+	w := newRotatingWriter(Options{Dir: dir})
+	w.maxSize = 100
+	w.now = func() time.Time { return now }
+
+	entry := strings.Repeat("a", 59) + "\n"
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an entry larger than the limit isn't split
+	if _, err := w.Write([]byte(strings.Repeat("b", 150) + "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := w.Write([]byte(entry)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	want := map[string]int{
+		"2026-10-19-1.log": 60,
+		"2026-10-19-2.log": 60,
+		"2026-10-19-3.log": 60,
+		"2026-10-19.log":   151,
+		"2026-10-20.log":   60,
+	}
+	if got := sizes(t, dir); !equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRotatingWriterMill(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	w := newRotatingWriter(Options{Dir: dir, MaxAge: 24 * time.Hour, MaxBackups: 3, Compress: true})
+	w.now = func() time.Time { return now }
+	w.date = "2026-10-19"
+
+	for name, age := range map[string]time.Duration{
+		"2026-10-19.log":      0,
+		"2026-10-19-2.log":    time.Hour,
+		"2026-10-19-1.log":    2 * time.Hour,
+		"2026-10-18.log.gz":   3 * time.Hour,
+		"2026-10-18-1.log":    4 * time.Hour,
+		"2026-10-17.log":      48 * time.Hour,
+		"notes.txt":           48 * time.Hour,
+		"2026-10-16.log.gzip": 72 * time.Hour,
+	} {
+		writeFile(t, filepath.Join(dir, name), name, now.Add(-age))
+	}
+
+	if err := w.millOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the active file and the files of others are left alone,
+	// the fourth newest backup is over max_backups and the one of 48 hours ago over max_age
+	want := []string{"2026-10-16.log.gzip", "2026-10-18.log.gz", "2026-10-19-1.log.gz", "2026-10-19-2.log.gz", "2026-10-19.log", "notes.txt"}
+	if got := names(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if got := read(t, filepath.Join(dir, "2026-10-19-1.log.gz")); got != "2026-10-19-1.log" {
+		t.Fatalf("expected the gzipped content, got %q", got)
+	}
+
+	// the age of a backup survives its compression
+	info, err := os.Stat(filepath.Join(dir, "2026-10-19-1.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(now.Add(-2 * time.Hour)) {
+		t.Fatalf("expected the modification time to be kept, got %s", info.ModTime())
+	}
+}
+
+func TestRotatingWriterConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+
+	milled := make(chan struct{}, 1)
+
+	w := newRotatingWriter(Options{Dir: dir, Compress: true})
+	w.maxSize = 1024
+	w.milled = func() {
+		select {
+		case milled <- struct{}{}:
+		default:
+		}
+	}
+	w.start()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Write([]byte(strings.Repeat("x", 31) + "\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	w.Close()
+
+	// the rotations of the last writes may still be compressing
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		w.mill()
+		<-milled
+
+		rotated := 0
+		for _, name := range names(t, dir) {
+			if name != w.date+".log" && filepath.Ext(name) == ".log" {
+				rotated++
+			}
+		}
+		if rotated == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected every rotated file to be gzipped, got %v", names(t, dir))
+		}
+	}
+
+	lines := 0
+	for _, name := range names(t, dir) {
+		for _, line := range strings.Split(read(t, filepath.Join(dir, name)), "\n") {
+			if line == "" {
+				continue
+			}
+			if line != strings.Repeat("x", 31) {
+				t.Fatalf("expected whole entries, got %q in %s", line, name)
+			}
+			lines++
+		}
+	}
+
+	if lines != 2000 {
+		t.Fatalf("expected 2000 entries, got %d", lines)
+	}
+}
+
+func writeFile(t *testing.T, name, content string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func sizes(t *testing.T, dir string) map[string]int {
+	t.Helper()
+
+	sizes := map[string]int{}
+	for _, name := range names(t, dir) {
+		sizes[name] = len(read(t, filepath.Join(dir, name)))
+	}
+
+	return sizes
+}
+
+func equal(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// read returns the content of name, gunzipped for a .gz
+func read(t *testing.T, name string) string {
+	t.Helper()
+
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filepath.Ext(name) != ".gz" {
+		return string(raw)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err = io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(raw)
+}
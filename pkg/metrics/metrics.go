@@ -0,0 +1,426 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType of WriteTo, the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+var (
+	// DefaultBuckets suit the latency of a request in seconds
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// Default is the registry the package functions register on and /metrics serves
+	Default = NewRegistry()
+)
+
+// ExponentialBuckets returns count upper bounds, starting at start and each factor times the one before
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+
+	return buckets
+}
+
+// Registry keeps the metric families by name and writes them sorted by name
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+type family interface {
+	describe() (typ string, labels []string)
+	write(w *bufio.Writer, name string)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]family),
+	}
+}
+
+// register returns the family already registered as name, so packages may declare the same one.
+// A family of another type or other labels under the same name is a programming error
+func (r *Registry) register(name string, f family) family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.families[name]
+	if !ok {
+		r.families[name] = f
+		return f
+	}
+
+	typ, labels := f.describe()
+	existingTyp, existingLabels := existing.describe()
+	if typ != existingTyp || strings.Join(labels, ",") != strings.Join(existingLabels, ",") {
+		panic(fmt.Sprintf("metrics: %s is already registered as a %s of %v", name, existingTyp, existingLabels))
+	}
+
+	return existing
+}
+
+// WriteTo writes every family in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	families := make(map[string]family, len(r.families))
+	for name, f := range r.families {
+		names = append(names, name)
+		families[name] = f
+	}
+	r.mu.Unlock()
+
+	sort.Strings(names)
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	for _, name := range names {
+		families[name].write(bw, name)
+	}
+
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// vec keeps the series of a family by their label values
+type vec[T any] struct {
+	help   string
+	labels []string
+	newT   func() T
+
+	mu     sync.RWMutex
+	series map[string]*series[T]
+}
+
+type series[T any] struct {
+	values []string
+	metric T
+}
+
+func newVec[T any](help string, labels []string, newT func() T) *vec[T] {
+	return &vec[T]{
+		help:   help,
+		labels: labels,
+		newT:   newT,
+		series: make(map[string]*series[T]),
+	}
+}
+
+func (v *vec[T]) with(values []string) T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values %v, got %d", len(v.labels), v.labels, len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s.metric
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if s, ok = v.series[key]; !ok {
+		// the values may live in a buffer that is reused, e.g. the method of a fiber request
+		cloned := make([]string, len(values))
+		for i, value := range values {
+			cloned[i] = strings.Clone(value)
+		}
+
+		// Join returns a single value as it is
+		s = &series[T]{values: cloned, metric: v.newT()}
+		v.series[strings.Clone(key)] = s
+	}
+
+	return s.metric
+}
+
+// sorted returns the series ordered by their label values, so a scrape reads the same every time
+func (v *vec[T]) sorted() []*series[T] {
+	v.mu.RLock()
+	all := make([]*series[T], 0, len(v.series))
+	for _, s := range v.series {
+		all = append(all, s)
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].values, "\xff") < strings.Join(all[j].values, "\xff")
+	})
+
+	return all
+}
+
+// Counter only goes up
+type Counter struct {
+	bits uint64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add ignores a negative v, a counter never goes down
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+
+	for {
+		old := atomic.LoadUint64(&c.bits)
+		if atomic.CompareAndSwapUint64(&c.bits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+type CounterVec struct {
+	*vec[*Counter]
+}
+
+// NewCounterVec registers a counter on Default
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewCounter registers a counter without labels on Default
+func NewCounter(name, help string) *Counter {
+	return NewCounterVec(name, help).With()
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newVec(help, labels, func() *Counter { return &Counter{} })}
+
+	return r.register(name, v).(*CounterVec)
+}
+
+// With returns the counter of the label values, in the order of the labels
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.with(values)
+}
+
+func (v *CounterVec) describe() (string, []string) {
+	return typeCounter, v.labels
+}
+
+func (v *CounterVec) write(w *bufio.Writer, name string) {
+	writeHeader(w, name, v.help, typeCounter)
+
+	for _, s := range v.sorted() {
+		writeSample(w, name, v.labels, s.values, "", "", s.metric.Value())
+	}
+}
+
+// Histogram counts observations in cumulative buckets
+type Histogram struct {
+	upperBounds []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	// the first bucket v fits in, the +Inf one is count itself
+	i := sort.SearchFloat64s(h.upperBounds, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+type HistogramVec struct {
+	*vec[*Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram on Default, buckets are the sorted upper bounds, DefaultBuckets when nil
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	v := &HistogramVec{
+		vec: newVec(help, labels, func() *Histogram {
+			return &Histogram{upperBounds: buckets, counts: make([]uint64, len(buckets))}
+		}),
+		buckets: buckets,
+	}
+
+	return r.register(name, v).(*HistogramVec)
+}
+
+// With returns the histogram of the label values, in the order of the labels
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.with(values)
+}
+
+func (v *HistogramVec) describe() (string, []string) {
+	return typeHistogram, v.labels
+}
+
+func (v *HistogramVec) write(w *bufio.Writer, name string) {
+	writeHeader(w, name, v.help, typeHistogram)
+
+	for _, s := range v.sorted() {
+		h := s.metric
+
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		count, sum := h.count, h.sum
+		h.mu.Unlock()
+
+		var cumulative uint64
+		for i, upperBound := range h.upperBounds {
+			cumulative += counts[i]
+			writeSample(w, name+"_bucket", v.labels, s.values, "le", formatFloat(upperBound), float64(cumulative))
+		}
+		writeSample(w, name+"_bucket", v.labels, s.values, "le", "+Inf", float64(count))
+		writeSample(w, name+"_sum", v.labels, s.values, "", "", sum)
+		writeSample(w, name+"_count", v.labels, s.values, "", "", float64(count))
+	}
+}
+
+// Sample is one series of a GaugeFunc, its label values in the order of the labels
+type Sample struct {
+	Values []string
+	Value  float64
+}
+
+// GaugeFunc reads its series when it is scraped, for a state that is kept elsewhere
+type GaugeFunc struct {
+	help    string
+	labels  []string
+	collect func() []Sample
+}
+
+// NewGaugeFunc registers a gauge on Default whose series collect returns on every scrape
+func NewGaugeFunc(name, help string, labels []string, collect func() []Sample) *GaugeFunc {
+	return Default.NewGaugeFunc(name, help, labels, collect)
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func() []Sample) *GaugeFunc {
+	g := &GaugeFunc{
+		help:    help,
+		labels:  labels,
+		collect: collect,
+	}
+
+	return r.register(name, g).(*GaugeFunc)
+}
+
+func (g *GaugeFunc) describe() (string, []string) {
+	return typeGauge, g.labels
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer, name string) {
+	writeHeader(w, name, g.help, typeGauge)
+
+	for _, s := range g.collect() {
+		if len(s.Values) != len(g.labels) {
+			continue
+		}
+
+		writeSample(w, name, g.labels, s.Values, "", "", s.Value)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	w.WriteString("# HELP " + name + " " + helpEscaper.Replace(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// writeSample writes name{labels="values",extraLabel="extraValue"} value
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + labelEscaper.Replace(values[i]) + `"`)
+		}
+
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraLabel + `="` + extraValue + `"`)
+		}
+
+		w.WriteByte('}')
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounterVec("requests_total", "Requests.", "route", "status")
+	requests.With("/b", "200").Inc()
+	requests.With("/a", "500").Add(2)
+	requests.With("/a", "500").Add(-1)
+
+	latency := r.NewHistogramVec("latency_seconds", "Latency\nof a request.", []float64{0.1, 1}, "route")
+	latency.With(`/"quoted"`).Observe(0.1)
+	latency.With(`/"quoted"`).Observe(0.5)
+	latency.With(`/"quoted"`).Observe(3)
+
+	r.NewGaugeFunc("open", "Open.", []string{"command"}, func() []Sample {
+		return []Sample{{Values: []string{"catalog"}, Value: 1}, {Values: nil, Value: 2}}
+	})
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP latency_seconds Latency\nof a request.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/\"quoted\"",le="0.1"} 1
+latency_seconds_bucket{route="/\"quoted\"",le="1"} 2
+latency_seconds_bucket{route="/\"quoted\"",le="+Inf"} 3
+latency_seconds_sum{route="/\"quoted\""} 3.6
+latency_seconds_count{route="/\"quoted\""} 3
+# HELP open Open.
+# TYPE open gauge
+open{command="catalog"} 1
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a",status="500"} 2
+requests_total{route="/b",status="200"} 1
+`
+	if b.String() != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, b.String())
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+
+	if r.NewCounterVec("total", "Total.", "a") != r.NewCounterVec("total", "Total.", "a") {
+		t.Fatal("expected the family registered first")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected other labels under the same name to panic")
+		}
+	}()
+	r.NewCounterVec("total", "Total.", "b")
+}
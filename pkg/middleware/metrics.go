@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"errors"
+	"lion-parcel-test/pkg/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec("http_requests_total",
+		"Requests handled, by method, declared route and status.",
+		"method", "route", "status")
+	httpRequestDuration = metrics.NewHistogramVec("http_request_duration_seconds",
+		"Time to handle a request, by method, declared route and status. A streamed body is sent afterwards.",
+		metrics.DefaultBuckets, "method", "route", "status")
+)
+
+// MetricsMiddleware counts the requests and times them per declared route, so /movies/:id stays one series
+func MetricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+
+	err := c.Next()
+
+	// the error handler only sets the status after the middlewares returned
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = fiber.StatusInternalServerError
+
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+
+	labels := []string{c.Method(), c.Route().Path, strconv.Itoa(status)}
+
+	httpRequests.With(labels...).Inc()
+	httpRequestDuration.With(labels...).Observe(time.Since(start).Seconds())
+
+	return err
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"lion-parcel-test/pkg/log"
+	"lion-parcel-test/pkg/tracing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxRequestIdLength keeps a caller from filling the logs through the header
+const maxRequestIdLength = 128
+
+// RequestIdMiddleware keeps the X-Request-ID of the caller or makes one up, and sends it back.
+// The logs of the request and its transaction carry it
+func RequestIdMiddleware(c *fiber.Ctx) error {
+	requestId := c.Get(fiber.HeaderXRequestID)
+	if !validRequestId(requestId) {
+		requestId = newRequestId()
+	}
+
+	c.Locals(log.RequestIdKey, requestId)
+	c.Set(fiber.HeaderXRequestID, requestId)
+
+	tracing.SetLabel(c.Context(), log.RequestIdKey, requestId)
+
+	return c.Next()
+}
+
+// validRequestId accepts printable ascii, a request id is copied into headers and log lines
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+
+	for i := 0; i < len(requestId); i++ {
+		if requestId[i] < 0x21 || requestId[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -7,23 +7,33 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func LoggingMiddleware(c *fiber.Ctx) error {
+// NewLoggingMiddleware logs the requests and responses of the sampled routes, redacted and capped by logger
+func NewLoggingMiddleware(logger *log.HttpLogger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
 
-	start := time.Now()
-	log.LogRequest(c, start)
+		start := time.Now()
 
-	// Process the request
-	c.Next()
+		// Process the request
+		c.Next()
 
-	responseStatus := c.Response().StatusCode()
-	responseBody := string(c.Response().Body()) // Capture the response body
-	responseTime := time.Since(start)
+		responseStatus := c.Response().StatusCode()
+		responseTime := time.Since(start)
 
-	// Use goroutine for response logging
-	go func() {
-		log.LogResponse(responseStatus, responseBody, responseTime)
+		// the declared route is only known once the router matched it
+		if !logger.Sampled(c.Method(), c.Route().Path, responseStatus) {
+			return nil
+		}
 
-	}()
+		responseLog := logger.LogRequest(c, start)
 
-	return nil
+		responseBody := logger.ResponseBody(c)
+
+		// Use goroutine for response logging
+		go func() {
+			log.LogResponse(responseLog, responseStatus, responseBody, responseTime)
+
+		}()
+
+		return nil
+	}
 }
@@ -0,0 +1,507 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// the reasons a schema can't be used by this binary, match them with errors.Is
+var (
+	ErrDirty     = errors.New("dirty schema")
+	ErrNewer     = errors.New("schema newer than the binary")
+	ErrChecksum  = errors.New("checksum mismatch")
+	ErrMissing   = errors.New("applied migration missing")
+	ErrPending   = errors.New("pending migrations")
+	ErrNoChanges = errors.New("no migration to roll back")
+)
+
+// file names look like 0001_initial_schema.up.sql and 0001_initial_schema.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    dirty BOOLEAN NOT NULL DEFAULT FALSE,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the sha256 of Up, an applied migration whose file changed afterwards is refused
+	Checksum string
+}
+
+// Status of one migration, known by the binary, recorded in schema_migrations or both
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+	// Mismatch means the file changed after it was applied
+	Mismatch bool
+	// Missing means the database has a version this binary has no file for
+	Missing bool
+}
+
+type applied struct {
+	version   int64
+	name      string
+	checksum  string
+	dirty     bool
+	appliedAt *time.Time
+}
+
+// Migrator applies the numbered migrations of a directory and records them in schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	// Baseline runs before the first migration of a database that has no recorded version yet,
+	// it lets a database created before the migrations existed catch up with the first one
+	Baseline func(ctx context.Context, tx *sql.Tx) error
+	// Rebind turns the ? placeholders of the bookkeeping queries into the ones of the driver, nil keeps them
+	Rebind func(query string) string
+}
+
+// New reads the migrations of fsys, every version needs an up and a down file
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Load parses the migration files in the root of fsys, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			migration.Checksum = checksum(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		if migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Latest is the version the binary expects, 0 when it has no migrations
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Check returns nil when the schema is exactly the one of the binary.
+// It is ErrPending when only migrations are missing, so the caller can decide to run Up
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = verify(statuses, m.Latest()); err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if !status.Applied {
+			return fmt.Errorf("%w, version %d_%s is not applied", ErrPending, status.Version, status.Name)
+		}
+	}
+
+	return nil
+}
+
+// verify refuses a dirty schema, a newer one and migrations that changed or disappeared after they were applied
+func verify(statuses []Status, latest int64) error {
+	for _, status := range statuses {
+		switch {
+		case status.Dirty:
+			return fmt.Errorf("%w, version %d_%s failed halfway, fix the schema by hand and run `migrate force %d` or `migrate force %d`",
+				ErrDirty, status.Version, status.Name, status.Version, status.Version-1)
+		case status.Missing && status.Version > latest:
+			return fmt.Errorf("%w, database is at version %d and the binary knows up to %d", ErrNewer, status.Version, latest)
+		case status.Missing:
+			return fmt.Errorf("%w, version %d_%s has no file", ErrMissing, status.Version, status.Name)
+		case status.Mismatch:
+			return fmt.Errorf("%w, version %d_%s changed after it was applied", ErrChecksum, status.Version, status.Name)
+		}
+	}
+
+	return nil
+}
+
+// Status lists the known and the applied migrations ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	records, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+
+		if record, ok := records[migration.Version]; ok {
+			status.Applied = true
+			status.Dirty = record.dirty
+			status.AppliedAt = record.appliedAt
+			status.Mismatch = record.checksum != migration.Checksum
+			delete(records, migration.Version)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, record := range records {
+		statuses = append(statuses, Status{
+			Version:   record.version,
+			Name:      record.name,
+			Applied:   true,
+			Dirty:     record.dirty,
+			AppliedAt: record.appliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// Up applies every pending migration in order, each one in its own transaction
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verify(statuses, m.Latest()); err != nil {
+		return nil, err
+	}
+
+	pending := map[int64]bool{}
+	fresh := true
+	for _, status := range statuses {
+		if status.Applied {
+			fresh = false
+		} else {
+			pending[status.Version] = true
+		}
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if !pending[migration.Version] {
+			continue
+		}
+
+		baseline := fresh && len(done) == 0
+		if err = m.apply(ctx, migration, baseline); err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verify(statuses, m.Latest()); err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]Migration{}
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var done []Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		if !statuses[i].Applied {
+			continue
+		}
+
+		migration := byVersion[statuses[i].Version]
+		if err = m.revert(ctx, migration); err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	if len(done) == 0 {
+		return nil, ErrNoChanges
+	}
+
+	return done, nil
+}
+
+// Force records version as the clean current schema after a dirty migration was fixed by hand,
+// versions above it are forgotten and 0 forgets them all
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, m.rebind(`DELETE FROM schema_migrations WHERE version > ?;`), version); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+
+		_, err = tx.ExecContext(ctx, m.rebind(`INSERT INTO schema_migrations (version, name, checksum, dirty) VALUES (?, ?, ?, FALSE)
+	ON CONFLICT (version) DO UPDATE SET name = excluded.name, checksum = excluded.checksum, dirty = FALSE;`),
+			migration.Version, migration.Name, migration.Checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// apply marks the version dirty first, so a crash in the middle of the migration is noticed on the next start
+func (m *Migrator) apply(ctx context.Context, migration Migration, baseline bool) error {
+	_, err := m.db.ExecContext(ctx, m.rebind(`INSERT INTO schema_migrations (version, name, checksum, dirty) VALUES (?, ?, ?, TRUE);`),
+		migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return err
+	}
+
+	err = m.inTx(ctx, func(tx *sql.Tx) error {
+		if baseline && m.Baseline != nil {
+			if err := m.Baseline(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, m.rebind(`UPDATE schema_migrations SET dirty = FALSE, applied_at = CURRENT_TIMESTAMP WHERE version = ?;`), migration.Version)
+		return err
+	})
+	if err != nil {
+		// the transaction left the schema untouched, only the marker has to go
+		m.db.ExecContext(context.WithoutCancel(ctx), m.rebind(`DELETE FROM schema_migrations WHERE version = ? AND dirty;`), migration.Version)
+		return err
+	}
+
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, migration Migration) error {
+	_, err := m.db.ExecContext(ctx, m.rebind(`UPDATE schema_migrations SET dirty = TRUE WHERE version = ?;`), migration.Version)
+	if err != nil {
+		return err
+	}
+
+	err = m.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, m.rebind(`DELETE FROM schema_migrations WHERE version = ?;`), migration.Version)
+		return err
+	})
+	if err != nil {
+		m.db.ExecContext(context.WithoutCancel(ctx), m.rebind(`UPDATE schema_migrations SET dirty = FALSE WHERE version = ?;`), migration.Version)
+		return err
+	}
+
+	return nil
+}
+
+func (m *Migrator) rebind(query string) string {
+	if m.Rebind == nil {
+		return query
+	}
+
+	return m.Rebind(query)
+}
+
+func (m *Migrator) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, createTable)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]applied, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, name, checksum, dirty, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := map[int64]applied{}
+	for rows.Next() {
+		var record applied
+
+		err = rows.Scan(&record.version, &record.name, &record.checksum, &record.dirty, &record.appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		records[record.version] = record
+	}
+
+	return records, rows.Err()
+}
+
+// Create writes an empty up and down file for name in dir, numbered after the newest one
+func Create(dir string, name string) ([]string, error) {
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := fmt.Sprintf("%04d_%s", version, name)
+	if !fileName.MatchString(base + ".up.sql") {
+		return nil, fmt.Errorf("migration name %q may only use lowercase letters, digits and underscores", name)
+	}
+
+	files := []string{
+		path.Join(dir, base+".up.sql"),
+		path.Join(dir, base+".down.sql"),
+	}
+
+	for _, file := range files {
+		if err = writeNew(file, fmt.Sprintf("-- %s\n", path.Base(file))); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// writeNew refuses to overwrite an existing file
+func writeNew(file string, content string) error {
+	out, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = out.WriteString(content)
+	return err
+}
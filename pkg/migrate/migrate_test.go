@@ -0,0 +1,208 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var migrations = fstest.MapFS{
+	"0001_create_movies.up.sql":   {Data: []byte(`CREATE TABLE movies (id INTEGER PRIMARY KEY, title TEXT NOT NULL);`)},
+	"0001_create_movies.down.sql": {Data: []byte(`DROP TABLE movies;`)},
+	"0002_add_year.up.sql":        {Data: []byte(`ALTER TABLE movies ADD COLUMN year INTEGER;`)},
+	"0002_add_year.down.sql":      {Data: []byte(`ALTER TABLE movies DROP COLUMN year;`)},
+}
+
+// with is migrations with files added, a nil file removes it
+func with(files map[string]*fstest.MapFile) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, file := range migrations {
+		fsys[name] = file
+	}
+	for name, file := range files {
+		if file == nil {
+			delete(fsys, name)
+			continue
+		}
+		fsys[name] = file
+	}
+
+	return fsys
+}
+
+// newDatabase is an in-memory sqlite database with every migration applied
+func newDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := New(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done, err := m.Up(context.Background()); err != nil || len(done) != 2 {
+		t.Fatalf("expected both migrations applied, got %v %v", done, err)
+	}
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("expected the schema of the binary, got %v", err)
+	}
+
+	return db
+}
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		tamper    string
+		fsys      fstest.MapFS
+		want      error
+		upApplies bool
+	}{
+		{
+			name:   "dirty",
+			tamper: `UPDATE schema_migrations SET dirty = TRUE WHERE version = 2;`,
+			want:   ErrDirty,
+		},
+		{
+			name:   "newer",
+			tamper: `INSERT INTO schema_migrations (version, name, checksum) VALUES (3, 'from_the_future', '');`,
+			want:   ErrNewer,
+		},
+		{
+			name:   "checksum",
+			tamper: `UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1;`,
+			want:   ErrChecksum,
+		},
+		{
+			name: "file changed",
+			fsys: with(map[string]*fstest.MapFile{
+				"0002_add_year.up.sql": {Data: []byte(`ALTER TABLE movies ADD COLUMN released INTEGER;`)},
+			}),
+			want: ErrChecksum,
+		},
+		{
+			name: "missing",
+			fsys: with(map[string]*fstest.MapFile{
+				"0001_create_movies.up.sql":   nil,
+				"0001_create_movies.down.sql": nil,
+			}),
+			want: ErrMissing,
+		},
+		{
+			name: "pending",
+			fsys: with(map[string]*fstest.MapFile{
+				"0003_add_rating.up.sql":   {Data: []byte(`ALTER TABLE movies ADD COLUMN rating REAL;`)},
+				"0003_add_rating.down.sql": {Data: []byte(`ALTER TABLE movies DROP COLUMN rating;`)},
+			}),
+			want:      ErrPending,
+			upApplies: true,
+		},
+		{
+			name:   "forgotten version",
+			tamper: `DELETE FROM schema_migrations WHERE version = 2;`,
+			want:   ErrPending,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newDatabase(t)
+
+			if tc.tamper != "" {
+				if _, err := db.Exec(tc.tamper); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fsys := migrations
+			if tc.fsys != nil {
+				fsys = tc.fsys
+			}
+			m, err := New(db, fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = m.Check(ctx); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+
+			_, err = m.Up(ctx)
+			if tc.want != ErrPending {
+				// Up refuses the same schemas and leaves them as they are
+				if !errors.Is(err, tc.want) {
+					t.Fatalf("expected Up to refuse with %v, got %v", tc.want, err)
+				}
+				if err = m.Check(ctx); !errors.Is(err, tc.want) {
+					t.Fatalf("expected the schema unchanged by Up, got %v", err)
+				}
+				return
+			}
+
+			if tc.upApplies {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = m.Check(ctx); err != nil {
+					t.Fatalf("expected the pending migration applied, got %v", err)
+				}
+				return
+			}
+
+			// the column of the forgotten version is there already, running it again fails and isn't recorded
+			if err == nil {
+				t.Fatal("expected the forgotten migration to fail on the existing column")
+			}
+			if err = m.Check(ctx); !errors.Is(err, ErrPending) {
+				t.Fatalf("expected the failed migration not to be left dirty, got %v", err)
+			}
+		})
+	}
+}
+
+func TestForce(t *testing.T) {
+	ctx := context.Background()
+	db := newDatabase(t)
+
+	m, err := New(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Exec(`UPDATE schema_migrations SET dirty = TRUE WHERE version = 2;`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Down(ctx, 1); !errors.Is(err, ErrDirty) {
+		t.Fatalf("expected Down to refuse a dirty schema, got %v", err)
+	}
+
+	// the column of 0002 was rolled back by hand, 0001 is the clean schema
+	if _, err = db.Exec(`ALTER TABLE movies DROP COLUMN year;`); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Force(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Check(ctx); !errors.Is(err, ErrPending) {
+		t.Fatalf("expected 0002 pending after forcing 0001, got %v", err)
+	}
+
+	if _, err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := m.Down(ctx, 2); err != nil || len(done) != 2 || done[0].Version != 2 {
+		t.Fatalf("expected both migrations rolled back newest first, got %v %v", done, err)
+	}
+	if _, err = m.Down(ctx, 1); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("expected nothing left to roll back, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package resp is a small client of the redis protocol (RESP2), it works with any redis compatible server
+package resp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAddr     = "localhost:6379"
+	defaultPoolSize = 10
+	defaultTimeout  = time.Second
+)
+
+var errClosed = errors.New("redis: client is closed")
+
+// Options of a Client, zero values use the defaults
+type Options struct {
+	// host:port, localhost:6379 when empty
+	Addr     string
+	Password string
+	DB       int
+	// idle connections kept open, 10 when empty
+	PoolSize int
+	// dial, read and write timeout of a command, 1s when empty
+	Timeout time.Duration
+}
+
+type Client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	// idle connections, a connection is only put back after a complete reply
+	idle   chan *conn
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewClient connects once to fail early on a wrong address or password
+func NewClient(opts Options) (*Client, error) {
+	if opts.Addr == "" {
+		opts.Addr = defaultAddr
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = defaultPoolSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	client := &Client{
+		addr:     opts.Addr,
+		password: opts.Password,
+		db:       opts.DB,
+		timeout:  opts.Timeout,
+		idle:     make(chan *conn, opts.PoolSize),
+	}
+
+	reply, err := client.Do(context.Background(), "PING")
+	if err == nil && reply != "PONG" {
+		err = fmt.Errorf("redis: unexpected PING reply %v", reply)
+	}
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to reach redis at %s: %w", opts.Addr, err)
+	}
+
+	return client, nil
+}
+
+// Do runs a command on an idle connection or a new one. The reply is a string, an int64, an []interface{}
+// of those, or ErrNil. An error reply is returned as an Error
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(c.deadline(ctx), args...)
+
+	// a connection that failed mid command is closed, its next reply couldn't be told apart from the one of the following command
+	var replyErr Error
+	if err != nil && !errors.Is(err, ErrNil) && !errors.As(err, &replyErr) {
+		cn.Close()
+		return nil, err
+	}
+
+	c.put(cn)
+
+	return reply, err
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	for {
+		select {
+		case idle := <-c.idle:
+			idle.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case idle := <-c.idle:
+		return idle, nil
+	default:
+	}
+
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, errClosed
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := newConn(netConn)
+
+	if c.password != "" {
+		if _, err := cn.do(c.deadline(ctx), "AUTH", c.password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	if c.db != 0 {
+		if _, err := cn.do(c.deadline(ctx), "SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+// put keeps the connection for the next command, unless the pool is full or closed
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		cn.Close()
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+// deadline is the timeout of a command, or the deadline of ctx when it is sooner
+func (c *Client) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+
+	return deadline
+}
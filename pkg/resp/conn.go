@@ -0,0 +1,142 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrNil is the nil reply, e.g. the one of a GET of a missing key
+var ErrNil = errors.New("redis: nil")
+
+// Error is an error reply, the connection is still usable after it
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// conn speaks RESP2 over one connection, commands are sent one at a time
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+func newConn(netConn net.Conn) *conn {
+	return &conn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+		writer:  bufio.NewWriter(netConn),
+	}
+}
+
+// do sends a command and reads its reply: a string for simple and bulk strings, an int64 for integers
+// and an []interface{} of those for arrays
+func (c *conn) do(deadline time.Time, args ...string) (interface{}, error) {
+	if err := c.netConn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := c.write(args); err != nil {
+		return nil, err
+	}
+
+	return c.read()
+}
+
+// write sends args as an array of bulk strings, which is how every command is sent
+func (c *conn) write(args []string) error {
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	return c.writer.Flush()
+}
+
+func (c *conn) read() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, ErrNil
+		}
+
+		// the string and its \r\n
+		bulk := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, bulk); err != nil {
+			return nil, err
+		}
+
+		return string(bulk[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length %q", line)
+		}
+		if size < 0 {
+			return nil, ErrNil
+		}
+
+		// nil and error elements are kept as nil and an Error, the array is read to its end either way
+		array := make([]interface{}, size)
+		for i := range array {
+			element, err := c.read()
+
+			var replyErr Error
+			switch {
+			case err == nil:
+				array[i] = element
+			case errors.Is(err, ErrNil):
+				array[i] = nil
+			case errors.As(err, &replyErr):
+				array[i] = replyErr
+			default:
+				return nil, err
+			}
+		}
+
+		return array, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func (c *conn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: invalid line %q", line)
+	}
+
+	return line[:len(line)-2], nil
+}
+
+func (c *conn) Close() error {
+	return c.netConn.Close()
+}
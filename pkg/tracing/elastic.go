@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.elastic.co/apm/module/apmfiber/v2"
+	"go.elastic.co/apm/module/apmhttp/v2"
+	"go.elastic.co/apm/v2"
+)
+
+// elasticTracer sends the traces to an Elastic APM server, configured by the ELASTIC_APM_* environment variables
+type elasticTracer struct {
+	tracer *apm.Tracer
+}
+
+// elasticSpan records the status of an external call on the apm span
+type elasticSpan struct {
+	*apm.Span
+}
+
+// NewElasticTracer uses the default apm tracer
+func NewElasticTracer() Tracer {
+	return &elasticTracer{tracer: apm.DefaultTracer()}
+}
+
+func (s elasticSpan) SetHttp(req *http.Request, res *http.Response) {
+	if s.Dropped() {
+		return
+	}
+
+	s.Context.SetHTTPRequest(req)
+	if res != nil {
+		s.Context.SetHTTPStatusCode(res.StatusCode)
+	}
+}
+
+func (t *elasticTracer) StartTransaction(ctx context.Context, name, typ string) (Span, context.Context) {
+	tx := t.tracer.StartTransaction(name, typ)
+
+	return tx, apm.ContextWithTransaction(ctx, tx)
+}
+
+func (t *elasticTracer) StartSpan(ctx context.Context, name, typ string) (Span, context.Context) {
+	span, ctx := apm.StartSpan(ctx, name, typ)
+
+	return elasticSpan{span}, ctx
+}
+
+func (t *elasticTracer) CaptureError(ctx context.Context, err error) {
+	if e := apm.CaptureError(ctx, err); e != nil {
+		e.Send()
+	}
+}
+
+func (t *elasticTracer) TraceId(ctx context.Context) string {
+	if span := apm.SpanFromContext(ctx); span != nil {
+		return span.TraceContext().Trace.String()
+	}
+
+	if tx := apm.TransactionFromContext(ctx); tx != nil {
+		return tx.TraceContext().Trace.String()
+	}
+
+	return ""
+}
+
+func (t *elasticTracer) SetLabel(ctx context.Context, key, value string) {
+	if tx := apm.TransactionFromContext(ctx); tx != nil {
+		tx.Context.SetLabel(key, value)
+	}
+}
+
+func (t *elasticTracer) SetUser(ctx context.Context, id string) {
+	if tx := apm.TransactionFromContext(ctx); tx != nil {
+		tx.Context.SetUserID(id)
+	}
+}
+
+// Middleware reads a W3C traceparent, or the elastic-apm-traceparent of an older agent
+func (t *elasticTracer) Middleware() fiber.Handler {
+	return apmfiber.Middleware(apmfiber.WithTracer(t.tracer))
+}
+
+func (t *elasticTracer) Inject(ctx context.Context, header http.Header) {
+	var traceContext apm.TraceContext
+
+	// a dropped span isn't sent, the caller's trace then continues from the transaction
+	if span := apm.SpanFromContext(ctx); span != nil && !span.Dropped() {
+		traceContext = span.TraceContext()
+	} else if tx := apm.TransactionFromContext(ctx); tx != nil {
+		traceContext = tx.TraceContext()
+	} else {
+		return
+	}
+
+	header.Set(HeaderTraceparent, apmhttp.FormatTraceparentHeader(traceContext))
+	if state := traceContext.State.String(); state != "" {
+		header.Set("tracestate", state)
+	}
+}
+
+func (t *elasticTracer) Detach(ctx context.Context) context.Context {
+	detached := context.Background()
+
+	if tx := apm.TransactionFromContext(ctx); tx != nil {
+		detached = apm.ContextWithTransaction(detached, tx)
+	}
+	if span := apm.SpanFromContext(ctx); span != nil {
+		detached = apm.ContextWithSpan(detached, span)
+	}
+
+	return detached
+}
+
+func (t *elasticTracer) Close(ctx context.Context) error {
+	t.tracer.Flush(ctx.Done())
+
+	return nil
+}
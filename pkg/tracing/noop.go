@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// noopTracer records nothing, tests and environments without a collector use it
+type noopTracer struct{}
+
+type noopSpan struct{}
+
+func NewNoopTracer() Tracer {
+	return noopTracer{}
+}
+
+func (noopSpan) End() {}
+
+func (noopTracer) StartTransaction(ctx context.Context, name, typ string) (Span, context.Context) {
+	return noopSpan{}, ctx
+}
+
+func (noopTracer) StartSpan(ctx context.Context, name, typ string) (Span, context.Context) {
+	return noopSpan{}, ctx
+}
+
+func (noopTracer) CaptureError(ctx context.Context, err error) {}
+
+func (noopTracer) TraceId(ctx context.Context) string {
+	return ""
+}
+
+func (noopTracer) SetLabel(ctx context.Context, key, value string) {}
+
+func (noopTracer) SetUser(ctx context.Context, id string) {}
+
+func (noopTracer) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Next()
+	}
+}
+
+func (noopTracer) Inject(ctx context.Context, header http.Header) {}
+
+func (noopTracer) Detach(ctx context.Context) context.Context {
+	return context.Background()
+}
+
+func (noopTracer) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,329 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OtlpOptions of the otlp tracer, the zero values of the optional fields are replaced by the defaults
+type OtlpOptions struct {
+	// base url of the collector, the spans are posted to <endpoint>/v1/traces
+	Endpoint string
+	// sent with every export, e.g. the api key of a hosted collector
+	Headers map[string]string
+	// service.name of the resource
+	ServiceName string
+	// share of the new traces recorded, 1 when 0. A caller's traceparent decides for the traces it continues
+	SampleRate float64
+	// spans sent per request, 512 when 0
+	BatchSize int
+	// spans buffered before new ones are dropped, 2048 when 0
+	QueueSize int
+	// longest a span waits to be sent, 5s when 0
+	FlushInterval time.Duration
+	// of an export request, 10s when 0
+	Timeout time.Duration
+}
+
+const (
+	defaultOtlpBatchSize     = 512
+	defaultOtlpQueueSize     = 2048
+	defaultOtlpFlushInterval = 5 * time.Second
+	defaultOtlpTimeout       = 10 * time.Second
+)
+
+// span kinds of the otlp protocol
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+// status codes of the otlp protocol
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// otlpTracer sends the spans to an OpenTelemetry collector over OTLP/HTTP in its JSON encoding
+type otlpTracer struct {
+	opts     OtlpOptions
+	exporter *otlpExporter
+}
+
+// spanKey holds the *otlpSpan of a ctx, the middleware sets it as a fiber local so c.Context() has it too
+type spanKey struct{}
+
+type otlpSpan struct {
+	tracer   *otlpTracer
+	sc       SpanContext
+	parentId [8]byte
+	// the transaction the span belongs to, the span itself for a transaction
+	root  *otlpSpan
+	name  string
+	kind  int
+	start time.Time
+
+	mu            sync.Mutex
+	end           time.Time
+	ended         bool
+	attributes    map[string]interface{}
+	events        []otlpSpanEvent
+	status        int
+	statusMessage string
+}
+
+type otlpSpanEvent struct {
+	time       time.Time
+	name       string
+	attributes map[string]interface{}
+}
+
+func NewOtlpTracer(opts OtlpOptions) (Tracer, error) {
+	if opts.Endpoint == "" {
+		return nil, errors.New("otlp endpoint is required")
+	}
+	if opts.SampleRate < 0 || opts.SampleRate > 1 {
+		return nil, fmt.Errorf("invalid sample rate %v, expected between 0 and 1", opts.SampleRate)
+	}
+	if opts.SampleRate == 0 {
+		opts.SampleRate = 1
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = defaultOtlpBatchSize
+	}
+	if opts.QueueSize == 0 {
+		opts.QueueSize = defaultOtlpQueueSize
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = defaultOtlpFlushInterval
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultOtlpTimeout
+	}
+
+	t := &otlpTracer{opts: opts}
+	t.exporter = newOtlpExporter(opts)
+
+	return t, nil
+}
+
+// start begins a span under parent, or a transaction continuing remote when parent is nil
+func (t *otlpTracer) start(parent *otlpSpan, remote SpanContext, name, typ string) *otlpSpan {
+	span := &otlpSpan{
+		tracer:     t,
+		name:       name,
+		kind:       spanKind(typ),
+		start:      time.Now(),
+		attributes: map[string]interface{}{"span.type": typ},
+	}
+
+	switch {
+	case parent != nil:
+		span.sc.TraceId = parent.sc.TraceId
+		span.sc.Sampled = parent.sc.Sampled
+		span.parentId = parent.sc.SpanId
+		span.root = parent.root
+	case remote.IsValid():
+		span.sc.TraceId = remote.TraceId
+		span.sc.Sampled = remote.Sampled
+		span.parentId = remote.SpanId
+	default:
+		rand.Read(span.sc.TraceId[:])
+		span.sc.Sampled = mrand.Float64() < t.opts.SampleRate
+	}
+
+	rand.Read(span.sc.SpanId[:])
+
+	if span.root == nil {
+		span.root = span
+	}
+
+	return span
+}
+
+// spanKind reads the kind from the type, e.g. external.http calls out of the service
+func spanKind(typ string) int {
+	switch {
+	case typ == TypeRequest:
+		return spanKindServer
+	case strings.HasPrefix(typ, "external"):
+		return spanKindClient
+	}
+
+	return spanKindInternal
+}
+
+func spanFromContext(ctx context.Context) *otlpSpan {
+	span, _ := ctx.Value(spanKey{}).(*otlpSpan)
+	return span
+}
+
+func (t *otlpTracer) StartTransaction(ctx context.Context, name, typ string) (Span, context.Context) {
+	span := t.start(nil, SpanContext{}, name, typ)
+
+	return span, context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t *otlpTracer) StartSpan(ctx context.Context, name, typ string) (Span, context.Context) {
+	parent := spanFromContext(ctx)
+	if parent == nil {
+		return noopSpan{}, ctx
+	}
+
+	span := t.start(parent, SpanContext{}, name, typ)
+
+	return span, context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t *otlpTracer) CaptureError(ctx context.Context, err error) {
+	if span := spanFromContext(ctx); span != nil && err != nil {
+		span.recordError(err)
+	}
+}
+
+func (t *otlpTracer) TraceId(ctx context.Context) string {
+	if span := spanFromContext(ctx); span != nil {
+		return hex.EncodeToString(span.sc.TraceId[:])
+	}
+
+	return ""
+}
+
+func (t *otlpTracer) SetLabel(ctx context.Context, key, value string) {
+	if span := spanFromContext(ctx); span != nil {
+		span.root.setAttribute(key, value)
+	}
+}
+
+func (t *otlpTracer) SetUser(ctx context.Context, id string) {
+	if span := spanFromContext(ctx); span != nil {
+		span.root.setAttribute("enduser.id", id)
+	}
+}
+
+// Middleware names the transaction after the declared route once the router matched it
+func (t *otlpTracer) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		remote, _ := ParseTraceparent(c.Get(HeaderTraceparent))
+
+		// the strings of fiber point into a buffer that is reused for the next request
+		method := strings.Clone(c.Method())
+
+		span := t.start(nil, remote, method+" "+strings.Clone(c.Path()), TypeRequest)
+		c.Locals(spanKey{}, span)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		route := strings.Clone(c.Route().Path)
+
+		span.mu.Lock()
+		span.name = method + " " + route
+		span.attributes["http.request.method"] = method
+		span.attributes["http.route"] = route
+		span.attributes["url.path"] = strings.Clone(c.Path())
+		span.attributes["http.response.status_code"] = status
+		if status >= fiber.StatusInternalServerError {
+			span.status = statusError
+		}
+		span.mu.Unlock()
+
+		span.End()
+
+		return err
+	}
+}
+
+func (t *otlpTracer) Inject(ctx context.Context, header http.Header) {
+	if span := spanFromContext(ctx); span != nil {
+		header.Set(HeaderTraceparent, span.sc.Traceparent())
+	}
+}
+
+func (t *otlpTracer) Detach(ctx context.Context) context.Context {
+	if span := spanFromContext(ctx); span != nil {
+		return context.WithValue(context.Background(), spanKey{}, span)
+	}
+
+	return context.Background()
+}
+
+func (t *otlpTracer) Close(ctx context.Context) error {
+	return t.exporter.close(ctx)
+}
+
+// End hands a sampled span to the exporter, ending it again does nothing
+func (s *otlpSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.exporter.enqueue(s)
+	}
+}
+
+func (s *otlpSpan) SetHttp(req *http.Request, res *http.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attributes["http.request.method"] = req.Method
+	s.attributes["url.full"] = req.URL.Redacted()
+	s.attributes["server.address"] = req.URL.Hostname()
+
+	// a client span fails on a 4xx too, the call didn't do what it was meant to
+	if res != nil {
+		s.attributes["http.response.status_code"] = res.StatusCode
+		if res.StatusCode >= http.StatusBadRequest {
+			s.status = statusError
+		}
+	}
+}
+
+func (s *otlpSpan) setAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attributes[key] = value
+}
+
+func (s *otlpSpan) recordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, otlpSpanEvent{
+		time: time.Now(),
+		name: "exception",
+		attributes: map[string]interface{}{
+			"exception.type":    fmt.Sprintf("%T", err),
+			"exception.message": err.Error(),
+		},
+	})
+	s.status = statusError
+	s.statusMessage = err.Error()
+}
@@ -0,0 +1,324 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// otlpExporter batches the ended spans and posts them to the collector from a goroutine of its own.
+// When the collector can't keep up the spans that don't fit the queue are dropped, a request never waits for it
+type otlpExporter struct {
+	opts   OtlpOptions
+	url    string
+	client *http.Client
+
+	queue   chan *otlpSpan
+	flushCh chan chan struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+func newOtlpExporter(opts OtlpOptions) *otlpExporter {
+	e := &otlpExporter{
+		opts: opts,
+		url:  strings.TrimSuffix(opts.Endpoint, "/") + "/v1/traces",
+		// not traced itself, an export would otherwise trace the next one
+		client:  &http.Client{Timeout: opts.Timeout},
+		queue:   make(chan *otlpSpan, opts.QueueSize),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+func (e *otlpExporter) enqueue(span *otlpSpan) {
+	select {
+	case <-e.done:
+	case e.queue <- span:
+	default:
+		// the queue is full, the span is dropped
+	}
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*otlpSpan, 0, e.opts.BatchSize)
+
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := e.export(batch); err != nil {
+			// the logger can't be used here, it reads the trace ids from this package
+			fmt.Fprintf(os.Stderr, "tracing: failed to export %d spans: %s\n", len(batch), err)
+		}
+
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.opts.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case flushed := <-e.flushCh:
+			e.drain(&batch, send)
+			send()
+			close(flushed)
+		case <-e.done:
+			e.drain(&batch, send)
+			send()
+			return
+		}
+	}
+}
+
+// drain moves the spans queued so far into batch
+func (e *otlpExporter) drain(batch *[]*otlpSpan, send func()) {
+	for {
+		select {
+		case span := <-e.queue:
+			*batch = append(*batch, span)
+			if len(*batch) >= e.opts.BatchSize {
+				send()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// close sends the queued spans and stops, spans ended afterwards are dropped
+func (e *otlpExporter) close(ctx context.Context) error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush sends the spans queued so far, the tests wait on it
+func (e *otlpExporter) flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	select {
+	case e.flushCh <- flushed:
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *otlpExporter) export(batch []*otlpSpan) error {
+	body, err := jsoniter.Marshal(e.request(batch))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.opts.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("collector answered %d: %s", res.StatusCode, msg)
+	}
+
+	io.Copy(io.Discard, res.Body)
+
+	return nil
+}
+
+// the JSON encoding of an ExportTraceServiceRequest: ids are hex, 64 bit integers and times are strings
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope      `json:"scope"`
+	Spans []otlpSpanData `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpanData struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue  `json:"attributes,omitempty"`
+	Events            []otlpEventData `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpEventData struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+const otlpScopeName = "lion-parcel-test/pkg/tracing"
+
+func (e *otlpExporter) request(batch []*otlpSpan) otlpRequest {
+	spans := make([]otlpSpanData, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, span.data())
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: keyValues(map[string]interface{}{"service.name": e.opts.ServiceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: otlpScopeName},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func (s *otlpSpan) data() otlpSpanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := otlpSpanData{
+		TraceId:           hex.EncodeToString(s.sc.TraceId[:]),
+		SpanId:            hex.EncodeToString(s.sc.SpanId[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(s.end),
+		Attributes:        keyValues(s.attributes),
+		Status:            otlpStatus{Code: s.status, Message: s.statusMessage},
+	}
+
+	if s.parentId != [8]byte{} {
+		data.ParentSpanId = hex.EncodeToString(s.parentId[:])
+	}
+
+	for _, event := range s.events {
+		data.Events = append(data.Events, otlpEventData{
+			TimeUnixNano: unixNano(event.time),
+			Name:         event.name,
+			Attributes:   keyValues(event.attributes),
+		})
+	}
+
+	return data
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// keyValues sorts the attributes by key, so the same span encodes the same way
+func keyValues(attributes map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		var value otlpAnyValue
+
+		switch v := attributes[key].(type) {
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+
+		values = append(values, otlpKeyValue{Key: key, Value: value})
+	}
+
+	return values
+}
@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderTraceparent carries the trace of a request between services, https://www.w3.org/TR/trace-context/
+const HeaderTraceparent = "traceparent"
+
+// SpanContext identifies a span across services
+type SpanContext struct {
+	TraceId [16]byte
+	SpanId  [8]byte
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceId != [16]byte{} && sc.SpanId != [8]byte{}
+}
+
+// Traceparent formats sc as 00-<trace id>-<span id>-<flags>
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + hex.EncodeToString(sc.TraceId[:]) + "-" + hex.EncodeToString(sc.SpanId[:]) + "-" + flags
+}
+
+// ParseTraceparent reads a version 00 traceparent, and the start of a later version as the spec asks
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent %q", value)
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return sc, fmt.Errorf("invalid traceparent version %q", parts[0])
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, fmt.Errorf("invalid traceparent flags %q", parts[3])
+	}
+
+	if _, err = hex.Decode(sc.TraceId[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("invalid trace id %q", parts[1])
+	}
+	if _, err = hex.Decode(sc.SpanId[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("invalid span id %q", parts[2])
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent %q, the ids can't be zero", value)
+	}
+
+	sc.Sampled = flags[0]&1 == 1
+
+	return sc, nil
+}
+
+// httpSpan is a span that records the outbound call it traces, res is nil when the call failed
+type httpSpan interface {
+	SetHttp(req *http.Request, res *http.Response)
+}
+
+// Transport starts an external span per request and sends the traceparent of that span along
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// WrapTransport traces the requests of base, http.DefaultTransport when nil
+func WrapTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span, ctx := StartSpan(req.Context(), req.Method+" "+req.URL.Host, TypeExternal)
+	defer span.End()
+
+	// a RoundTripper may not modify the request it was given
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	res, err := t.Base.RoundTrip(req)
+	if err != nil {
+		CaptureError(ctx, err)
+	}
+
+	if span, ok := span.(httpSpan); ok {
+		span.SetHttp(req, res)
+	}
+
+	return res, err
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// span types, the first part of the type picks the kind of an otlp span
+const (
+	TypeRequest   = "request"
+	TypeScheduler = "scheduler"
+	TypeExternal  = "external.http"
+)
+
+// Span is ended by whoever started it, usually deferred right after
+type Span interface {
+	End()
+}
+
+// Tracer is a tracing backend, the layers reach it through the package functions
+type Tracer interface {
+	// StartTransaction starts a trace of its own, for work that isn't a request, e.g. a scheduled job
+	StartTransaction(ctx context.Context, name, typ string) (Span, context.Context)
+	// StartSpan starts a child of the span of ctx, a span without a parent isn't recorded
+	StartSpan(ctx context.Context, name, typ string) (Span, context.Context)
+	// CaptureError reports err on the span of ctx
+	CaptureError(ctx context.Context, err error)
+	// TraceId is the hex id of the trace of ctx, empty when ctx isn't traced
+	TraceId(ctx context.Context) string
+	// SetLabel and SetUser annotate the transaction of ctx
+	SetLabel(ctx context.Context, key, value string)
+	SetUser(ctx context.Context, id string)
+	// Middleware starts the transaction of a request, continuing the trace of its traceparent header
+	Middleware() fiber.Handler
+	// Inject sets the traceparent header of an outbound request to the span of ctx
+	Inject(ctx context.Context, header http.Header)
+	// Detach is a context.Background with the span of ctx, for work that outlives the request of ctx
+	Detach(ctx context.Context) context.Context
+	// Close sends the spans that are still buffered
+	Close(ctx context.Context) error
+}
+
+var (
+	mu     sync.RWMutex
+	tracer Tracer = NewNoopTracer()
+)
+
+// SetTracer replaces the tracer of the package functions, the noop one until it is called
+func SetTracer(t Tracer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	tracer = t
+}
+
+func current() Tracer {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return tracer
+}
+
+func StartTransaction(ctx context.Context, name, typ string) (Span, context.Context) {
+	return current().StartTransaction(ctx, name, typ)
+}
+
+func StartSpan(ctx context.Context, name, typ string) (Span, context.Context) {
+	return current().StartSpan(ctx, name, typ)
+}
+
+func CaptureError(ctx context.Context, err error) {
+	current().CaptureError(ctx, err)
+}
+
+func TraceId(ctx context.Context) string {
+	return current().TraceId(ctx)
+}
+
+func SetLabel(ctx context.Context, key, value string) {
+	current().SetLabel(ctx, key, value)
+}
+
+func SetUser(ctx context.Context, id string) {
+	current().SetUser(ctx, id)
+}
+
+// Middleware is the one of the tracer set at the time, register it after SetTracer
+func Middleware() fiber.Handler {
+	return current().Middleware()
+}
+
+func Inject(ctx context.Context, header http.Header) {
+	current().Inject(ctx, header)
+}
+
+func Detach(ctx context.Context) context.Context {
+	return current().Detach(ctx)
+}
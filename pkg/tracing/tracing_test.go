@@ -0,0 +1,310 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		// a later version may add fields after the flags
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	} {
+		sc, err := ParseTraceparent(tc.value)
+		if (err == nil) != tc.valid {
+			t.Fatalf("%q: expected valid %v, got %v", tc.value, tc.valid, err)
+		}
+		if !tc.valid {
+			continue
+		}
+
+		if sc.Sampled != tc.sampled {
+			t.Fatalf("%q: expected sampled %v", tc.value, tc.sampled)
+		}
+		if got := sc.Traceparent(); got[3:52] != tc.value[3:52] {
+			t.Fatalf("%q: formatted as %q", tc.value, got)
+		}
+	}
+}
+
+// collector is a fake OTLP/HTTP receiver
+type collector struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	spans   []otlpSpanData
+	headers http.Header
+	service string
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var req otlpRequest
+		if err := jsoniter.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.headers = r.Header
+		for _, resource := range req.ResourceSpans {
+			c.service = *resource.Resource.Attributes[0].Value.StringValue
+			for _, scope := range resource.ScopeSpans {
+				c.spans = append(c.spans, scope.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func (c *collector) span(t *testing.T, name string) otlpSpanData {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	t.Fatalf("no span %q in %+v", name, c.spans)
+	return otlpSpanData{}
+}
+
+func attribute(span otlpSpanData, key string) string {
+	for _, kv := range span.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		if kv.Value.StringValue != nil {
+			return *kv.Value.StringValue
+		}
+		if kv.Value.IntValue != nil {
+			return *kv.Value.IntValue
+		}
+	}
+
+	return ""
+}
+
+func TestOtlpTracer(t *testing.T) {
+	c := newCollector(t)
+
+	tracer, err := NewOtlpTracer(OtlpOptions{Endpoint: c.URL, ServiceName: "movies", Headers: map[string]string{"Api-Key": "k"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTracer(tracer)
+	defer SetTracer(NewNoopTracer())
+
+	// a service the handler calls, it gets the traceparent of the outbound span
+	var downstream string
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get(HeaderTraceparent)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer service.Close()
+
+	client := &http.Client{Transport: WrapTransport(nil)}
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/movies/:id", func(c *fiber.Ctx) error {
+		SetUser(c.Context(), "7")
+
+		span, ctx := StartSpan(c.Context(), "GetMovie", "usecase")
+		defer span.End()
+
+		CaptureError(ctx, errors.New("not found"))
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, service.URL, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+
+		return c.SendString(TraceId(ctx))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/movies/1", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+
+	if string(body) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected the trace of the caller, got %q", body)
+	}
+
+	if err = tracer.(*otlpTracer).exporter.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	request := c.span(t, "GET /movies/:id")
+	usecase := c.span(t, "GetMovie")
+	external := c.span(t, "GET "+strings.TrimPrefix(service.URL, "http://"))
+
+	if request.ParentSpanId != "00f067aa0ba902b7" || request.Kind != spanKindServer {
+		t.Fatalf("expected the request to continue the caller's span, got %+v", request)
+	}
+	if attribute(request, "http.response.status_code") != "200" || attribute(request, "enduser.id") != "7" || attribute(request, "url.path") != "/movies/1" {
+		t.Fatalf("unexpected request attributes %+v", request.Attributes)
+	}
+
+	if usecase.ParentSpanId != request.SpanId || usecase.TraceId != request.TraceId || usecase.Kind != spanKindInternal {
+		t.Fatalf("expected the usecase under the request, got %+v", usecase)
+	}
+	if usecase.Status.Code != statusError || len(usecase.Events) != 1 || usecase.Events[0].Name != "exception" {
+		t.Fatalf("expected the captured error, got %+v", usecase)
+	}
+
+	if external.ParentSpanId != usecase.SpanId || external.Kind != spanKindClient || external.Status.Code != statusError || attribute(external, "http.response.status_code") != "502" {
+		t.Fatalf("unexpected external span %+v", external)
+	}
+	if downstream != "00-"+external.TraceId+"-"+external.SpanId+"-01" {
+		t.Fatalf("expected the traceparent of the external span, got %q", downstream)
+	}
+
+	if c.service != "movies" || c.headers.Get("Api-Key") != "k" {
+		t.Fatalf("unexpected export %q %v", c.service, c.headers)
+	}
+}
+
+func TestOtlpTracerSampling(t *testing.T) {
+	c := newCollector(t)
+
+	tracer, err := NewOtlpTracer(OtlpOptions{Endpoint: c.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the caller decided not to record the trace, its traceparent is still passed on
+	span := tracer.(*otlpTracer).start(nil, SpanContext{TraceId: [16]byte{1}, SpanId: [8]byte{2}}, "unsampled", TypeRequest)
+	ctx := context.WithValue(context.Background(), spanKey{}, span)
+
+	child, ctx := tracer.StartSpan(ctx, "child", "usecase")
+	header := http.Header{}
+	tracer.Inject(ctx, header)
+	child.End()
+	span.End()
+
+	if !strings.HasSuffix(header.Get(HeaderTraceparent), "-00") {
+		t.Fatalf("expected an unsampled traceparent, got %q", header.Get(HeaderTraceparent))
+	}
+
+	// a span without a transaction isn't recorded
+	orphan, orphanCtx := tracer.StartSpan(context.Background(), "orphan", "usecase")
+	orphan.End()
+	if orphanCtx != context.Background() {
+		t.Fatal("expected the ctx of an orphan span to be left alone")
+	}
+
+	tx, _ := tracer.StartTransaction(context.Background(), "job", TypeScheduler)
+	tx.End()
+
+	// Close sends what is queued, long before the flush interval
+	if err = tracer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.spans) != 1 || c.spans[0].Name != "job" || c.spans[0].ParentSpanId != "" {
+		t.Fatalf("expected the job only, got %+v", c.spans)
+	}
+}
+
+func TestOtlpDetach(t *testing.T) {
+	c := newCollector(t)
+
+	tracer, err := NewOtlpTracer(OtlpOptions{Endpoint: c.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTracer(tracer)
+	defer SetTracer(NewNoopTracer())
+
+	// a streamed body is written after the handler returned and the request ctx was handed back
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/export", func(c *fiber.Ctx) error {
+		ctx := Detach(c.Context())
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			span, ctx := StartSpan(ctx, "Export", "usecase")
+			defer span.End()
+
+			w.WriteString(TraceId(ctx))
+		})
+
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+
+	if string(body) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected the trace of the request in the stream, got %q", body)
+	}
+
+	if err = tracer.(*otlpTracer).exporter.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	request := c.span(t, "GET /export")
+	export := c.span(t, "Export")
+	if export.ParentSpanId != request.SpanId || export.TraceId != request.TraceId {
+		t.Fatalf("expected the stream under the request, got %+v", export)
+	}
+
+	if Detach(context.Background()) != context.Background() {
+		t.Fatal("expected an untraced ctx to detach to context.Background")
+	}
+}